@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// blockUsage describes the block subcommands
+const blockUsage = "usage: block add [-reason text] [-tombstone] <domain> | block remove <domain> | block list"
+
+// runBlockCommand manages the persistent domain blocklist
+func runBlockCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(blockUsage)
+	}
+
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("block add", flag.ContinueOnError)
+		reason := fs.String("reason", "", "why the domain is blocked")
+		tombstone := fs.Bool("tombstone", false, "also hide existing nodes for the domain and its subdomains")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf(blockUsage)
+		}
+
+		domain := strings.ToLower(fs.Arg(0))
+		if err := store.BlockDomain(domain, *reason); err != nil {
+			return err
+		}
+		logrus.Infof("Blocked %s (running crawls pick this up within a minute)", domain)
+
+		if *tombstone {
+			count, err := store.TombstoneDomain(domain)
+			if err != nil {
+				return err
+			}
+			logrus.Infof("Tombstoned %d existing nodes", count)
+		}
+		return nil
+
+	case "remove":
+		if len(args) != 2 {
+			return fmt.Errorf(blockUsage)
+		}
+		removed, err := store.UnblockDomain(args[1])
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("domain %q is not blocked", args[1])
+		}
+		logrus.Infof("Unblocked %s", args[1])
+		return nil
+
+	case "list":
+		blocked, err := store.ListBlockedDomains()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DOMAIN\tBLOCKED AT\tREASON")
+		for _, b := range blocked {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", b.Domain, b.CreatedAt.Format("2006-01-02 15:04"), b.Reason)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown block command %q", args[0])
+	}
+}
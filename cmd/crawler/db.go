@@ -0,0 +1,348 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/crawler"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const dbUsage = "usage: db backup <dest> | db recompute-depths [seed-domain...] | db sessions | db runs | db import-seeds [-limit n] <file|-> | db seeds | db errors [-category c] [-domain d] [-limit n] | db https | db queue-export [-o file] | db queue-import <file|-> | db merge <db>..."
+
+// runDBCommand handles the `db` subcommands operating on the crawl database
+func runDBCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(dbUsage)
+	}
+
+	switch args[0] {
+	case "backup":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: db backup <dest>")
+		}
+		return backupDatabase(cfg, args[1])
+	case "recompute-depths":
+		return recomputeDepths(cfg, args[1:])
+	case "sessions":
+		return listSessions(cfg)
+	case "runs":
+		return listRuns(cfg)
+	case "import-seeds":
+		return importSeeds(cfg, args[1:])
+	case "seeds":
+		return listSeeds(cfg)
+	case "errors":
+		return listErrors(cfg, args[1:])
+	case "https":
+		return reportHTTPSAdoption(cfg)
+	case "queue-export":
+		return exportQueue(cfg, args[1:])
+	case "queue-import":
+		return importQueue(cfg, args[1:])
+	case "merge":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: db merge <db>...")
+		}
+		return mergeDatabases(cfg, args[1:])
+	default:
+		return fmt.Errorf("unknown db command %q", args[0])
+	}
+}
+
+// backupDatabase snapshots the configured database while a crawl may be running
+func backupDatabase(cfg *config.Config, dest string) error {
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	logrus.Infof("Backing up %s to %s...", cfg.DBPath, dest)
+
+	pages, err := store.Backup(dest)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Backup complete: %d pages written to %s", pages, dest)
+	return nil
+}
+
+// mergeDatabases copies the session's graph from other databases into the
+// configured one, such as those of processes that shared a Redis frontier
+func mergeDatabases(cfg *config.Config, paths []string) error {
+	target, err := filepath.Abs(cfg.DBPath)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		if abs, err := filepath.Abs(path); err == nil && abs == target {
+			return fmt.Errorf("%s is the configured database", path)
+		}
+		// Opening the database brings its schema up to date
+		src, err := storage.NewStorage(path, cfg.Session)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		src.Close()
+	}
+
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	for _, path := range paths {
+		stats, err := store.Merge(path)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Merged %s: %d nodes, %d edges, %d errors", path, stats.Nodes, stats.Edges, stats.Errors)
+	}
+	return nil
+}
+
+// recomputeDepths rewrites last_depth as the BFS distance from the seeds
+// Defaults to the configured seeds, seed_file included, when no seed
+// domains are given; configured seeds never crawled are left out
+func recomputeDepths(cfg *config.Config, seeds []string) error {
+	configured := len(seeds) == 0
+	if configured {
+		var err error
+		if seeds, _, err = crawler.StartSeeds(cfg, nil); err != nil {
+			return err
+		}
+	}
+
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	var seedIDs []int
+	found := seeds[:0]
+	for _, domain := range seeds {
+		node, err := store.GetNode(strings.ToLower(domain))
+		if err != nil {
+			return err
+		}
+		if node == nil {
+			if configured {
+				continue
+			}
+			return fmt.Errorf("seed domain %q not found in database", domain)
+		}
+		seedIDs = append(seedIDs, node.NodeID)
+		found = append(found, domain)
+	}
+	if len(seedIDs) == 0 {
+		return fmt.Errorf("none of the configured seeds is in the database")
+	}
+	// Seed lists may hold thousands of roots; name only the first ones
+	shown := strings.Join(found[:min(len(found), 10)], ", ")
+	if len(found) > 10 {
+		shown += ", ..."
+	}
+	logrus.Infof("Recomputing depths from %d seed(s): %s", len(seedIDs), shown)
+
+	stats, err := store.RecomputeDepths(seedIDs)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Depths recomputed: %d reachable, %d updated, %d unreachable (left unchanged)",
+		stats.Reachable, stats.Changed, stats.Unreachable)
+	return nil
+}
+
+// listSessions prints every crawl session stored in the database
+func listSessions(cfg *config.Config) error {
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	sessions, err := store.ListSessions()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(sessions))
+	for name := range sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SESSION\tNODES\t")
+	for _, name := range names {
+		marker := ""
+		if name == cfg.Session {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", name, sessions[name], marker)
+	}
+	return w.Flush()
+}
+
+// listRuns prints the crawl runs recorded for the current session
+func listRuns(cfg *config.Config) error {
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	runs, err := store.ListRuns()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN\tSTARTED\tFINISHED\tREASON\tMETRICS\t")
+	for _, run := range runs {
+		finished, reason := "-", run.TerminationReason
+		if !run.FinishedAt.IsZero() {
+			finished = run.FinishedAt.Format(time.RFC3339)
+		}
+		if reason == "" {
+			reason = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", run.RunID,
+			run.StartedAt.Format(time.RFC3339), finished, reason, run.MetricsPath)
+	}
+	return w.Flush()
+}
+
+// listSeeds prints the session's seeds with the number of nodes each was
+// first to reach
+func listSeeds(cfg *config.Config) error {
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	seeds, err := store.ListSeeds()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEED	NODES	CRAWLED	")
+	for _, seed := range seeds {
+		fmt.Fprintf(w, "%s\t%d\t%d\t\n", seed.Node.DomainName, seed.Attributed, seed.Node.CrawlCount)
+	}
+	return w.Flush()
+}
+
+// listErrors prints a per-category summary of the session's fetch errors
+// followed by the most recent ones matching the flags
+func listErrors(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("db errors", flag.ContinueOnError)
+	category := fs.String("category", "", "only errors of this category ("+strings.Join(storage.ErrorCategories, ", ")+")")
+	domain := fs.String("domain", "", "only errors fetching this domain")
+	limit := fs.Int("limit", 50, "print at most this many errors (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: db errors [-category c] [-domain d] [-limit n]")
+	}
+	if *category != "" && !slices.Contains(storage.ErrorCategories, *category) {
+		return fmt.Errorf("unknown error category %q (supported: %s)", *category, strings.Join(storage.ErrorCategories, ", "))
+	}
+
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	counts, err := store.CountFetchErrors()
+	if err != nil {
+		return err
+	}
+	records, err := store.ListFetchErrors(storage.FetchErrorFilter{
+		Domain:   strings.ToLower(*domain),
+		Category: *category,
+	}, *limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CATEGORY\tERRORS\t")
+	for _, c := range storage.ErrorCategories {
+		if counts[c] > 0 {
+			fmt.Fprintf(w, "%s\t%d\t\n", c, counts[c])
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "TIME\tDOMAIN\tCATEGORY\tSTATUS\tATTEMPT\tMESSAGE\t")
+	for _, r := range records {
+		status := "-"
+		if r.StatusCode != 0 {
+			status = fmt.Sprint(r.StatusCode)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t\n", r.OccurredAt.Format(time.RFC3339),
+			r.Domain, r.Category, status, r.Attempt, r.Message)
+	}
+	return w.Flush()
+}
+
+// reportHTTPSAdoption prints how the session's live domains split by the
+// schemes they were observed to serve
+func reportHTTPSAdoption(cfg *config.Config) error {
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	adoption, err := store.HTTPSAdoption()
+	if err != nil {
+		return err
+	}
+
+	percent := func(n int) string {
+		if adoption.Domains == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(adoption.Domains))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCHEMES\tDOMAINS\tSHARE\t")
+	for _, row := range []struct {
+		label string
+		count int
+	}{
+		{"https, http redirects to https", adoption.Redirecting},
+		{"https and http", adoption.Both},
+		{"https only", adoption.HTTPSOnly},
+		{"https, http not probed", adoption.HTTPSUnprobed},
+		{"http only", adoption.HTTPOnly},
+		{"neither", adoption.Neither},
+	} {
+		fmt.Fprintf(w, "%s\t%d\t%s\t\n", row.label, row.count, percent(row.count))
+	}
+	fmt.Fprintf(w, "total\t%d\t\t\n", adoption.Domains)
+	return w.Flush()
+}
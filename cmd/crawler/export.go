@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/export"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// graphFlags are the output flags shared by the export and sample commands
+type graphFlags struct {
+	format     *string
+	output     *string
+	edgeTypes  *string
+	categories *string
+	opts       export.Options
+}
+
+// addGraphFlags registers the output flags on fs; formatHelp describes the
+// accepted formats
+func addGraphFlags(fs *flag.FlagSet, formatHelp string) *graphFlags {
+	f := &graphFlags{
+		format:     fs.String("format", "cytoscape", "output format: "+formatHelp),
+		output:     fs.String("o", "", "output file, compressed when ending in .gz or .zst (default: stdout)"),
+		edgeTypes:  fs.String("edge-types", "", "comma-separated edge types to include: "+strings.Join(storage.EdgeTypes, ", ")+" (default: all)"),
+		categories: fs.String("category", "", "comma-separated node categories to include: "+strings.Join(storage.Categories, ", ")+" (default: all, classified or not)"),
+	}
+	fs.BoolVar(&f.opts.LogWeights, "log-weights", false, "export 1 + log2(weight) instead of raw edge weights")
+	fs.IntVar(&f.opts.MinWeight, "min-weight", 0, "drop edges with a lower weight")
+	fs.IntVar(&f.opts.TopK, "top-k", 0, "keep only each node's K heaviest outgoing edges (0 = all)")
+	return f
+}
+
+// parseSelection validates the parsed flags and returns the selected edge
+// types and node categories
+func (f *graphFlags) parseSelection() (edgeTypes, categories []string, err error) {
+	if f.opts.MinWeight < 0 || f.opts.TopK < 0 {
+		return nil, nil, fmt.Errorf("-min-weight and -top-k must be >= 0")
+	}
+	if edgeTypes, err = storage.ParseEdgeTypes(*f.edgeTypes); err != nil {
+		return nil, nil, err
+	}
+	if categories, err = storage.ParseCategories(*f.categories); err != nil {
+		return nil, nil, err
+	}
+	return edgeTypes, categories, nil
+}
+
+// runExportCommand writes the crawl graph in a visualization-friendly format
+func runExportCommand(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags := addGraphFlags(fs, strings.Join(export.Formats(), ", ")+", duckdb (SQL script)")
+	ego := fs.String("ego", "", "export only the neighborhood of this domain")
+	radius := fs.Int("radius", 1, "hops around -ego included in the export")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	edgeTypes, categories, err := flags.parseSelection()
+	if err != nil {
+		return err
+	}
+	if *flags.format == "duckdb" && (flags.opts != export.Options{}) {
+		return fmt.Errorf("-log-weights, -min-weight, and -top-k are not supported with -format duckdb; use its views instead")
+	}
+	if *flags.format == "duckdb" && *ego != "" {
+		return fmt.Errorf("-ego is not supported with -format duckdb")
+	}
+
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	var graph export.Graph = export.StoreGraph{Store: store, EdgeTypes: edgeTypes, Categories: categories}
+	if *ego != "" {
+		center, err := nodeIDOf(store, *ego)
+		if err != nil {
+			return err
+		}
+		graph, err = export.Sample(graph, export.SampleOptions{Method: export.SampleEgo, Center: center, Radius: *radius})
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeGraphOutput(*flags.output, *flags.format, func(w io.Writer) error {
+		if *flags.format == "duckdb" {
+			// DuckDB reads the database itself; the script just needs its location
+			dbPath, err := filepath.Abs(cfg.DBPath)
+			if err != nil {
+				return fmt.Errorf("failed to resolve database path: %w", err)
+			}
+			return export.WriteDuckDBScript(w, dbPath, cfg.Session, edgeTypes, categories)
+		}
+		return export.Write(w, *flags.format, graph, flags.opts)
+	})
+}
+
+// writeGraphOutput runs write against the output file (stdout if empty),
+// compressing by file extension
+func writeGraphOutput(output, format string, write func(w io.Writer) error) error {
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if output != "" {
+		var err error
+		file, err = os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	compressed, err := export.NewCompressedWriter(out, output)
+	if err != nil {
+		return fmt.Errorf("failed to start compression: %w", err)
+	}
+
+	buffered := bufio.NewWriter(compressed)
+	if err := write(buffered); err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if err := compressed.Close(); err != nil {
+		return fmt.Errorf("failed to finish compressed export: %w", err)
+	}
+
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		if compression := export.Compression(output); compression != "" {
+			logrus.Infof("Exported graph as %s (%s) to %s", format, compression, output)
+		} else {
+			logrus.Infof("Exported graph as %s to %s", format, output)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/crawler"
+)
+
+// runCheckFilters prints how the configured domain filters treat a URL
+func runCheckFilters(cfg *config.Config, rawURL string) error {
+	domain, err := crawler.ExtractDomain(rawURL)
+	if err != nil || domain == "" {
+		return fmt.Errorf("cannot extract a domain from %q (absolute URLs only)", rawURL)
+	}
+
+	// Filters see the canonical domain, as in a crawl
+	if canonical, rule := crawler.NewCanonicalizer(cfg.CanonicalRules).Match(domain); rule >= 0 {
+		if canonical == "" {
+			fmt.Printf("%s: dropped by canonical_rules[%d], rewrite leaves no domain\n", domain, rule)
+			return nil
+		}
+		fmt.Printf("%s: rewritten to %s by canonical_rules[%d]\n\n", domain, canonical, rule)
+		domain = canonical
+	}
+	if aggregated := crawler.NewPlatforms(cfg.PlatformMode, cfg.Platforms).Aggregate(domain); aggregated != domain {
+		fmt.Printf("%s: aggregated to %s (platform_mode %q)\n\n", domain, aggregated, cfg.PlatformMode)
+		domain = aggregated
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RULE\tPATTERN\tMATCH\t")
+	for _, rules := range [][]config.FilterRule{cfg.ExcludeRules, cfg.IncludeRules} {
+		for _, rule := range rules {
+			match := ""
+			if rule.Regexp.MatchString(domain) {
+				match = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t\n", rule.Origin(), rule.Pattern, match)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(cfg.AllowedTLDs) > 0 || len(cfg.BlockedTLDs) > 0 {
+		if allowed, tld := crawler.NewTLDFilter(cfg.AllowedTLDs, cfg.BlockedTLDs).Match(domain); !allowed {
+			fmt.Printf("\n%s: excluded by TLD %q (allowed_tlds/blocked_tlds)\n", domain, tld)
+			return nil
+		}
+	}
+
+	allowed, rule := crawler.NewDomainFilter(cfg.ExcludeRules, cfg.IncludeRules).Match(domain)
+	switch {
+	case !allowed && rule != nil:
+		fmt.Printf("\n%s: excluded by %s\n", domain, rule)
+	case !allowed:
+		fmt.Printf("\n%s: excluded, matches no include_patterns\n", domain)
+	case rule != nil:
+		fmt.Printf("\n%s: allowed by %s\n", domain, rule)
+	default:
+		fmt.Printf("\n%s: allowed, no rule matched\n", domain)
+	}
+	return nil
+}
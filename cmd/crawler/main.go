@@ -1,40 +1,182 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/alvmarrod/web-weaver/internal/api"
 	"github.com/alvmarrod/web-weaver/internal/config"
 	"github.com/alvmarrod/web-weaver/internal/crawler"
+	"github.com/alvmarrod/web-weaver/internal/events"
 	"github.com/alvmarrod/web-weaver/internal/metrics"
+	"github.com/alvmarrod/web-weaver/internal/notify"
+	"github.com/alvmarrod/web-weaver/internal/simulation"
 	"github.com/alvmarrod/web-weaver/internal/storage"
 	"github.com/alvmarrod/web-weaver/internal/version"
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
-	// Configure logging
+	// Parse command line flags
+	simulate := flag.Bool("simulate", false, "crawl a synthetic in-process site graph instead of the live web")
+	simNodes := flag.Int("sim-nodes", 200, "number of synthetic domains in simulation mode")
+	simFanOut := flag.Int("sim-fanout", 5, "outbound links per synthetic page in simulation mode")
+	simFailureRate := flag.Float64("sim-failure-rate", 0.05, "fraction of synthetic domains that fail in simulation mode")
+	simSeed := flag.Int64("sim-seed", 1, "RNG seed for the synthetic site graph")
+	checkFilters := flag.String("check-filters", "", "show which exclude/include pattern matches a URL and exit")
+	configPath := flag.String("config", "config.json", "path of the JSON config file")
+
+	// Config overrides apply in command-line order over config file values
+	var overrides []config.Override
+	stringOverride := func(name, key, usage string) {
+		flag.Func(name, usage, func(value string) error {
+			overrides = append(overrides, config.StringOverride(key, value))
+			return nil
+		})
+	}
+	intOverride := func(name, key, usage string) {
+		flag.Func(name, usage, func(value string) error {
+			if _, err := strconv.Atoi(value); err != nil {
+				return fmt.Errorf("not an integer: %q", value)
+			}
+			overrides = append(overrides, config.NewOverride(key, value))
+			return nil
+		})
+	}
+	stringOverride("session", "session", "named crawl session to use within the database (overrides config)")
+	// Each -seed replaces the configured seeds with every -seed so far
+	var seeds []string
+	flag.Func("seed", "starting URL, repeatable (overrides config seed_urls and seed_url)", func(value string) error {
+		seeds = append(seeds, value)
+		overrides = append(overrides, config.StringOverride("seed_url", ""), config.ListOverride("seed_urls", seeds))
+		return nil
+	})
+	intOverride("depth", "max_depth", "maximum crawl depth (overrides config max_depth)")
+	intOverride("workers", "concurrent_workers", "concurrent workers (overrides config concurrent_workers)")
+	stringOverride("db", "db_path", "SQLite database path (overrides config db_path)")
+	stringOverride("serve", "http_addr", "serve the HTTP API on this address, e.g. :8080 (overrides config http_addr)")
+	flag.Func("set", "override any config field as key=value, with JSON values (repeatable)", func(arg string) error {
+		o, err := config.ParseOverride(arg)
+		if err != nil {
+			return err
+		}
+		overrides = append(overrides, o)
+		return nil
+	})
+	flag.Parse()
+
+	// Configure logging; every line carries the run ID for correlation
+	startTime := time.Now()
+	runID := newRunID(startTime)
 	logrus.SetLevel(logrus.InfoLevel)
 	logrus.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: true,
 	})
+	logrus.AddHook(runIDHook{runID: runID})
 
 	logrus.Infof("Web Weaver v%s starting...", version.Version)
 
 	// Load configuration
-	cfg, err := config.LoadConfig("config.json")
+	cfg, err := config.LoadConfig(*configPath, overrides...)
 	if err != nil {
 		logrus.Fatalf("Failed to load config: %v", err)
 	}
 
-	logrus.Infof("Configuration loaded: seed=%s, depth=%d, workers=%d",
-		cfg.SeedURL, cfg.MaxDepth, cfg.ConcurrentWorkers)
+	if *checkFilters != "" {
+		if err := runCheckFilters(cfg, *checkFilters); err != nil {
+			logrus.Fatalf("check-filters failed: %v", err)
+		}
+		return
+	}
+
+	// Subcommands operate on existing data and exit without crawling
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
+		case "block":
+			if err := runBlockCommand(cfg, flag.Args()[1:]); err != nil {
+				logrus.Fatalf("block command failed: %v", err)
+			}
+		case "db":
+			if err := runDBCommand(cfg, flag.Args()[1:]); err != nil {
+				logrus.Fatalf("db command failed: %v", err)
+			}
+		case "export":
+			if err := runExportCommand(cfg, flag.Args()[1:]); err != nil {
+				logrus.Fatalf("export command failed: %v", err)
+			}
+		case "plan":
+			if err := runPlanCommand(cfg, flag.Args()[1:]); err != nil {
+				logrus.Fatalf("plan command failed: %v", err)
+			}
+		case "query":
+			if err := runQueryCommand(cfg, flag.Args()[1:]); err != nil {
+				logrus.Fatalf("query command failed: %v", err)
+			}
+		case "sample":
+			if err := runSampleCommand(cfg, flag.Args()[1:]); err != nil {
+				logrus.Fatalf("sample command failed: %v", err)
+			}
+		case "search":
+			if err := runSearchCommand(cfg, flag.Args()[1:]); err != nil {
+				logrus.Fatalf("search command failed: %v", err)
+			}
+		default:
+			logrus.Fatalf("Unknown command %q", flag.Arg(0))
+		}
+		return
+	}
+
+	// Simulation mode: swap the seed for the synthetic graph and keep all
+	// output in a scratch directory so real crawl data is never touched
+	var site *simulation.Site
+	if *simulate {
+		site, err = simulation.NewSite(simulation.Options{
+			Nodes:       *simNodes,
+			FanOut:      *simFanOut,
+			FailureRate: *simFailureRate,
+			Seed:        *simSeed,
+		})
+		if err != nil {
+			logrus.Fatalf("Failed to build simulated site: %v", err)
+		}
+
+		outDir, err := os.MkdirTemp("", "web-weaver-sim-")
+		if err != nil {
+			logrus.Fatalf("Failed to create simulation directory: %v", err)
+		}
+
+		cfg.SeedURLs = []string{site.SeedURL()}
+		cfg.DBPath = filepath.Join(outDir, "crawler.db")
+		cfg.MetricsPath = filepath.Join(outDir, "metrics.log")
+
+		logrus.Infof("Simulation mode: %d nodes, fan-out %d, failure rate %.2f, seed %d (fingerprint %x)",
+			*simNodes, *simFanOut, *simFailureRate, *simSeed, site.Fingerprint())
+		logrus.Infof("Simulation expects %d nodes within depth %d; output in %s",
+			site.Reachable(cfg.MaxDepth), cfg.MaxDepth, outDir)
+	}
+
+	// Keep each run's metrics instead of overwriting the previous file
+	cfg.MetricsPath = runMetricsPath(cfg.MetricsPath, runID)
+
+	seedSummary := strings.Join(cfg.SeedURLs, ",")
+	if cfg.SeedFile != "" {
+		seedSummary = strings.TrimSpace(seedSummary + " +" + cfg.SeedFile)
+	}
+	logrus.Infof("Configuration loaded: seeds=%s, depth=%d, workers=%d, session=%s",
+		seedSummary, cfg.MaxDepth, cfg.ConcurrentWorkers, cfg.Session)
 
 	// Initialize storage
-	store, err := storage.NewStorage(cfg.DBPath)
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
 	if err != nil {
 		logrus.Fatalf("Failed to initialize storage: %v", err)
 	}
@@ -42,30 +184,137 @@ func main() {
 
 	logrus.Infof("Database initialized: %s", cfg.DBPath)
 
-	// Initialize metrics tracker
-	tracker := metrics.NewTracker()
-
-	// Metrics callback for crawler
-	metricsCallback := func(nodesCrawled, nodesDiscovered, edgesRecorded, pagesFetched, pagesFailed int) {
-		if nodesCrawled > 0 {
-			tracker.IncrementNodesCrawled()
+	// Fail on a broken environment before any worker starts
+	if !cfg.SkipPreflight {
+		var transport http.RoundTripper
+		if site != nil {
+			transport = site
 		}
-		if nodesDiscovered > 0 {
-			tracker.IncrementNodesDiscovered()
+		if err := runPreflight(cfg, store, transport); err != nil {
+			logrus.Fatalf("Preflight failed: %v", err)
 		}
-		if edgesRecorded > 0 {
-			tracker.IncrementEdgesRecorded()
+	}
+
+	// Record the run so its logs and metrics can be traced from the database
+	if err := store.StartRun(storage.CrawlRun{
+		RunID:       runID,
+		SeedURL:     strings.Join(cfg.SeedURLs, " "),
+		MetricsPath: cfg.MetricsPath,
+		StartedAt:   startTime,
+	}); err != nil {
+		logrus.Warnf("Failed to record run: %v", err)
+	}
+
+	// Age the stored graph before this run adds to it
+	if cfg.EdgeDecayPerWeek > 0 {
+		stats, err := store.DecayEdges(cfg.EdgeDecayPerWeek, startTime)
+		if err != nil {
+			logrus.Fatalf("Failed to decay edge weights: %v", err)
 		}
-		if pagesFetched > 0 {
-			tracker.IncrementPagesFetched()
+		logrus.Infof("Decayed edge weights by %g per week: %d edges lowered, %d dropped", cfg.EdgeDecayPerWeek, stats.Decayed, stats.Dropped)
+	}
+
+	// Start optional HTTP API
+	var apiServer *api.Server
+	var apiReplica *storage.Replica
+	var eventBus *events.Bus
+	if cfg.HTTPAddr != "" {
+		eventBus = events.NewBus()
+		apiServer, err = api.NewServer(cfg.HTTPAddr, store, eventBus)
+		if err != nil {
+			logrus.Fatalf("Failed to initialize HTTP API: %v", err)
 		}
-		if pagesFailed > 0 {
-			tracker.IncrementPagesFailed()
+		apiServer.SetToken(cfg.APIToken)
+
+		// Serve reads apart from the crawler's connections
+		if replica, err := openAPIReplica(cfg, store); err != nil {
+			logrus.Warnf("Failed to open API read replica, reading through the crawler's connection: %v", err)
+		} else {
+			apiReplica = replica
+			defer apiReplica.Close()
+			apiServer.SetReplica(apiReplica)
 		}
 	}
 
+	// Initialize metrics tracker
+	tracker := metrics.NewTracker(runID)
+	tracker.SetHistory(cfg.MetricsHistory)
+
+	// Any source may request the graceful shutdown; the first reason wins
+	sd := newShutdown(runID, cfg.Session, tracker)
+
+	// Every crawl event goes to each registered metrics sink
+	sinks := metrics.Sinks{tracker}
+
 	// Initialize crawler
-	c := crawler.NewCrawler(cfg, store, metricsCallback)
+	c := crawler.NewCrawler(cfg, store, sinks)
+	if site != nil {
+		c.SetTransport(site)
+	}
+	if eventBus != nil {
+		c.SetEventBus(eventBus)
+	}
+	liveState := func() metrics.LiveState {
+		return metrics.LiveState{
+			QueueSize:      c.QueueSize(),
+			InFlight:       c.InFlight(),
+			ActiveWorkers:  c.ActiveWorkers(),
+			VisitedSetSize: c.VisitedCount(),
+		}
+	}
+	// Expose the metrics to Prometheus, on the API listener if they share
+	// an address
+	var exporter *metrics.Exporter
+	if cfg.MetricsAddr != "" {
+		exporter = metrics.NewExporter(tracker, liveState)
+		if apiServer != nil && cfg.MetricsAddr == cfg.HTTPAddr {
+			apiServer.Handle("/metrics", exporter)
+		} else {
+			exporter.Start(cfg.MetricsAddr)
+		}
+	}
+	if apiServer != nil {
+		apiServer.SetBlocklist(c.Blocklist())
+		sd.workers = c.Workers
+		sd.live = liveState
+		apiServer.SetControl(sd)
+		apiServer.Start()
+	}
+
+	// A shared frontier must be reachable before anything is queued
+	if err := c.ConnectQueue(); err != nil {
+		logrus.Fatalf("Failed to connect to the queue backend: %v", err)
+	}
+
+	// Load persistent blocklist
+	if err := c.LoadBlocklist(); err != nil {
+		logrus.Fatalf("Failed to load blocklist: %v", err)
+	}
+
+	// Reputation sources are consulted before discovered domains are enqueued
+	if err := c.LoadReputationSources(); err != nil {
+		logrus.Fatalf("Failed to load reputation sources: %v", err)
+	}
+
+	// Front pages are classified against the built-in rules and the model
+	if err := c.LoadClassificationModel(); err != nil {
+		logrus.Fatalf("Failed to load classification model: %v", err)
+	}
+
+	// Load cache validators so fresh pages aren't refetched
+	if err := c.LoadHTTPCache(); err != nil {
+		logrus.Warnf("Failed to load HTTP cache: %v", err)
+	}
+
+	// Recent fetches by any run hold back re-fetches within min_refetch_interval_sec
+	if err := c.LoadFetchTimes(); err != nil {
+		logrus.Warnf("Failed to load fetch times: %v", err)
+	}
+
+	// Restore counted subdomains so max_subdomains_per_root holds across resumes
+	if err := c.LoadSubdomainLimits(); err != nil {
+		logrus.Warnf("Failed to load subdomain limits: %v", err)
+	}
 
 	// Handle resume logic - check for saved queue state first
 	queueEntries, err := c.LoadQueueState()
@@ -75,6 +324,7 @@ func main() {
 
 	if len(queueEntries) > 0 {
 		logrus.Infof("Resuming crawl: found %d saved queue entries", len(queueEntries))
+		resumed := resumeMetrics(store, tracker, runID)
 
 		// Load nodes from storage into memory graph
 		if err := c.LoadFromStorage(); err != nil {
@@ -84,7 +334,9 @@ func main() {
 		// Re-queue all saved entries with their original depths
 		for _, entry := range queueEntries {
 			c.Enqueue(entry)
-			tracker.IncrementNodesDiscovered()
+			if !resumed {
+				tracker.NodeDiscovered()
+			}
 		}
 
 		logrus.Infof("Resumed with %d pending entries at their original depths", len(queueEntries))
@@ -97,6 +349,7 @@ func main() {
 
 		if len(resumableNodes) > 0 {
 			logrus.Infof("Found %d resumable nodes, loading into memory...", len(resumableNodes))
+			resumed := resumeMetrics(store, tracker, runID)
 
 			// Load nodes from storage into memory graph
 			if err := c.LoadFromStorage(); err != nil {
@@ -111,38 +364,54 @@ func main() {
 					Depth:      node.LastDepth,
 				}
 				c.Enqueue(entry)
-				tracker.IncrementNodesDiscovered()
+				if !resumed {
+					tracker.NodeDiscovered()
+				}
 			}
 
 			logrus.Infof("Resumed %d nodes at their last known depths", len(resumableNodes))
 		} else {
-			// No resumable nodes - start fresh with seed
-			logrus.Info("No resumable nodes found, starting fresh crawl with seed")
-
-			// Extract seed domain
-			seedDomain, err := crawler.ExtractDomain(cfg.SeedURL)
+			// No resumable nodes - start fresh with the seeds; seed_file
+			// entries pass the same admission rules as discovered links
+			admits, err := crawler.SeedAdmission(cfg, store)
 			if err != nil {
-				logrus.Fatalf("Invalid seed URL: %v", err)
+				logrus.Fatalf("Failed to load blocklist: %v", err)
 			}
-
-			// Check if seed exists and reset crawl_count if needed
-			existingSeed, err := store.GetNode(seedDomain)
+			seeds, skipped, err := crawler.StartSeeds(cfg, admits)
 			if err != nil {
-				logrus.Fatalf("Failed to check for existing seed: %v", err)
+				logrus.Fatalf("Failed to read seeds: %v", err)
+			}
+			if cfg.SeedFile != "" {
+				logrus.Infof("Read seeds from %s (%d lines unparsable, filtered, or blocked)", cfg.SeedFile, skipped)
 			}
+			if len(seeds) == 0 {
+				logrus.Fatal("No usable seeds")
+			}
+			logrus.Infof("No resumable nodes found, starting fresh crawl with %d seed(s)", len(seeds))
 
-			if existingSeed != nil && existingSeed.CrawlCount >= cfg.MaxCrawlsPerNode {
-				logrus.Infof("Seed %s exists with crawl_count=%d, resetting to 0", seedDomain, existingSeed.CrawlCount)
-				if err := store.ResetCrawlCount(existingSeed.NodeID); err != nil {
-					logrus.Fatalf("Failed to reset crawl count: %v", err)
+			for _, seedDomain := range seeds {
+				// Check if seed exists and reset crawl_count if needed
+				existingSeed, err := store.GetNode(seedDomain)
+				if err != nil {
+					logrus.Fatalf("Failed to check for existing seed: %v", err)
 				}
-			}
 
-			// Enqueue seed URL (will create node in memory if doesn't exist)
-			if _, err := c.EnqueueSeed(cfg.SeedURL); err != nil {
-				logrus.Fatalf("Failed to enqueue seed: %v", err)
+				if existingSeed != nil && existingSeed.CrawlCount >= cfg.MaxCrawlsPerNode {
+					logrus.Infof("Seed %s exists with crawl_count=%d, resetting to 0", seedDomain, existingSeed.CrawlCount)
+					if err := store.ResetCrawlCount(existingSeed.NodeID); err != nil {
+						logrus.Fatalf("Failed to reset crawl count: %v", err)
+					}
+				}
+
+				// Enqueue seed URL (will create node in memory if doesn't exist)
+				if _, err := c.EnqueueSeed(crawler.SeedURL(cfg, seedDomain)); err != nil {
+					logrus.Fatalf("Failed to enqueue seed: %v", err)
+				}
+				tracker.NodeDiscovered()
+			}
+			if checked, unhealthy := c.SeedChecks(); checked > 0 && unhealthy == checked {
+				logrus.Errorf("All %d checked seeds have problems (see above); the crawl is likely to end right away with next to nothing found", checked)
 			}
-			tracker.IncrementNodesDiscovered()
 		}
 	}
 
@@ -153,10 +422,24 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// Track termination reason
-	var terminationReason string
 	var wg sync.WaitGroup
-	shutdownComplete := make(chan struct{})
+
+	// Reload extraction selectors from the config file on SIGHUP; other
+	// settings only take effect on restart
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	go func() {
+		for range reloadChan {
+			reloaded, err := config.LoadConfig(*configPath, overrides...)
+			if err != nil {
+				logrus.Errorf("Config reload failed, keeping current selectors: %v", err)
+				continue
+			}
+			c.UpdateSelectors(reloaded)
+			logrus.Infof("Reloaded extraction selectors: %d title, %d description, %d site overrides",
+				len(reloaded.Selectors.Title), len(reloaded.Selectors.Description), len(reloaded.SiteSelectorSets))
+		}
+	}()
 
 	// Handle force quit on second signal
 	forceQuitChan := make(chan os.Signal, 1)
@@ -175,9 +458,28 @@ func main() {
 		}
 
 		// Emergency metrics save
-		if err := tracker.WriteToFile(cfg.MetricsPath, "forced_exit"); err != nil {
+		tracker.SampleRuntime(c.VisitedCount())
+		tracker.RecordCacheStats(c.CacheStats())
+		tracker.RecordAbandonedFetches(c.AbandonedFetches())
+		tracker.RecordRetriedFetches(c.RetriedFetches())
+		tracker.RecordHTTPFallbacks(c.HTTPFallbacks())
+		tracker.RecordSubdomainStats(c.SubdomainStats())
+		tracker.RecordDNSStats(c.DNSStats())
+		tracker.RecordSitemapStats(c.SitemapStats())
+		tracker.RecordReputationStats(c.ReputationStats())
+		tracker.RecordTLDSkips(c.TLDSkips())
+		tracker.RecordErrorCounts(c.ErrorCounts())
+		tracker.RecordLanguages(c.Languages())
+		tracker.RecordCategories(c.Categories())
+		if err := tracker.WriteToFile(cfg.MetricsPath, storage.TerminationForcedExit); err != nil {
 			logrus.Errorf("Emergency metrics save failed: %v", err)
 		}
+		if err := store.SaveRunCounters(runID, tracker.Cumulative()); err != nil {
+			logrus.Errorf("Emergency metrics counters save failed: %v", err)
+		}
+		if err := store.FinishRun(runID, storage.TerminationForcedExit); err != nil {
+			logrus.Errorf("Emergency run record failed: %v", err)
+		}
 		os.Exit(1)
 	}()
 
@@ -186,23 +488,215 @@ func main() {
 	go func() {
 		defer wg.Done()
 		c.WaitUntilEmpty()
-		terminationReason = "queue_empty"
+
+		// Stopping for another reason also drains the queue; keep that
+		// reason and the saved queue state
+		if !sd.RequestStop(storage.TerminationQueueEmpty) {
+			return
+		}
 
 		// Clear saved queue state on successful completion
 		logrus.Info("Natural completion: clearing saved queue state...")
 		if err := store.ClearQueueEntries(); err != nil {
 			logrus.Warnf("Failed to clear queue state: %v", err)
 		}
+	}()
 
-		// Signal main goroutine
-		select {
-		case <-shutdownComplete:
-			// Already shutting down
-		default:
-			sigChan <- syscall.SIGTERM
+	// Monitor free disk space on the DB volume, unless min_free_disk_mb is -1
+	stopDiskGuard := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if cfg.MinFreeDiskMB < 0 {
+			return
+		}
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+
+		minFree := uint64(cfg.MinFreeDiskMB) * 1024 * 1024
+		for {
+			select {
+			case <-ticker.C:
+				free, err := storage.FreeDiskBytes(cfg.DBPath)
+				if err != nil {
+					logrus.Warnf("Disk space check failed, disabling guard: %v", err)
+					return
+				}
+				if free >= minFree {
+					continue
+				}
+
+				logrus.Errorf("Free disk space %dMB below minimum %dMB - stopping crawl",
+					free/(1024*1024), cfg.MinFreeDiskMB)
+				c.CloseFrontier()
+				sd.RequestStop(storage.TerminationDiskFull)
+				return
+			case <-stopDiskGuard:
+				return
+			}
 		}
 	}()
 
+	// Refresh the API's read snapshot
+	stopSnapshots := make(chan struct{})
+	if apiReplica != nil && cfg.APISnapshotIntervalSec > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(time.Duration(cfg.APISnapshotIntervalSec) * time.Second)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					if err := apiReplica.Refresh(); err != nil {
+						logrus.Warnf("Failed to refresh API snapshot: %v", err)
+					}
+				case <-stopSnapshots:
+					return
+				}
+			}
+		}()
+	}
+
+	// Finish early once new root domains stop turning up
+	stopPlateauGuard := make(chan struct{})
+	if cfg.PlateauWindowSec > 0 {
+		logrus.Infof("Discovery plateau stop enabled: fewer than %d new root domains in %ds",
+			cfg.PlateauMinNewRoots, cfg.PlateauWindowSec)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(10 * time.Second)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					plateaued, found := c.DiscoveryPlateaued()
+					if !plateaued {
+						continue
+					}
+
+					logrus.Infof("Discovery plateau: %d new root domains in the last %ds - finishing crawl",
+						found, cfg.PlateauWindowSec)
+					c.CloseFrontier()
+					sd.RequestStop(storage.TerminationPlateau)
+					return
+				case <-stopPlateauGuard:
+					return
+				}
+			}
+		}()
+	}
+
+	// Abort when most recent fetches fail instead of burning the frontier
+	stopFailureGuard := make(chan struct{})
+	if cfg.FailureWindow > 0 {
+		logrus.Infof("Failure abort enabled: more than %.0f%% of the last %d fetches failing",
+			cfg.MaxFailurePercent, cfg.FailureWindow)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-c.FailureThresholdReached():
+				failed, total := c.FailureStats()
+				logrus.Errorf("%d of the last %d fetches failed (network outage, DNS, or IP block?) - stopping crawl",
+					failed, total)
+				c.CloseFrontier()
+				sd.RequestStop(storage.TerminationFailureThreshold)
+			case <-stopFailureGuard:
+			}
+		}()
+	}
+
+	// Stop once the configured time or node budget is spent
+	stopBudgetGuard := make(chan struct{})
+	if cfg.TimeBudgetSec > 0 || cfg.NodeBudget > 0 {
+		logrus.Infof("Crawl budget: %ds, %d nodes crawled (0 = unlimited)", cfg.TimeBudgetSec, cfg.NodeBudget)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var deadline <-chan time.Time
+			if cfg.TimeBudgetSec > 0 {
+				timer := time.NewTimer(time.Duration(cfg.TimeBudgetSec) * time.Second)
+				defer timer.Stop()
+				deadline = timer.C
+			}
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-deadline:
+					logrus.Infof("Time budget of %ds spent - finishing crawl", cfg.TimeBudgetSec)
+					c.CloseFrontier()
+					sd.RequestStop(storage.TerminationTimeBudget)
+					return
+				case <-ticker.C:
+					if cfg.NodeBudget == 0 || tracker.RunCounters().NodesCrawled < cfg.NodeBudget {
+						continue
+					}
+					logrus.Infof("Node budget of %d crawled nodes spent - finishing crawl", cfg.NodeBudget)
+					c.CloseFrontier()
+					sd.RequestStop(storage.TerminationNodeBudget)
+					return
+				case <-stopBudgetGuard:
+					return
+				}
+			}
+		}()
+	}
+
+	// Checkpoint periodically, so a crash loses at most one interval of
+	// the graph and queue held in memory
+	stopCheckpoints := make(chan struct{})
+	if cfg.CheckpointIntervalSec > 0 || cfg.CheckpointPages > 0 {
+		var every []string
+		if cfg.CheckpointIntervalSec > 0 {
+			every = append(every, fmt.Sprintf("%ds", cfg.CheckpointIntervalSec))
+		}
+		if cfg.CheckpointPages > 0 {
+			every = append(every, fmt.Sprintf("%d pages fetched", cfg.CheckpointPages))
+		}
+		logrus.Infof("Checkpoints every %s", strings.Join(every, " or "))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+
+			last, lastPages, lastFetches := time.Now(), 0, 0
+			for {
+				select {
+				case <-ticker.C:
+					counters := tracker.RunCounters()
+					pages, fetches := counters.PagesFetched, counters.PagesFetched+counters.PagesFailed
+					due := cfg.CheckpointIntervalSec > 0 && time.Since(last) >= time.Duration(cfg.CheckpointIntervalSec)*time.Second
+					due = due || cfg.CheckpointPages > 0 && pages-lastPages >= cfg.CheckpointPages
+					if !due {
+						continue
+					}
+					// Every flush rewrites all nodes; an idle crawl has
+					// nothing new to save
+					if fetches > lastFetches {
+						logrus.Infof("Checkpoint: %d pages fetched since the last one", pages-lastPages)
+						if err := c.FlushToStorage(); err != nil {
+							logrus.Errorf("Checkpoint failed: %v", err)
+						}
+					}
+					last, lastPages, lastFetches = time.Now(), pages, fetches
+				case <-stopCheckpoints:
+					return
+				}
+			}
+		}()
+	}
+
 	// Start progress logger
 	stopProgress := make(chan struct{})
 	wg.Add(1)
@@ -214,27 +708,52 @@ func main() {
 		for {
 			select {
 			case <-ticker.C:
+				tracker.SampleRuntime(c.VisitedCount())
+				tracker.RecordCacheStats(c.CacheStats())
+				tracker.RecordAbandonedFetches(c.AbandonedFetches())
+				tracker.RecordRetriedFetches(c.RetriedFetches())
+				tracker.RecordHTTPFallbacks(c.HTTPFallbacks())
+				tracker.RecordSubdomainStats(c.SubdomainStats())
+				tracker.RecordDNSStats(c.DNSStats())
+				tracker.RecordSitemapStats(c.SitemapStats())
+				tracker.RecordReputationStats(c.ReputationStats())
+				tracker.RecordTLDSkips(c.TLDSkips())
+				tracker.RecordErrorCounts(c.ErrorCounts())
+				tracker.RecordLanguages(c.Languages())
+				tracker.RecordCategories(c.Categories())
 				logrus.Info(tracker.LogProgress())
+
+				// Keep a recent snapshot on disk in case the process is killed
+				if err := tracker.WriteSnapshot(cfg.MetricsPath); err != nil {
+					logrus.Warnf("Failed to write metrics snapshot: %v", err)
+				}
+				if err := store.SaveRunCounters(runID, tracker.Cumulative()); err != nil {
+					logrus.Warnf("Failed to save metrics counters: %v", err)
+				}
 			case <-stopProgress:
 				return
 			}
 		}
 	}()
 
-	// Wait for signal (SIGTERM or natural completion)
-	sig := <-sigChan
-	logrus.Infof("Received signal: %v", sig)
-
-	// Mark shutdown in progress
-	close(shutdownComplete)
+	// Wait for a signal or any other shutdown request
+	select {
+	case sig := <-sigChan:
+		logrus.Infof("Received signal: %v", sig)
+		sd.RequestStop(storage.TerminationSignal)
+	case <-sd.Requested():
+	}
+	terminationReason := sd.Reason()
+	logrus.Infof("Termination reason: %s", terminationReason)
 
-	// Stop progress logger first
+	// Stop progress logger and guards first
 	close(stopProgress)
-
-	// Determine termination reason if not already set
-	if terminationReason == "" {
-		terminationReason = "signal"
-	}
+	close(stopDiskGuard)
+	close(stopPlateauGuard)
+	close(stopFailureGuard)
+	close(stopBudgetGuard)
+	close(stopSnapshots)
+	close(stopCheckpoints)
 
 	logrus.Info("Initiating graceful shutdown...")
 	logrus.Info("Step 1/5: Stopping crawler workers...")
@@ -270,7 +789,27 @@ func main() {
 	logrus.Info("Step 4/5: Writing final metrics...")
 
 	// Final progress log
+	tracker.SampleRuntime(c.VisitedCount())
+	tracker.RecordCacheStats(c.CacheStats())
+	tracker.RecordAbandonedFetches(c.AbandonedFetches())
+	tracker.RecordRetriedFetches(c.RetriedFetches())
+	tracker.RecordHTTPFallbacks(c.HTTPFallbacks())
+	tracker.RecordSubdomainStats(c.SubdomainStats())
+	tracker.RecordDNSStats(c.DNSStats())
+	tracker.RecordSitemapStats(c.SitemapStats())
+	tracker.RecordReputationStats(c.ReputationStats())
+	tracker.RecordTLDSkips(c.TLDSkips())
+	tracker.RecordErrorCounts(c.ErrorCounts())
+	tracker.RecordLanguages(c.Languages())
+	tracker.RecordCategories(c.Categories())
 	logrus.Info("Final stats: " + tracker.LogProgress())
+	if cfg.MetricsTopN > 0 {
+		if top, err := store.TopDomains(cfg.MetricsTopN); err != nil {
+			logrus.Errorf("Failed to rank top domains: %v", err)
+		} else {
+			tracker.RecordTopDomains(top)
+		}
+	}
 
 	// Write metrics to file
 	if err := tracker.WriteToFile(cfg.MetricsPath, terminationReason); err != nil {
@@ -278,10 +817,72 @@ func main() {
 	} else {
 		logrus.Infof("Metrics written to %s", cfg.MetricsPath)
 	}
+	if err := store.SaveRunCounters(runID, tracker.Cumulative()); err != nil {
+		logrus.Errorf("Failed to save metrics counters: %v", err)
+	}
+	if err := store.FinishRun(runID, terminationReason); err != nil {
+		logrus.Errorf("Failed to record run finish: %v", err)
+	}
+
+	// Report the outcome of unattended crawls; simulations never notify
+	if notifiers := notify.FromConfig(cfg); len(notifiers) > 0 && !*simulate {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		notify.SendAll(ctx, notifiers, notify.Report{
+			Session:  cfg.Session,
+			SeedURLs: cfg.SeedURLs,
+			Metrics:  tracker.GetSnapshot(),
+		})
+		cancel()
+	}
 
 	logrus.Info("Step 5/5: Closing database connection...")
 
+	if apiServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := apiServer.Shutdown(ctx); err != nil {
+			logrus.Warnf("HTTP API shutdown: %v", err)
+		}
+		cancel()
+	}
+
+	if exporter != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := exporter.Shutdown(ctx); err != nil {
+			logrus.Warnf("Prometheus metrics shutdown: %v", err)
+		}
+		cancel()
+	}
+
 	// Database is closed via defer store.Close()
 
 	logrus.Info("Graceful shutdown complete. Goodbye!")
 }
+
+// resumeMetrics continues the tracker from the counters saved by the
+// session's previous run; false if there are none, e.g. for databases
+// written before counters were saved
+func resumeMetrics(store *storage.Storage, tracker *metrics.Tracker, runID string) bool {
+	previousRunID, counters, err := store.PreviousRunCounters(runID)
+	if err != nil {
+		logrus.Warnf("Failed to load previous metrics, counting from zero: %v", err)
+		return false
+	}
+	if counters == nil {
+		return false
+	}
+	tracker.Resume(previousRunID, *counters)
+	logrus.Infof("Continuing metrics of run %s: %d nodes crawled, %d pages fetched so far",
+		previousRunID, counters.NodesCrawled, counters.PagesFetched)
+	return true
+}
+
+// openAPIReplica opens the storage the HTTP API reads from: a snapshot
+// refreshed every api_snapshot_interval_sec, or a read-only connection to
+// the live database
+func openAPIReplica(cfg *config.Config, store *storage.Storage) (*storage.Replica, error) {
+	if cfg.APISnapshotIntervalSec > 0 {
+		logrus.Infof("HTTP API reads a snapshot refreshed every %ds", cfg.APISnapshotIntervalSec)
+		return storage.OpenSnapshotReplica(store, cfg.DBPath)
+	}
+	return storage.OpenReplica(cfg.DBPath, cfg.Session)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/crawler"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// runPlanCommand estimates the fetches the next run would perform with the
+// current config, from the stored graph and without touching the network
+func runPlanCommand(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	top := fs.Int("top", 20, "root domains listed, by estimated fetches (0 lists all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: plan [-top n]")
+	}
+
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	plan, err := crawler.PlanCrawl(cfg, store)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Session %s starts from the %s (%d entries), max_depth %d\n", cfg.Session, plan.Start, plan.Entries, cfg.MaxDepth)
+	fmt.Printf("Estimated fetches: %d, of which %d on pages never crawled", plan.Fetches, plan.Unexplored)
+	if plan.Unexplored > 0 {
+		fmt.Print(" (their links are unknown, so the run will likely fetch more)")
+	}
+	fmt.Println()
+	if plan.BudgetCapped {
+		fmt.Printf("Stops at node_budget (%d)\n", cfg.NodeBudget)
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DEPTH\tFETCHES\t")
+	for depth, fetches := range plan.ByDepth {
+		fmt.Fprintf(w, "%d\t%d\t\n", depth, fetches)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "SKIPPED\tCANDIDATES\t")
+	for _, row := range []struct {
+		label string
+		count int
+	}{
+		{"at max_crawls_per_node", plan.AtMaxCrawls},
+		{"filtered or blocked", plan.Filtered},
+		{"over max_subdomains_per_root", plan.SubdomainCap},
+		{"over depth_fanout_limits", plan.FanOutCap},
+		{"fetched too recently", plan.TooRecent},
+	} {
+		fmt.Fprintf(w, "%s\t%d\t\n", row.label, row.count)
+	}
+	fmt.Fprintln(w)
+
+	roots := make([]string, 0, len(plan.ByRoot))
+	for root := range plan.ByRoot {
+		roots = append(roots, root)
+	}
+	slices.SortFunc(roots, func(a, b string) int {
+		return cmp.Or(cmp.Compare(plan.ByRoot[b], plan.ByRoot[a]), cmp.Compare(a, b))
+	})
+	if *top > 0 && len(roots) > *top {
+		roots = roots[:*top]
+	}
+	fmt.Fprintln(w, "ROOT\tFETCHES\t")
+	for _, root := range roots {
+		fmt.Fprintf(w, "%s\t%d\t\n", root, plan.ByRoot[root])
+	}
+	return w.Flush()
+}
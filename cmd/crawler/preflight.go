@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/crawler"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// preflightCheck is one startup check; run returns an actionable error
+type preflightCheck struct {
+	name  string
+	needs string // check whose failure makes this one moot, if any
+	run   func() error
+}
+
+// runPreflight checks, before any worker starts, what every fetch and flush
+// depends on: disk space and write access for the database, DNS, and
+// outbound HTTP to the preflight URL. Every check runs unless one it needs
+// failed, and each failure is logged with what to fix, so a misconfigured
+// host fails once and clearly rather than as a cascade of per-domain fetch
+// failures
+// transport is the one fetches go through; DNS isn't checked when it's
+// replaced, as in simulation mode
+func runPreflight(cfg *config.Config, store *storage.Storage, transport http.RoundTripper) error {
+	checks := []preflightCheck{
+		{"disk space", "", func() error { return checkDiskSpace(cfg) }},
+		{"database writes", "", func() error {
+			if err := store.CheckWritable(); err != nil {
+				return fmt.Errorf("%s is not writable (%w); check the permissions of the file and its directory", cfg.DBPath, err)
+			}
+			return nil
+		}},
+	}
+
+	target := cfg.PreflightURL
+	if target == "" && len(cfg.SeedURLs) > 0 {
+		target = cfg.SeedURLs[0]
+	}
+	if target == "" {
+		logrus.Info("Preflight: no preflight_url or seed URL, skipping DNS and connectivity checks")
+	} else {
+		timeout := time.Duration(cfg.RequestTimeoutMs) * time.Millisecond
+		if transport == nil {
+			checks = append(checks, preflightCheck{"DNS", "", func() error { return checkDNS(target, timeout) }})
+		}
+		checks = append(checks, preflightCheck{"connectivity", "DNS", func() error { return checkConnectivity(cfg, target, transport, timeout) }})
+	}
+
+	failed := make(map[string]bool)
+	for _, check := range checks {
+		if failed[check.needs] {
+			logrus.Warnf("Preflight %s: skipped, %s failed", check.name, check.needs)
+			continue
+		}
+		if err := check.run(); err != nil {
+			logrus.Errorf("Preflight %s: %v", check.name, err)
+			failed[check.name] = true
+			continue
+		}
+		logrus.Infof("Preflight %s: ok", check.name)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d checks failed, see above (skip_preflight disables them)", len(failed), len(checks))
+	}
+	return nil
+}
+
+// checkDiskSpace verifies the database volume has min_free_disk_mb free,
+// below which the disk guard would stop the crawl right away
+func checkDiskSpace(cfg *config.Config) error {
+	if cfg.MinFreeDiskMB <= 0 {
+		return nil
+	}
+	free, err := storage.FreeDiskBytes(cfg.DBPath)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if free < uint64(cfg.MinFreeDiskMB)*1024*1024 {
+		return fmt.Errorf("%d MB free on the volume of %s, below min_free_disk_mb (%d); free up space or move db_path",
+			free/(1024*1024), cfg.DBPath, cfg.MinFreeDiskMB)
+	}
+	return nil
+}
+
+// checkDNS resolves the host of target
+func checkDNS(target string, timeout time.Duration) error {
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("invalid preflight URL %q", target)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if _, err := net.DefaultResolver.LookupHost(ctx, u.Hostname()); err != nil {
+		return fmt.Errorf("cannot resolve %s (%w); check the resolver configuration and network, or set preflight_url to a host known to resolve", u.Hostname(), err)
+	}
+	return nil
+}
+
+// checkConnectivity fetches target with the user agent the crawler would
+// send it; any HTTP answer, even an error
+// status, shows outbound requests get through
+func checkConnectivity(cfg *config.Config, target string, transport http.RoundTripper, timeout time.Duration) error {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("cannot fetch %s (%w); set preflight_url to a valid URL", target, err)
+	}
+	req.Header.Set("User-Agent", crawler.UserAgent(cfg, req.URL.Hostname()))
+
+	client := &http.Client{Transport: transport, Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot fetch %s (%w); check network access, proxies and firewalls, or set preflight_url to a URL known to be up", target, err)
+	}
+	resp.Body.Close()
+	return nil
+}
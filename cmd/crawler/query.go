@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+const queryUsage = "usage: query provenance <domain>"
+
+// runQueryCommand handles the `query` subcommands answering questions about
+// individual nodes
+func runQueryCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(queryUsage)
+	}
+
+	switch args[0] {
+	case "provenance":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: query provenance <domain>")
+		}
+		return printProvenance(cfg, args[1])
+	default:
+		return fmt.Errorf("unknown query command %q", args[0])
+	}
+}
+
+// printProvenance prints the chain of first discoveries from the seed to domain
+func printProvenance(cfg *config.Config, domain string) error {
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	domain = strings.ToLower(domain)
+	chain, err := store.DiscoveryChain(domain)
+	if err != nil {
+		return err
+	}
+	if chain == nil {
+		return fmt.Errorf("unknown domain %q", domain)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOP\tDOMAIN\tFOUND ON\t")
+	for hop, node := range chain {
+		foundOn := node.SourceURL
+		switch {
+		case node.SeedNodeID == node.NodeID:
+			foundOn = "(seed)"
+		case node.SeedNodeID == 0:
+			foundOn = "(unattributed)"
+		case foundOn == "":
+			foundOn = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t\n", hop, node.DomainName, foundOn)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/crawler"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// exportQueue writes the session's saved frontier as a queue file
+func exportQueue(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("db queue-export", flag.ContinueOnError)
+	output := fs.String("o", "", "output file (default: stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: db queue-export [-o file]")
+	}
+
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	entries, err := store.LoadQueueEntries()
+	if err != nil {
+		return err
+	}
+	// Queue files list domains; inner pages of url crawl mode are left out
+	values := make([]storage.QueueEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.URL == "" {
+			values = append(values, *entry)
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	buffered := bufio.NewWriter(out)
+	if err := crawler.WriteQueueFile(buffered, values); err != nil {
+		return fmt.Errorf("failed to write queue file: %w", err)
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write queue file: %w", err)
+	}
+
+	if *output != "" {
+		logrus.Infof("Exported %d queue entries of session %s to %s", len(values), cfg.Session, *output)
+	}
+	return nil
+}
+
+// importQueue appends a queue file to the session's saved frontier, so the
+// next run starts from it
+func importQueue(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("db queue-import", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: db queue-import <file|->")
+	}
+
+	var r io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open queue file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	entries, skipped, err := crawler.ParseQueueFile(r)
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	admits, err := crawler.SeedAdmission(cfg, store)
+	if err != nil {
+		return err
+	}
+	accepted := entries[:0]
+	filtered, tooDeep := 0, 0
+	for _, entry := range entries {
+		switch {
+		case entry.Depth > cfg.MaxDepth:
+			tooDeep++
+		case !admits(entry.DomainName):
+			filtered++
+		default:
+			accepted = append(accepted, entry)
+		}
+	}
+
+	stats, err := store.ImportQueue(accepted)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Imported queue into session %s: %d queued (%d new nodes), %d already queued, %d filtered or blocked, %d beyond max_depth, %d unparsable lines",
+		cfg.Session, stats.Queued, stats.NewNodes, stats.AlreadyQueued, filtered, tooDeep, skipped)
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newRunID returns a sortable, unique ID for this process: the UTC start
+// time plus random bytes so runs started in the same second don't collide
+func newRunID(start time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return start.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// runIDHook tags every log entry with the run ID
+type runIDHook struct {
+	runID string
+}
+
+// Levels applies the hook to every level
+func (h runIDHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire adds the run_id field to the entry
+func (h runIDHook) Fire(entry *logrus.Entry) error {
+	entry.Data["run_id"] = h.runID
+	return nil
+}
+
+// runMetricsPath inserts the run ID into the metrics file name so repeated
+// runs don't overwrite each other: metrics.log -> metrics-<run>.log
+func runMetricsPath(path, runID string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + runID + ext
+}
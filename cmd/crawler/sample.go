@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/export"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// runSampleCommand exports a representative subgraph small enough to render
+func runSampleCommand(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("sample", flag.ContinueOnError)
+	flags := addGraphFlags(fs, strings.Join(export.Formats(), ", "))
+	var opts export.SampleOptions
+	fs.StringVar(&opts.Method, "method", export.SampleForestFire, "sampling method: "+strings.Join(export.SampleMethods, ", "))
+	fs.IntVar(&opts.Nodes, "nodes", 500, "target number of nodes for forest-fire and random-walk")
+	fs.Int64Var(&opts.Seed, "seed", 1, "RNG seed; the same seed gives the same sample")
+	center := fs.String("center", "", "domain at the center of an ego network")
+	fs.IntVar(&opts.Radius, "radius", 1, "hops around -center included in an ego network")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	edgeTypes, categories, err := flags.parseSelection()
+	if err != nil {
+		return err
+	}
+	if opts.Method == export.SampleEgo && *center == "" {
+		return fmt.Errorf("-method ego requires -center <domain>")
+	}
+
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	if *center != "" {
+		if opts.Center, err = nodeIDOf(store, *center); err != nil {
+			return err
+		}
+	}
+
+	graph, err := export.Sample(export.StoreGraph{Store: store, EdgeTypes: edgeTypes, Categories: categories}, opts)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Sampled graph with %s", opts.Method)
+
+	return writeGraphOutput(*flags.output, *flags.format, func(w io.Writer) error {
+		return export.Write(w, *flags.format, graph, flags.opts)
+	})
+}
+
+// nodeIDOf returns the ID of domain's node in the active session
+func nodeIDOf(store *storage.Storage, domain string) (int, error) {
+	node, err := store.GetNode(strings.ToLower(domain))
+	if err != nil {
+		return 0, err
+	}
+	if node == nil {
+		return 0, fmt.Errorf("no node found for %q", domain)
+	}
+	return node.NodeID, nil
+}
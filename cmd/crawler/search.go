@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// searchLimit caps the number of results printed by the search command
+const searchLimit = 20
+
+// runSearchCommand prints nodes matching a free-text query
+func runSearchCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: search <query>")
+	}
+
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	results, err := store.SearchNodes(strings.Join(args, " "), searchLimit)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No matching domains")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DOMAIN\tDEGREE\tSCORE\tDESCRIPTION")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%d\t%.2f\t%s\n", r.Node.DomainName, r.Degree, r.Score, r.Node.Description)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/crawler"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// importSeeds bulk-seeds the session from an external index dump such as a
+// Common Crawl index query result, a CDX file, or a CSV of domains
+func importSeeds(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("db import-seeds", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "import at most this many domains (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: db import-seeds [-limit n] <file|->")
+	}
+
+	domains, skipped, err := crawler.ReadSeedFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	admits, err := crawler.SeedAdmission(cfg, store)
+	if err != nil {
+		return err
+	}
+	accepted := domains[:0]
+	filtered := 0
+	for _, domain := range domains {
+		if !admits(domain) {
+			filtered++
+			continue
+		}
+		accepted = append(accepted, domain)
+	}
+	if *limit > 0 && len(accepted) > *limit {
+		accepted = accepted[:*limit]
+	}
+
+	stats, err := store.ImportSeeds(accepted)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Imported seeds into session %s: %d new, %d already known, %d filtered or blocked, %d unparsable lines",
+		cfg.Session, stats.Inserted, stats.Existing, filtered, skipped)
+	if stats.Queued > 0 {
+		logrus.Infof("Appended %d seeds to the saved crawl queue", stats.Queued)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/api"
+	"github.com/alvmarrod/web-weaver/internal/crawler"
+	"github.com/alvmarrod/web-weaver/internal/metrics"
+)
+
+// shutdown coordinates a graceful stop requested by any source (signal,
+// natural completion, guards, budgets, or the admin API); the first reason
+// wins and later requests are ignored
+type shutdown struct {
+	runID   string
+	session string
+	tracker *metrics.Tracker
+	workers func() []crawler.WorkerStatus // nil until the crawler exists
+	live    func() metrics.LiveState      // nil until the crawler exists
+
+	mu        sync.Mutex
+	reason    string
+	requested chan struct{}
+}
+
+// newShutdown creates a coordinator for the given run
+func newShutdown(runID, session string, tracker *metrics.Tracker) *shutdown {
+	return &shutdown{
+		runID:     runID,
+		session:   session,
+		tracker:   tracker,
+		requested: make(chan struct{}),
+	}
+}
+
+// RequestStop starts the shutdown sequence with reason
+// Returns false if a shutdown was already requested
+func (s *shutdown) RequestStop(reason string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reason != "" {
+		return false
+	}
+	s.reason = reason
+	close(s.requested)
+	return true
+}
+
+// Requested is closed once a shutdown has been requested
+func (s *shutdown) Requested() <-chan struct{} {
+	return s.requested
+}
+
+// Reason returns the termination reason, or "" while running
+func (s *shutdown) Reason() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reason
+}
+
+// Status reports the run's state for the API
+func (s *shutdown) Status() api.CrawlStatus {
+	status := api.CrawlStatus{
+		RunID:   s.runID,
+		Session: s.session,
+		State:   api.StateRunning,
+		Metrics: s.tracker.GetSnapshot(),
+	}
+	if s.live != nil {
+		live := s.live()
+		status.QueueSize = live.QueueSize
+		status.InFlight = live.InFlight
+		status.ActiveWorkers = live.ActiveWorkers
+		status.VisitedSetSize = live.VisitedSetSize
+	}
+	if reason := s.Reason(); reason != "" {
+		status.State = api.StateStopping
+		status.TerminationReason = reason
+	}
+	return status
+}
+
+// Workers reports the crawler's workers for the API
+func (s *shutdown) Workers() []api.WorkerStatus {
+	if s.workers == nil {
+		return []api.WorkerStatus{}
+	}
+	now := time.Now()
+	workers := s.workers()
+	statuses := make([]api.WorkerStatus, len(workers))
+	for i, w := range workers {
+		status := api.WorkerStatus{
+			ID:       w.ID,
+			State:    w.State,
+			Domain:   w.Domain,
+			Since:    w.Since,
+			Fetching: make([]api.WorkerFetch, len(w.Fetching)),
+			Fetches:  w.Fetches,
+		}
+		for j, f := range w.Fetching {
+			status.Fetching[j] = api.WorkerFetch{Domain: f.Domain, URL: f.URL, Since: f.Since, ElapsedMs: now.Sub(f.Since).Milliseconds()}
+		}
+		if w.LastError != "" {
+			status.LastError = &api.WorkerError{Domain: w.LastErrorDomain, Message: w.LastError, At: w.LastErrorAt}
+		}
+		statuses[i] = status
+	}
+	return statuses
+}
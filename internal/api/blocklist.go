@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// LiveBlocklist is updated alongside storage so a running crawl reacts
+// immediately instead of at its next periodic reload
+type LiveBlocklist interface {
+	Add(domain string)
+	Remove(domain string)
+}
+
+// blockRequest is the body of POST /api/blocklist
+type blockRequest struct {
+	Domain    string `json:"domain"`
+	Reason    string `json:"reason"`
+	Tombstone bool   `json:"tombstone"`
+}
+
+// SetBlocklist attaches the running crawler's blocklist
+func (s *Server) SetBlocklist(blocklist LiveBlocklist) {
+	s.blocklist = blocklist
+}
+
+// registerBlocklist adds the /api/blocklist routes
+func (s *Server) registerBlocklist() {
+	s.mux.HandleFunc("GET /api/blocklist", s.handleListBlocked)
+	s.mux.Handle("POST /api/blocklist", s.requireToken(s.handleBlock))
+	s.mux.Handle("DELETE /api/blocklist/{domain}", s.requireToken(s.handleUnblock))
+}
+
+// handleListBlocked serves GET /api/blocklist
+func (s *Server) handleListBlocked(w http.ResponseWriter, r *http.Request) {
+	blocked, err := s.store.ListBlockedDomains()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": blocked})
+}
+
+// handleBlock serves POST /api/blocklist
+func (s *Server) handleBlock(w http.ResponseWriter, r *http.Request) {
+	var req blockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body"))
+		return
+	}
+
+	domain := strings.ToLower(strings.TrimSpace(req.Domain))
+	if domain == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("domain is required"))
+		return
+	}
+
+	if err := s.store.BlockDomain(domain, req.Reason); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if s.blocklist != nil {
+		s.blocklist.Add(domain)
+	}
+
+	tombstoned := 0
+	if req.Tombstone {
+		var err error
+		if tombstoned, err = s.store.TombstoneDomain(domain); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"domain": domain, "tombstoned_nodes": tombstoned})
+}
+
+// handleUnblock serves DELETE /api/blocklist/{domain}
+func (s *Server) handleUnblock(w http.ResponseWriter, r *http.Request) {
+	domain := strings.ToLower(r.PathValue("domain"))
+
+	removed, err := s.store.UnblockDomain(domain)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, fmt.Errorf("domain %q is not blocked", domain))
+		return
+	}
+	if s.blocklist != nil {
+		s.blocklist.Remove(domain)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
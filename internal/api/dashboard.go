@@ -0,0 +1,142 @@
+package api
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// Graph endpoint bounds: a force layout in the browser stays fluid up to a
+// few thousand nodes
+const (
+	defaultGraphNodes = 2000
+	maxGraphNodes     = 20000
+	maxGraphLinks     = 5 * maxGraphNodes
+	graphLinksPage    = 5000
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// dashboardAssets holds the scripts the dashboard loads, so it doesn't
+// depend on a CDN
+//
+//go:embed assets
+var dashboardAssets embed.FS
+
+// graphJSON is the body of GET /api/graph, in the shape force-graph reads
+type graphJSON struct {
+	Nodes     []graphNodeJSON `json:"nodes"`
+	Links     []graphLinkJSON `json:"links"`
+	Truncated bool            `json:"truncated"` // the stored graph has more than was returned
+}
+
+// graphNodeJSON is a node of GET /api/graph
+type graphNodeJSON struct {
+	ID     string `json:"id"`
+	Depth  int    `json:"depth"`
+	Status string `json:"status"`
+	Title  string `json:"title,omitempty"`
+}
+
+// graphLinkJSON is an edge of GET /api/graph
+type graphLinkJSON struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+	Weight int    `json:"weight"`
+}
+
+// registerDashboard adds the dashboard page and the graph it draws
+func (s *Server) registerDashboard() {
+	s.mux.HandleFunc("GET /dashboard", s.handleDashboard)
+	assets, _ := fs.Sub(dashboardAssets, "assets")
+	s.mux.Handle("GET /dashboard/assets/", http.StripPrefix("/dashboard/assets/", http.FileServerFS(assets)))
+	s.mux.Handle("GET /api/graph", s.pinReader(http.HandlerFunc(s.handleGraph)))
+}
+
+// handleDashboard serves GET /dashboard
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+// handleGraph serves GET /api/graph: the first limit nodes in discovery
+// order, which keeps the part of the graph closest to the seeds, and the
+// edges between them
+// Query: limit (default defaultGraphNodes, at most maxGraphNodes)
+func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
+	store := s.reader(r.Context())
+
+	limit, err := intParam(r.URL.Query(), "limit")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if limit == 0 {
+		limit = defaultGraphNodes
+	}
+	if limit > maxGraphNodes {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be at most %d", maxGraphNodes))
+		return
+	}
+
+	nodes, err := store.ListNodes(storage.NodeFilter{}, 0, limit+1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	graph := graphJSON{Nodes: []graphNodeJSON{}, Links: []graphLinkJSON{}}
+	if len(nodes) > limit {
+		nodes = nodes[:limit]
+		graph.Truncated = true
+	}
+	if len(nodes) == 0 {
+		writeJSON(w, http.StatusOK, graph)
+		return
+	}
+
+	domains := make(map[int]string, len(nodes))
+	for _, node := range nodes {
+		domains[node.NodeID] = node.DomainName
+		graph.Nodes = append(graph.Nodes, graphNodeJSON{
+			ID:     node.DomainName,
+			Depth:  node.LastDepth,
+			Status: node.Status,
+			Title:  node.Title,
+		})
+	}
+
+	// Node IDs grow with discovery, so the listed nodes are all those up
+	// to the last one
+	filter := storage.EdgeFilter{MaxNodeID: nodes[len(nodes)-1].NodeID}
+	for afterID := 0; ; {
+		edges, err := store.ListEdges(filter, afterID, graphLinksPage)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		for _, edge := range edges {
+			graph.Links = append(graph.Links, graphLinkJSON{
+				Source: domains[edge.FromNodeID],
+				Target: domains[edge.ToNodeID],
+				Type:   edge.Type,
+				Weight: edge.Weight,
+			})
+		}
+		if len(edges) < graphLinksPage {
+			break
+		}
+		if len(graph.Links) >= maxGraphLinks {
+			graph.Links = graph.Links[:maxGraphLinks]
+			graph.Truncated = true
+			break
+		}
+		afterID = edges[len(edges)-1].EdgeID
+	}
+
+	writeJSON(w, http.StatusOK, graph)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/alvmarrod/web-weaver/internal/events"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+// newEventStreamHandler streams crawl events as JSON text frames
+func newEventStreamHandler(bus *events.Bus) http.Handler {
+	return websocket.Server{
+		// Read-only stream: accept any origin, including non-browser clients
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			stream, cancel := bus.Subscribe()
+			defer cancel()
+
+			// Detect client disconnects; clients are not expected to send anything
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				var discard string
+				for websocket.Message.Receive(ws, &discard) == nil {
+				}
+			}()
+
+			for {
+				select {
+				case event := <-stream:
+					if err := websocket.JSON.Send(ws, event); err != nil {
+						logrus.Debugf("Event stream client gone: %v", err)
+						return
+					}
+				case <-closed:
+					return
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,576 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/graphql-go/graphql"
+)
+
+// Default and maximum hops of path searches; each hop may expand a large
+// part of the graph with a query per node
+const (
+	defaultMaxHops = 6
+	maxMaxHops     = 2 * defaultMaxHops
+)
+
+// graphqlRequest is the standard GraphQL-over-HTTP request body
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName"`
+}
+
+// page is the resolved value of a connection type
+type page struct {
+	Items       any
+	EndCursor   string `json:"endCursor"`
+	HasNextPage bool   `json:"hasNextPage"`
+}
+
+// newGraphQLHandler builds the schema and returns the /graphql handler
+func (s *Server) newGraphQLHandler() (http.Handler, error) {
+	schema, err := s.buildSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphqlRequest
+		switch r.Method {
+		case http.MethodGet:
+			req.Query = r.URL.Query().Get("query")
+			req.OperationName = r.URL.Query().Get("operationName")
+			if vars := r.URL.Query().Get("variables"); vars != "" {
+				if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+					writeError(w, http.StatusBadRequest, fmt.Errorf("invalid variables"))
+					return
+				}
+			}
+		case http.MethodPost:
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body"))
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  req.Query,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        r.Context(),
+		})
+		writeJSON(w, http.StatusOK, result)
+	}), nil
+}
+
+// buildSchema defines the read-only graph schema
+func (s *Server) buildSchema() (graphql.Schema, error) {
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"endCursor":   &graphql.Field{Type: graphql.String},
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+
+	linkStatsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LinkStats",
+		Fields: graphql.Fields{
+			"total": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.LinkStats).Total, nil },
+			},
+			"internal": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.LinkStats).Internal, nil },
+			},
+			"external": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.LinkStats).External, nil },
+			},
+			"externalDomains": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.LinkStats).ExternalDomains, nil },
+			},
+		},
+	})
+
+	responseType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Response",
+		Fields: graphql.Fields{
+			"statusCode": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Response).StatusCode, nil },
+			},
+			"responseTimeMs": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return int(p.Source.(*storage.Response).ResponseTimeMs), nil
+				},
+			},
+			"contentLength": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return int(p.Source.(*storage.Response).ContentLength), nil
+				},
+			},
+			"crawledAt": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.DateTime),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Response).CrawledAt, nil },
+			},
+		},
+	})
+
+	var nodeType, edgeType *graphql.Object
+
+	connection := func(name string, item func() *graphql.Object) *graphql.Object {
+		return graphql.NewObject(graphql.ObjectConfig{
+			Name: name,
+			Fields: graphql.FieldsThunk(func() graphql.Fields {
+				return graphql.Fields{
+					"items": &graphql.Field{
+						Type:    graphql.NewList(item()),
+						Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(page).Items, nil },
+					},
+					"pageInfo": &graphql.Field{
+						Type:    pageInfoType,
+						Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source, nil },
+					},
+				}
+			}),
+		})
+	}
+	nodeConnectionType := connection("NodeConnection", func() *graphql.Object { return nodeType })
+	edgeConnectionType := connection("EdgeConnection", func() *graphql.Object { return edgeType })
+
+	directionEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Direction",
+		Values: graphql.EnumValueConfigMap{
+			"OUT":  &graphql.EnumValueConfig{Value: storage.EdgesOut},
+			"IN":   &graphql.EnumValueConfig{Value: storage.EdgesIn},
+			"BOTH": &graphql.EnumValueConfig{Value: storage.EdgesBoth},
+		},
+	})
+
+	edgeTypeValues := graphql.EnumValueConfigMap{}
+	for _, t := range storage.EdgeTypes {
+		edgeTypeValues[strings.ToUpper(t)] = &graphql.EnumValueConfig{Value: t}
+	}
+	edgeTypeEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:   "EdgeType",
+		Values: edgeTypeValues,
+	})
+
+	pageArgs := func(extra graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		args := graphql.FieldConfigArgument{
+			"first": &graphql.ArgumentConfig{Type: graphql.Int},
+			"after": &graphql.ArgumentConfig{Type: graphql.String},
+		}
+		for k, v := range extra {
+			args[k] = v
+		}
+		return args
+	}
+
+	edgeArgs := pageArgs(graphql.FieldConfigArgument{
+		"minWeight": &graphql.ArgumentConfig{Type: graphql.Int},
+		"types":     &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(edgeTypeEnum))},
+	})
+
+	nodeEdges := func(direction storage.EdgeDirection) *graphql.Field {
+		return &graphql.Field{
+			Type: edgeConnectionType,
+			Args: edgeArgs,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				node := p.Source.(*storage.Node)
+				return s.resolveEdges(s.reader(p.Context), p.Args, storage.EdgeFilter{NodeID: node.NodeID, Direction: direction})
+			},
+		}
+	}
+
+	nodeType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Node",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": &graphql.Field{
+					Type:    graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Node).NodeID, nil },
+				},
+				"domain": &graphql.Field{
+					Type:    graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Node).DomainName, nil },
+				},
+				"title": &graphql.Field{
+					Type:    graphql.String,
+					Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Node).Title, nil },
+				},
+				"metaDescription": &graphql.Field{
+					Type:    graphql.String,
+					Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Node).MetaDescription, nil },
+				},
+				"description": &graphql.Field{
+					Type:    graphql.String,
+					Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Node).Description, nil },
+				},
+				"linkStats": &graphql.Field{
+					Type: linkStatsType,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						if stats := p.Source.(*storage.Node).LinkStats; stats != nil {
+							return stats, nil
+						}
+						return nil, nil
+					},
+				},
+				"response": &graphql.Field{
+					Type: responseType,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						if response := p.Source.(*storage.Node).Response; response != nil {
+							return response, nil
+						}
+						return nil, nil
+					},
+				},
+				"crawlCount": &graphql.Field{
+					Type:    graphql.Int,
+					Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Node).CrawlCount, nil },
+				},
+				"lastDepth": &graphql.Field{
+					Type:    graphql.Int,
+					Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Node).LastDepth, nil },
+				},
+				"status": &graphql.Field{
+					Type:    graphql.String,
+					Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Node).Status, nil },
+				},
+				"language": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						if language := p.Source.(*storage.Node).Language; language != "" {
+							return language, nil
+						}
+						return nil, nil
+					},
+				},
+				"reputation": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						if verdict := p.Source.(*storage.Node).Reputation; verdict != "" {
+							return verdict, nil
+						}
+						return nil, nil
+					},
+				},
+				"category": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						if category := p.Source.(*storage.Node).Category; category != "" {
+							return category, nil
+						}
+						return nil, nil
+					},
+				},
+				"sitemapPages": &graphql.Field{
+					Type:    graphql.Int,
+					Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Node).SitemapPages, nil },
+				},
+				"createdAt": &graphql.Field{
+					Type:    graphql.DateTime,
+					Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Node).CreatedAt, nil },
+				},
+				"parent": &graphql.Field{
+					Type: nodeType,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						id := p.Source.(*storage.Node).ParentNodeID
+						if id == 0 {
+							return nil, nil
+						}
+						return s.reader(p.Context).GetNodeByID(id)
+					},
+				},
+				"seed": &graphql.Field{
+					Type: nodeType,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						id := p.Source.(*storage.Node).SeedNodeID
+						if id == 0 {
+							return nil, nil
+						}
+						return s.reader(p.Context).GetNodeByID(id)
+					},
+				},
+				"sourceUrl": &graphql.Field{
+					Type:    graphql.String,
+					Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Node).SourceURL, nil },
+				},
+				"outEdges": nodeEdges(storage.EdgesOut),
+				"inEdges":  nodeEdges(storage.EdgesIn),
+				"neighbors": &graphql.Field{
+					Type: graphql.NewList(nodeType),
+					Args: graphql.FieldConfigArgument{
+						"direction": &graphql.ArgumentConfig{Type: directionEnum, DefaultValue: storage.EdgesOut},
+						"minWeight": &graphql.ArgumentConfig{Type: graphql.Int},
+						"first":     &graphql.ArgumentConfig{Type: graphql.Int},
+					},
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return s.resolveNeighbors(s.reader(p.Context), p.Source.(*storage.Node), p.Args)
+					},
+				},
+			}
+		}),
+	})
+
+	edgeType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Edge",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Edge).EdgeID, nil },
+			},
+			"type": &graphql.Field{
+				Type:    graphql.NewNonNull(edgeTypeEnum),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Edge).Type, nil },
+			},
+			"weight": &graphql.Field{
+				Type:    graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Edge).Weight, nil },
+			},
+			"anchorText": &graphql.Field{
+				Type:    graphql.String,
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Edge).AnchorText, nil },
+			},
+			"rel": &graphql.Field{
+				Type:    graphql.String,
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*storage.Edge).Rel, nil },
+			},
+			"from": &graphql.Field{
+				Type: nodeType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return s.reader(p.Context).GetNodeByID(p.Source.(*storage.Edge).FromNodeID)
+				},
+			},
+			"to": &graphql.Field{
+				Type: nodeType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return s.reader(p.Context).GetNodeByID(p.Source.(*storage.Edge).ToNodeID)
+				},
+			},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"node": &graphql.Field{
+				Type: nodeType,
+				Args: graphql.FieldConfigArgument{
+					"domain": &graphql.ArgumentConfig{Type: graphql.String},
+					"id":     &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if id, ok := p.Args["id"].(int); ok {
+						return s.reader(p.Context).GetNodeByID(id)
+					}
+					if domain, ok := p.Args["domain"].(string); ok {
+						return s.reader(p.Context).GetNode(domain)
+					}
+					return nil, fmt.Errorf("node requires id or domain")
+				},
+			},
+			"nodes": &graphql.Field{
+				Type: nodeConnectionType,
+				Args: pageArgs(graphql.FieldConfigArgument{
+					"minDepth":       &graphql.ArgumentConfig{Type: graphql.Int},
+					"maxDepth":       &graphql.ArgumentConfig{Type: graphql.Int},
+					"createdAfter":   &graphql.ArgumentConfig{Type: graphql.DateTime},
+					"domainContains": &graphql.ArgumentConfig{Type: graphql.String},
+					"seed":           &graphql.ArgumentConfig{Type: graphql.String},
+					"status":         &graphql.ArgumentConfig{Type: graphql.String},
+					"category":       &graphql.ArgumentConfig{Type: graphql.String},
+				}),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return s.resolveNodes(s.reader(p.Context), p.Args) },
+			},
+			"edges": &graphql.Field{
+				Type: edgeConnectionType,
+				Args: edgeArgs,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return s.resolveEdges(s.reader(p.Context), p.Args, storage.EdgeFilter{})
+				},
+			},
+			"path": &graphql.Field{
+				Type: graphql.NewList(nodeType),
+				Args: graphql.FieldConfigArgument{
+					"from":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"to":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"maxHops": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultMaxHops},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) { return s.resolvePath(s.reader(p.Context), p.Args) },
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// resolveNodes lists a page of nodes
+func (s *Server) resolveNodes(store *storage.Storage, args map[string]any) (any, error) {
+	afterID, limit, err := pageBounds(args)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := storage.NodeFilter{}
+	if depth, ok := args["minDepth"].(int); ok {
+		filter.MinDepth = &depth
+	}
+	if depth, ok := args["maxDepth"].(int); ok {
+		filter.MaxDepth = &depth
+	}
+	filter.CreatedAfter, _ = args["createdAfter"].(time.Time)
+	filter.DomainContains, _ = args["domainContains"].(string)
+	filter.Seed, _ = args["seed"].(string)
+	filter.Status, _ = args["status"].(string)
+	if category, _ := args["category"].(string); category != "" {
+		filter.Categories = []string{category}
+	}
+
+	nodes, err := store.ListNodes(filter, afterID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := page{HasNextPage: len(nodes) > limit}
+	if result.HasNextPage {
+		nodes = nodes[:limit]
+	}
+	if len(nodes) > 0 {
+		result.EndCursor = encodeCursor(nodes[len(nodes)-1].NodeID)
+	}
+	result.Items = nodes
+	return result, nil
+}
+
+// resolveEdges lists a page of edges, optionally scoped to one node
+func (s *Server) resolveEdges(store *storage.Storage, args map[string]any, filter storage.EdgeFilter) (any, error) {
+	afterID, limit, err := pageBounds(args)
+	if err != nil {
+		return nil, err
+	}
+	filter.MinWeight, _ = args["minWeight"].(int)
+	if types, ok := args["types"].([]any); ok {
+		for _, t := range types {
+			if edgeType, ok := t.(string); ok {
+				filter.Types = append(filter.Types, edgeType)
+			}
+		}
+	}
+
+	edges, err := store.ListEdges(filter, afterID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := page{HasNextPage: len(edges) > limit}
+	if result.HasNextPage {
+		edges = edges[:limit]
+	}
+	if len(edges) > 0 {
+		result.EndCursor = encodeCursor(edges[len(edges)-1].EdgeID)
+	}
+	result.Items = edges
+	return result, nil
+}
+
+// resolveNeighbors returns the nodes adjacent to node
+func (s *Server) resolveNeighbors(store *storage.Storage, node *storage.Node, args map[string]any) (any, error) {
+	direction, _ := args["direction"].(storage.EdgeDirection)
+	minWeight, _ := args["minWeight"].(int)
+	first, _ := args["first"].(int)
+
+	edges, err := store.ListEdges(storage.EdgeFilter{
+		NodeID:    node.NodeID,
+		Direction: direction,
+		MinWeight: minWeight,
+	}, 0, clampPageSize(first))
+	if err != nil {
+		return nil, err
+	}
+
+	neighbors := make([]*storage.Node, 0, len(edges))
+	for _, edge := range edges {
+		id := edge.ToNodeID
+		if id == node.NodeID {
+			id = edge.FromNodeID
+		}
+		neighbor, err := store.GetNodeByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if neighbor != nil {
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+	return neighbors, nil
+}
+
+// resolvePath returns the nodes along a shortest directed path
+func (s *Server) resolvePath(store *storage.Storage, args map[string]any) (any, error) {
+	from, err := store.GetNode(args["from"].(string))
+	if err != nil {
+		return nil, err
+	}
+	if from == nil {
+		return nil, fmt.Errorf("unknown domain %q", args["from"])
+	}
+	to, err := store.GetNode(args["to"].(string))
+	if err != nil {
+		return nil, err
+	}
+	if to == nil {
+		return nil, fmt.Errorf("unknown domain %q", args["to"])
+	}
+
+	maxHops, _ := args["maxHops"].(int)
+	ids, err := store.ShortestPath(from.NodeID, to.NodeID, clampHops(maxHops))
+	if err != nil {
+		return nil, err
+	}
+
+	path := make([]*storage.Node, 0, len(ids))
+	for _, id := range ids {
+		node, err := store.GetNodeByID(id)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, node)
+	}
+	return path, nil
+}
+
+// clampHops applies the default and maximum hops of path searches
+func clampHops(hops int) int {
+	if hops <= 0 {
+		return defaultMaxHops
+	}
+	return min(hops, maxMaxHops)
+}
+
+// pageBounds extracts the cursor and page size from connection arguments
+func pageBounds(args map[string]any) (afterID, limit int, err error) {
+	after, _ := args["after"].(string)
+	afterID, err = decodeCursor(after)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	first, _ := args["first"].(int)
+	return afterID, clampPageSize(first), nil
+}
@@ -0,0 +1,394 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// nodeJSON is the REST representation of a node
+type nodeJSON struct {
+	ID              int            `json:"id"`
+	Domain          string         `json:"domain"`
+	Title           string         `json:"title"`
+	MetaDescription string         `json:"meta_description"`
+	Description     string         `json:"description"`
+	LinkStats       *linkStatsJSON `json:"link_stats,omitempty"`
+	Response        *responseJSON  `json:"response,omitempty"`
+	CrawlCount      int            `json:"crawl_count"`
+	LastDepth       int            `json:"last_depth"`
+	Status          string         `json:"status"`
+	ParentID        int            `json:"parent_id,omitempty"`
+	SeedID          int            `json:"seed_id,omitempty"`
+	SourceURL       string         `json:"source_url,omitempty"`
+	Language        string         `json:"language,omitempty"`
+	Reputation      string         `json:"reputation,omitempty"`
+	Category        string         `json:"category,omitempty"`
+	SitemapPages    int            `json:"sitemap_pages,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+// linkStatsJSON is the REST representation of a node's outbound link stats
+type linkStatsJSON struct {
+	Total           int `json:"total"`
+	Internal        int `json:"internal"`
+	External        int `json:"external"`
+	ExternalDomains int `json:"external_domains"`
+}
+
+// edgeJSON is the REST representation of an edge
+type edgeJSON struct {
+	ID         int    `json:"id"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Type       string `json:"type"`
+	Weight     int    `json:"weight"`
+	AnchorText string `json:"anchor_text,omitempty"`
+	Rel        string `json:"rel,omitempty"`
+}
+
+// searchResultJSON is the REST representation of a search match
+type searchResultJSON struct {
+	nodeJSON
+	Score  float64 `json:"score"`
+	Degree int     `json:"degree"`
+}
+
+// pageJSON wraps a page of results with the cursor for the next one
+type pageJSON struct {
+	Items      any    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// registerREST adds the /api routes
+func (s *Server) registerREST() {
+	s.mux.Handle("GET /api/nodes", s.pinReader(http.HandlerFunc(s.handleListNodes)))
+	s.mux.Handle("GET /api/nodes/{domain}", s.pinReader(http.HandlerFunc(s.handleGetNode)))
+	s.mux.Handle("GET /api/nodes/{domain}/edges", s.pinReader(http.HandlerFunc(s.handleListNodeEdges)))
+	s.mux.Handle("GET /api/search", s.pinReader(http.HandlerFunc(s.handleSearch)))
+}
+
+// handleListNodes serves GET /api/nodes
+// Query: limit, cursor, depth, min_depth, max_depth, created_after (RFC 3339),
+// seed (domain of the seed that first led to the node), status
+func (s *Server) handleListNodes(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	afterID, limit, err := restPageBounds(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	filter, err := nodeFilterParams(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	nodes, err := s.reader(r.Context()).ListNodes(filter, afterID, limit+1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	result := pageJSON{}
+	if len(nodes) > limit {
+		nodes = nodes[:limit]
+		result.NextCursor = encodeCursor(nodes[len(nodes)-1].NodeID)
+	}
+
+	items := make([]nodeJSON, len(nodes))
+	for i, node := range nodes {
+		items[i] = toNodeJSON(node)
+	}
+	result.Items = items
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleSearch serves GET /api/search, the best matches first, like the
+// search command
+// Query: q (required), limit
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	query := strings.TrimSpace(q.Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("q is required"))
+		return
+	}
+	limit, err := intParam(q, "limit")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	results, err := s.reader(r.Context()).SearchNodes(query, clampPageSize(limit))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	items := make([]searchResultJSON, len(results))
+	for i, result := range results {
+		items[i] = searchResultJSON{nodeJSON: toNodeJSON(&result.Node), Score: result.Score, Degree: result.Degree}
+	}
+	writeJSON(w, http.StatusOK, pageJSON{Items: items})
+}
+
+// handleGetNode serves GET /api/nodes/{domain}
+func (s *Server) handleGetNode(w http.ResponseWriter, r *http.Request) {
+	node, ok := s.lookupNode(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, toNodeJSON(node))
+}
+
+// handleListNodeEdges serves GET /api/nodes/{domain}/edges
+// Query: limit, cursor, min_weight, direction (out, in, both; default out),
+// type (comma-separated edge types; default all)
+func (s *Server) handleListNodeEdges(w http.ResponseWriter, r *http.Request) {
+	store := s.reader(r.Context())
+	node, ok := s.lookupNode(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	afterID, limit, err := restPageBounds(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	minWeight, err := intParam(q, "min_weight")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	direction := storage.EdgeDirection(strings.ToLower(q.Get("direction")))
+	switch direction {
+	case "":
+		direction = storage.EdgesOut
+	case storage.EdgesOut, storage.EdgesIn, storage.EdgesBoth:
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("direction must be out, in, or both"))
+		return
+	}
+
+	edgeTypes, err := storage.ParseEdgeTypes(q.Get("type"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	edges, err := store.ListEdges(storage.EdgeFilter{
+		NodeID:    node.NodeID,
+		Direction: direction,
+		MinWeight: minWeight,
+		Types:     edgeTypes,
+	}, afterID, limit+1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	result := pageJSON{}
+	if len(edges) > limit {
+		edges = edges[:limit]
+		result.NextCursor = encodeCursor(edges[len(edges)-1].EdgeID)
+	}
+
+	// Resolve node IDs to domains, caching across the page
+	domains := map[int]string{node.NodeID: node.DomainName}
+	domainOf := func(id int) (string, error) {
+		if domain, ok := domains[id]; ok {
+			return domain, nil
+		}
+		n, err := store.GetNodeByID(id)
+		if err != nil || n == nil {
+			return "", err
+		}
+		domains[id] = n.DomainName
+		return n.DomainName, nil
+	}
+
+	items := make([]edgeJSON, 0, len(edges))
+	for _, edge := range edges {
+		from, err := domainOf(edge.FromNodeID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		to, err := domainOf(edge.ToNodeID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		items = append(items, edgeJSON{
+			ID: edge.EdgeID, From: from, To: to, Type: edge.Type, Weight: edge.Weight,
+			AnchorText: edge.AnchorText, Rel: edge.Rel,
+		})
+	}
+	result.Items = items
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// lookupNode resolves the {domain} path value, writing a 404 if missing
+func (s *Server) lookupNode(w http.ResponseWriter, r *http.Request) (*storage.Node, bool) {
+	domain := strings.ToLower(r.PathValue("domain"))
+	node, err := s.reader(r.Context()).GetNode(domain)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if node == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown domain %q", domain))
+		return nil, false
+	}
+	return node, true
+}
+
+// restPageBounds parses the cursor and limit query parameters
+func restPageBounds(q url.Values) (afterID, limit int, err error) {
+	afterID, err = decodeCursor(q.Get("cursor"))
+	if err != nil {
+		return 0, 0, err
+	}
+	limit, err = intParam(q, "limit")
+	if err != nil {
+		return 0, 0, err
+	}
+	return afterID, clampPageSize(limit), nil
+}
+
+// nodeFilterParams parses the node listing filters
+func nodeFilterParams(q url.Values) (storage.NodeFilter, error) {
+	var filter storage.NodeFilter
+	var err error
+
+	if filter.MinDepth, err = optionalIntParam(q, "min_depth"); err != nil {
+		return filter, err
+	}
+	if filter.MaxDepth, err = optionalIntParam(q, "max_depth"); err != nil {
+		return filter, err
+	}
+	if q.Has("depth") {
+		depth, err := optionalIntParam(q, "depth")
+		if err != nil {
+			return filter, err
+		}
+		filter.MinDepth, filter.MaxDepth = depth, depth
+	}
+	if q.Has("created_after") {
+		filter.CreatedAfter, err = time.Parse(time.RFC3339, q.Get("created_after"))
+		if err != nil {
+			return filter, fmt.Errorf("created_after must be an RFC 3339 timestamp")
+		}
+	}
+	filter.Seed = q.Get("seed")
+	if filter.Status = q.Get("status"); filter.Status != "" && !slices.Contains(storage.NodeStatuses, filter.Status) {
+		return filter, fmt.Errorf("status must be one of %s", strings.Join(storage.NodeStatuses, ", "))
+	}
+	if category := q.Get("category"); category != "" {
+		if !slices.Contains(storage.Categories, category) {
+			return filter, fmt.Errorf("category must be one of %s", strings.Join(storage.Categories, ", "))
+		}
+		filter.Categories = []string{category}
+	}
+
+	return filter, nil
+}
+
+// responseJSON is the REST representation of a node's latest front page
+// response
+type responseJSON struct {
+	StatusCode     int       `json:"status_code"`
+	ResponseTimeMs int64     `json:"response_time_ms"`
+	ContentLength  int64     `json:"content_length"`
+	CrawledAt      time.Time `json:"crawled_at"`
+}
+
+// intParam parses an optional non-negative integer query parameter
+func intParam(q url.Values, name string) (int, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return value, nil
+}
+
+// optionalIntParam parses a non-negative integer query parameter, nil when
+// absent, for filters where 0 is a value rather than unset
+func optionalIntParam(q url.Values, name string) (*int, error) {
+	if !q.Has(name) {
+		return nil, nil
+	}
+	value, err := strconv.Atoi(q.Get(name))
+	if err != nil || value < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return &value, nil
+}
+
+// toNodeJSON converts a storage node to its REST representation
+func toNodeJSON(node *storage.Node) nodeJSON {
+	var links *linkStatsJSON
+	if node.LinkStats != nil {
+		links = &linkStatsJSON{
+			Total:           node.LinkStats.Total,
+			Internal:        node.LinkStats.Internal,
+			External:        node.LinkStats.External,
+			ExternalDomains: node.LinkStats.ExternalDomains,
+		}
+	}
+	var response *responseJSON
+	if r := node.Response; r != nil {
+		response = &responseJSON{
+			StatusCode:     r.StatusCode,
+			ResponseTimeMs: r.ResponseTimeMs,
+			ContentLength:  r.ContentLength,
+			CrawledAt:      r.CrawledAt,
+		}
+	}
+
+	return nodeJSON{
+		ID:              node.NodeID,
+		Domain:          node.DomainName,
+		Title:           node.Title,
+		MetaDescription: node.MetaDescription,
+		Description:     node.Description,
+		LinkStats:       links,
+		Response:        response,
+		CrawlCount:      node.CrawlCount,
+		LastDepth:       node.LastDepth,
+		Status:          node.Status,
+		ParentID:        node.ParentNodeID,
+		SeedID:          node.SeedNodeID,
+		SourceURL:       node.SourceURL,
+		Language:        node.Language,
+		Reputation:      node.Reputation,
+		Category:        node.Category,
+		SitemapPages:    node.SitemapPages,
+		CreatedAt:       node.CreatedAt,
+	}
+}
+
+// writeError writes a JSON error body
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
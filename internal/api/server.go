@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/events"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// Default and maximum page sizes for paginated reads
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// Server is the optional HTTP listener exposing read APIs over the graph
+// Reads go to SQLite, so they reflect the graph as of the last flush, or of
+// the last snapshot when reading from one
+type Server struct {
+	store      *storage.Storage
+	replica    *storage.Replica // serves graph reads; nil reads from store
+	mux        *http.ServeMux
+	httpServer *http.Server
+	blocklist  LiveBlocklist
+	control    CrawlControl
+	token      string // guards routes that change the crawl; empty allows loopback only
+}
+
+// NewServer creates a server bound to addr with all API routes registered
+// The live event stream is only served when bus is non-nil
+func NewServer(addr string, store *storage.Storage, bus *events.Bus) (*Server, error) {
+	s := &Server{
+		store: store,
+		mux:   http.NewServeMux(),
+	}
+
+	graphqlHandler, err := s.newGraphQLHandler()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build GraphQL schema: %w", err)
+	}
+	s.mux.Handle("/graphql", s.pinReader(graphqlHandler))
+	s.registerREST()
+	s.registerBlocklist()
+	s.registerStatus()
+	s.registerDashboard()
+	if bus != nil {
+		s.mux.Handle("/ws/events", newEventStreamHandler(bus))
+	}
+
+	s.httpServer = &http.Server{
+		Addr:              addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s, nil
+}
+
+// SetReplica makes graph reads go to replica instead of the crawler's
+// storage; call it before Start
+func (s *Server) SetReplica(replica *storage.Replica) {
+	s.replica = replica
+}
+
+// SetToken sets the bearer token required by routes that change the crawl;
+// call it before Start. Without one, those routes only answer loopback clients
+func (s *Server) SetToken(token string) {
+	s.token = token
+}
+
+// requireToken guards a route that changes the crawl: the request must
+// carry the bearer token, or come from loopback if none is configured, and
+// a browser request must come from a page served by this server
+func (s *Server) requireToken(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				writeError(w, http.StatusForbidden, fmt.Errorf("cross-origin requests are not allowed"))
+				return
+			}
+		}
+
+		if s.token == "" {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+				writeError(w, http.StatusForbidden, fmt.Errorf("only loopback clients are allowed without api_token"))
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid bearer token"))
+			return
+		}
+		next(w, r)
+	})
+}
+
+// readerKey is the context key of the storage a request reads from
+type readerKey struct{}
+
+// pinReader makes every read of a request go to the same storage, held
+// until the request ends, so the request sees a single snapshot even if the
+// replica is refreshed meanwhile
+func (s *Server) pinReader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		store := s.store
+		if s.replica != nil {
+			var release func()
+			store, release = s.replica.Pin()
+			defer release()
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), readerKey{}, store)))
+	})
+}
+
+// reader returns the storage pinned to a request's context
+func (s *Server) reader(ctx context.Context) *storage.Storage {
+	if store, ok := ctx.Value(readerKey{}).(*storage.Storage); ok {
+		return store
+	}
+	return s.store
+}
+
+// Handle registers an additional handler on the shared listener
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start begins serving in the background
+func (s *Server) Start() {
+	go func() {
+		logrus.Infof("HTTP API listening on %s", s.httpServer.Addr)
+		if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Errorf("HTTP API stopped: %v", err)
+		}
+	}()
+}
+
+// Shutdown stops accepting requests and waits for active ones to finish
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Warnf("Failed to encode API response: %v", err)
+	}
+}
+
+// encodeCursor turns a row ID into an opaque pagination cursor
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+// decodeCursor parses a cursor produced by encodeCursor; empty means start
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	id, err := strconv.Atoi(string(raw))
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return id, nil
+}
+
+// clampPageSize applies the default and maximum page sizes
+func clampPageSize(size int) int {
+	if size <= 0 {
+		return defaultPageSize
+	}
+	return min(size, maxPageSize)
+}
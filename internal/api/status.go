@@ -0,0 +1,108 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// Crawl states reported by GET /api/status
+const (
+	StateRunning  = "running"
+	StateStopping = "stopping"
+)
+
+// CrawlControl lets the API report on and stop the running crawl
+type CrawlControl interface {
+	Status() CrawlStatus
+	Workers() []WorkerStatus
+	RequestStop(reason string) bool
+}
+
+// CrawlStatus is the body of GET /api/status
+type CrawlStatus struct {
+	RunID             string          `json:"run_id"`
+	Session           string          `json:"session"`
+	State             string          `json:"state"`
+	TerminationReason string          `json:"termination_reason,omitempty"`
+	Metrics           storage.Metrics `json:"metrics"`
+
+	// Frontier and worker pool at the time of the request; zero until the
+	// crawler is up
+	QueueSize      int `json:"queue_size"`
+	InFlight       int `json:"in_flight"`
+	ActiveWorkers  int `json:"active_workers"`
+	VisitedSetSize int `json:"visited_set_size"`
+}
+
+// WorkerStatus is one worker in GET /api/admin/workers
+type WorkerStatus struct {
+	ID        int           `json:"id"`
+	State     string        `json:"state"`
+	Domain    string        `json:"domain,omitempty"`
+	Since     time.Time     `json:"since"`
+	Fetching  []WorkerFetch `json:"fetching"`
+	Fetches   int           `json:"fetches"`
+	LastError *WorkerError  `json:"last_error,omitempty"`
+}
+
+// WorkerFetch is a fetch a worker has in flight
+type WorkerFetch struct {
+	Domain    string    `json:"domain"`
+	URL       string    `json:"url"`
+	Since     time.Time `json:"since"`
+	ElapsedMs int64     `json:"elapsed_ms"`
+}
+
+// WorkerError is the last fetch a worker saw fail
+type WorkerError struct {
+	Domain  string    `json:"domain"`
+	Message string    `json:"message"`
+	At      time.Time `json:"at"`
+}
+
+// SetControl attaches the running crawl and enables the status and stop routes
+func (s *Server) SetControl(control CrawlControl) {
+	s.control = control
+}
+
+// registerStatus adds the status and admin routes
+func (s *Server) registerStatus() {
+	s.mux.HandleFunc("GET /api/status", s.handleStatus)
+	s.mux.Handle("GET /api/admin/workers", s.requireToken(s.handleWorkers))
+	s.mux.Handle("POST /api/admin/stop", s.requireToken(s.handleStop))
+}
+
+// handleStatus serves GET /api/status
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if s.control == nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("no crawl attached"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.control.Status())
+}
+
+// handleWorkers serves GET /api/admin/workers
+func (s *Server) handleWorkers(w http.ResponseWriter, r *http.Request) {
+	if s.control == nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("no crawl attached"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": s.control.Workers()})
+}
+
+// handleStop serves POST /api/admin/stop, running the same graceful
+// shutdown as a signal: flush, save the queue, write metrics
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	if s.control == nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("no crawl attached"))
+		return
+	}
+	if !s.control.RequestStop(storage.TerminationAdminStop) {
+		writeError(w, http.StatusConflict, fmt.Errorf("crawl is already stopping (%s)", s.control.Status().TerminationReason))
+		return
+	}
+	writeJSON(w, http.StatusAccepted, s.control.Status())
+}
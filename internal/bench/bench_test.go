@@ -0,0 +1,294 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/crawler"
+	"github.com/alvmarrod/web-weaver/internal/memory"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// dataset is shared by the benchmarks and generated on first use, so
+// plain test runs don't pay for it
+var dataset = sync.OnceValue(func() *Dataset { return NewDataset(1) })
+
+func TestMain(m *testing.M) {
+	// Flushes log at Info; keep the benchmark output readable
+	logrus.SetLevel(logrus.WarnLevel)
+	os.Exit(m.Run())
+}
+
+func BenchmarkFrontier(b *testing.B) {
+	d := dataset()
+	b.Run("PushPop", d.benchFrontierPushPop)
+	b.Run("PushDuplicate", d.benchFrontierPushDuplicate)
+}
+
+func BenchmarkFilter(b *testing.B) {
+	b.Run("FilterLinks", dataset().benchFilterLinks)
+}
+
+func BenchmarkLimiter(b *testing.B) {
+	b.Run("Add", dataset().benchLimiterAdd)
+}
+
+func BenchmarkStorage(b *testing.B) {
+	d := dataset()
+	b.Run("UpsertNode", d.benchUpsertNode)
+	b.Run("UpsertEdge", d.benchUpsertEdge)
+}
+
+func BenchmarkMemoryGraph(b *testing.B) {
+	d := dataset()
+	b.Run("UpsertEdge", d.benchMemoryUpsertEdge)
+	b.Run("Flush", d.benchMemoryFlush)
+}
+
+func BenchmarkQueue(b *testing.B) {
+	d := dataset()
+	b.Run("SaveRows", func(b *testing.B) { d.benchSaveQueue(b, storage.QueueCodecRows) })
+	b.Run("SaveBinary", func(b *testing.B) { d.benchSaveQueue(b, storage.QueueCodecBinary) })
+}
+
+func BenchmarkParse(b *testing.B) {
+	d := dataset()
+	b.Run("Goquery", d.benchParseGoquery)
+	b.Run("Tokenizer", d.benchParseTokenizer)
+}
+
+// benchFrontierPushPop queues entries over all depths and drains them, in
+// rounds of one entry per domain and depth; one op is one entry pushed and
+// popped
+func (d *Dataset) benchFrontierPushPop(b *testing.B) {
+	const depths = 6
+	for done := 0; done < b.N; {
+		f := crawler.NewFrontier(depths-1, 8, 0, 0, 1<<30, crawler.NewHostLatency(0))
+		round := min(b.N-done, len(d.Domains)*depths)
+		for i := range round {
+			f.Push(storage.QueueEntry{
+				DomainName: d.Domains[i%len(d.Domains)],
+				Depth:      i / len(d.Domains),
+				Priority:   float64(i % 7),
+			})
+		}
+		for !f.IsEmpty() {
+			f.Pop(context.Background())
+		}
+		f.Stop()
+		done += round
+	}
+}
+
+// benchFrontierPushDuplicate pushes domains already queued, as most links
+// found late in a crawl are
+func (d *Dataset) benchFrontierPushDuplicate(b *testing.B) {
+	f := crawler.NewFrontier(5, 8, 0, 0, 1<<30, crawler.NewHostLatency(0))
+	defer f.Stop()
+	for _, domain := range d.Domains {
+		f.Push(storage.QueueEntry{DomainName: domain, Depth: 1})
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		f.Push(storage.QueueEntry{DomainName: d.Domains[i%len(d.Domains)], Depth: 1})
+	}
+}
+
+// benchFilterLinks filters one page's links through exclude and include
+// patterns; one op is one page
+func (d *Dataset) benchFilterLinks(b *testing.B) {
+	rules := func(field string, patterns ...string) []config.FilterRule {
+		compiled := make([]config.FilterRule, len(patterns))
+		for i, pattern := range patterns {
+			compiled[i] = config.FilterRule{Field: field, Index: i, Pattern: pattern, Regexp: regexp.MustCompile(pattern)}
+		}
+		return compiled
+	}
+	filter := crawler.NewDomainFilter(
+		rules("exclude_patterns", `\.es$`, `^ads\.`, `root1[0-9]\.`),
+		rules("include_patterns", `\.(com|org|net|io|dev)$`, `\.co\.uk$`),
+	)
+
+	b.ResetTimer()
+	for i := range b.N {
+		n := i % len(d.Pages)
+		filter.FilterLinks("https://"+d.Domains[n]+"/", d.Pages[n], 10)
+	}
+}
+
+// benchLimiterAdd counts domains against their root's subdomain limit
+func (d *Dataset) benchLimiterAdd(b *testing.B) {
+	limiter := crawler.NewSubdomainLimiter(3)
+
+	b.ResetTimer()
+	for i := range b.N {
+		limiter.Add(d.Domains[i%len(d.Domains)])
+	}
+}
+
+// benchUpsertNode upserts nodes one statement at a time, inserting each
+// domain once and updating it afterwards
+func (d *Dataset) benchUpsertNode(b *testing.B) {
+	store := openStore(b)
+	defer store.Close()
+	links := &storage.LinkStats{Total: 120, Internal: 36, External: 72, ExternalDomains: 40}
+
+	b.ResetTimer()
+	for i := range b.N {
+		if _, err := store.UpsertNodeWithDepth(d.Domains[i%len(d.Domains)], "Title", "Description", links, 2); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchUpsertEdge upserts edges between stored nodes one statement at a
+// time
+func (d *Dataset) benchUpsertEdge(b *testing.B) {
+	store := openStore(b)
+	defer store.Close()
+
+	ids := make([]int, 1000)
+	for i := range ids {
+		id, err := store.UpsertNode(d.Domains[i], "", "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids[i] = id
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		from, to := ids[i%len(ids)], ids[(i*7+1)%len(ids)]
+		if err := store.UpsertEdge(from, to, storage.EdgeLink, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchMemoryUpsertEdge records edges in the memory graph, as every
+// selected link of a fetched page does
+func (d *Dataset) benchMemoryUpsertEdge(b *testing.B) {
+	mg := memory.NewMemoryGraph()
+	ids := make([]int, len(d.Domains))
+	for i, domain := range d.Domains {
+		id, _, err := mg.UpsertNodeWithDepth(domain, 1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids[i] = id
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		if err := mg.UpsertEdge(ids[i%len(ids)], ids[(i*31+7)%len(ids)], storage.EdgeLink); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchMemoryFlush flushes a memory graph of flushNodes nodes and
+// flushEdges edges; one op is one flush, into a database holding the
+// graph from the previous op
+func (d *Dataset) benchMemoryFlush(b *testing.B) {
+	store := openStore(b)
+	defer store.Close()
+
+	for range b.N {
+		b.StopTimer()
+		mg := memory.NewMemoryGraph()
+		ids := make([]int, flushNodes)
+		for i := range ids {
+			id, _, err := mg.UpsertNodeWithDepth(d.Domains[i], 1)
+			if err != nil {
+				b.Fatal(err)
+			}
+			mg.SetLinkStats(d.Domains[i], storage.LinkStats{Total: pageLinks, External: 72})
+			mg.SetOrigin(d.Domains[i], d.Domains[0], d.Domains[0], "https://"+d.Domains[0]+"/")
+			ids[i] = id
+		}
+		for i := range flushEdges {
+			mg.UpsertEdge(ids[i%flushNodes], ids[(i*13+1)%flushNodes], storage.EdgeLink)
+		}
+		b.StartTimer()
+
+		if err := mg.Flush(store); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchSaveQueue saves a frontier of queueEntries entries with codec, as
+// a checkpoint does; one op is one save, replacing the previous one
+func (d *Dataset) benchSaveQueue(b *testing.B, codec string) {
+	store := openStore(b)
+	defer store.Close()
+
+	entries := make([]storage.QueueEntry, queueEntries)
+	for i := range entries {
+		entries[i] = storage.QueueEntry{NodeID: i + 1, DomainName: d.Domains[i%len(d.Domains)], Depth: i % 6, Priority: float64(i%100) / 10}
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		if err := store.SaveQueue(entries, codec); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchParseGoquery parses a page into a DOM and reads its links and title,
+// as the goquery html_parser does; one op is one page
+func (d *Dataset) benchParseGoquery(b *testing.B) {
+	page := d.pageHTML(0)
+	b.SetBytes(int64(len(page)))
+
+	b.ResetTimer()
+	for range b.N {
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+		if err != nil {
+			b.Fatal(err)
+		}
+		links := 0
+		doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+			if _, ok := s.Attr("href"); ok {
+				links++
+			}
+		})
+		if doc.Find("title").Text() == "" || links != pageLinks {
+			b.Fatal("page misparsed")
+		}
+	}
+}
+
+// benchParseTokenizer reads the same page with the tokenizer html_parser
+func (d *Dataset) benchParseTokenizer(b *testing.B) {
+	page := d.pageHTML(0)
+	b.SetBytes(int64(len(page)))
+
+	b.ResetTimer()
+	for range b.N {
+		links := 0
+		summary := crawler.ScanHTML(page, func(crawler.Link) { links++ })
+		if summary.Title == "" || links != pageLinks {
+			b.Fatal("page misparsed")
+		}
+	}
+}
+
+// openStore opens a fresh database in a temporary directory
+func openStore(b *testing.B) *storage.Storage {
+	store, err := storage.NewStorage(filepath.Join(b.TempDir(), "bench.db"), "bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return store
+}
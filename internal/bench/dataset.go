@@ -0,0 +1,80 @@
+// Package bench holds the synthetic datasets of the benchmarks, which run
+// with go test -bench
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+)
+
+// Dataset sizes; large enough that maps and indexes outgrow the CPU caches
+// as in a real crawl, small enough that a full run takes about a minute
+const (
+	datasetDomains = 20000
+	datasetRoots   = 5000
+	pageLinks      = 120 // <a href> links on a synthetic page
+	flushNodes     = 2000
+	flushEdges     = 10000
+	queueEntries   = datasetDomains // saved frontier size
+)
+
+// tlds weights the synthetic domains towards the common TLDs
+var tlds = []string{"com", "com", "com", "org", "net", "io", "de", "co.uk", "es", "dev"}
+
+// Dataset is a synthetic site graph: domains spread over root domains, and
+// the links found on a page of each domain
+type Dataset struct {
+	Domains []string
+	Pages   [][]string // absolute link URLs, by domain
+}
+
+// NewDataset generates a dataset; the same seed always generates the same
+// dataset
+func NewDataset(seed uint64) *Dataset {
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	d := &Dataset{Domains: make([]string, datasetDomains)}
+	for i := range d.Domains {
+		root := rng.IntN(datasetRoots)
+		d.Domains[i] = fmt.Sprintf("sub%d.root%d.%s", i, root, tlds[root%len(tlds)])
+	}
+
+	// Pages link mostly off-site, with some same-site and junk links as on
+	// real pages
+	d.Pages = make([][]string, len(d.Domains))
+	for i, domain := range d.Domains {
+		links := make([]string, pageLinks)
+		for j := range links {
+			switch n := rng.IntN(10); {
+			case n < 6:
+				links[j] = fmt.Sprintf("https://%s/page/%d?ref=%d", d.Domains[rng.IntN(len(d.Domains))], j, i)
+			case n < 9:
+				links[j] = fmt.Sprintf("https://%s/section/%d", domain, j)
+			default:
+				links[j] = "mailto:someone@" + domain
+			}
+		}
+		d.Pages[i] = links
+	}
+	return d
+}
+
+// pageHTML renders the page of domain i: a typical front page layout with
+// navigation, article teasers holding its links, and a footer
+func (d *Dataset) pageHTML(i int) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<!DOCTYPE html><html lang="en"><head><meta charset="utf-8"><title>%s &amp; friends</title>`, d.Domains[i])
+	fmt.Fprintf(&b, `<meta name="description" content="Front page of %s"><link rel="stylesheet" href="/style.css"></head><body>`, d.Domains[i])
+	b.WriteString(`<header><nav class="menu"><ul>`)
+	for j := range 10 {
+		fmt.Fprintf(&b, `<li class="item"><span>Section %d</span></li>`, j)
+	}
+	b.WriteString(`</ul></nav></header><main>`)
+	for j, link := range d.Pages[i] {
+		fmt.Fprintf(&b, `<article class="teaser"><h2><a href="%s">Story %d</a></h2><p class="summary">`, link, j)
+		b.WriteString(`Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore.</p></article>`)
+	}
+	b.WriteString(`</main><footer><p>&copy; Example</p></footer></body></html>`)
+	return b.Bytes()
+}
@@ -3,26 +3,302 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
+)
+
+// DefaultSession is the crawl session used when none is configured
+const DefaultSession = "default"
+
+// Strategies for choosing which of a page's links count toward max_outbound_links
+const (
+	LinkSelectionFirst    = "first"    // document order
+	LinkSelectionRandom   = "random"   // uniform sample
+	LinkSelectionPriority = "priority" // unseen root domains, then unseen hosts, then known hosts
+)
+
+// Crawl modes
+const (
+	CrawlModeDomain = "domain" // fetch only each domain's front page
+	CrawlModeURL    = "url"    // also follow same-host links to inner pages
+)
+
+// HTML parsers
+const (
+	HTMLParserGoquery   = "goquery"   // full DOM: every selector, structural and rule edges
+	HTMLParserTokenizer = "tokenizer" // streaming: links, structural edges, title, description and language
+)
+
+// Codecs of the queue saved at checkpoints and shutdown; they match the
+// storage.QueueCodec* names
+const (
+	QueueCodecRows   = "rows"   // one SQL row per entry
+	QueueCodecBinary = "binary" // one compressed blob, for large frontiers
+)
+
+// Frontier backends
+const (
+	QueueBackendMemory = "memory" // in-process frontier
+	QueueBackendRedis  = "redis"  // frontier and dedup set shared through Redis
+)
+
+// Actions taken on domains of low reputation
+const (
+	ReputationActionRecord = "record" // record the node and edge, but never crawl it
+	ReputationActionSkip   = "skip"   // drop the link, leaving no node or edge
+)
+
+// Aggregation modes for hosts on multi-tenant platforms
+const (
+	PlatformModePlatform = "platform" // one node per platform, e.g. github.io
+	PlatformModeTenant   = "tenant"   // one node per tenant, e.g. alice.github.io
 )
 
 // Config holds all runtime configuration parameters
 type Config struct {
-	SeedURL              string `json:"seed_url"`
-	MaxDepth             int    `json:"max_depth"`
-	MaxCrawlsPerNode     int    `json:"max_crawls_per_node"`
-	MaxSubdomainsPerRoot int    `json:"max_subdomains_per_root"`
-	MaxOutboundLinks     int    `json:"max_outbound_links"`
-	ConcurrentWorkers    int    `json:"concurrent_workers"`
-	RequestTimeoutMs     int    `json:"request_timeout_ms"`
-	RetryAttempts        int    `json:"retry_attempts"`
-	RetryDelayMs         int    `json:"retry_delay_ms"`
-	DBPath               string `json:"db_path"`
-	MetricsPath          string `json:"metrics_path"`
+	SeedURLs               []string    `json:"seed_urls"`
+	SeedURL                string      `json:"seed_url"`  // single-seed form of seed_urls, folded into it on load
+	SeedFile               string      `json:"seed_file"` // more seeds: a URL or domain per line, CSV, or index dump (.gz ok)
+	MaxDepth               int         `json:"max_depth"`
+	MaxCrawlsPerNode       int         `json:"max_crawls_per_node"`
+	MaxSubdomainsPerRoot   int         `json:"max_subdomains_per_root"`
+	DepthFanOutLimits      map[int]int `json:"depth_fanout_limits"` // depth -> max new domains enqueued at it
+	MaxOutboundLinks       int         `json:"max_outbound_links"`
+	LinkSelection          string      `json:"link_selection"`
+	ConcurrentWorkers      int         `json:"concurrent_workers"`
+	MinWorkers             int         `json:"min_workers"` // autoscaling floor (default 1)
+	MaxWorkers             int         `json:"max_workers"` // autoscaling ceiling; 0 keeps concurrent_workers fixed
+	RequestTimeoutMs       int         `json:"request_timeout_ms"`
+	RetryAttempts          int         `json:"retry_attempts"` // per entry and run, for transient failures (default 3, -1 disables)
+	RetryDelayMs           int         `json:"retry_delay_ms"` // before the first retry, doubling after (default 5000)
+	DBPath                 string      `json:"db_path"`
+	MetricsPath            string      `json:"metrics_path"`
+	MetricsTopN            int         `json:"metrics_top_n"`    // top domains in final metrics (default 10, -1 disables)
+	MetricsHistory         int         `json:"metrics_history"`  // replaced metrics files kept as .1 to .N (0 keeps none)
+	MinFreeDiskMB          int         `json:"min_free_disk_mb"` // default 100, -1 disables the disk guard
+	QueueCodec             string      `json:"queue_codec"`      // saved frontier format (see QueueCodec*, default "rows")
+	PolitenessDelayMs      int         `json:"politeness_delay_ms"`
+	PolitenessJitterMs     int         `json:"politeness_jitter_ms"`
+	RandomDelayMs          int         `json:"random_delay_ms"`
+	SlowHostMs             int         `json:"slow_host_ms"`
+	FetchDeadlineMs        int         `json:"fetch_deadline_ms"`        // hard limit per fetch task (default 3x request_timeout_ms)
+	MinRefetchIntervalSec  int         `json:"min_refetch_interval_sec"` // across runs and sessions (0 disables)
+	MaxDescriptionRunes    int         `json:"max_description_runes"`
+	MaxHTMLElements        int         `json:"max_html_elements"`        // parsed per page, the rest is cut (default 50000, -1 disables)
+	MaxHTMLDepth           int         `json:"max_html_depth"`           // deeper subtrees are dropped (default 256, -1 disables)
+	MaxHTMLAttributeBytes  int         `json:"max_html_attribute_bytes"` // longer attributes are dropped (default 16384, -1 disables)
+	HTTPAddr               string      `json:"http_addr"`
+	APIToken               string      `json:"api_token"`                 // bearer token for admin and blocklist writes; unset allows loopback only
+	MetricsAddr            string      `json:"metrics_addr"`              // Prometheus /metrics listener; may equal http_addr
+	APISnapshotIntervalSec int         `json:"api_snapshot_interval_sec"` // API reads a snapshot refreshed this often (0 reads the live DB)
+	Session                string      `json:"session"`
+
+	// Startup checks of disk space, database writes, DNS and connectivity;
+	// the connectivity checks fetch preflight_url, else the first seed URL
+	SkipPreflight bool   `json:"skip_preflight"`
+	PreflightURL  string `json:"preflight_url"`
+
+	// Test-fetch the first seeds as they are enqueued, logging their status,
+	// redirects, TLS and robots.txt verdicts and what to do about problems
+	SkipSeedCheck bool `json:"skip_seed_check"`
+
+	// Scheme support: probe each fetched domain over plain HTTP, without
+	// following redirects, to report HTTPS adoption
+	ProbeHTTPScheme bool `json:"probe_http_scheme"`
+
+	// HTTP fallback: fetch a domain's front page over plain HTTP when HTTPS
+	// is refused or its certificate is broken, before counting it as failed
+	AllowHTTPFallback bool `json:"allow_http_fallback"`
+
+	// Sitemaps: read https://domain/sitemap.xml (and the sitemaps an index
+	// lists) when a domain is first crawled, for sitemap edges
+	ReadSitemaps   bool `json:"read_sitemaps"`
+	SitemapMaxURLs int  `json:"sitemap_max_urls"` // per domain (default 10000)
+
+	// Dependency edges: record the hosts of third-party scripts and
+	// stylesheets, for supply-chain analysis; inflates the graph
+	RecordDependencies bool `json:"record_dependencies"`
+
+	// Image edges: record the hosts of external images, for hotlinking and
+	// CDN mapping, without crawling them
+	RecordImages bool `json:"record_images"`
+
+	// Edge weight decay: at the start of each run, the weight of the
+	// session's edges is multiplied by this factor per week since they were
+	// last seen, so the graph follows current linking; 0 disables
+	EdgeDecayPerWeek float64 `json:"edge_decay_per_week"`
+
+	// Extraction rules for edges beyond <a href> and the structural ones;
+	// record_dependencies and record_images are shorthands for built-in rules
+	EdgeRules      []EdgeRule `json:"edge_rules"`
+	EdgeExtraction []EdgeRule `json:"-"` // shorthands and edge_rules, compiled by LoadConfig
+
+	// Crawl mode (see CrawlMode*); url mode follows a domain's same-host
+	// links past a splash-screen front page
+	CrawlMode         string `json:"crawl_mode"`           // default "domain"
+	MaxPagesPerDomain int    `json:"max_pages_per_domain"` // url mode, front page included (default 10)
+
+	// Politeness by depth: depth -> multiplier of politeness_delay_ms and
+	// politeness_jitter_ms for entries at that depth and deeper, up to the
+	// next listed depth; below 1 a host's entries are also fetched
+	// round(1/multiplier) at a time
+	DepthPoliteness map[int]float64 `json:"depth_politeness"`
+
+	// HTML parser (see HTMLParser*); the tokenizer skips building a DOM for
+	// faster parsing, at the cost of selectors and rule edges
+	HTMLParser string `json:"html_parser"` // default "goquery"
+
+	// Domain filters (regexes matched against the host name); exclusions
+	// win, and a non-empty include list admits only matching domains
+	// The files add one regex per line to the patterns above
+	ExcludePatterns     []string     `json:"exclude_patterns"`
+	IncludePatterns     []string     `json:"include_patterns"`
+	ExcludePatternsFile string       `json:"exclude_patterns_file"`
+	IncludePatternsFile string       `json:"include_patterns_file"`
+	ExcludeRules        []FilterRule `json:"-"` // compiled by LoadConfig
+	IncludeRules        []FilterRule `json:"-"`
+
+	// Domain canonicalization; the first rule whose pattern matches a
+	// discovered domain rewrites it before the filters and node creation
+	CanonicalRules []CanonicalRule `json:"canonical_rules"`
+
+	// Multi-tenant platform aggregation (see PlatformMode*); "" leaves
+	// platform hosts to the regular subdomain handling
+	PlatformMode string   `json:"platform_mode"`
+	Platforms    []string `json:"platforms"` // platform domains (default: DefaultPlatforms)
+
+	// TLD filters; a non-empty allow list denies every other TLD, and
+	// blocked TLDs are skipped either way. Entries may span labels ("co.uk")
+	AllowedTLDs []string `json:"allowed_tlds"`
+	BlockedTLDs []string `json:"blocked_tlds"`
+
+	// Frontier priority within a depth (see DomainScoring); empty disables scoring
+	DomainScoring DomainScoring `json:"domain_scoring"`
+
+	// Domains crawled per language declared by their front page, e.g.
+	// {"en": -1, "*": 1000}; "*" shares one quota among the languages
+	// without an entry of their own, and -1 leaves a language unlimited
+	LanguageQuotas map[string]int `json:"language_quotas"`
+
+	// Reputation lookup of discovered domains (see DomainReputation); no
+	// source disables it
+	DomainReputation DomainReputation `json:"domain_reputation"`
+
+	// Frontier backend (see QueueBackend*); redis shares the frontier
+	// between processes through the server in queue_redis
+	QueueBackend string     `json:"queue_backend"` // default "memory"
+	QueueRedis   QueueRedis `json:"queue_redis"`
+
+	// Content-based categories of crawled domains (see NodeClassification)
+	NodeClassification NodeClassification `json:"node_classification"`
+
+	// Title and description extraction (see selectors.go); reloaded on SIGHUP
+	TitleSelectors       []string                 `json:"title_selectors"`
+	DescriptionSelectors []string                 `json:"description_selectors"`
+	SiteSelectors        map[string]SiteSelectors `json:"site_selectors"` // by host or root domain
+	Selectors            SelectorSet              `json:"-"`              // compiled by LoadConfig
+	SiteSelectorSets     map[string]SelectorSet   `json:"-"`
+
+	// Completion notifications (empty disables the channel)
+	NotifySlackWebhook string   `json:"notify_slack_webhook"`
+	NotifySMTPAddr     string   `json:"notify_smtp_addr"`
+	NotifySMTPUsername string   `json:"notify_smtp_username"`
+	NotifySMTPPassword string   `json:"notify_smtp_password"`
+	NotifyEmailFrom    string   `json:"notify_email_from"`
+	NotifyEmailTo      []string `json:"notify_email_to"`
+
+	// Discovery plateau auto-stop (0 window disables)
+	PlateauWindowSec   int `json:"plateau_window_sec"`
+	PlateauMinNewRoots int `json:"plateau_min_new_roots"`
+
+	// Periodic checkpoints, whichever comes first
+	CheckpointIntervalSec int `json:"checkpoint_interval_sec"` // default 300, -1 disables
+	CheckpointPages       int `json:"checkpoint_pages"`        // pages fetched (0 disables)
+
+	// Crawl budgets (0 disables)
+	TimeBudgetSec int `json:"time_budget_sec"`
+	NodeBudget    int `json:"node_budget"` // nodes crawled
+
+	// Failure-ratio abort (0 window disables)
+	FailureWindow     int     `json:"failure_window"`
+	MaxFailurePercent float64 `json:"max_failure_percent"`
+
+	// Collectors: root domains are spread over collector_pool_size shared
+	// collectors; roots listed in root_collectors get a dedicated one
+	CollectorPoolSize int                      `json:"collector_pool_size"` // default 1
+	RootCollectors    map[string]RootCollector `json:"root_collectors"`
+
+	// DNS prefetching (0 lookahead disables)
+	DNSPrefetchAhead  int `json:"dns_prefetch_ahead"`   // upcoming frontier hosts to resolve
+	DNSPrefetchPerSec int `json:"dns_prefetch_per_sec"` // lookup rate limit (default 20)
+	DNSCacheTTLSec    int `json:"dns_cache_ttl_sec"`    // answer lifetime (default 300)
+
+	// Log sampling of per-page events (edges, visits, fetches)
+	LogSampleRate float64 `json:"log_sample_rate"` // fraction logged at Info (default 1, -1 logs none)
+	LogSummarySec int     `json:"log_summary_sec"` // event count summary interval (0 disables)
+
+	// Adaptive throttling (0 disables the check)
+	MaxRSSMB      int     `json:"max_rss_mb"`
+	MaxCPUPercent float64 `json:"max_cpu_percent"`
+}
+
+// DomainScoring weights the features of a candidate domain into its
+// frontier priority; a candidate's score is the sum of its matching weights
+type DomainScoring struct {
+	TLDWeights     map[string]float64 `json:"tld_weights"`      // by TLD, e.g. "org"
+	TokenWeights   map[string]float64 `json:"token_weights"`    // by word of the domain name
+	InDegreeWeight float64            `json:"in_degree_weight"` // per node linking to it so far
+}
+
+// Enabled reports whether any weight is configured
+func (s DomainScoring) Enabled() bool {
+	return len(s.TLDWeights) > 0 || len(s.TokenWeights) > 0 || s.InDegreeWeight != 0
+}
+
+// DomainReputation rates discovered domains from 0 (bad) to 1 (good); a
+// domain's score is the lowest any source gives it
+type DomainReputation struct {
+	CSVPath   string  `json:"csv_path"`   // "domain,score" lines; a listed domain covers its subdomains
+	DNSBLZone string  `json:"dnsbl_zone"` // domain blocklist zone; domains listed in it score 0
+	HTTPURL   string  `json:"http_url"`   // GET with {domain} replaced, answering {"score": n}
+	MinScore  float64 `json:"min_score"`  // lower scores are low reputation (default 0.5, -1 flags none)
+	Action    string  `json:"action"`     // for low reputation, see ReputationAction* (default "record")
+}
+
+// Enabled reports whether any source is configured
+func (r DomainReputation) Enabled() bool {
+	return r.CSVPath != "" || r.DNSBLZone != "" || r.HTTPURL != ""
 }
 
-// LoadConfig reads and validates configuration from a JSON file
-func LoadConfig(path string) (*Config, error) {
+// NodeClassification tags each crawled domain with the kind of site its
+// front page shows, from weighted features of the page and its URL
+type NodeClassification struct {
+	Enabled   bool    `json:"enabled"`
+	ModelPath string  `json:"model_path"` // JSON feature weights per category, added to the built-in rules
+	MinScore  float64 `json:"min_score"`  // lowest score a category wins with (default 1)
+}
+
+// QueueRedis locates the Redis server of a shared frontier; processes
+// using the same server and key prefix crawl one frontier together
+type QueueRedis struct {
+	Addr      string `json:"addr"` // host:port (default "localhost:6379")
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	DB        int    `json:"db"`
+	KeyPrefix string `json:"key_prefix"` // of every key the frontier uses (default "web-weaver")
+}
+
+// RootCollector overrides collector settings for one root domain
+type RootCollector struct {
+	Parallelism int    `json:"parallelism"` // concurrent requests (default: as a shared collector)
+	Proxy       string `json:"proxy"`       // http(s) or socks5 URL; empty connects directly
+	UserAgent   string `json:"user_agent"`  // empty keeps the default
+}
+
+// LoadConfig reads and validates configuration from a JSON file;
+// overrides are applied in order over the file's values, before defaults
+func LoadConfig(path string, overrides ...Override) (*Config, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open config file: %w", err)
@@ -34,6 +310,11 @@ func LoadConfig(path string) (*Config, error) {
 	if err := decoder.Decode(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config JSON: %w", err)
 	}
+	for _, o := range overrides {
+		if err := applyOverride(&cfg, o); err != nil {
+			return nil, err
+		}
+	}
 
 	// Apply defaults for missing values
 	applyDefaults(&cfg)
@@ -48,6 +329,8 @@ func LoadConfig(path string) (*Config, error) {
 
 // applyDefaults sets default values for unspecified fields
 func applyDefaults(cfg *Config) {
+	cfg.SeedURLs = seedURLs(cfg.SeedURL, cfg.SeedURLs)
+	cfg.SeedURL = ""
 	if cfg.MaxDepth == 0 {
 		cfg.MaxDepth = 5
 	}
@@ -60,9 +343,15 @@ func applyDefaults(cfg *Config) {
 	if cfg.MaxOutboundLinks == 0 {
 		cfg.MaxOutboundLinks = 10
 	}
+	if cfg.LinkSelection == "" {
+		cfg.LinkSelection = LinkSelectionFirst
+	}
 	if cfg.ConcurrentWorkers == 0 {
 		cfg.ConcurrentWorkers = 3
 	}
+	if cfg.MaxWorkers > 0 && cfg.MinWorkers == 0 {
+		cfg.MinWorkers = 1
+	}
 	if cfg.RequestTimeoutMs == 0 {
 		cfg.RequestTimeoutMs = 5000
 	}
@@ -78,12 +367,118 @@ func applyDefaults(cfg *Config) {
 	if cfg.MetricsPath == "" {
 		cfg.MetricsPath = "metrics.log"
 	}
+	if cfg.MetricsTopN == 0 {
+		cfg.MetricsTopN = 10
+	}
+	if cfg.CollectorPoolSize == 0 {
+		cfg.CollectorPoolSize = 1
+	}
+	if cfg.MaxDescriptionRunes == 0 {
+		cfg.MaxDescriptionRunes = 160
+	}
+	if cfg.MaxHTMLElements == 0 {
+		cfg.MaxHTMLElements = 50000
+	}
+	if cfg.MaxHTMLDepth == 0 {
+		cfg.MaxHTMLDepth = 256
+	}
+	if cfg.MaxHTMLAttributeBytes == 0 {
+		cfg.MaxHTMLAttributeBytes = 16384
+	}
+	if cfg.LogSampleRate == 0 {
+		cfg.LogSampleRate = 1
+	}
+	if cfg.SitemapMaxURLs == 0 {
+		cfg.SitemapMaxURLs = 10000
+	}
+	if cfg.CrawlMode == "" {
+		cfg.CrawlMode = CrawlModeDomain
+	}
+	if cfg.HTMLParser == "" {
+		cfg.HTMLParser = HTMLParserGoquery
+	}
+	if cfg.QueueCodec == "" {
+		cfg.QueueCodec = QueueCodecRows
+	}
+	if cfg.QueueBackend == "" {
+		cfg.QueueBackend = QueueBackendMemory
+	}
+	if cfg.QueueBackend == QueueBackendRedis {
+		if cfg.QueueRedis.Addr == "" {
+			cfg.QueueRedis.Addr = "localhost:6379"
+		}
+		if cfg.QueueRedis.KeyPrefix == "" {
+			cfg.QueueRedis.KeyPrefix = "web-weaver"
+		}
+	}
+	if cfg.MaxPagesPerDomain == 0 {
+		cfg.MaxPagesPerDomain = 10
+	}
+	if cfg.DomainReputation.MinScore == 0 {
+		cfg.DomainReputation.MinScore = 0.5
+	}
+	if cfg.DomainReputation.Action == "" {
+		cfg.DomainReputation.Action = ReputationActionRecord
+	}
+	if cfg.NodeClassification.MinScore == 0 {
+		cfg.NodeClassification.MinScore = 1
+	}
+	if cfg.MinFreeDiskMB == 0 {
+		cfg.MinFreeDiskMB = 100
+	}
+	if cfg.CheckpointIntervalSec == 0 {
+		cfg.CheckpointIntervalSec = 300
+	}
+	if cfg.PlateauWindowSec > 0 && cfg.PlateauMinNewRoots == 0 {
+		cfg.PlateauMinNewRoots = 1
+	}
+	if cfg.FailureWindow > 0 && cfg.MaxFailurePercent == 0 {
+		cfg.MaxFailurePercent = 90
+	}
+	if cfg.DNSPrefetchAhead > 0 && cfg.DNSPrefetchPerSec == 0 {
+		cfg.DNSPrefetchPerSec = 20
+	}
+	if cfg.DNSPrefetchAhead > 0 && cfg.DNSCacheTTLSec == 0 {
+		cfg.DNSCacheTTLSec = 300
+	}
+	if cfg.Session == "" {
+		cfg.Session = DefaultSession
+	}
+	if len(cfg.TitleSelectors) == 0 {
+		cfg.TitleSelectors = append([]string(nil), DefaultTitleSelectors...)
+	}
+	if len(cfg.DescriptionSelectors) == 0 {
+		cfg.DescriptionSelectors = append([]string(nil), DefaultDescriptionSelectors...)
+	}
+	// An explicit empty list disables the default exclusions
+	if cfg.ExcludePatterns == nil {
+		cfg.ExcludePatterns = append([]string(nil), DefaultExcludePatterns...)
+	}
+	if cfg.Platforms == nil {
+		cfg.Platforms = append([]string(nil), DefaultPlatforms...)
+	}
+}
+
+// seedURLs returns the configured seeds: seed_url, if set, then seed_urls,
+// without blanks and duplicates
+func seedURLs(single string, list []string) []string {
+	var seeds []string
+	seen := make(map[string]bool)
+	for _, seed := range append([]string{single}, list...) {
+		seed = strings.TrimSpace(seed)
+		if seed == "" || seen[seed] {
+			continue
+		}
+		seen[seed] = true
+		seeds = append(seeds, seed)
+	}
+	return seeds
 }
 
 // validate checks that required fields are present and values are sensible
 func validate(cfg *Config) error {
-	if cfg.SeedURL == "" {
-		return fmt.Errorf("seed_url is required")
+	if len(cfg.SeedURLs) == 0 && cfg.SeedFile == "" {
+		return fmt.Errorf("seed_urls (or seed_url) or seed_file is required")
 	}
 	if cfg.MaxDepth < 1 {
 		return fmt.Errorf("max_depth must be >= 1")
@@ -91,11 +486,239 @@ func validate(cfg *Config) error {
 	if cfg.MaxCrawlsPerNode < 1 {
 		return fmt.Errorf("max_crawls_per_node must be >= 1")
 	}
+	if cfg.RetryAttempts < -1 {
+		return fmt.Errorf("retry_attempts must be > 0, or -1 to disable")
+	}
+	if cfg.RetryDelayMs < 0 {
+		return fmt.Errorf("retry_delay_ms must be >= 0")
+	}
+	for depth, limit := range cfg.DepthFanOutLimits {
+		if depth < 1 || depth > cfg.MaxDepth {
+			return fmt.Errorf("depth_fanout_limits: depth %d must be between 1 and max_depth (%d)", depth, cfg.MaxDepth)
+		}
+		if limit < 1 {
+			return fmt.Errorf("depth_fanout_limits: limit for depth %d must be >= 1; omit the depth to leave it unlimited", depth)
+		}
+	}
+	for depth, multiplier := range cfg.DepthPoliteness {
+		if depth < 0 || depth > cfg.MaxDepth {
+			return fmt.Errorf("depth_politeness: depth %d must be between 0 and max_depth (%d)", depth, cfg.MaxDepth)
+		}
+		if multiplier <= 0 {
+			return fmt.Errorf("depth_politeness: multiplier for depth %d must be > 0", depth)
+		}
+	}
+	for tld := range cfg.DomainScoring.TLDWeights {
+		if strings.Trim(tld, ".") == "" {
+			return fmt.Errorf("domain_scoring.tld_weights: TLD must not be empty")
+		}
+	}
+	for token := range cfg.DomainScoring.TokenWeights {
+		if token == "" {
+			return fmt.Errorf("domain_scoring.token_weights: token must not be empty")
+		}
+	}
+	for language, quota := range cfg.LanguageQuotas {
+		if language == "" {
+			return fmt.Errorf("language_quotas: language must not be empty")
+		}
+		if quota < -1 {
+			return fmt.Errorf("language_quotas: quota for %q must be >= 0, or -1 for unlimited", language)
+		}
+	}
+	if rep := cfg.DomainReputation; rep.Enabled() {
+		if (rep.MinScore < 0 && rep.MinScore != -1) || rep.MinScore > 1 {
+			return fmt.Errorf("domain_reputation.min_score must be between 0 and 1, or -1 to flag no domain")
+		}
+		if rep.Action != ReputationActionRecord && rep.Action != ReputationActionSkip {
+			return fmt.Errorf("domain_reputation.action must be %q or %q", ReputationActionRecord, ReputationActionSkip)
+		}
+		if rep.HTTPURL != "" {
+			if u, err := url.Parse(rep.HTTPURL); err != nil || u.Host == "" || !strings.Contains(rep.HTTPURL, "{domain}") {
+				return fmt.Errorf("domain_reputation.http_url must be an absolute URL containing {domain}")
+			}
+		}
+	}
+	if cfg.NodeClassification.MinScore < 0 {
+		return fmt.Errorf("node_classification.min_score must be > 0")
+	}
+	if cfg.NodeClassification.ModelPath != "" && !cfg.NodeClassification.Enabled {
+		return fmt.Errorf("node_classification.model_path requires node_classification.enabled")
+	}
+	if cfg.MaxOutboundLinks < 1 {
+		return fmt.Errorf("max_outbound_links must be >= 1")
+	}
+	switch cfg.PlatformMode {
+	case "", PlatformModePlatform, PlatformModeTenant:
+	default:
+		return fmt.Errorf("platform_mode must be empty, %q, or %q", PlatformModePlatform, PlatformModeTenant)
+	}
+	switch cfg.LinkSelection {
+	case LinkSelectionFirst, LinkSelectionRandom, LinkSelectionPriority:
+	default:
+		return fmt.Errorf("link_selection must be %q, %q, or %q",
+			LinkSelectionFirst, LinkSelectionRandom, LinkSelectionPriority)
+	}
 	if cfg.ConcurrentWorkers < 1 {
 		return fmt.Errorf("concurrent_workers must be >= 1")
 	}
+	if cfg.MinWorkers < 0 || cfg.MaxWorkers < 0 {
+		return fmt.Errorf("min_workers and max_workers must be >= 0")
+	}
+	if cfg.MaxWorkers > 0 && cfg.MinWorkers > cfg.MaxWorkers {
+		return fmt.Errorf("min_workers must be <= max_workers")
+	}
 	if cfg.RequestTimeoutMs < 1000 {
 		return fmt.Errorf("request_timeout_ms must be >= 1000")
 	}
-	return nil
+	if cfg.PolitenessDelayMs < 0 {
+		return fmt.Errorf("politeness_delay_ms must be >= 0")
+	}
+	if cfg.PolitenessJitterMs < 0 {
+		return fmt.Errorf("politeness_jitter_ms must be >= 0")
+	}
+	if cfg.RandomDelayMs < 0 {
+		return fmt.Errorf("random_delay_ms must be >= 0")
+	}
+	if cfg.SitemapMaxURLs < 0 {
+		return fmt.Errorf("sitemap_max_urls must be >= 0")
+	}
+	if cfg.CrawlMode != CrawlModeDomain && cfg.CrawlMode != CrawlModeURL {
+		return fmt.Errorf("crawl_mode must be %q or %q", CrawlModeDomain, CrawlModeURL)
+	}
+	if cfg.MaxPagesPerDomain < 1 {
+		return fmt.Errorf("max_pages_per_domain must be >= 1")
+	}
+	if cfg.HTMLParser != HTMLParserGoquery && cfg.HTMLParser != HTMLParserTokenizer {
+		return fmt.Errorf("html_parser must be %q or %q", HTMLParserGoquery, HTMLParserTokenizer)
+	}
+	if cfg.QueueCodec != QueueCodecRows && cfg.QueueCodec != QueueCodecBinary {
+		return fmt.Errorf("queue_codec must be %q or %q", QueueCodecRows, QueueCodecBinary)
+	}
+	if cfg.QueueBackend != QueueBackendMemory && cfg.QueueBackend != QueueBackendRedis {
+		return fmt.Errorf("queue_backend must be %q or %q", QueueBackendMemory, QueueBackendRedis)
+	}
+	if cfg.QueueRedis.DB < 0 {
+		return fmt.Errorf("queue_redis.db must be >= 0")
+	}
+	if cfg.FetchDeadlineMs != 0 && cfg.FetchDeadlineMs < cfg.RequestTimeoutMs {
+		return fmt.Errorf("fetch_deadline_ms must be >= request_timeout_ms")
+	}
+	if cfg.SlowHostMs < 0 {
+		return fmt.Errorf("slow_host_ms must be >= 0")
+	}
+	if cfg.MaxDescriptionRunes < 0 {
+		return fmt.Errorf("max_description_runes must be >= 0")
+	}
+	if cfg.MaxHTMLElements < -1 {
+		return fmt.Errorf("max_html_elements must be > 0, or -1 to disable")
+	}
+	if cfg.MaxHTMLDepth < -1 {
+		return fmt.Errorf("max_html_depth must be > 0, or -1 to disable")
+	}
+	if cfg.MaxHTMLAttributeBytes < -1 {
+		return fmt.Errorf("max_html_attribute_bytes must be > 0, or -1 to disable")
+	}
+	if cfg.MetricsTopN < -1 {
+		return fmt.Errorf("metrics_top_n must be > 0, or -1 to disable")
+	}
+	if cfg.CheckpointIntervalSec < -1 {
+		return fmt.Errorf("checkpoint_interval_sec must be > 0, or -1 to disable")
+	}
+	if cfg.CheckpointPages < 0 {
+		return fmt.Errorf("checkpoint_pages must be >= 0")
+	}
+	if (cfg.LogSampleRate < 0 && cfg.LogSampleRate != -1) || cfg.LogSampleRate > 1 {
+		return fmt.Errorf("log_sample_rate must be between 0 and 1, or -1 to log no per-page events at Info")
+	}
+	if cfg.LogSummarySec < 0 {
+		return fmt.Errorf("log_summary_sec must be >= 0")
+	}
+	if cfg.MinFreeDiskMB < -1 {
+		return fmt.Errorf("min_free_disk_mb must be > 0, or -1 to disable")
+	}
+	if cfg.MaxRSSMB < 0 {
+		return fmt.Errorf("max_rss_mb must be >= 0")
+	}
+	if cfg.MaxCPUPercent < 0 {
+		return fmt.Errorf("max_cpu_percent must be >= 0")
+	}
+	if cfg.PlateauWindowSec < 0 {
+		return fmt.Errorf("plateau_window_sec must be >= 0")
+	}
+	if cfg.PlateauMinNewRoots < 0 {
+		return fmt.Errorf("plateau_min_new_roots must be >= 0")
+	}
+	if cfg.TimeBudgetSec < 0 || cfg.NodeBudget < 0 {
+		return fmt.Errorf("time_budget_sec and node_budget must be >= 0")
+	}
+	if cfg.CollectorPoolSize < 1 {
+		return fmt.Errorf("collector_pool_size must be >= 1")
+	}
+	for root, rc := range cfg.RootCollectors {
+		if root == "" {
+			return fmt.Errorf("root_collectors: root domain must not be empty")
+		}
+		if rc.Parallelism < 0 {
+			return fmt.Errorf("root_collectors[%q]: parallelism must be >= 0", root)
+		}
+		if rc.Proxy != "" {
+			if u, err := url.Parse(rc.Proxy); err != nil || u.Host == "" {
+				return fmt.Errorf("root_collectors[%q]: invalid proxy URL %q", root, rc.Proxy)
+			}
+		}
+	}
+	if cfg.DNSPrefetchAhead < 0 || cfg.DNSPrefetchPerSec < 0 || cfg.DNSCacheTTLSec < 0 {
+		return fmt.Errorf("dns_prefetch_ahead, dns_prefetch_per_sec, and dns_cache_ttl_sec must be >= 0")
+	}
+	if cfg.PreflightURL != "" {
+		if u, err := url.Parse(cfg.PreflightURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("preflight_url must be an absolute http(s) URL")
+		}
+	}
+	if cfg.MetricsHistory < 0 {
+		return fmt.Errorf("metrics_history must be >= 0")
+	}
+	if cfg.MinRefetchIntervalSec < 0 {
+		return fmt.Errorf("min_refetch_interval_sec must be >= 0")
+	}
+	if cfg.APISnapshotIntervalSec < 0 {
+		return fmt.Errorf("api_snapshot_interval_sec must be >= 0")
+	}
+	if cfg.FailureWindow < 0 {
+		return fmt.Errorf("failure_window must be >= 0")
+	}
+	if cfg.EdgeDecayPerWeek < 0 || cfg.EdgeDecayPerWeek >= 1 {
+		return fmt.Errorf("edge_decay_per_week must be >= 0 and < 1")
+	}
+	if cfg.MaxFailurePercent < 0 || cfg.MaxFailurePercent > 100 {
+		return fmt.Errorf("max_failure_percent must be between 0 and 100")
+	}
+	if cfg.NotifySMTPAddr != "" && (cfg.NotifyEmailFrom == "" || len(cfg.NotifyEmailTo) == 0) {
+		return fmt.Errorf("notify_email_from and notify_email_to are required with notify_smtp_addr")
+	}
+
+	if err := compileDomainFilters(cfg); err != nil {
+		return err
+	}
+	if err := compileCanonicalRules(cfg.CanonicalRules); err != nil {
+		return err
+	}
+	if err := compileEdgeRules(cfg); err != nil {
+		return err
+	}
+	if len(cfg.EdgeExtraction) > 0 && cfg.HTMLParser == HTMLParserTokenizer {
+		return fmt.Errorf("edge_rules, record_dependencies and record_images need html_parser %q", HTMLParserGoquery)
+	}
+	var err error
+	if cfg.AllowedTLDs, err = normalizeSuffixes("allowed_tlds", cfg.AllowedTLDs); err != nil {
+		return err
+	}
+	if cfg.BlockedTLDs, err = normalizeSuffixes("blocked_tlds", cfg.BlockedTLDs); err != nil {
+		return err
+	}
+	if cfg.Platforms, err = normalizeSuffixes("platforms", cfg.Platforms); err != nil {
+		return err
+	}
+	return compileSelectorSets(cfg)
 }
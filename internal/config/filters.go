@@ -0,0 +1,178 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// DefaultExcludePatterns are used when exclude_patterns is not set:
+// social media, ads, and analytics domains
+var DefaultExcludePatterns = []string{
+	`(?i)(facebook|fb)\.com`,
+	`(?i)twitter\.com`,
+	`(?i)instagram\.com`,
+	`(?i)linkedin\.com`,
+	`(?i)youtube\.com`,
+	`(?i)google-analytics\.com`,
+	`(?i)doubleclick\.net`,
+	`(?i)^ads?\.`,
+	`(?i)^analytics?\.`,
+	`(?i)googletagmanager\.com`,
+	`(?i)googleapis\.com`,
+}
+
+// FilterRule is a compiled domain filter pattern with its origin in the config
+type FilterRule struct {
+	Field   string // config field, e.g. "exclude_patterns", or the patterns file
+	Index   int    // position within the field, or line number within the file
+	File    bool   // read from exclude_patterns_file or include_patterns_file
+	Pattern string
+	Regexp  *regexp.Regexp
+}
+
+// Origin identifies where the rule was configured: "exclude_patterns[2]",
+// or "path:line" for a patterns file
+func (r FilterRule) Origin() string {
+	if r.File {
+		return fmt.Sprintf("%s:%d", r.Field, r.Index)
+	}
+	return fmt.Sprintf("%s[%d]", r.Field, r.Index)
+}
+
+// String identifies the rule as it appears in config.json or its file
+func (r FilterRule) String() string {
+	return fmt.Sprintf("%s `%s`", r.Origin(), r.Pattern)
+}
+
+// compileFilterRules compiles the patterns of one config field
+func compileFilterRules(field string, patterns []string) ([]FilterRule, error) {
+	rules := make([]FilterRule, 0, len(patterns))
+	for i, pattern := range patterns {
+		if pattern == "" {
+			return nil, fmt.Errorf("%s[%d]: pattern must not be empty", field, i)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: invalid regex `%s`: %w", field, i, pattern, err)
+		}
+		rules = append(rules, FilterRule{Field: field, Index: i, Pattern: pattern, Regexp: re})
+	}
+	return rules, nil
+}
+
+// readFilterRules compiles the patterns of a patterns file, one regex per
+// line; blank lines and lines starting with # are skipped
+func readFilterRules(field, path string) ([]FilterRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", field, err)
+	}
+	defer file.Close()
+
+	var rules []FilterRule
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		pattern := strings.TrimSpace(scanner.Text())
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid regex `%s`: %w", path, line, pattern, err)
+		}
+		rules = append(rules, FilterRule{Field: path, Index: line, File: true, Pattern: pattern, Regexp: re})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", field, err)
+	}
+	return rules, nil
+}
+
+// compileDomainFilters compiles exclude_patterns and include_patterns,
+// followed by the patterns of their files
+func compileDomainFilters(cfg *Config) error {
+	var err error
+	if cfg.ExcludeRules, err = compileFilterRules("exclude_patterns", cfg.ExcludePatterns); err != nil {
+		return err
+	}
+	if cfg.IncludeRules, err = compileFilterRules("include_patterns", cfg.IncludePatterns); err != nil {
+		return err
+	}
+	if cfg.ExcludePatternsFile != "" {
+		rules, err := readFilterRules("exclude_patterns_file", cfg.ExcludePatternsFile)
+		if err != nil {
+			return err
+		}
+		cfg.ExcludeRules = append(cfg.ExcludeRules, rules...)
+	}
+	if cfg.IncludePatternsFile != "" {
+		rules, err := readFilterRules("include_patterns_file", cfg.IncludePatternsFile)
+		if err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			// An empty allowlist would silently admit every domain
+			return fmt.Errorf("include_patterns_file %s has no patterns", cfg.IncludePatternsFile)
+		}
+		cfg.IncludeRules = append(cfg.IncludeRules, rules...)
+	}
+	return nil
+}
+
+// CanonicalRule rewrites discovered domains matching Pattern to Replace,
+// which may refer to capture groups as $1 or ${name}
+type CanonicalRule struct {
+	Pattern string         `json:"pattern"`
+	Replace string         `json:"replace"`
+	Regexp  *regexp.Regexp `json:"-"` // compiled by LoadConfig
+}
+
+// compileCanonicalRules compiles the patterns of canonical_rules in place
+func compileCanonicalRules(rules []CanonicalRule) error {
+	for i := range rules {
+		if rules[i].Pattern == "" {
+			return fmt.Errorf("canonical_rules[%d]: pattern must not be empty", i)
+		}
+		re, err := regexp.Compile(rules[i].Pattern)
+		if err != nil {
+			return fmt.Errorf("canonical_rules[%d]: invalid regex `%s`: %w", i, rules[i].Pattern, err)
+		}
+		rules[i].Regexp = re
+	}
+	return nil
+}
+
+// DefaultPlatforms are used when platforms is not set: multi-tenant
+// hosting platforms whose subdomains belong to unrelated tenants
+var DefaultPlatforms = []string{
+	"github.io",
+	"gitlab.io",
+	"herokuapp.com",
+	"cloudfront.net",
+	"netlify.app",
+	"vercel.app",
+	"pages.dev",
+	"appspot.com",
+	"azurewebsites.net",
+	"blogspot.com",
+	"wordpress.com",
+	"tumblr.com",
+}
+
+// normalizeSuffixes lowercases the domain suffixes (TLDs, platforms) of one
+// config field and strips leading dots, so ".CO.UK" and "co.uk" are the
+// same entry
+func normalizeSuffixes(field string, suffixes []string) ([]string, error) {
+	normalized := make([]string, 0, len(suffixes))
+	for i, suffix := range suffixes {
+		suffix = strings.ToLower(strings.TrimLeft(strings.TrimSpace(suffix), "."))
+		if suffix == "" {
+			return nil, fmt.Errorf("%s[%d]: entry must not be empty", field, i)
+		}
+		normalized = append(normalized, suffix)
+	}
+	return normalized, nil
+}
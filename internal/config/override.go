@@ -0,0 +1,68 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Override sets one config field, by its JSON name, over the value read
+// from the config file, e.g. from the command line
+type Override struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// NewOverride sets key to value, read as JSON if it is valid JSON and as a
+// string otherwise, so max_depth=3 is a number and seed_url=https://x a
+// string
+func NewOverride(key, value string) Override {
+	if json.Valid([]byte(value)) {
+		return Override{Key: key, Value: json.RawMessage(value)}
+	}
+	return StringOverride(key, value)
+}
+
+// StringOverride sets key to value as a string, even if value looks like
+// a number
+func StringOverride(key, value string) Override {
+	quoted, _ := json.Marshal(value)
+	return Override{Key: key, Value: quoted}
+}
+
+// ListOverride sets key to values as an array of strings
+func ListOverride(key string, values []string) Override {
+	list, _ := json.Marshal(values)
+	return Override{Key: key, Value: list}
+}
+
+// ParseOverride parses a "key=value" override, see NewOverride
+func ParseOverride(arg string) (Override, error) {
+	key, value, ok := strings.Cut(arg, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return Override{}, fmt.Errorf("override %q is not key=value", arg)
+	}
+	return NewOverride(key, value), nil
+}
+
+// String renders the override as key=value
+func (o Override) String() string {
+	return o.Key + "=" + string(o.Value)
+}
+
+// applyOverride decodes the override into cfg as if it were a field of
+// the config file; objects are merged into the file's, arrays replace it
+func applyOverride(cfg *Config, o Override) error {
+	doc, err := json.Marshal(map[string]json.RawMessage{o.Key: o.Value})
+	if err != nil {
+		return fmt.Errorf("invalid override %s: %w", o, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("invalid override %s: %w", o, err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/andybalholm/cascadia"
+)
+
+// DefaultTitleSelectors are used when title_selectors is not set
+var DefaultTitleSelectors = []string{`title`}
+
+// DefaultDescriptionSelectors are used when description_selectors is not
+// set: the meta description, falling back to the Open Graph one
+var DefaultDescriptionSelectors = []string{
+	`meta[name=description]@content`,
+	`meta[property="og:description"]@content`,
+}
+
+// SiteSelectors overrides the extraction selectors for one site; an empty
+// list keeps the global one
+type SiteSelectors struct {
+	Title       []string `json:"title"`
+	Description []string `json:"description"`
+}
+
+// Selector is a compiled extraction selector: the first element matching
+// CSS yields its Attr value, or its text when Attr is empty
+type Selector struct {
+	Pattern string // as written in config.json
+	CSS     cascadia.Selector
+	Attr    string
+}
+
+// SelectorSet holds the selectors tried in order for a page's title and
+// description; the first non-empty match wins
+type SelectorSet struct {
+	Title       []Selector
+	Description []Selector
+}
+
+// compileSelectors compiles the selectors of one config field
+func compileSelectors(field string, patterns []string) ([]Selector, error) {
+	selectors := make([]Selector, 0, len(patterns))
+	for i, pattern := range patterns {
+		selector, err := compileSelector(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", field, i, err)
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
+
+// compileSelector compiles a CSS selector, optionally followed by @attr to
+// read an attribute instead of the element text
+func compileSelector(pattern string) (Selector, error) {
+	css, attr := pattern, ""
+	// An @ inside an attribute selector, e.g. [href*="@"], is not a suffix
+	if at := strings.LastIndex(pattern, "@"); at >= 0 && !strings.ContainsAny(pattern[at+1:], `]"'`) {
+		css, attr = pattern[:at], strings.TrimSpace(pattern[at+1:])
+		if attr == "" {
+			return Selector{}, fmt.Errorf("missing attribute name after @ in `%s`", pattern)
+		}
+	}
+	if strings.TrimSpace(css) == "" {
+		return Selector{}, fmt.Errorf("selector must not be empty")
+	}
+	matcher, err := cascadia.Compile(css)
+	if err != nil {
+		return Selector{}, fmt.Errorf("invalid selector `%s`: %w", pattern, err)
+	}
+	return Selector{Pattern: pattern, CSS: matcher, Attr: attr}, nil
+}
+
+// EdgeRule turns the elements a selector matches into edges: the URL in
+// the selector's @attr, resolved against the page, becomes an edge of type
+// Edge to its domain
+type EdgeRule struct {
+	Selector   string   `json:"selector"`    // CSS selector with @attr, e.g. "script[src]@src"
+	Edge       string   `json:"edge"`        // one of storage.EdgeTypes
+	Offsite    bool     `json:"offsite"`     // only targets in another root domain
+	RecordOnly bool     `json:"record_only"` // record the edge without enqueueing the target
+	Match      Selector `json:"-"`           // compiled by LoadConfig
+}
+
+// DependencyEdgeRules are the rules record_dependencies adds: third-party
+// scripts and stylesheets
+var DependencyEdgeRules = []EdgeRule{
+	{Selector: `script[src]@src`, Edge: storage.EdgeDependency, Offsite: true},
+	{Selector: `link[rel~=stylesheet][href]@href`, Edge: storage.EdgeDependency, Offsite: true},
+}
+
+// ImageEdgeRules are the rules record_images adds: external images, whose
+// hosts are mapped but not crawled
+var ImageEdgeRules = []EdgeRule{
+	{Selector: `img[src]@src`, Edge: storage.EdgeImage, Offsite: true, RecordOnly: true},
+}
+
+// compileEdgeRules compiles edge_rules in place and collects them, after
+// the rules of the enabled record_* switches, into cfg.EdgeExtraction
+func compileEdgeRules(cfg *Config) error {
+	for i := range cfg.EdgeRules {
+		if err := compileEdgeRule(&cfg.EdgeRules[i]); err != nil {
+			return fmt.Errorf("edge_rules[%d]: %w", i, err)
+		}
+	}
+
+	var rules []EdgeRule
+	if cfg.RecordDependencies {
+		rules = append(rules, DependencyEdgeRules...)
+	}
+	if cfg.RecordImages {
+		rules = append(rules, ImageEdgeRules...)
+	}
+	for i := range rules {
+		if err := compileEdgeRule(&rules[i]); err != nil {
+			return err
+		}
+	}
+	cfg.EdgeExtraction = append(rules, cfg.EdgeRules...)
+	return nil
+}
+
+// compileEdgeRule checks a rule's edge type and compiles its selector
+func compileEdgeRule(rule *EdgeRule) error {
+	if !storage.ValidEdgeType(rule.Edge) {
+		return fmt.Errorf("edge must be one of %s", strings.Join(storage.EdgeTypes, ", "))
+	}
+	match, err := compileSelector(rule.Selector)
+	if err != nil {
+		return err
+	}
+	if match.Attr == "" {
+		return fmt.Errorf("selector `%s` must end in @attr naming the URL attribute", rule.Selector)
+	}
+	rule.Match = match
+	return nil
+}
+
+// compileSelectorSets compiles the global and per-site selectors into cfg
+func compileSelectorSets(cfg *Config) error {
+	var err error
+	if cfg.Selectors.Title, err = compileSelectors("title_selectors", cfg.TitleSelectors); err != nil {
+		return err
+	}
+	if cfg.Selectors.Description, err = compileSelectors("description_selectors", cfg.DescriptionSelectors); err != nil {
+		return err
+	}
+
+	cfg.SiteSelectorSets = make(map[string]SelectorSet, len(cfg.SiteSelectors))
+	for site, overrides := range cfg.SiteSelectors {
+		if site == "" {
+			return fmt.Errorf("site_selectors: site must not be empty")
+		}
+		set := cfg.Selectors
+		if len(overrides.Title) > 0 {
+			if set.Title, err = compileSelectors(fmt.Sprintf("site_selectors[%q].title", site), overrides.Title); err != nil {
+				return err
+			}
+		}
+		if len(overrides.Description) > 0 {
+			if set.Description, err = compileSelectors(fmt.Sprintf("site_selectors[%q].description", site), overrides.Description); err != nil {
+				return err
+			}
+		}
+		cfg.SiteSelectorSets[strings.ToLower(site)] = set
+	}
+	return nil
+}
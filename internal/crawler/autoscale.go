@@ -0,0 +1,114 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// autoscaleInterval is how often the worker scaler samples queue pressure
+const autoscaleInterval = 2 * time.Second
+
+// WorkerScaler grows and shrinks the active worker pool between min and max
+// based on queue depth and in-flight saturation
+// Like ResourceThrottle, it parks workers whose id is above the current limit
+type WorkerScaler struct {
+	minWorkers int
+	maxWorkers int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int
+	stopped bool
+}
+
+// NewWorkerScaler creates a scaler from the config; it is disabled unless
+// max_workers is set, in which case it starts at concurrent_workers clamped
+// into [min_workers, max_workers]
+func NewWorkerScaler(cfg *config.Config) *WorkerScaler {
+	s := &WorkerScaler{
+		minWorkers: cfg.MinWorkers,
+		maxWorkers: cfg.MaxWorkers,
+		limit:      cfg.ConcurrentWorkers,
+	}
+	if s.Enabled() {
+		s.limit = min(max(cfg.ConcurrentWorkers, cfg.MinWorkers), cfg.MaxWorkers)
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// workerPoolSize is the number of worker goroutines to start
+func workerPoolSize(cfg *config.Config) int {
+	return max(cfg.ConcurrentWorkers, cfg.MaxWorkers)
+}
+
+// Enabled reports whether autoscaling is configured
+func (s *WorkerScaler) Enabled() bool {
+	return s.maxWorkers > 0
+}
+
+// Run samples queue pressure at autoscaleInterval until stop is closed
+func (s *WorkerScaler) Run(queueSize, inFlight func() int, stop <-chan struct{}) {
+	ticker := time.NewTicker(autoscaleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sample(queueSize(), inFlight())
+		case <-stop:
+			return
+		}
+	}
+}
+
+// sample adjusts the limit one worker at a time: up while every active
+// worker has a request in flight and more entries are waiting than workers,
+// down once fewer than half are busy and the queue is shallow
+func (s *WorkerScaler) sample(queued, inFlight int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.limit
+	switch {
+	case inFlight >= s.limit && queued > s.limit:
+		s.limit = min(s.maxWorkers, s.limit+1)
+	case inFlight < s.limit/2 && queued < s.limit:
+		s.limit = max(s.minWorkers, s.limit-1)
+	}
+
+	if s.limit != previous {
+		logrus.Infof("Autoscale: workers %d -> %d (queue=%d, in-flight=%d)", previous, s.limit, queued, inFlight)
+		s.cond.Broadcast()
+	}
+}
+
+// Wait blocks worker id while it is above the current limit
+// Returns false if the scaler was stopped
+func (s *WorkerScaler) Wait(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id > s.limit && !s.stopped {
+		s.cond.Wait()
+	}
+	return !s.stopped
+}
+
+// Limit returns the current number of active workers
+func (s *WorkerScaler) Limit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit
+}
+
+// Stop releases all parked workers
+func (s *WorkerScaler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	s.cond.Broadcast()
+}
@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"strings"
+	"sync"
+)
+
+// Blocklist holds manually blocked domains; blocking a domain also blocks
+// all of its subdomains
+type Blocklist struct {
+	mu      sync.RWMutex
+	domains map[string]bool
+}
+
+// NewBlocklist creates an empty blocklist
+func NewBlocklist() *Blocklist {
+	return &Blocklist{
+		domains: make(map[string]bool),
+	}
+}
+
+// Replace swaps the whole set, e.g. after reloading from storage
+func (b *Blocklist) Replace(domains []string) {
+	set := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		set[strings.ToLower(domain)] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.domains = set
+}
+
+// Add blocks a domain
+func (b *Blocklist) Add(domain string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.domains[strings.ToLower(domain)] = true
+}
+
+// Remove unblocks a domain
+func (b *Blocklist) Remove(domain string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.domains, strings.ToLower(domain))
+}
+
+// IsBlocked checks the domain and each of its parent domains
+func (b *Blocklist) IsBlocked(domain string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.domains) == 0 {
+		return false
+	}
+
+	for {
+		if b.domains[domain] {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
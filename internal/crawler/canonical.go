@@ -0,0 +1,43 @@
+package crawler
+
+import (
+	"strings"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+)
+
+// Canonicalizer rewrites discovered domains with the canonical_rules, e.g.
+// collapsing every *.blogspot.com blog into one node or stripping language
+// subdomains, to control the granularity of the graph
+type Canonicalizer struct {
+	rules []config.CanonicalRule
+}
+
+// NewCanonicalizer creates a canonicalizer from compiled rules
+func NewCanonicalizer(rules []config.CanonicalRule) *Canonicalizer {
+	return &Canonicalizer{rules: rules}
+}
+
+// Canonicalize returns the canonical form of domain: the first matching
+// rule's rewrite, or domain unchanged. A rewrite that leaves no host name
+// returns "", so the link is dropped
+func (c *Canonicalizer) Canonicalize(domain string) string {
+	canonical, _ := c.Match(domain)
+	return canonical
+}
+
+// Match returns the canonical form of domain and the index of the rule that
+// produced it, or -1 if no rule matched
+func (c *Canonicalizer) Match(domain string) (string, int) {
+	for i, rule := range c.rules {
+		if !rule.Regexp.MatchString(domain) {
+			continue
+		}
+		canonical := strings.ToLower(strings.Trim(rule.Regexp.ReplaceAllString(domain, rule.Replace), "."))
+		if canonical == "" || strings.ContainsAny(canonical, "/: ") {
+			return "", i
+		}
+		return canonical, i
+	}
+	return domain, -1
+}
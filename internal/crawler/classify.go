@@ -0,0 +1,430 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxClassifyTextBytes bounds the page text read for classification; the
+// top of a front page is what says what kind of site it is
+const maxClassifyTextBytes = 32 * 1024
+
+// PageFeatures describes a fetched front page, for classification
+type PageFeatures struct {
+	URL         *url.URL // the page's final URL
+	Title       string
+	Description string   // meta description, else the Open Graph one
+	Text        string   // visible text, at most maxClassifyTextBytes of it
+	Types       []string // og:type and JSON-LD @type values
+	Generator   string   // <meta name="generator"> content
+	Links       int      // <a href> elements
+	Status      int      // HTTP status the page was served with
+}
+
+// NodeClassifier tags a crawled domain with the storage.Category* category
+// its front page shows, or "" when it can't tell
+// Classifiers are called from every worker, so they must be safe for
+// concurrent use and fast
+type NodeClassifier interface {
+	Classify(page PageFeatures) string
+}
+
+// classificationFeatureKinds are the kinds of feature RuleClassifier
+// weighs; a feature is named kind:value, in lowercase:
+//
+//	word:<w>        a word of the title, description, or text
+//	pair:<w> <w>    two adjacent words of one of them
+//	title:<w>       a word of the title
+//	host:<label>    a label of the host below its last two, e.g. host:docs
+//	path:<segment>  a segment of the URL path
+//	type:<t>        an og:type or JSON-LD @type, e.g. type:product
+//	generator:<w>   the first word of the generator meta tag
+//	links:none      no <a href> at all; links:few, fewer than 5
+//	text:short      fewer than 30 words of text
+//	status:4xx      served with a 4xx status; status:5xx, a 5xx one
+var classificationFeatureKinds = []string{"word", "pair", "title", "host", "path", "type", "generator", "links", "text", "status"}
+
+// classificationRules are the built-in feature weights by category; a
+// feature worth 1 is enough on its own at the default min_score
+var classificationRules = map[string]map[string]float64{
+	storage.CategoryEcommerce: {
+		"pair:to cart": 1, "pair:to basket": 1, "pair:to bag": 0.6, "pair:shopping cart": 1,
+		"pair:free shipping": 0.6, "word:checkout": 0.5, "word:cart": 0.3, "word:wishlist": 0.4,
+		"word:shipping": 0.3, "type:product": 1, "type:offer": 0.5,
+		"generator:shopify": 1, "generator:woocommerce": 1, "generator:prestashop": 1, "generator:magento": 1,
+		"host:shop": 0.6, "host:store": 0.6, "path:shop": 0.5, "path:products": 0.5, "path:cart": 0.5,
+	},
+	storage.CategoryBlog: {
+		"type:blog": 1, "type:blogposting": 1, "host:blog": 0.8, "path:blog": 0.8,
+		"generator:wordpress": 0.5, "generator:ghost": 0.8, "generator:jekyll": 0.5, "generator:hugo": 0.4,
+		"pair:posted by": 0.5, "pair:posted on": 0.5, "pair:recent posts": 0.6, "pair:read more": 0.3,
+		"word:blog": 0.4, "word:blogroll": 0.6, "word:archives": 0.3, "word:comments": 0.2,
+	},
+	storage.CategoryNews: {
+		"type:newsarticle": 1, "type:newsmediaorganization": 1, "host:news": 0.8, "path:news": 0.5,
+		"pair:breaking news": 0.8, "pair:latest news": 0.5, "word:headlines": 0.4, "word:newsroom": 0.3,
+		"word:politics": 0.3, "word:journalism": 0.3, "word:opinion": 0.2, "word:sports": 0.2,
+	},
+	storage.CategoryDocumentation: {
+		"host:docs": 1, "host:wiki": 0.5, "host:developer": 0.4, "path:docs": 0.8, "path:documentation": 0.8,
+		"path:reference": 0.4, "path:api": 0.3, "generator:sphinx": 1, "generator:docusaurus": 1,
+		"generator:mkdocs": 1, "generator:gitbook": 1, "generator:vitepress": 0.8, "generator:mediawiki": 0.6,
+		"pair:api reference": 0.6, "pair:getting started": 0.4, "pair:table of": 0.3,
+		"word:documentation": 0.4, "word:docs": 0.3, "word:installation": 0.3, "word:tutorial": 0.2,
+	},
+	storage.CategoryParked: {
+		"pair:is parked": 1, "pair:buy this": 0.7, "pair:related searches": 0.8, "pair:for sale": 0.5,
+		"pair:this domain": 0.5, "pair:an offer": 0.4, "pair:domain name": 0.3, "pair:coming soon": 0.4,
+		"word:parked": 0.6, "word:hugedomains": 1, "word:sedo": 0.8, "word:afternic": 0.8,
+		"links:few": 0.2, "text:short": 0.2,
+	},
+	storage.CategoryErrorPage: {
+		"title:404": 0.8, "title:error": 0.4, "pair:not found": 0.6, "pair:bad gateway": 1,
+		"pair:service unavailable": 1, "pair:internal server": 0.8, "pair:access denied": 0.6,
+		"pair:it works": 0.8, "pair:test page": 0.5, "pair:under construction": 0.6,
+		"word:suspended": 0.5, "word:forbidden": 0.5, "word:nginx": 0.6,
+		"links:none": 0.3, "text:short": 0.2, "status:4xx": 1, "status:5xx": 1,
+	},
+}
+
+// jsonLDType matches the @type values of JSON-LD structured data
+var jsonLDType = regexp.MustCompile(`"@type"\s*:\s*(?:"([^"]+)"|\[([^\]]*)\])`)
+
+// RuleClassifier classifies pages by weighted features: a category's score
+// is the sum of the weights of the features the page has, each counted
+// once, and the best category at or above the minimum score wins, ties
+// going to the one listed first in storage.Categories
+type RuleClassifier struct {
+	weights  map[string]map[string]float64 // feature -> category -> weight
+	minScore float64
+}
+
+// NewRuleClassifier creates a classifier with the built-in rules; nil when
+// node_classification is disabled, so nodes are left unclassified
+func NewRuleClassifier(cfg config.NodeClassification) NodeClassifier {
+	if !cfg.Enabled {
+		return nil
+	}
+	c := &RuleClassifier{
+		weights:  make(map[string]map[string]float64),
+		minScore: cfg.MinScore,
+	}
+	c.Add(classificationRules)
+	return c
+}
+
+// Add adds category -> feature -> weight weights to those already set;
+// call it before the crawl starts
+func (c *RuleClassifier) Add(weights map[string]map[string]float64) {
+	for category, features := range weights {
+		for feature, weight := range features {
+			if c.weights[feature] == nil {
+				c.weights[feature] = make(map[string]float64)
+			}
+			c.weights[feature][category] += weight
+		}
+	}
+}
+
+// Classify returns the best-scoring category of page, or "" if none
+// reaches the minimum score
+func (c *RuleClassifier) Classify(page PageFeatures) string {
+	s := classification{
+		weights: c.weights,
+		scores:  make(map[string]float64),
+		seen:    make(map[string]bool),
+	}
+
+	titleWords := pageWords(page.Title)
+	for _, word := range titleWords {
+		s.add("title", word)
+	}
+	textWords := pageWords(page.Text)
+	for _, words := range [][]string{titleWords, pageWords(page.Description), textWords} {
+		for i, word := range words {
+			s.add("word", word)
+			if i > 0 {
+				s.add("pair", words[i-1], word)
+			}
+		}
+	}
+
+	if page.URL != nil {
+		labels := strings.Split(strings.ToLower(page.URL.Hostname()), ".")
+		for _, label := range labels[:max(len(labels)-2, 0)] {
+			s.add("host", label)
+		}
+		for _, segment := range strings.Split(strings.ToLower(page.URL.Path), "/") {
+			if segment != "" {
+				s.add("path", segment)
+			}
+		}
+	}
+	for _, t := range page.Types {
+		// JSON-LD types may be written as schema.org URLs
+		s.add("type", strings.ToLower(t[strings.LastIndexAny(t, "/:")+1:]))
+	}
+	if generator := pageWords(page.Generator); len(generator) > 0 {
+		s.add("generator", generator[0])
+	}
+	switch {
+	case page.Links == 0:
+		s.add("links", "none")
+	case page.Links < 5:
+		s.add("links", "few")
+	}
+	if len(textWords) < 30 {
+		s.add("text", "short")
+	}
+	if page.Status >= 400 {
+		s.add("status", fmt.Sprintf("%dxx", page.Status/100))
+	}
+
+	best := ""
+	for _, category := range storage.Categories {
+		if score := s.scores[category]; score >= c.minScore && (best == "" || score > s.scores[best]) {
+			best = category
+		}
+	}
+	return best
+}
+
+// classification scores one page
+type classification struct {
+	weights map[string]map[string]float64
+	scores  map[string]float64
+	seen    map[string]bool
+	buf     []byte
+}
+
+// add counts the weights of feature kind:value, the value's words joined
+// by spaces, unless already counted
+func (s *classification) add(kind string, words ...string) {
+	s.buf = append(append(s.buf[:0], kind...), ':')
+	for i, word := range words {
+		if i > 0 {
+			s.buf = append(s.buf, ' ')
+		}
+		s.buf = append(s.buf, word...)
+	}
+	weights, ok := s.weights[string(s.buf)]
+	if !ok || s.seen[string(s.buf)] {
+		return
+	}
+	s.seen[string(s.buf)] = true
+	for category, weight := range weights {
+		s.scores[category] += weight
+	}
+}
+
+// pageWords splits text into its lowercase alphanumeric words
+func pageWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// readPageFeatures reads the classification features of the page at
+// pageURL in one pass of the HTML tokenizer, whichever html_parser is set
+func readPageFeatures(pageURL *url.URL, body []byte) PageFeatures {
+	var (
+		page          = PageFeatures{URL: pageURL}
+		text          strings.Builder
+		ogDescription string
+		titleSeen     bool
+		inTitle       bool
+		inJSONLD      bool
+		hidden        int // depth of open elements whose text isn't shown
+	)
+	z := nethtml.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		switch tt {
+		case nethtml.ErrorToken:
+			if page.Description == "" {
+				page.Description = ogDescription
+			}
+			page.Text = text.String()
+			return page
+
+		case nethtml.TextToken:
+			switch {
+			case inTitle:
+				page.Title += string(z.Text())
+			case inJSONLD:
+				for _, m := range jsonLDType.FindAllSubmatch(z.Text(), -1) {
+					if len(m[1]) > 0 {
+						page.Types = append(page.Types, string(m[1]))
+					}
+					for _, t := range bytes.Split(m[2], []byte(",")) {
+						if t = bytes.Trim(t, " \t\r\n\""); len(t) > 0 {
+							page.Types = append(page.Types, string(t))
+						}
+					}
+				}
+			case hidden == 0 && text.Len() < maxClassifyTextBytes:
+				text.Write(z.Text())
+				text.WriteByte(' ')
+			}
+
+		case nethtml.EndTagToken:
+			switch name, _ := z.TagName(); atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.Script:
+				inJSONLD = false
+				hidden = max(hidden-1, 0)
+			case atom.Style, atom.Noscript, atom.Template:
+				hidden = max(hidden-1, 0)
+			}
+
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.A:
+				if tagAttr(z, hasAttr, "href") != "" {
+					page.Links++
+				}
+			case atom.Title:
+				inTitle = !titleSeen && tt == nethtml.StartTagToken
+				titleSeen = true
+			case atom.Script:
+				if tt == nethtml.StartTagToken {
+					inJSONLD = strings.Contains(strings.ToLower(tagAttr(z, hasAttr, "type")), "ld+json")
+					hidden++
+				}
+			case atom.Style, atom.Noscript, atom.Template:
+				if tt == nethtml.StartTagToken {
+					hidden++
+				}
+			case atom.Meta:
+				var name, property, content string
+				for more := hasAttr; more; {
+					var key, val []byte
+					key, val, more = z.TagAttr()
+					switch string(key) {
+					case "name":
+						name = strings.ToLower(string(val))
+					case "property":
+						property = strings.ToLower(string(val))
+					case "content":
+						content = string(val)
+					}
+				}
+				switch {
+				case name == "description" && page.Description == "":
+					page.Description = content
+				case property == "og:description" && ogDescription == "":
+					ogDescription = content
+				case property == "og:type" && content != "":
+					page.Types = append(page.Types, content)
+				case name == "generator" && page.Generator == "":
+					page.Generator = content
+				}
+			}
+		}
+	}
+}
+
+// ReadClassificationModel reads a model file of category -> feature ->
+// weight entries, e.g. {"blog": {"word:recipes": 0.4}}
+func ReadClassificationModel(path string) (map[string]map[string]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read classification model: %w", err)
+	}
+	var model map[string]map[string]float64
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("failed to parse classification model: %w", err)
+	}
+
+	weights := make(map[string]map[string]float64, len(model))
+	for category, features := range model {
+		if category == storage.CategoryOther || !slices.Contains(storage.Categories, category) {
+			return nil, fmt.Errorf("classification model: unknown category %q", category)
+		}
+		weights[category] = make(map[string]float64, len(features))
+		for feature, weight := range features {
+			kind, value, ok := strings.Cut(strings.ToLower(feature), ":")
+			if !ok || value == "" || !slices.Contains(classificationFeatureKinds, kind) {
+				return nil, fmt.Errorf("classification model: feature %q of %s must be kind:value, kind one of %s",
+					feature, category, strings.Join(classificationFeatureKinds, ", "))
+			}
+			weights[category][kind+":"+value] = weight
+		}
+	}
+	return weights, nil
+}
+
+// LoadClassificationModel adds the weights of node_classification's model
+// file to the built-in rules
+func (c *Crawler) LoadClassificationModel() error {
+	path := c.cfg.NodeClassification.ModelPath
+	rules, ok := c.classifier.(*RuleClassifier)
+	if path == "" || !ok {
+		return nil
+	}
+	model, err := ReadClassificationModel(path)
+	if err != nil {
+		return err
+	}
+	rules.Add(model)
+
+	features := 0
+	for _, weights := range model {
+		features += len(weights)
+	}
+	logrus.Infof("Loaded %d classification model weights from %s", features, path)
+	return nil
+}
+
+// SetClassifier replaces the node classifier, e.g. with a custom
+// implementation when embedding the crawler; nil turns classification off
+// Call it before Start
+func (c *Crawler) SetClassifier(classifier NodeClassifier) {
+	c.classifier = classifier
+}
+
+// Categories returns how many domains were classified this run per
+// category
+func (c *Crawler) Categories() map[string]int {
+	c.categoriesMu.Lock()
+	defer c.categoriesMu.Unlock()
+	return maps.Clone(c.categories)
+}
+
+// classify records the category of domain's front page, fetched as r
+func (c *Crawler) classify(domain string, r *colly.Response) {
+	if c.classifier == nil || !isHTML(r) {
+		return
+	}
+	page := readPageFeatures(r.Request.URL, r.Body)
+	page.Status = r.StatusCode
+	category := c.classifier.Classify(page)
+	if category == "" || !slices.Contains(storage.Categories, category) {
+		category = storage.CategoryOther
+	}
+	if err := c.memGraph.SetCategory(domain, category); err != nil {
+		logrus.Warnf("Failed to update category of %s: %v", domain, err)
+		return
+	}
+
+	c.categoriesMu.Lock()
+	defer c.categoriesMu.Unlock()
+	c.categories[category]++
+}
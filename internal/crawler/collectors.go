@@ -0,0 +1,117 @@
+package crawler
+
+import (
+	"hash/fnv"
+	"net/http"
+	"net/url"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// CollectorPool spreads fetches over several colly collectors by root
+// domain, so each collector keeps its own cookie jar and request queue and
+// a misbehaving root can only tie up the collector it maps to
+// Roots listed in root_collectors get a dedicated collector with their own
+// parallelism, proxy, and user agent; all others hash onto the shared ones
+type CollectorPool struct {
+	shared    []*colly.Collector
+	dedicated map[string]*colly.Collector // root domain -> collector
+}
+
+// NewCollectorPool builds the configured collectors with newCollector,
+// which returns a collector allowing the given parallelism through the
+// given proxy (nil for none)
+// The workers' parallelism is divided among the shared collectors
+func NewCollectorPool(cfg *config.Config, newCollector func(parallelism int, proxy func(*http.Request) (*url.URL, error)) *colly.Collector) *CollectorPool {
+	slots := cfg.CollectorPoolSize
+	perSlot := (workerPoolSize(cfg) + slots - 1) / slots
+
+	p := &CollectorPool{
+		shared:    make([]*colly.Collector, slots),
+		dedicated: make(map[string]*colly.Collector, len(cfg.RootCollectors)),
+	}
+	for i := range p.shared {
+		p.shared[i] = newCollector(perSlot, nil)
+	}
+
+	for root, rc := range cfg.RootCollectors {
+		parallelism := rc.Parallelism
+		if parallelism == 0 {
+			parallelism = perSlot
+		}
+		var proxy func(*http.Request) (*url.URL, error)
+		if rc.Proxy != "" {
+			if proxyURL, err := url.Parse(rc.Proxy); err != nil {
+				logrus.Warnf("Ignoring proxy for %s: %v", root, err)
+			} else {
+				proxy = http.ProxyURL(proxyURL)
+			}
+		}
+		collector := newCollector(parallelism, proxy)
+		if rc.UserAgent != "" {
+			collector.UserAgent = rc.UserAgent
+		}
+		p.dedicated[ExtractRootDomain(root)] = collector
+	}
+	return p
+}
+
+// For returns the collector that fetches domain
+func (p *CollectorPool) For(domain string) *colly.Collector {
+	root := ExtractRootDomain(domain)
+	if collector, ok := p.dedicated[root]; ok {
+		return collector
+	}
+	if len(p.shared) == 1 {
+		return p.shared[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(root))
+	return p.shared[h.Sum32()%uint32(len(p.shared))]
+}
+
+// UserAgent returns the user agent the crawler fetches domain with: that of
+// its root's entry in root_collectors, or colly's default
+// It lets requests sent before the crawler exists, like preflight checks,
+// identify themselves as its fetches will
+func UserAgent(cfg *config.Config, domain string) string {
+	root := ExtractRootDomain(domain)
+	for r, rc := range cfg.RootCollectors {
+		if rc.UserAgent != "" && ExtractRootDomain(r) == root {
+			return rc.UserAgent
+		}
+	}
+	return colly.NewCollector().UserAgent
+}
+
+// Size returns the number of shared and dedicated collectors
+func (p *CollectorPool) Size() (shared, dedicated int) {
+	return len(p.shared), len(p.dedicated)
+}
+
+// SetTransport replaces the HTTP transport of every collector, dropping
+// any per-root proxy
+func (p *CollectorPool) SetTransport(transport http.RoundTripper) {
+	p.each(func(collector *colly.Collector) {
+		collector.WithTransport(transport)
+	})
+}
+
+// Wait blocks until every collector has finished its in-flight requests
+func (p *CollectorPool) Wait() {
+	p.each(func(collector *colly.Collector) {
+		collector.Wait()
+	})
+}
+
+// each calls fn for every collector in the pool
+func (p *CollectorPool) each(fn func(*colly.Collector)) {
+	for _, collector := range p.shared {
+		fn(collector)
+	}
+	for _, collector := range p.dedicated {
+		fn(collector)
+	}
+}
@@ -1,12 +1,21 @@
 package crawler
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/events"
 	"github.com/alvmarrod/web-weaver/internal/memory"
+	"github.com/alvmarrod/web-weaver/internal/metrics"
 	"github.com/alvmarrod/web-weaver/internal/storage"
 	"github.com/gocolly/colly/v2"
 	"github.com/sirupsen/logrus"
@@ -14,129 +23,269 @@ import (
 
 // Crawler orchestrates the web crawling process
 type Crawler struct {
-	cfg             *config.Config
-	storage         *storage.Storage
-	memGraph        *memory.MemoryGraph
-	queue           *Queue
-	limiter         *SubdomainLimiter
-	collector       *colly.Collector
-	contextMap      map[string]storage.QueueEntry
-	contextMu       sync.RWMutex
-	wg              sync.WaitGroup
-	stopChan        chan struct{}
-	stopOnce        sync.Once
-	inFlightMu      sync.Mutex
-	inFlight        int
-	metricsCallback func(nodesCrawled, nodesDiscovered, edgesRecorded, pagesFetched, pagesFailed int)
-}
-
-// NewCrawler creates a new crawler instance
-func NewCrawler(cfg *config.Config, store *storage.Storage, metricsCallback func(int, int, int, int, int)) *Crawler {
+	cfg            *config.Config
+	storage        *storage.Storage
+	memGraph       *memory.MemoryGraph
+	frontier       Queue
+	canonical      *Canonicalizer
+	platforms      *Platforms
+	filter         *DomainFilter
+	tlds           *TLDFilter
+	blocklist      *Blocklist
+	throttle       *ResourceThrottle
+	scaler         *WorkerScaler
+	plateau        *PlateauDetector
+	fanOut         *FanOutLimiter
+	languages      *LanguageQuota
+	scorer         DomainScorer // nil keeps the frontier FIFO within a depth
+	requestHook    RequestHook  // nil sends requests unmodified
+	failures       *FailureMonitor
+	httpCache      *HTTPCache
+	refetch        *RefetchGuard
+	errorLog       *ErrorLog
+	schemes        *SchemeLog
+	sitemaps       *SitemapReader
+	seeds          *SeedChecker
+	pages          *PageBudget
+	reputation     *Reputation
+	classifier     NodeClassifier // nil leaves nodes unclassified
+	categories     map[string]int // domains classified this run per category
+	categoriesMu   sync.Mutex
+	recordOnly     map[string]bool // edge types whose targets are never enqueued
+	logSampler     *LogSampler
+	extractor      *Extractor
+	latency        *HostLatency
+	dnsPrefetcher  *DNSPrefetcher
+	dnsCache       *DNSCache // nil unless DNS prefetching is enabled
+	frontierClosed atomic.Bool
+	collectors     *CollectorPool
+	wg             sync.WaitGroup
+	ctx            context.Context // cancelled by Stop; everything a worker waits on ends with it
+	cancel         context.CancelFunc
+	stopOnce       sync.Once
+	flushMu        sync.Mutex
+	inFlightMu     sync.Mutex
+	inFlight       int
+	workers        *WorkerBoard
+	deadline       time.Duration
+	open           *openFetches
+	abandonCount   atomic.Int64
+	httpFallbacks  atomic.Int64
+	retries        *Retries
+	htmlGuard      *HTMLGuard
+	metrics        metrics.Sink
+	events         *events.Bus
+}
+
+// NewCrawler creates a new crawler instance reporting its events to sink,
+// which may be nil
+func NewCrawler(cfg *config.Config, store *storage.Storage, sink metrics.Sink) *Crawler {
+	if sink == nil {
+		sink = metrics.Sinks(nil)
+	}
+	latency := NewHostLatency(slowHostThreshold(cfg))
+	poolSize := workerPoolSize(cfg)
 	c := &Crawler{
-		cfg:             cfg,
-		storage:         store,
-		memGraph:        memory.NewMemoryGraph(),
-		queue:           NewQueue(),
-		limiter:         NewSubdomainLimiter(cfg.MaxSubdomainsPerRoot),
-		contextMap:      make(map[string]storage.QueueEntry),
-		stopChan:        make(chan struct{}),
-		metricsCallback: metricsCallback,
+		cfg:        cfg,
+		storage:    store,
+		memGraph:   memory.NewMemoryGraph(),
+		frontier:   newQueue(cfg, poolSize, latency),
+		latency:    latency,
+		canonical:  NewCanonicalizer(cfg.CanonicalRules),
+		platforms:  NewPlatforms(cfg.PlatformMode, cfg.Platforms),
+		filter:     NewDomainFilter(cfg.ExcludeRules, cfg.IncludeRules),
+		tlds:       NewTLDFilter(cfg.AllowedTLDs, cfg.BlockedTLDs),
+		blocklist:  NewBlocklist(),
+		throttle:   NewResourceThrottle(cfg.MaxRSSMB, cfg.MaxCPUPercent, poolSize),
+		scaler:     NewWorkerScaler(cfg),
+		fanOut:     NewFanOutLimiter(cfg.DepthFanOutLimits),
+		languages:  NewLanguageQuota(cfg.LanguageQuotas),
+		scorer:     NewRuleScorer(cfg.DomainScoring),
+		plateau:    NewPlateauDetector(time.Duration(cfg.PlateauWindowSec)*time.Second, cfg.PlateauMinNewRoots),
+		failures:   NewFailureMonitor(cfg.FailureWindow, cfg.MaxFailurePercent),
+		httpCache:  NewHTTPCache(),
+		refetch:    NewRefetchGuard(time.Duration(cfg.MinRefetchIntervalSec) * time.Second),
+		errorLog:   NewErrorLog(),
+		schemes:    NewSchemeLog(cfg.ProbeHTTPScheme, time.Duration(cfg.RequestTimeoutMs)*time.Millisecond),
+		sitemaps:   NewSitemapReader(cfg.ReadSitemaps, cfg.SitemapMaxURLs, time.Duration(cfg.RequestTimeoutMs)*time.Millisecond),
+		seeds:      NewSeedChecker(!cfg.SkipSeedCheck, time.Duration(cfg.RequestTimeoutMs)*time.Millisecond),
+		pages:      NewPageBudget(cfg),
+		reputation: NewReputation(cfg.DomainReputation.MinScore, time.Duration(cfg.RequestTimeoutMs)*time.Millisecond),
+		classifier: NewRuleClassifier(cfg.NodeClassification),
+		categories: make(map[string]int),
+		recordOnly: recordOnlyTypes(cfg.EdgeExtraction),
+		extractor:  NewExtractor(cfg),
+		logSampler: NewLogSampler(cfg.LogSampleRate, time.Duration(cfg.LogSummarySec)*time.Second),
+		workers:    NewWorkerBoard(),
+		deadline:   fetchDeadline(cfg),
+		open:       newOpenFetches(),
+		retries:    NewRetries(max(cfg.RetryAttempts, 0), time.Duration(cfg.RetryDelayMs)*time.Millisecond),
+		htmlGuard:  NewHTMLGuard(cfg),
+		metrics:    sink,
 	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
 
-	c.setupColly()
+	if prefetcher := NewDNSPrefetcher(cfg, NewDNSCache(time.Duration(cfg.DNSCacheTTLSec)*time.Second)); prefetcher.Enabled() {
+		c.dnsPrefetcher = prefetcher
+		c.dnsCache = prefetcher.cache
+	}
+
+	if c.platforms.Enabled() {
+		c.frontier.Limiter().SetRootFunc(c.platforms.Root)
+	}
+	if len(cfg.DepthPoliteness) > 0 {
+		c.frontier.SetDepthPoliteness(NewDepthPoliteness(cfg.DepthPoliteness))
+	}
+
+	c.collectors = NewCollectorPool(cfg, c.newCollector)
+	c.schemes.SetTransport(c.hooked(http.DefaultTransport))
+	c.sitemaps.SetTransport(c.hooked(http.DefaultTransport))
+	c.seeds.SetTransport(c.hooked(http.DefaultTransport))
 	return c
 }
 
-// setupColly configures the Colly collector with callbacks
-func (c *Crawler) setupColly() {
-	c.collector = colly.NewCollector(
+// newCollector creates a Colly collector allowing parallelism concurrent
+// requests through proxy (nil for a direct connection), with the crawler's
+// callbacks registered
+func (c *Crawler) newCollector(parallelism int, proxy func(*http.Request) (*url.URL, error)) *colly.Collector {
+	collector := colly.NewCollector(
 		colly.Async(true),
-		colly.MaxDepth(0), // Managed manually via queue depth
+		colly.MaxDepth(0),     // Managed manually via queue depth
+		colly.DetectCharset(), // Decode non-UTF-8 pages that don't declare a charset
 	)
 
+	// Each collector owns its transport, wrapped so the request hook sees
+	// every request; resolve through the cache the DNS prefetcher fills
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.dnsCache != nil {
+		transport.DialContext = c.dnsCache.DialContext
+	}
+	if proxy != nil {
+		transport.Proxy = proxy
+		transport.DisableKeepAlives = true
+	}
+	collector.WithTransport(c.hooked(transport))
+
 	// Set request timeout
-	c.collector.SetRequestTimeout(time.Duration(c.cfg.RequestTimeoutMs) * time.Millisecond)
+	collector.SetRequestTimeout(time.Duration(c.cfg.RequestTimeoutMs) * time.Millisecond)
 
-	// Limit parallelism
-	c.collector.Limit(&colly.LimitRule{
+	// Limit parallelism; RandomDelay keeps request timing from looking scripted
+	collector.Limit(&colly.LimitRule{
 		DomainGlob:  "*",
-		Parallelism: c.cfg.ConcurrentWorkers,
+		Parallelism: parallelism,
 		Delay:       0,
+		RandomDelay: time.Duration(c.cfg.RandomDelayMs) * time.Millisecond,
 	})
 
-	// Extract title
-	c.collector.OnHTML("title", func(e *colly.HTMLElement) {
-		domain, err := ExtractDomain(e.Request.URL.String())
+	// Revalidate stale pages with the validators from previous runs
+	collector.OnRequest(func(r *colly.Request) {
+		r.Ctx.Put(fetchStartKey, time.Now())
+		c.sendFetch(r)
+
+		domain, err := ExtractDomain(r.URL.String())
 		if err != nil || domain == "" {
 			return
 		}
-
-		ctx := c.getContextWithFallback(domain)
-		if ctx == nil {
+		r.Ctx.Put(linkStatsKey, newPageLinks(domain))
+		r.Ctx.Put(requestedURLKey, r.URL.String())
+		// Validators belong to the front page
+		if innerPage(r.Ctx) {
 			return
 		}
-
-		title := e.Text
-		if len(title) > 60 {
-			title = title[:60]
+		entry, ok := c.httpCache.Get(cacheKey(domain))
+		if !ok {
+			return
 		}
-
-		// Update node description with title (in memory)
-		_, err = c.memGraph.UpsertNode(ctx.DomainName, title)
-		if err != nil {
-			logrus.Warnf("Failed to update node description: %v", err)
+		if entry.ETag != "" {
+			r.Headers.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			r.Headers.Set("If-Modified-Since", entry.LastModified)
 		}
 	})
 
-	// Extract meta description as fallback
-	c.collector.OnHTML("meta[name=description]", func(e *colly.HTMLElement) {
-		domain, err := ExtractDomain(e.Request.URL.String())
-		if err != nil || domain == "" {
-			return
+	// The fetch deadline runs from the moment the request holds a connection
+	// slot; a fetch abandoned before its headers arrive skips the download
+	collector.OnRequestHeaders(func(r *colly.Request) {
+		c.armDeadline(r.Ctx)
+	})
+	collector.OnResponseHeaders(func(r *colly.Response) {
+		if c.abandoned(r.Ctx) {
+			r.Request.Abort()
 		}
+	})
 
-		ctx := c.getContextWithFallback(domain)
-		if ctx == nil {
+	// Response callbacks run before the body is parsed for the HTML
+	// callbacks, so pathological pages are pruned before they're parsed
+	collector.OnResponse(func(r *colly.Response) {
+		if c.abandoned(r.Ctx) {
 			return
 		}
+		r.Ctx.Put(bodyBytesKey, len(r.Body))
+		c.htmlGuard.Guard(r)
+	})
 
-		// Only use meta description if title hasn't been set
-		node, err := c.memGraph.GetNode(ctx.DomainName)
-		if err != nil || node == nil || node.Description != "" {
+	// Parse pages with the configured html_parser
+	if c.cfg.HTMLParser == config.HTMLParserTokenizer {
+		collector.OnResponse(func(r *colly.Response) {
+			if c.abandoned(r.Ctx) {
+				return
+			}
+			c.scanPage(r)
+		})
+	} else {
+		c.registerHTMLCallbacks(collector)
+	}
+
+	// Follow the selected links and store the page's link statistics once
+	// all links have been seen
+	collector.OnScraped(func(r *colly.Response) {
+		links, ok := r.Ctx.GetAny(linkStatsKey).(*pageLinks)
+		if !ok {
 			return
 		}
-
-		description := e.Attr("content")
-		if len(description) > 60 {
-			description = description[:60]
+		// Scraping is the last use of the page's links
+		defer func() {
+			r.Ctx.Put(linkStatsKey, nil)
+			links.release()
+		}()
+		if c.abandoned(r.Ctx) {
+			return
 		}
 
-		_, err = c.memGraph.UpsertNode(ctx.DomainName, description)
-		if err != nil {
-			logrus.Warnf("Failed to update node description: %v", err)
-		}
-	})
-
-	// Extract links
-	c.collector.OnHTML("a[href]", func(e *colly.HTMLElement) {
-		domain, err := ExtractDomain(e.Request.URL.String())
+		domain, err := ExtractDomain(r.Request.URL.String())
 		if err != nil || domain == "" {
 			return
 		}
 
-		ctx := c.getContextWithFallback(domain)
+		ctx := c.fetchEntry(r.Ctx, domain)
 		if ctx == nil {
-			// Silently skip - likely a redirect to an untracked domain
 			return
 		}
 
-		link := e.Attr("href")
-		c.handleLink(ctx, link)
+		targets := c.selectTargets(links.Targets())
+		c.preloadOrigins(ctx.DomainName, targets)
+		for _, target := range targets {
+			c.handleLink(ctx, r.Request.URL.String(), target, storage.EdgeLink, true)
+			text, rel := links.Anchor(target)
+			c.memGraph.SetAnchor(ctx.DomainName, target, text, rel)
+		}
+		c.queueInnerPages(ctx, links.Pages())
+
+		// A node's link statistics are its front page's
+		if innerPage(r.Ctx) {
+			return
+		}
+		if err := c.memGraph.SetLinkStats(ctx.DomainName, links.Stats()); err != nil {
+			logrus.Warnf("Failed to update link stats for %s: %v", ctx.DomainName, err)
+		}
 	})
 
 	// Handle successful response
-	c.collector.OnResponse(func(r *colly.Response) {
-		defer c.decrementInFlight()
+	collector.OnResponse(func(r *colly.Response) {
+		if c.abandoned(r.Ctx) {
+			return
+		}
 
 		// Extract domain from response URL
 		domain, err := ExtractDomain(r.Request.URL.String())
@@ -144,21 +293,76 @@ func (c *Crawler) setupColly() {
 			return
 		}
 
-		ctx := c.getContextWithFallback(domain)
+		// Record redirects that left the requested site
+		c.recordRedirect(r.Ctx, r.Ctx.Get(requestedURLKey), r.Request.URL.String())
+
+		ctx := c.fetchEntry(r.Ctx, domain)
 		if ctx == nil {
 			// Silently skip - likely a redirect outside our crawl scope
 			return
 		}
 
-		logrus.Infof("Worker fetched %s (depth=%d, status=%d)", ctx.DomainName, ctx.Depth, r.StatusCode)
-		if c.metricsCallback != nil {
-			c.metricsCallback(0, 0, 0, 1, 0) // pagesFetched++
+		c.logSampler.Infof(logFetched, "Worker fetched %s (depth=%d, status=%d)", ctx.DomainName, ctx.Depth, r.StatusCode)
+		duration := c.observeLatency(ctx.DomainName, r)
+		c.failures.Record(false)
+		c.setStatus(ctx.DomainName, storage.NodeCrawled)
+		c.recordResponse(ctx.DomainName, r, duration)
+		c.recordScheme(r.Ctx, ctx.DomainName)
+		c.readSitemap(*ctx)
+		if r.Headers != nil && !innerPage(r.Ctx) {
+			c.httpCache.Update(cacheKey(ctx.DomainName), *r.Headers)
 		}
+		if !innerPage(r.Ctx) {
+			c.classify(ctx.DomainName, r)
+		}
+		c.metrics.PageFetched(duration)
+		c.publish(events.Event{Type: events.PageFetched, Domain: ctx.DomainName, Depth: ctx.Depth, Status: r.StatusCode})
 	})
 
 	// Handle errors with retry logic
-	c.collector.OnError(func(r *colly.Response, err error) {
-		defer c.decrementInFlight()
+	collector.OnError(func(r *colly.Response, err error) {
+		var fetchCtx *colly.Context
+		if r != nil {
+			fetchCtx = r.Ctx
+		}
+		if c.abandoned(fetchCtx) {
+			return
+		}
+		// A domain refusing HTTPS may still serve HTTP; the fetch goes on
+		if c.fallBackToHTTP(r, err) {
+			return
+		}
+		var duration time.Duration
+		if r != nil && r.Request != nil {
+			duration = c.observeLatency(r.Request.URL.Hostname(), r)
+		}
+
+		// Colly reports 304 as an error; it means our cached knowledge still
+		// holds. A page fetched again in full settles with that request
+		if r != nil && r.StatusCode == http.StatusNotModified {
+			if c.handleNotModified(r, duration) {
+				c.settleFetch(fetchCtx, nil)
+			}
+			return
+		}
+
+		failure := err
+		defer func() { c.settleFetch(fetchCtx, failure) }()
+
+		// Colly refuses redirects to pages it already visited; the redirect
+		// itself is still a relationship worth recording
+		var visited *colly.AlreadyVisitedError
+		if r != nil && errors.As(err, &visited) {
+			failure = nil
+			requestedURL := r.Ctx.Get(requestedURLKey)
+			c.recordRedirect(r.Ctx, requestedURL, visited.Destination.String())
+			c.failures.Record(false)
+			if requested, err := ExtractDomain(requestedURL); err == nil {
+				c.setStatus(requested, storage.NodeCrawled)
+			}
+			return
+		}
+		c.failures.Record(true)
 
 		// Log even if context is missing
 		if r != nil && r.Request != nil {
@@ -167,31 +371,407 @@ func (c *Crawler) setupColly() {
 			// Extract domain and delete context
 			domain, extractErr := ExtractDomain(r.Request.URL.String())
 			if extractErr == nil && domain != "" {
-				c.deleteContext(domain)
-
-				if c.metricsCallback != nil {
-					c.metricsCallback(0, 0, 0, 0, 1) // pagesFailed++
+				depth := 0
+				entry := c.fetchEntry(r.Ctx, domain)
+				if entry != nil {
+					depth = entry.Depth
+					domain = entry.DomainName
+				}
+				c.errorLog.Record(domain, r.Request.URL.String(), c.attemptOf(domain), r.StatusCode, err)
+				// Transient failures are fetched again after a backoff; until
+				// then the status records this attempt
+				if entry != nil {
+					c.retries.Schedule(*entry, r.Request, err, r.StatusCode, c.frontier.Requeue)
 				}
+				// A failed inner page says nothing about the domain
+				if !innerPage(r.Ctx) {
+					if r.StatusCode != 0 {
+						c.recordScheme(r.Ctx, domain)
+					} else if !fellBack(r.Ctx) && httpsRefused(err, r.StatusCode) {
+						c.schemes.RecordHTTPS(domain, false, c.collectors.For(domain).UserAgent)
+					}
+					c.setStatus(domain, failureStatus(err, r.StatusCode))
+					c.recordResponse(domain, r, duration)
+					// An error status may still come with a page, which
+					// says what went wrong
+					if r.StatusCode >= 400 && len(r.Body) > 0 {
+						c.classify(domain, r)
+					}
+				}
+
+				c.metrics.PageFailed()
+				c.publish(events.Event{Type: events.FetchFailed, Domain: domain, Depth: depth, Status: r.StatusCode, Error: err.Error()})
 			}
 		} else {
 			logrus.Errorf("OnError called with nil response: %v", err)
 		}
 	})
+
+	// A successful fetch ends once its page has been processed. Colly runs
+	// OnScraped callbacks in registration order, so this one stays last:
+	// the in-flight count only drops after the page's links and edges are
+	// recorded, and a drained crawl never flushes ahead of them
+	collector.OnScraped(func(r *colly.Response) {
+		c.settleFetch(r.Ctx, nil)
+	})
+
+	return collector
+}
+
+// registerHTMLCallbacks registers the callbacks of the goquery
+// html_parser, which read pages from the DOM Colly parses
+func (c *Crawler) registerHTMLCallbacks(collector *colly.Collector) {
+	// Extract title and description with the configured selectors; which
+	// one is displayed is decided on read
+	collector.OnHTML("html", func(e *colly.HTMLElement) {
+		// A node's title and description are its front page's
+		if c.abandoned(e.Request.Ctx) || innerPage(e.Request.Ctx) {
+			return
+		}
+		domain, err := ExtractDomain(e.Request.URL.String())
+		if err != nil || domain == "" {
+			return
+		}
+
+		ctx := c.fetchEntry(e.Request.Ctx, domain)
+		if ctx == nil {
+			return
+		}
+
+		title, description := c.extractor.Extract(ctx.DomainName, e.DOM)
+		c.setPageText(ctx.DomainName, title, description, pageLanguage(e))
+	})
+
+	// Extract links
+	collector.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		if c.abandoned(e.Request.Ctx) {
+			return
+		}
+		domain, err := ExtractDomain(e.Request.URL.String())
+		if err != nil || domain == "" {
+			return
+		}
+
+		ctx := c.fetchEntry(e.Request.Ctx, domain)
+		if ctx == nil {
+			// Silently skip - likely a redirect to an untracked domain
+			return
+		}
+
+		links, ok := e.Request.Ctx.GetAny(linkStatsKey).(*pageLinks)
+		if !ok {
+			return
+		}
+		link := e.Attr("href")
+		c.addLink(ctx, links, domain, link, e.Request.AbsoluteURL(link), e.Text, e.Attr("rel"))
+	})
+
+	// Follow canonical, hreflang and feed declarations; these are structural
+	// and not subject to max_outbound_links
+	collector.OnHTML("link[href]", func(e *colly.HTMLElement) {
+		edgeType := structuralEdgeType(e.Attr("rel"), e.Attr("hreflang"), e.Attr("type"))
+		if edgeType == "" || c.abandoned(e.Request.Ctx) {
+			return
+		}
+		c.recordPageEdge(e.Request.Ctx, e.Request.URL.String(), e.Request.AbsoluteURL(e.Attr("href")), edgeType, false, true)
+	})
+
+	// Follow meta refresh redirects; parked and legacy domains often reveal
+	// their real destination only this way
+	collector.OnHTML("meta[http-equiv]", func(e *colly.HTMLElement) {
+		if !strings.EqualFold(strings.TrimSpace(e.Attr("http-equiv")), "refresh") || c.abandoned(e.Request.Ctx) {
+			return
+		}
+		target := metaRefreshTarget(e.Attr("content"))
+		if target == "" {
+			return
+		}
+		c.recordPageEdge(e.Request.Ctx, e.Request.URL.String(), e.Request.AbsoluteURL(target), storage.EdgeRedirect, true, true)
+	})
+
+	// Record the edges of the extraction rules: edge_rules and the rules
+	// record_dependencies and record_images stand for
+	if rules := c.cfg.EdgeExtraction; len(rules) > 0 {
+		collector.OnHTML("html", func(e *colly.HTMLElement) {
+			if c.abandoned(e.Request.Ctx) {
+				return
+			}
+			c.applyEdgeRules(e, rules)
+		})
+	}
+}
+
+// setPageText stores the title, description and language read from
+// domain's front page
+func (c *Crawler) setPageText(domain, title, description, language string) {
+	if title = cleanText(title, c.cfg.MaxDescriptionRunes); title != "" {
+		if err := c.memGraph.SetTitle(domain, title); err != nil {
+			logrus.Warnf("Failed to update node title: %v", err)
+		}
+	}
+	if description = cleanText(description, c.cfg.MaxDescriptionRunes); description != "" {
+		if err := c.memGraph.SetMetaDescription(domain, description); err != nil {
+			logrus.Warnf("Failed to update node meta description: %v", err)
+		}
+	}
+	if language != "" {
+		c.setLanguage(domain, language)
+	}
+}
+
+// maxAnchorRunes bounds the anchor text stored on a link edge
+const maxAnchorRunes = 256
+
+// addLink records a link found on a page of ctx's domain: link as written
+// in the page, absolute resolved against it, with the link's text and rel
+// attribute
+func (c *Crawler) addLink(ctx *storage.QueueEntry, links *pageLinks, domain, link, absolute, text, rel string) {
+	links.Add(absolute)
+	if target := c.linkTarget(ctx, link); target != "" {
+		links.AddTarget(target, cleanText(text, maxAnchorRunes), strings.Join(strings.Fields(strings.ToLower(rel)), " "))
+	}
+	if c.pages.Enabled() {
+		links.AddPage(absolute, domain)
+	}
+}
+
+// replayKnownLinks feeds the stored out-links of a fresh page through link
+// selection and handleLink as if the page had just been fetched
+// Returns false if the page is unknown to storage, so it must be fetched
+func (c *Crawler) replayKnownLinks(entry *storage.QueueEntry) bool {
+	node, err := c.storage.GetNode(entry.DomainName)
+	if err != nil || node == nil {
+		return false
+	}
+
+	var targets []string
+	structural := make(map[string][]string) // edge type -> target domains
+	afterID := 0
+	for {
+		edges, err := c.storage.ListEdges(storage.EdgeFilter{NodeID: node.NodeID, Direction: storage.EdgesOut}, afterID, 500)
+		if err != nil {
+			logrus.Warnf("Failed to load known links of %s: %v", entry.DomainName, err)
+			return false
+		}
+		for _, edge := range edges {
+			target, err := c.storage.GetNodeByID(edge.ToNodeID)
+			if err != nil {
+				logrus.Warnf("Failed to load known links of %s: %v", entry.DomainName, err)
+				return false
+			}
+			if target == nil {
+				continue
+			}
+			if edge.Type == storage.EdgeLink {
+				targets = append(targets, target.DomainName)
+			} else {
+				structural[edge.Type] = append(structural[edge.Type], target.DomainName)
+			}
+		}
+		if len(edges) < 500 {
+			break
+		}
+		afterID = edges[len(edges)-1].EdgeID
+	}
+
+	var candidates []string
+	for _, target := range targets {
+		if domain := c.linkTarget(entry, cacheKey(target)); domain != "" {
+			candidates = append(candidates, domain)
+		}
+	}
+	sourceURL := "https://" + entry.DomainName
+	selected := c.selectTargets(candidates)
+	c.preloadOrigins(entry.DomainName, selected)
+	for _, target := range selected {
+		c.handleLink(entry, sourceURL, target, storage.EdgeLink, true)
+	}
+	for edgeType, domains := range structural {
+		for _, target := range domains {
+			if domain := c.linkTarget(entry, cacheKey(target)); domain != "" {
+				c.handleLink(entry, sourceURL, domain, edgeType, !c.recordOnly[edgeType])
+			}
+		}
+	}
+	return true
+}
+
+// handleNotModified records a 304 answer to a conditional request, which
+// took duration, replaying the page's stored links
+// Returns false if storage doesn't know the page: its validators are
+// dropped and it is requested again unconditionally, on the same fetch
+func (c *Crawler) handleNotModified(r *colly.Response, duration time.Duration) bool {
+	c.failures.Record(false)
+	c.httpCache.notModified.Add(1)
+
+	domain, err := ExtractDomain(r.Request.URL.String())
+	if err != nil || domain == "" {
+		return true
+	}
+
+	depth := 0
+	if ctx := c.fetchEntry(r.Ctx, domain); ctx != nil {
+		if !c.replayKnownLinks(ctx) {
+			c.httpCache.Forget(cacheKey(ctx.DomainName))
+			r.Request.Headers.Del("If-None-Match")
+			r.Request.Headers.Del("If-Modified-Since")
+			err := r.Request.Retry()
+			if err == nil {
+				logrus.Debugf("No stored links for %s, fetching it again in full", ctx.DomainName)
+				return false
+			}
+			logrus.Debugf("Full fetch of %s not sent: %v", ctx.DomainName, err)
+		}
+		depth = ctx.Depth
+		domain = ctx.DomainName
+	}
+	c.setStatus(domain, storage.NodeCrawled)
+	c.recordResponse(domain, r, duration)
+	c.recordScheme(r.Ctx, domain)
+
+	if r.Headers != nil {
+		c.httpCache.Update(cacheKey(domain), *r.Headers)
+	}
+
+	c.logSampler.Infof(logRevalidated, "Worker revalidated %s (depth=%d, not modified)", domain, depth)
+	c.publish(events.Event{Type: events.PageFetched, Domain: domain, Depth: depth, Status: r.StatusCode})
+	return true
+}
+
+// cleanText normalizes page text for storage: decodes HTML entities left
+// over after parsing (pages often double-escape them), drops invalid UTF-8,
+// collapses whitespace and truncates to maxLen runes
+func cleanText(text string, maxLen int) string {
+	text = html.UnescapeString(text)
+	text = strings.ToValidUTF8(text, "")
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxLen {
+		text = strings.TrimSpace(string(runes[:maxLen]))
+	}
+	return text
+}
+
+// setStatus records the outcome of the latest crawl attempt of domain
+func (c *Crawler) setStatus(domain, status string) {
+	if err := c.memGraph.SetStatus(domain, status); err != nil {
+		logrus.Warnf("Failed to update status of %s: %v", domain, err)
+	}
+}
+
+// bodyBytesKey is the colly request context key holding the size of the
+// response body as received, before the HTML guard prunes it
+const bodyBytesKey = "body_bytes"
+
+// recordResponse records the answer to a fetch of domain's front page,
+// which took duration; answers to inner pages are ignored
+func (c *Crawler) recordResponse(domain string, r *colly.Response, duration time.Duration) {
+	if innerPage(r.Ctx) {
+		return
+	}
+	size, ok := r.Ctx.GetAny(bodyBytesKey).(int)
+	if !ok {
+		size = len(r.Body)
+	}
+	response := storage.Response{
+		StatusCode:     r.StatusCode,
+		ResponseTimeMs: duration.Milliseconds(),
+		ContentLength:  int64(size),
+		CrawledAt:      time.Now(),
+	}
+	if err := c.memGraph.SetResponse(domain, response); err != nil {
+		logrus.Warnf("Failed to update response of %s: %v", domain, err)
+	}
 }
 
-// EnqueueSeed enqueues the initial seed URL
+// attemptOf returns which crawl of domain the current fetch is
+func (c *Crawler) attemptOf(domain string) int {
+	node, err := c.memGraph.GetNode(domain)
+	if err != nil || node == nil {
+		return 1
+	}
+	return node.CrawlCount
+}
+
+// fetchStartKey is the colly request context key holding the fetch start time
+const fetchStartKey = "fetch_start"
+
+// observeLatency feeds a completed fetch's duration to the slow-host
+// tracker and returns it; zero if the fetch wasn't timed
+func (c *Crawler) observeLatency(domain string, r *colly.Response) time.Duration {
+	start, ok := r.Ctx.GetAny(fetchStartKey).(time.Time)
+	if !ok {
+		return 0
+	}
+	duration := time.Since(start)
+	c.latency.Observe(domain, duration)
+	return duration
+}
+
+// slowHostThreshold returns the average latency at which a host counts as
+// slow: slow_host_ms if set, otherwise 75% of the request timeout
+func slowHostThreshold(cfg *config.Config) time.Duration {
+	if cfg.SlowHostMs > 0 {
+		return time.Duration(cfg.SlowHostMs) * time.Millisecond
+	}
+	return time.Duration(cfg.RequestTimeoutMs) * time.Millisecond * 3 / 4
+}
+
+// SlowHosts returns how many hosts are currently deprioritized as slow
+func (c *Crawler) SlowHosts() int {
+	return c.latency.SlowHosts()
+}
+
+// cacheKey is the HTTP cache key for a domain's crawled page
+func cacheKey(domain string) string {
+	return "https://" + domain
+}
+
+// SetTransport replaces the HTTP transport used by the collectors
+// Used by simulation mode to serve a synthetic site graph in-process; DNS
+// prefetching is turned off, as the transport no longer uses its cache
+func (c *Crawler) SetTransport(transport http.RoundTripper) {
+	c.collectors.SetTransport(c.hooked(transport))
+	c.schemes.SetTransport(c.hooked(transport))
+	c.sitemaps.SetTransport(c.hooked(transport))
+	c.seeds.SetTransport(c.hooked(transport))
+	c.dnsPrefetcher = nil
+}
+
+// UpdateSelectors swaps in the title and description selectors compiled in
+// cfg; pages fetched afterwards use them
+func (c *Crawler) UpdateSelectors(cfg *config.Config) {
+	c.extractor.Update(cfg)
+}
+
+// SetEventBus publishes crawl events to bus for live consumers
+func (c *Crawler) SetEventBus(bus *events.Bus) {
+	c.events = bus
+}
+
+// publish sends an event if an event bus is attached
+func (c *Crawler) publish(event events.Event) {
+	if c.events != nil {
+		c.events.Publish(event)
+	}
+}
+
+// EnqueueSeed enqueues a seed URL at depth 0, as the seed of its own
+// discoveries, after test-fetching it unless skip_seed_check is set; a
+// seed found unhealthy is logged with a diagnosis and enqueued all the same
 func (c *Crawler) EnqueueSeed(seedURL string) (int, error) {
 	// Extract seed domain and create initial node
 	seedDomain, err := ExtractDomain(seedURL)
 	if err != nil || seedDomain == "" {
 		return 0, fmt.Errorf("invalid seed URL: %w", err)
 	}
+	c.checkSeed(seedURL, seedDomain)
 
-	// Upsert seed node
-	nodeID, err := c.storage.UpsertNode(seedDomain, "")
+	// Upsert seed node (in memory, so workers can find it)
+	nodeID, _, err := c.memGraph.UpsertNodeWithDepth(seedDomain, 0)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create seed node: %w", err)
 	}
+	c.memGraph.SetOrigin(seedDomain, "", seedDomain, "")
 
 	// Enqueue seed
 	c.Enqueue(storage.QueueEntry{
@@ -205,147 +785,389 @@ func (c *Crawler) EnqueueSeed(seedURL string) (int, error) {
 
 // Start begins the crawler workers
 func (c *Crawler) Start() {
-	logrus.Infof("Starting %d crawler workers", c.cfg.ConcurrentWorkers)
+	poolSize := workerPoolSize(c.cfg)
+	if c.scaler.Enabled() {
+		logrus.Infof("Starting %d crawler workers (autoscaling %d-%d, %d active)",
+			poolSize, c.cfg.MinWorkers, c.cfg.MaxWorkers, c.scaler.Limit())
+		go c.scaler.Run(c.frontier.Size, c.getInFlight, c.ctx.Done())
+	} else {
+		logrus.Infof("Starting %d crawler workers", poolSize)
+	}
+
+	if shared, dedicated := c.collectors.Size(); shared > 1 || dedicated > 0 {
+		logrus.Infof("Fetching through %d shared collectors and %d dedicated to configured roots", shared, dedicated)
+	}
+
+	if c.cfg.LogSummarySec > 0 {
+		go c.logSampler.Run(c.ctx.Done())
+	}
+
+	if c.dnsPrefetcher != nil {
+		logrus.Infof("Prefetching DNS for the next %d frontier hosts (%d lookups/s)", c.cfg.DNSPrefetchAhead, c.cfg.DNSPrefetchPerSec)
+		go c.dnsPrefetcher.Run(c.frontier.Upcoming, c.ctx.Done())
+	}
+
+	if c.reputation.Enabled() {
+		go c.reputation.Run(c.frontier.Upcoming, c.ctx.Done())
+	}
 
 	// Start workers
-	for i := 0; i < c.cfg.ConcurrentWorkers; i++ {
+	for i := 0; i < poolSize; i++ {
 		c.wg.Add(1)
 		go c.worker(i + 1)
 	}
+
+	// Pick up blocklist changes made by other processes (e.g. the CLI)
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.LoadBlocklist(); err != nil {
+					logrus.Warnf("Failed to reload blocklist: %v", err)
+				}
+			case <-c.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Start resource monitor
+	if c.throttle.Enabled() {
+		logrus.Infof("Adaptive throttling enabled (max_rss_mb=%d, max_cpu_percent=%.0f)",
+			c.cfg.MaxRSSMB, c.cfg.MaxCPUPercent)
+		go c.throttle.Run(2*time.Second, c.pushHeld, c.frontier.IsEmpty, c.ctx.Done())
+	}
 }
 
 // worker processes queue entries
 func (c *Crawler) worker(id int) {
 	defer c.wg.Done()
+	defer c.workers.Set(id, WorkerStopped, "")
 
 	logrus.Infof("Worker %d started", id)
 
 	for {
-		select {
-		case <-c.stopChan:
+		if c.ctx.Err() != nil {
 			logrus.Infof("Worker %d received stop signal", id)
 			return
-		default:
 		}
 
-		// Pop next entry (blocks if empty)
-		entry, ok := c.queue.Pop()
-		if !ok {
-			// Queue stopped and empty
-			logrus.Infof("Worker %d: queue stopped, exiting", id)
+		// Park while resource pressure has shrunk the worker pool below our id
+		c.workers.Set(id, WorkerParked, "")
+		if !c.throttle.Wait(id) {
+			logrus.Infof("Worker %d received stop signal", id)
 			return
 		}
 
-		logrus.Debugf("Worker %d: popped %s (depth=%d)", id, entry.DomainName, entry.Depth)
+		// Park while the autoscaler has shrunk the pool below our id, and
+		// keep active workers from outrunning their in-flight requests
+		if c.scaler.Enabled() {
+			if !c.scaler.Wait(id) || !c.waitForFetchSlot() {
+				logrus.Infof("Worker %d received stop signal", id)
+				return
+			}
+		}
 
-		// Check crawl count limit (from memory)
-		node, err := c.memGraph.GetNode(entry.DomainName)
-		if err != nil {
-			logrus.Warnf("Worker %d: failed to get node %s: %v", id, entry.DomainName, err)
-			continue
+		// Pop next entry whose host is ready (blocks otherwise)
+		c.workers.Set(id, WorkerIdle, "")
+		entry, ok := c.frontier.Pop(c.ctx)
+		if !ok {
+			logrus.Infof("Worker %d: frontier stopped, exiting", id)
+			return
 		}
+		c.workers.Set(id, WorkerDispatching, entry.DomainName)
 
-		if node == nil {
-			logrus.Warnf("Worker %d: node not found for %s, skipping", id, entry.DomainName)
-			continue
+		// Fetches release their entry once they settle; the ones no fetch
+		// took are released here
+		if !c.dispatch(id, entry) {
+			c.frontier.Done(entry)
 		}
+	}
+}
+
+// dispatch checks an entry popped by worker id and schedules its fetch;
+// false if it was skipped instead
+func (c *Crawler) dispatch(id int, entry storage.QueueEntry) bool {
+	logrus.Debugf("Worker %d: popped %s (depth=%d)", id, entry.DomainName, entry.Depth)
 
-		if node.CrawlCount >= c.cfg.MaxCrawlsPerNode {
-			logrus.Debugf("Worker %d: node %s at max crawls, skipping", id, entry.DomainName)
-			continue
+	// Entries of a shared queue carry no node ID, as another process
+	// may have discovered them; they get a node here
+	if entry.NodeID == 0 {
+		nodeID, _, err := c.memGraph.UpsertNodeWithDepth(entry.DomainName, entry.Depth)
+		if err != nil {
+			logrus.Warnf("Worker %d: failed to create node %s: %v", id, entry.DomainName, err)
+			return false
 		}
+		entry.NodeID = nodeID
+	}
 
-		// Construct URL and fetch
-		targetURL := "https://" + entry.DomainName
-		c.setContext(entry.DomainName, entry)
+	// Check crawl count limit (from memory)
+	node, err := c.memGraph.GetNode(entry.DomainName)
+	if err != nil {
+		logrus.Warnf("Worker %d: failed to get node %s: %v", id, entry.DomainName, err)
+		return false
+	}
 
-		// Increment crawl count (in memory)
+	if node == nil {
+		logrus.Warnf("Worker %d: node not found for %s, skipping", id, entry.DomainName)
+		return false
+	}
+
+	// A retry belongs to the crawl whose fetch failed
+	if entry.URL == "" && entry.Retries == 0 && node.CrawlCount >= c.cfg.MaxCrawlsPerNode {
+		logrus.Debugf("Worker %d: node %s at max crawls, skipping", id, entry.DomainName)
+		return false
+	}
+
+	if c.blocklist.IsBlocked(entry.DomainName) {
+		logrus.Debugf("Worker %d: node %s is blocked, skipping", id, entry.DomainName)
+		c.setStatus(entry.DomainName, storage.NodeBlocked)
+		return false
+	}
+
+	// Seeds are crawled whatever their reputation
+	if entry.Depth > 0 && !c.reputationAdmits(entry.DomainName) {
+		logrus.Debugf("Worker %d: node %s has low reputation, skipping", id, entry.DomainName)
+		return false
+	}
+
+	if entry.URL != "" {
+		c.fetchInnerPage(id, entry)
+		return true
+	}
+
+	// Domains of a language past its quota keep their crawl count, so a
+	// later run with a larger quota can still fetch them
+	if !c.languages.Admits(entry.DomainName, node.Language) {
+		logrus.Debugf("Worker %d: node %s is past the %q language quota, skipping", id, entry.DomainName, node.Language)
+		return false
+	}
+
+	// Construct URL and fetch
+	targetURL := "https://" + entry.DomainName
+
+	// Pages still fresh per their caching headers need no request; their
+	// links are replayed from the stored graph instead
+	if c.httpCache.IsFresh(cacheKey(entry.DomainName)) && c.replayKnownLinks(&entry) {
+		c.logSampler.Infof(logFresh, "Worker %d: %s is fresh, served from cache knowledge (depth=%d)", id, targetURL, entry.Depth)
+		c.httpCache.fresh.Add(1)
+		c.setStatus(entry.DomainName, storage.NodeCrawled)
 		if err := c.memGraph.IncrementCrawlCount(entry.NodeID); err != nil {
 			logrus.Warnf("Worker %d: failed to increment crawl count: %v", id, err)
 		}
+		return false
+	}
 
-		if c.metricsCallback != nil {
-			c.metricsCallback(1, 0, 0, 0, 0) // nodesCrawled++
+	// Hold back a domain fetched within min_refetch_interval_sec until the
+	// interval has passed; the node keeps its crawl count meanwhile
+	if entry.Retries == 0 {
+		if ok, wait := c.refetch.Acquire(entry.DomainName); !ok {
+			c.logSampler.Infof(logTooRecent, "Worker %d: %s fetched too recently, deferring (allowed again in %s)", id, entry.DomainName, wait.Round(time.Second))
+			c.refetch.Defer(entry, wait, c.frontier.Requeue)
+			return false
 		}
+	}
+
+	// The front page counts against the domain's page budget
+	c.pages.Admit(entry.DomainName, targetURL)
 
-		// Increment in-flight counter before async visit
-		c.incrementInFlight()
+	// Increment crawl count (in memory), once per crawl however many
+	// times its fetch is retried
+	if entry.Retries == 0 {
+		if err := c.memGraph.IncrementCrawlCount(entry.NodeID); err != nil {
+			logrus.Warnf("Worker %d: failed to increment crawl count: %v", id, err)
+		}
+		c.metrics.NodeCrawled()
+	}
 
-		// Visit URL
-		if err := c.collector.Visit(targetURL); err != nil {
-			c.decrementInFlight() // Decrement on immediate failure
-			logrus.Warnf("Worker %d: visit failed for %s: %v", id, targetURL, err)
-			c.deleteContext(entry.DomainName)
+	// Increment in-flight counter before async visit
+	c.incrementInFlight()
+
+	// Visit URL
+	fetchCtx := c.workers.StartFetch(id, entry.DomainName, targetURL)
+	c.trackFetch(fetchCtx, entry, targetURL)
+	if err := c.request(entry, targetURL, fetchCtx); err != nil {
+		logrus.Warnf("Worker %d: visit failed for %s: %v", id, targetURL, err)
+		// Re-crawls refused by Colly never reached the network
+		var visited *colly.AlreadyVisitedError
+		if errors.As(err, &visited) {
+			c.settleFetch(fetchCtx, nil)
 		} else {
-			logrus.Infof("Worker %d: scheduled visit to %s (depth=%d)", id, targetURL, entry.Depth)
+			c.settleFetch(fetchCtx, err)
+			c.errorLog.Record(entry.DomainName, targetURL, c.attemptOf(entry.DomainName), 0, err)
+			c.setStatus(entry.DomainName, failureStatus(err, 0))
 		}
+	} else {
+		c.logSampler.Infof(logScheduled, "Worker %d: scheduled visit to %s (depth=%d)", id, targetURL, entry.Depth)
 	}
+	return true
 }
 
-// handleLink processes a single extracted link
-func (c *Crawler) handleLink(sourceCtx *storage.QueueEntry, link string) {
+// linkTarget returns the target domain of an extracted link, or "" if the
+// link must not be followed
+func (c *Crawler) linkTarget(sourceCtx *storage.QueueEntry, link string) string {
 	targetDomain, err := ExtractDomain(link)
 	if err != nil || targetDomain == "" {
-		return
+		return ""
+	}
+
+	// Rewrite to the canonical domain before anything else sees it, then
+	// to the platform or tenant node for hosts on a multi-tenant platform
+	if targetDomain = c.canonical.Canonicalize(targetDomain); targetDomain == "" {
+		return ""
 	}
+	targetDomain = c.platforms.Aggregate(targetDomain)
 
 	// Skip same-domain links
 	if targetDomain == sourceCtx.DomainName {
-		return
+		return ""
 	}
 
-	// Skip excluded domains
-	if IsExcluded(targetDomain) {
-		return
+	// Skip filtered and manually blocked domains
+	if !c.tlds.Allows(targetDomain) || !c.filter.Allows(targetDomain) || c.blocklist.IsBlocked(targetDomain) {
+		return ""
 	}
 
 	// Check subdomain limit
-	if !c.limiter.CanAdd(targetDomain) {
+	if !c.frontier.Admits(targetDomain) {
+		return ""
+	}
+
+	return targetDomain
+}
+
+// handleLink records an edge of the given type, found on the page at
+// sourceURL, to a selected target domain, and enqueues the target if
+// enqueue is set
+func (c *Crawler) handleLink(sourceCtx *storage.QueueEntry, sourceURL, targetDomain, edgeType string, enqueue bool) {
+	// Re-check the subdomain limit; earlier links of the page may have used it up
+	if !c.frontier.Admits(targetDomain) {
+		return
+	}
+
+	// Low-reputation targets are dropped, or recorded but never crawled
+	verdict, skip := c.checkReputation(targetDomain)
+	if skip {
 		return
 	}
+	if verdict == storage.ReputationLow {
+		enqueue = false
+	}
 
 	// Calculate depth for target node
 	targetDepth := sourceCtx.Depth + 1
 
 	// Upsert target node with depth (in memory)
-	targetNodeID, err := c.memGraph.UpsertNodeWithDepth(targetDomain, "", targetDepth)
+	targetNodeID, created, err := c.memGraph.UpsertNodeWithDepth(targetDomain, targetDepth)
 	if err != nil {
 		logrus.Warnf("Failed to upsert target node %s: %v", targetDomain, err)
 		return
 	}
 
+	if verdict != "" {
+		c.memGraph.SetReputation(targetDomain, verdict)
+	}
+
+	c.plateau.Observe(targetDomain)
+	if seed := c.seedOf(sourceCtx.DomainName); seed != "" && c.seedOf(targetDomain) == "" {
+		c.memGraph.SetOrigin(targetDomain, sourceCtx.DomainName, seed, sourceURL)
+	}
+
 	// Increment nodes discovered (new node found via link)
-	if c.metricsCallback != nil {
-		c.metricsCallback(0, 1, 0, 0, 0) // nodesDiscovered++
+	c.metrics.NodeDiscovered()
+	if created {
+		c.publish(events.Event{Type: events.NodeDiscovered, Domain: targetDomain, Depth: targetDepth})
 	}
 
 	// Record edge (in memory)
-	if err := c.memGraph.UpsertEdge(sourceCtx.NodeID, targetNodeID); err != nil {
+	if err := c.memGraph.UpsertEdge(sourceCtx.NodeID, targetNodeID, edgeType); err != nil {
 		logrus.Warnf("Failed to upsert edge %s -> %s: %v", sourceCtx.DomainName, targetDomain, err)
 		return
 	}
 
 	// Increment edges metric
-	if c.metricsCallback != nil {
-		c.metricsCallback(0, 0, 1, 0, 0) // edgesRecorded++
-	}
+	c.metrics.EdgeRecorded()
+	c.publish(events.Event{Type: events.EdgeRecorded, Domain: sourceCtx.DomainName, Target: targetDomain, Edge: edgeType, Depth: targetDepth})
 
-	logrus.Infof("Edge: %s -> %s (depth %d->%d)", sourceCtx.DomainName, targetDomain, sourceCtx.Depth, targetDepth)
+	c.logSampler.Infof(logEdge, "Edge: %s -> %s (%s, depth %d->%d)", sourceCtx.DomainName, targetDomain, edgeType, sourceCtx.Depth, targetDepth)
 
 	// Check depth limit
-	if targetDepth > c.cfg.MaxDepth {
+	if !enqueue || targetDepth > c.cfg.MaxDepth {
 		return
 	}
 
-	// Add to subdomain limiter
-	c.limiter.Add(targetDomain)
+	// A closed frontier doesn't grow; the node is still recorded with
+	// crawl_count 0, so it remains resumable
+	if c.frontierClosed.Load() {
+		return
+	}
+
+	// Past its language's quota too; until fetched, a target is assumed to
+	// share the language of the page linking to it
+	language := c.languageOf(targetDomain)
+	if language == "" {
+		language = c.languageOf(sourceCtx.DomainName)
+	}
+	if !c.languages.Admits(targetDomain, language) {
+		return
+	}
+
+	// Past its depth's fan-out limit the node is likewise only recorded
+	if !c.fanOut.Reserve(targetDepth) {
+		return
+	}
 
 	// Enqueue target
-	c.queue.Push(storage.QueueEntry{
+	entry := storage.QueueEntry{
 		NodeID:     targetNodeID,
 		DomainName: targetDomain,
 		Depth:      targetDepth,
-	})
+	}
+	c.prioritize(&entry)
+
+	// Under resource pressure the frontier doesn't grow; the throttle holds
+	// the entry until pressure eases
+	if c.throttle.Hold(entry) {
+		return
+	}
+	if !c.frontier.Push(entry) {
+		// Already queued or visited, so not a new domain
+		c.fanOut.Release(targetDepth)
+	}
+}
+
+// pushHeld enqueues an entry the throttle held back under pressure
+func (c *Crawler) pushHeld(entry storage.QueueEntry) {
+	if !c.frontierClosed.Load() && c.frontier.Push(entry) {
+		return
+	}
+	if entry.URL == "" {
+		c.fanOut.Release(entry.Depth)
+	}
+}
+
+// DiscoveryPlateaued reports whether new root domains have dried up, along
+// with how many were found in the current window
+func (c *Crawler) DiscoveryPlateaued() (bool, int) {
+	return c.plateau.Plateaued()
+}
+
+// FailureThresholdReached is closed once too many recent fetches failed
+func (c *Crawler) FailureThresholdReached() <-chan struct{} {
+	return c.failures.Tripped()
+}
+
+// FailureStats returns the failed and total fetch counts in the failure window
+func (c *Crawler) FailureStats() (failed, total int) {
+	return c.failures.Stats()
+}
+
+// CloseFrontier stops newly discovered nodes from being enqueued
+// Links are still recorded as nodes and edges; only queue growth stops
+func (c *Crawler) CloseFrontier() {
+	c.frontierClosed.Store(true)
 }
 
 // Stop gracefully stops the crawler (safe to call multiple times)
@@ -353,12 +1175,16 @@ func (c *Crawler) Stop() {
 	c.stopOnce.Do(func() {
 		logrus.Info("Stopping crawler...")
 
-		// Stop queue and signal workers
-		logrus.Debug("Stopping queue...")
-		c.queue.Stop()
-
+		// Cancelling the crawl context is what stops the workers: they
+		// check it each round and Pop returns once it is done, so the
+		// order of the stops below doesn't matter. Those release workers
+		// parked by the throttle or autoscaler, and make the frontier
+		// refuse further pushes
 		logrus.Debug("Signaling workers to stop...")
-		close(c.stopChan)
+		c.cancel()
+		c.frontier.Stop()
+		c.throttle.Stop()
+		c.scaler.Stop()
 
 		// Wait for workers to finish with timeout
 		logrus.Debug("Waiting for workers to finish...")
@@ -375,36 +1201,61 @@ func (c *Crawler) Stop() {
 			logrus.Warn("Workers timeout (5s) - some workers may still be running")
 		}
 
-		// Wait for collector to finish in-flight requests (with aggressive timeout)
+		// Wait for collector to finish in-flight requests (with aggressive
+		// timeout). The wait holds even with nothing in flight: a fetch
+		// abandoned at its deadline has released its slot, but callbacks
+		// that were already past their abandoned check may still be
+		// recording its edges, and the flush that follows Stop must see them
 		inFlight := c.getInFlight()
 		if inFlight > 0 {
 			logrus.Infof("Waiting for %d in-flight requests (max 10s)...", inFlight)
-			collectorDone := make(chan struct{})
-			go func() {
-				c.collector.Wait()
-				close(collectorDone)
-			}()
+		}
+		collectorDone := make(chan struct{})
+		go func() {
+			c.collectors.Wait()
+			close(collectorDone)
+		}()
 
-			select {
-			case <-collectorDone:
+		select {
+		case <-collectorDone:
+			if inFlight > 0 {
 				logrus.Info("All in-flight requests completed")
-			case <-time.After(10 * time.Second):
-				remaining := c.getInFlight()
-				logrus.Warnf("Timeout waiting for requests - abandoning %d in-flight requests", remaining)
 			}
+		case <-time.After(10 * time.Second):
+			remaining := c.getInFlight()
+			logrus.Warnf("Timeout waiting for requests - abandoning %d in-flight requests", remaining)
 		}
 
+		c.schemes.Wait(time.Duration(c.cfg.RequestTimeoutMs) * time.Millisecond)
+		c.sitemaps.Wait(time.Duration(c.cfg.RequestTimeoutMs) * time.Millisecond)
+
 		logrus.Info("Crawler stopped")
 	})
 }
 
-// Enqueue adds a node to the crawl queue
+// Enqueue adds a node, or in url crawl mode an inner page, to the crawl
+// frontier
 func (c *Crawler) Enqueue(entry storage.QueueEntry) bool {
-	// Add to subdomain limiter
-	c.limiter.Add(entry.DomainName)
+	if entry.URL != "" && !c.pages.Admit(entry.DomainName, entry.URL) {
+		return false
+	}
+	c.prioritize(&entry)
+	return c.frontier.Push(entry)
+}
+
+// QueueSize returns the number of entries in the frontier
+func (c *Crawler) QueueSize() int {
+	return c.frontier.Size()
+}
 
-	// Push to queue
-	return c.queue.Push(entry)
+// InFlight returns the number of fetches in flight
+func (c *Crawler) InFlight() int {
+	return c.getInFlight()
+}
+
+// VisitedCount returns the size of the frontier's deduplication set
+func (c *Crawler) VisitedCount() int {
+	return c.frontier.VisitedCount()
 }
 
 // WaitUntilEmpty blocks until the queue is empty AND no requests are in-flight
@@ -415,25 +1266,22 @@ func (c *Crawler) WaitUntilEmpty() {
 	for {
 		select {
 		case <-ticker.C:
-			size := c.queue.Size()
+			size := c.frontier.Size()
 			inFlight := c.getInFlight()
 			nodeCount, edgeCount := c.memGraph.GetStats()
-			logrus.Infof("Queue: %d items, %d in-flight | Memory: %d nodes, %d edges",
-				size, inFlight, nodeCount, edgeCount)
+			logrus.Infof("Queue: %d items, %d in-flight, %d awaiting retry | Memory: %d nodes, %d edges",
+				size, inFlight, c.retries.Pending(), nodeCount, edgeCount)
 		default:
 		}
 
 		time.Sleep(1 * time.Second)
 
-		queueEmpty := c.queue.IsEmpty()
-		inFlight := c.getInFlight()
-
-		if queueEmpty && inFlight == 0 {
+		if c.frontier.Drained(c.busy()) {
 			// Double-check after a short delay
 			logrus.Infof("Queue and in-flight both zero, double-checking...")
 			time.Sleep(2 * time.Second)
 
-			if c.queue.IsEmpty() && c.getInFlight() == 0 {
+			if c.frontier.Drained(c.busy()) {
 				logrus.Info("Queue confirmed empty with no in-flight requests, initiating natural shutdown")
 				c.Stop()
 				return
@@ -442,24 +1290,84 @@ func (c *Crawler) WaitUntilEmpty() {
 	}
 }
 
+// busy reports whether fetches are in flight, retries pending, sitemaps
+// being read, or entries held back by the throttle; retries waiting out
+// their backoff, the finds of sitemap reads and held entries are in
+// neither the queue nor in flight
+func (c *Crawler) busy() bool {
+	return c.getInFlight() > 0 || c.retries.Pending() > 0 || c.sitemaps.Pending() > 0 || c.throttle.Holding() > 0
+}
+
 // FlushToStorage flushes in-memory graph and queue state to SQLite
+// Flushes run one at a time, so the shutdown flush waits for a checkpoint
+// still in progress and its queue save is the one that stays
 func (c *Crawler) FlushToStorage() error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
 	// Flush graph data
 	if err := c.memGraph.Flush(c.storage); err != nil {
 		return err
 	}
 
+	// Flush cache knowledge gathered since the last checkpoint
+	if err := c.storage.SaveHTTPCache(c.httpCache.TakeDirty()); err != nil {
+		return err
+	}
+
+	// Fetch times for the re-fetch guard of later runs
+	if err := c.storage.SaveFetchTimes(c.refetch.TakeDirty()); err != nil {
+		return err
+	}
+
+	// Fetch errors recorded since the last checkpoint
+	if err := c.storage.SaveFetchErrors(c.errorLog.Take()); err != nil {
+		return err
+	}
+
+	// Scheme support observed since the last checkpoint
+	if err := c.storage.SaveSchemeSupport(c.schemes.Take()); err != nil {
+		return err
+	}
+
+	// Keep counted subdomains so a resume can't exceed max_subdomains_per_root
+	if err := c.storage.SaveSubdomains(c.frontier.Limiter().Snapshot()); err != nil {
+		return err
+	}
+
 	// Save queue state
 	return c.SaveQueueState()
 }
 
 // SaveQueueState persists current queue entries to database
 func (c *Crawler) SaveQueueState() error {
-	// Get all pending queue entries
-	entries := c.queue.GetAllEntries()
+	// Get all pending queue entries, with the retries still waiting out
+	// their backoff, the entries held back by min_refetch_interval_sec or
+	// the resource throttle, and the entries of fetches still open, which a
+	// crash would otherwise lose; a resume deduplicates one caught twice
+	entries := append(c.frontier.GetAllEntries(), c.retries.Waiting()...)
+	entries = append(entries, c.refetch.Deferred()...)
+	entries = append(entries, c.throttle.Held()...)
+	entries = append(entries, c.open.Entries()...)
 
 	// Save to database via memory graph
-	return c.memGraph.SaveQueueState(c.storage, entries)
+	return c.memGraph.SaveQueueState(c.storage, entries, c.cfg.QueueCodec)
+}
+
+// ConnectQueue checks that the server of a shared frontier answers; the
+// in-memory frontier needs none
+func (c *Crawler) ConnectQueue() error {
+	queue, ok := c.frontier.(*RedisQueue)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, time.Duration(c.cfg.RequestTimeoutMs)*time.Millisecond)
+	defer cancel()
+	if err := queue.Ping(ctx); err != nil {
+		return err
+	}
+	logrus.Infof("Sharing the frontier through Redis at %s (key prefix %q)", c.cfg.QueueRedis.Addr, c.cfg.QueueRedis.KeyPrefix)
+	return nil
 }
 
 // LoadFromStorage loads resumable nodes from SQLite into memory
@@ -467,11 +1375,124 @@ func (c *Crawler) LoadFromStorage() error {
 	return c.memGraph.LoadFromStorage(c.storage, c.cfg.MaxCrawlsPerNode)
 }
 
+// LoadBlocklist refreshes the blocklist from the database
+func (c *Crawler) LoadBlocklist() error {
+	blocked, err := c.storage.ListBlockedDomains()
+	if err != nil {
+		return err
+	}
+
+	domains := make([]string, len(blocked))
+	for i, b := range blocked {
+		domains[i] = b.Domain
+	}
+	c.blocklist.Replace(domains)
+	return nil
+}
+
+// LoadHTTPCache loads cache validators and freshness from previous runs
+func (c *Crawler) LoadHTTPCache() error {
+	entries, err := c.storage.LoadHTTPCache()
+	if err != nil {
+		return err
+	}
+	c.httpCache.Load(entries)
+	return nil
+}
+
+// LoadFetchTimes loads the fetches recent enough to hold back a re-fetch,
+// from every session of the database
+func (c *Crawler) LoadFetchTimes() error {
+	if c.refetch.Interval() == 0 {
+		return nil
+	}
+	times, err := c.storage.RecentFetches(time.Now().Add(-c.refetch.Interval()))
+	if err != nil {
+		return err
+	}
+	c.refetch.Load(times)
+	return nil
+}
+
+// LoadSubdomainLimits restores the subdomains counted per root by previous runs
+func (c *Crawler) LoadSubdomainLimits() error {
+	subdomains, err := c.storage.LoadSubdomains()
+	if err != nil {
+		return err
+	}
+	c.frontier.Limiter().Restore(subdomains)
+	return nil
+}
+
+// SubdomainStats returns the root domains tracked by the subdomain limiter,
+// the subdomains counted, and the roots at max_subdomains_per_root
+func (c *Crawler) SubdomainStats() (roots, subdomains, saturated int) {
+	return c.frontier.Limiter().Stats()
+}
+
+// DNSStats returns DNS cache hits, resolver lookups, and prefetches; all
+// zero when DNS prefetching is disabled
+func (c *Crawler) DNSStats() (hits, lookups, prefetched int) {
+	if c.dnsCache == nil {
+		return 0, 0, 0
+	}
+	return c.dnsCache.Stats()
+}
+
+// SitemapStats returns the sitemap files read and the page URLs they listed
+func (c *Crawler) SitemapStats() (files, urls int) {
+	return c.sitemaps.Stats()
+}
+
+// CacheStats returns fetches skipped as fresh and 304 revalidations
+func (c *Crawler) CacheStats() (fresh, notModified int) {
+	return c.httpCache.Stats()
+}
+
+// ErrorCounts returns how many failed fetches were recorded per error
+// category this run
+func (c *Crawler) ErrorCounts() map[string]int {
+	return c.errorLog.Counts()
+}
+
+// TLDSkips returns how many links were skipped per TLD by the TLD filter
+func (c *Crawler) TLDSkips() map[string]int {
+	return c.tlds.Skips()
+}
+
+// Blocklist returns the live blocklist so other components can update it
+func (c *Crawler) Blocklist() *Blocklist {
+	return c.blocklist
+}
+
 // LoadQueueState loads persisted queue entries from database
 func (c *Crawler) LoadQueueState() ([]storage.QueueEntry, error) {
 	return c.memGraph.LoadQueueState(c.storage)
 }
 
+// waitForFetchSlot blocks while in-flight requests fill the active worker
+// limit; returns false if the crawler stopped meanwhile
+func (c *Crawler) waitForFetchSlot() bool {
+	for c.getInFlight() >= c.scaler.Limit() {
+		select {
+		case <-c.ctx.Done():
+			return false
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	return true
+}
+
+// Workers reports what each worker is doing and the fetches it has in flight
+func (c *Crawler) Workers() []WorkerStatus {
+	return c.workers.Snapshot()
+}
+
+// ActiveWorkers returns the number of workers currently allowed to fetch
+func (c *Crawler) ActiveWorkers() int {
+	return c.scaler.Limit()
+}
+
 // Helper methods for in-flight request tracking
 func (c *Crawler) incrementInFlight() {
 	c.inFlightMu.Lock()
@@ -491,47 +1512,71 @@ func (c *Crawler) getInFlight() int {
 	return c.inFlight
 }
 
-// Context management helpers (use domain as key, not full URL)
-func (c *Crawler) setContext(domain string, entry storage.QueueEntry) {
-	c.contextMu.Lock()
-	defer c.contextMu.Unlock()
-	c.contextMap[domain] = entry
+// fetchEntry returns the queue entry whose fetch in ctx served a page of
+// domain, or nil if the request isn't a tracked fetch or a redirect took it
+// to another root domain
+func (c *Crawler) fetchEntry(ctx *colly.Context, domain string) *storage.QueueEntry {
+	task := fetchTaskOf(ctx)
+	if task == nil || ExtractRootDomain(task.entry.DomainName) != ExtractRootDomain(domain) {
+		return nil
+	}
+	entry := task.entry
+	return &entry
 }
 
-func (c *Crawler) getContext(domain string) *storage.QueueEntry {
-	c.contextMu.RLock()
-	defer c.contextMu.RUnlock()
-	if entry, ok := c.contextMap[domain]; ok {
-		return &entry
+// seedOf returns the seed that first led to domain, or "" if unattributed
+// Origins of nodes from earlier runs are read from storage once and cached
+func (c *Crawler) seedOf(domain string) string {
+	if seed, ok := c.memGraph.Seed(domain); ok {
+		return seed
 	}
-	return nil
+	c.loadOrigins([]string{domain})
+	seed, _ := c.memGraph.Seed(domain)
+	return seed
 }
 
-func (c *Crawler) getContextWithFallback(domain string) *storage.QueueEntry {
-	// Try exact match first
-	ctx := c.getContext(domain)
-	if ctx != nil {
-		return ctx
+// preloadOrigins caches the stored origins of a page's link targets with
+// one storage query, instead of one per target in handleLink
+// Targets' origins are only needed when the source page has a seed
+func (c *Crawler) preloadOrigins(source string, targets []string) {
+	if len(targets) < 2 || c.seedOf(source) == "" {
+		return
 	}
+	c.loadOrigins(targets)
+}
 
-	// Try root domain match (handles www.example.com vs example.com redirects)
-	c.contextMu.RLock()
-	defer c.contextMu.RUnlock()
-
-	rootDomain := ExtractRootDomain(domain)
-	for key, entry := range c.contextMap {
-		if ExtractRootDomain(key) == rootDomain {
-			// Only log this at trace level to reduce spam
-			entryCopy := entry
-			return &entryCopy
+// loadOrigins reads the seeds of domains whose origin isn't cached yet from
+// storage and caches them; domains storage doesn't attribute are cached as
+// unattributed
+func (c *Crawler) loadOrigins(domains []string) {
+	var unknown []string
+	for _, domain := range domains {
+		if _, ok := c.memGraph.Seed(domain); !ok {
+			unknown = append(unknown, domain)
 		}
 	}
+	if len(unknown) == 0 {
+		return
+	}
 
-	return nil
-}
+	nodes, err := c.storage.GetNodes(unknown)
+	if err != nil {
+		logrus.Debugf("Failed to load origins: %v", err)
+		return
+	}
 
-func (c *Crawler) deleteContext(domain string) {
-	c.contextMu.Lock()
-	defer c.contextMu.Unlock()
-	delete(c.contextMap, domain)
+	seeds := make(map[int]string) // seed node ID -> domain
+	for _, domain := range unknown {
+		seed := ""
+		if node := nodes[domain]; node != nil && node.SeedNodeID != 0 {
+			var ok bool
+			if seed, ok = seeds[node.SeedNodeID]; !ok {
+				if seedNode, err := c.storage.GetNodeByID(node.SeedNodeID); err == nil && seedNode != nil {
+					seed = seedNode.DomainName
+				}
+				seeds[node.SeedNodeID] = seed
+			}
+		}
+		c.memGraph.SetOrigin(domain, "", seed, "")
+	}
 }
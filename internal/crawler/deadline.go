@@ -0,0 +1,193 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/events"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// fetchTaskKey is the colly request context key holding a fetch's task
+const fetchTaskKey = "fetch_task"
+
+// errFetchDeadline fails fetches abandoned at fetch_deadline_ms; it is a
+// timeout, so abandonFetch schedules a retry like any other timed out fetch
+var errFetchDeadline = fmt.Errorf("fetch deadline exceeded: %w", context.DeadlineExceeded)
+
+// Fetch task states
+const (
+	fetchOpen int32 = iota
+	fetchDone
+	fetchAbandoned
+)
+
+// fetchTask is one scheduled fetch. It settles exactly once: when its last
+// callback runs, or when the deadline abandons it first
+type fetchTask struct {
+	entry storage.QueueEntry
+	url   string
+	state atomic.Int32
+
+	mu    sync.Mutex
+	req   *colly.Request // sent request, replayed if the fetch is retried
+	timer *time.Timer    // started once the request holds a connection slot
+}
+
+// fetchDeadline returns how long a fetch may take from leaving colly's
+// queue to its last callback: fetch_deadline_ms if set, otherwise three
+// request timeouts
+func fetchDeadline(cfg *config.Config) time.Duration {
+	if cfg.FetchDeadlineMs > 0 {
+		return time.Duration(cfg.FetchDeadlineMs) * time.Millisecond
+	}
+	return 3 * time.Duration(cfg.RequestTimeoutMs) * time.Millisecond
+}
+
+// openFetches holds the tasks of fetches that haven't settled, so a queue
+// checkpoint can save the entries they took off the frontier
+type openFetches struct {
+	mu    sync.Mutex
+	tasks map[*fetchTask]struct{}
+}
+
+// newOpenFetches creates an empty registry
+func newOpenFetches() *openFetches {
+	return &openFetches{tasks: make(map[*fetchTask]struct{})}
+}
+
+func (o *openFetches) add(task *fetchTask) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.tasks[task] = struct{}{}
+}
+
+func (o *openFetches) remove(task *fetchTask) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.tasks, task)
+}
+
+// Entries returns the queue entries of the open fetches
+func (o *openFetches) Entries() []storage.QueueEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries := make([]storage.QueueEntry, 0, len(o.tasks))
+	for task := range o.tasks {
+		entries = append(entries, task.entry)
+	}
+	return entries
+}
+
+// trackFetch attaches a task for the fetch of entry at url to its request
+// context, where the request's callbacks find the entry, and registers it
+// as open until it settles
+func (c *Crawler) trackFetch(ctx *colly.Context, entry storage.QueueEntry, url string) {
+	task := &fetchTask{entry: entry, url: url}
+	c.open.add(task)
+	ctx.Put(fetchTaskKey, task)
+}
+
+// fetchTaskOf returns the task in ctx, or nil for untracked requests
+func fetchTaskOf(ctx *colly.Context) *fetchTask {
+	if ctx == nil {
+		return nil
+	}
+	task, _ := ctx.GetAny(fetchTaskKey).(*fetchTask)
+	return task
+}
+
+// sendFetch records the request of the fetch in its context, so an
+// abandoned fetch can be retried
+func (c *Crawler) sendFetch(req *colly.Request) {
+	task := fetchTaskOf(req.Ctx)
+	if task == nil {
+		return
+	}
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	task.req = req
+}
+
+// armDeadline starts the deadline of the fetch in ctx. Colly calls it once
+// the request holds a connection slot, so time spent queued behind the
+// parallelism limit doesn't count
+func (c *Crawler) armDeadline(ctx *colly.Context) {
+	task := fetchTaskOf(ctx)
+	if task == nil {
+		return
+	}
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	if task.timer == nil && task.state.Load() == fetchOpen {
+		task.timer = time.AfterFunc(c.deadline, func() { c.abandonFetch(ctx, task) })
+	}
+}
+
+// abandoned reports whether the fetch in ctx was abandoned at its deadline;
+// callbacks of an abandoned fetch that still arrive are ignored
+func (c *Crawler) abandoned(ctx *colly.Context) bool {
+	task := fetchTaskOf(ctx)
+	return task != nil && task.state.Load() == fetchAbandoned
+}
+
+// settleFetch ends the fetch in ctx, failed with err unless it is nil, and
+// releases its in-flight slot. Later calls, and calls for an abandoned
+// fetch, do nothing
+func (c *Crawler) settleFetch(ctx *colly.Context, err error) {
+	task := fetchTaskOf(ctx)
+	if task != nil {
+		if !task.state.CompareAndSwap(fetchOpen, fetchDone) {
+			return
+		}
+		task.mu.Lock()
+		if task.timer != nil {
+			task.timer.Stop()
+		}
+		task.mu.Unlock()
+		c.open.remove(task)
+		c.frontier.Done(task.entry)
+	}
+	c.decrementInFlight()
+	c.workers.FinishFetch(ctx, err)
+}
+
+// abandonFetch gives up on a fetch that outlived its deadline: it counts as
+// a failed fetch, scheduled for a retry, and frees its in-flight slot, while
+// whatever still runs for it (a hung connection, a pathological page)
+// finishes unobserved
+func (c *Crawler) abandonFetch(ctx *colly.Context, task *fetchTask) {
+	if !task.state.CompareAndSwap(fetchOpen, fetchAbandoned) {
+		return
+	}
+	c.abandonCount.Add(1)
+	c.decrementInFlight()
+	c.workers.FinishFetch(ctx, errFetchDeadline)
+
+	logrus.Warnf("Abandoned fetch of %s after %s", task.url, c.deadline)
+	domain := task.entry.DomainName
+	c.failures.Record(true)
+	c.errorLog.Record(domain, task.url, c.attemptOf(domain), 0, errFetchDeadline)
+	task.mu.Lock()
+	req := task.req
+	task.mu.Unlock()
+	c.retries.Schedule(task.entry, req, errFetchDeadline, 0, c.frontier.Requeue)
+	c.open.remove(task)
+	c.frontier.Done(task.entry)
+	if !innerPage(ctx) {
+		c.setStatus(domain, storage.NodeFailedTransient)
+	}
+	c.metrics.PageFailed()
+	c.publish(events.Event{Type: events.FetchFailed, Domain: domain, Depth: task.entry.Depth, Error: errFetchDeadline.Error()})
+}
+
+// AbandonedFetches returns how many fetches were abandoned at their deadline
+func (c *Crawler) AbandonedFetches() int {
+	return int(c.abandonCount.Load())
+}
@@ -0,0 +1,237 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// dnsLookupTimeout bounds a single lookup, prefetched or not
+	dnsLookupTimeout = 5 * time.Second
+
+	// dnsNegativeTTL keeps failed lookups from being retried on every
+	// prefetch round; capped by the cache ttl
+	dnsNegativeTTL = 30 * time.Second
+)
+
+// DNSCache resolves host names through a TTL cache shared by the fetch
+// dialer and the prefetcher, so prefetched answers are used by the workers
+// Go's resolver reports no TTLs, so answers live for the configured ttl and
+// failures for dnsNegativeTTL
+type DNSCache struct {
+	resolver *net.Resolver
+	dialer   *net.Dialer
+	ttl      time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]dnsEntry
+	inflight  map[string]*dnsCall
+	lastPrune time.Time
+
+	hits       atomic.Int64
+	lookups    atomic.Int64
+	prefetched atomic.Int64
+}
+
+// dnsEntry is a cached answer or failure
+type dnsEntry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// dnsCall is a lookup in progress; concurrent askers wait on done
+type dnsCall struct {
+	done  chan struct{}
+	addrs []string
+	err   error
+}
+
+// NewDNSCache creates a cache keeping answers for ttl
+func NewDNSCache(ttl time.Duration) *DNSCache {
+	return &DNSCache{
+		resolver: net.DefaultResolver,
+		dialer:   &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		ttl:      ttl,
+		entries:  make(map[string]dnsEntry),
+		inflight: make(map[string]*dnsCall),
+	}
+}
+
+// Cached reports whether host has a live answer, including a recent failure,
+// or a lookup in progress
+func (d *DNSCache) Cached(host string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.inflight[host]; ok {
+		return true
+	}
+	entry, ok := d.entries[host]
+	return ok && time.Now().Before(entry.expires)
+}
+
+// Lookup returns the addresses of host, from the cache when possible
+// Concurrent lookups of the same host share one query
+func (d *DNSCache) Lookup(ctx context.Context, host string) ([]string, error) {
+	d.mu.Lock()
+	if entry, ok := d.entries[host]; ok && time.Now().Before(entry.expires) {
+		d.mu.Unlock()
+		d.hits.Add(1)
+		return entry.addrs, entry.err
+	}
+	if call, ok := d.inflight[host]; ok {
+		d.mu.Unlock()
+		d.hits.Add(1)
+		select {
+		case <-call.done:
+			return call.addrs, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &dnsCall{done: make(chan struct{})}
+	d.inflight[host] = call
+	d.mu.Unlock()
+
+	// Detached from ctx so a cancelled fetch doesn't fail the shared lookup
+	lookupCtx, cancel := context.WithTimeout(context.Background(), dnsLookupTimeout)
+	call.addrs, call.err = d.resolver.LookupHost(lookupCtx, host)
+	cancel()
+	d.lookups.Add(1)
+
+	d.mu.Lock()
+	delete(d.inflight, host)
+	ttl := d.ttl
+	if call.err != nil {
+		ttl = min(ttl, dnsNegativeTTL)
+	}
+	now := time.Now()
+	d.entries[host] = dnsEntry{addrs: call.addrs, err: call.err, expires: now.Add(ttl)}
+	// Drop expired answers now and then; most hosts are fetched only once
+	if now.Sub(d.lastPrune) > d.ttl {
+		for name, entry := range d.entries {
+			if now.After(entry.expires) {
+				delete(d.entries, name)
+			}
+		}
+		d.lastPrune = now
+	}
+	d.mu.Unlock()
+	close(call.done)
+
+	return call.addrs, call.err
+}
+
+// Prefetch resolves host ahead of its fetch unless it is already cached
+func (d *DNSCache) Prefetch(host string) {
+	if d.Cached(host) {
+		return
+	}
+	d.prefetched.Add(1)
+	if _, err := d.Lookup(context.Background(), host); err != nil {
+		logrus.Debugf("DNS prefetch of %s failed: %v", host, err)
+	}
+}
+
+// DialContext dials addr, resolving its host through the cache and trying
+// each address in turn
+func (d *DNSCache) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+
+	addrs, err := d.Lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	for _, ip := range addrs {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no addresses for %s", host)
+	}
+	return nil, firstErr
+}
+
+// Stats returns cache hits, lookups sent to the resolver, and prefetches
+func (d *DNSCache) Stats() (hits, lookups, prefetched int) {
+	return int(d.hits.Load()), int(d.lookups.Load()), int(d.prefetched.Load())
+}
+
+// DNSPrefetcher resolves the hosts of upcoming frontier entries at a bounded
+// rate, so workers rarely wait on DNS
+type DNSPrefetcher struct {
+	cache *DNSCache
+	ahead int
+	rate  int
+}
+
+// NewDNSPrefetcher creates a prefetcher from config; it is disabled unless
+// dns_prefetch_ahead is set
+func NewDNSPrefetcher(cfg *config.Config, cache *DNSCache) *DNSPrefetcher {
+	return &DNSPrefetcher{
+		cache: cache,
+		ahead: cfg.DNSPrefetchAhead,
+		rate:  cfg.DNSPrefetchPerSec,
+	}
+}
+
+// Enabled reports whether prefetching is configured
+func (p *DNSPrefetcher) Enabled() bool {
+	return p.ahead > 0 && p.rate > 0
+}
+
+// Run starts at most rate lookups per second for uncached hosts among the
+// next ahead entries reported by upcoming, until stop is closed
+func (p *DNSPrefetcher) Run(upcoming func(int) []string, stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Second / time.Duration(p.rate))
+	defer ticker.Stop()
+
+	var pending []string
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		// Refill once the previous batch is spent, skipping cached hosts
+		if len(pending) == 0 {
+			for _, host := range upcoming(p.ahead) {
+				if !p.cache.Cached(host) {
+					pending = append(pending, host)
+				}
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		host := pending[0]
+		pending = pending[1:]
+		go p.cache.Prefetch(host)
+	}
+}
@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/gocolly/colly/v2"
+)
+
+// builtinEdgeTypes are the edge types extracted by built-in callbacks,
+// whose targets are always enqueued
+var builtinEdgeTypes = []string{
+	storage.EdgeLink, storage.EdgeRedirect, storage.EdgeCanonical,
+	storage.EdgeHreflang, storage.EdgeFeed, storage.EdgeSitemap,
+}
+
+// applyEdgeRules records the edges each extraction rule finds on a page,
+// in rule order
+func (c *Crawler) applyEdgeRules(e *colly.HTMLElement, rules []config.EdgeRule) {
+	pageURL := e.Request.URL.String()
+	for _, rule := range rules {
+		e.DOM.FindMatcher(rule.Match.CSS).Each(func(_ int, s *goquery.Selection) {
+			ref := strings.TrimSpace(s.AttrOr(rule.Match.Attr, ""))
+			if ref == "" {
+				return
+			}
+			c.recordPageEdge(e.Request.Ctx, pageURL, e.Request.AbsoluteURL(ref), rule.Edge, rule.Offsite, !rule.RecordOnly)
+		})
+	}
+}
+
+// recordOnlyTypes returns the edge types produced only by record-only
+// rules; replayed edges of these types leave their targets unqueued too
+func recordOnlyTypes(rules []config.EdgeRule) map[string]bool {
+	types := make(map[string]bool)
+	for _, rule := range rules {
+		recordOnly, seen := types[rule.Edge]
+		types[rule.Edge] = (recordOnly || !seen) && rule.RecordOnly
+	}
+	for _, edgeType := range builtinEdgeTypes {
+		delete(types, edgeType)
+	}
+	return types
+}
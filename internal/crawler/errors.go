@@ -0,0 +1,115 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// ErrorLog buffers failed fetches until the next flush writes them to the
+// errors table
+type ErrorLog struct {
+	mu      sync.Mutex
+	pending []storage.FetchError
+	counts  map[string]int // errors recorded this run per category
+}
+
+// NewErrorLog creates an empty error log
+func NewErrorLog() *ErrorLog {
+	return &ErrorLog{counts: make(map[string]int)}
+}
+
+// Record adds a failed fetch of url, categorizing err by its cause
+func (l *ErrorLog) Record(domain, url string, attempt, status int, err error) {
+	record := storage.FetchError{
+		Domain:     domain,
+		URL:        url,
+		Category:   categorizeFetchError(err, status),
+		StatusCode: status,
+		Message:    err.Error(),
+		Attempt:    max(attempt, 1),
+		OccurredAt: time.Now(),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append(l.pending, record)
+	l.counts[record.Category]++
+}
+
+// Counts returns how many errors were recorded this run per category
+func (l *ErrorLog) Counts() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return maps.Clone(l.counts)
+}
+
+// Take returns the errors recorded since the last call and clears them
+func (l *ErrorLog) Take() []storage.FetchError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	records := l.pending
+	l.pending = nil
+	return records
+}
+
+// categorizeFetchError maps a fetch error to a storage.Error* category
+func categorizeFetchError(err error, status int) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case status >= 400:
+		return storage.ErrorHTTP
+	case errors.As(err, &dnsErr):
+		return storage.ErrorDNS
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return storage.ErrorTLS
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return storage.ErrorTimeout
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return storage.ErrorConnection
+	default:
+		return storage.ErrorOther
+	}
+}
+
+// failureStatus returns the node status for a failed fetch: permanent when
+// retrying cannot help (unknown host, client errors, bad certificates),
+// transient otherwise
+func failureStatus(err error, status int) string {
+	switch categorizeFetchError(err, status) {
+	case storage.ErrorHTTP:
+		if status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500 {
+			return storage.NodeFailedTransient
+		}
+		return storage.NodeFailedPermanent
+	case storage.ErrorDNS:
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return storage.NodeFailedPermanent
+		}
+		return storage.NodeFailedTransient
+	case storage.ErrorTLS:
+		return storage.NodeFailedPermanent
+	default:
+		return storage.NodeFailedTransient
+	}
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alvmarrod/web-weaver/internal/config"
+)
+
+// Extractor picks a page's title and description with the configured
+// selectors, preferring a site's own selectors over the global ones
+// The selectors can be swapped while crawling
+type Extractor struct {
+	mu       sync.RWMutex
+	defaults config.SelectorSet
+	sites    map[string]config.SelectorSet // host or root domain -> selectors
+}
+
+// NewExtractor creates an extractor from the compiled selectors in cfg
+func NewExtractor(cfg *config.Config) *Extractor {
+	e := &Extractor{}
+	e.Update(cfg)
+	return e
+}
+
+// Update replaces the selectors with those compiled in cfg
+func (e *Extractor) Update(cfg *config.Config) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.defaults = cfg.Selectors
+	e.sites = cfg.SiteSelectorSets
+}
+
+// selectorsFor returns the selectors for domain: its own, its root
+// domain's, or the global ones
+func (e *Extractor) selectorsFor(domain string) config.SelectorSet {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if set, ok := e.sites[domain]; ok {
+		return set
+	}
+	if set, ok := e.sites[ExtractRootDomain(domain)]; ok {
+		return set
+	}
+	return e.defaults
+}
+
+// Extract returns the raw title and description of the page of domain;
+// either is "" if no selector matched with a non-empty value
+func (e *Extractor) Extract(domain string, doc *goquery.Selection) (title, description string) {
+	set := e.selectorsFor(domain)
+	return firstMatch(doc, set.Title), firstMatch(doc, set.Description)
+}
+
+// firstMatch returns the first non-empty value found by selectors, in order
+func firstMatch(doc *goquery.Selection, selectors []config.Selector) string {
+	for _, sel := range selectors {
+		var value string
+		doc.FindMatcher(sel.CSS).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if sel.Attr != "" {
+				value, _ = s.Attr(sel.Attr)
+			} else {
+				value = s.Text()
+			}
+			return strings.TrimSpace(value) == ""
+		})
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"sync"
+)
+
+// FailureMonitor tracks the outcome of the most recent fetches and trips
+// once the failure ratio over a full window exceeds the threshold, which
+// usually means a network outage, broken DNS, or an IP block
+type FailureMonitor struct {
+	maxPercent float64
+
+	mu       sync.Mutex
+	outcomes []bool // ring buffer, true = failed
+	next     int
+	filled   int
+	failures int
+
+	tripped  chan struct{}
+	tripOnce sync.Once
+}
+
+// NewFailureMonitor creates a monitor over the last window fetches; a zero
+// window disables it
+func NewFailureMonitor(window int, maxPercent float64) *FailureMonitor {
+	return &FailureMonitor{
+		maxPercent: maxPercent,
+		outcomes:   make([]bool, window),
+		tripped:    make(chan struct{}),
+	}
+}
+
+// Enabled reports whether a window is configured
+func (m *FailureMonitor) Enabled() bool {
+	return len(m.outcomes) > 0
+}
+
+// Record adds a fetch outcome to the window
+func (m *FailureMonitor) Record(failed bool) {
+	if !m.Enabled() {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Evict the oldest outcome once the window is full
+	if m.filled == len(m.outcomes) {
+		if m.outcomes[m.next] {
+			m.failures--
+		}
+	} else {
+		m.filled++
+	}
+
+	m.outcomes[m.next] = failed
+	if failed {
+		m.failures++
+	}
+	m.next = (m.next + 1) % len(m.outcomes)
+
+	// Never judge a partial window: a handful of early failures is noise
+	if m.filled == len(m.outcomes) && float64(m.failures)*100 > m.maxPercent*float64(m.filled) {
+		m.tripOnce.Do(func() { close(m.tripped) })
+	}
+}
+
+// Tripped is closed once the failure ratio exceeds the threshold
+func (m *FailureMonitor) Tripped() <-chan struct{} {
+	return m.tripped
+}
+
+// Stats returns the failed and total fetch counts in the current window
+func (m *FailureMonitor) Stats() (failed, total int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.failures, m.filled
+}
@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"net/http"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// httpFallbackKey is the colly request context key marking a fetch that
+// fell back to plain HTTP
+const httpFallbackKey = "http_fallback"
+
+// fellBack reports whether ctx belongs to a fetch that fell back to HTTP
+func fellBack(ctx *colly.Context) bool {
+	return ctx != nil && ctx.Get(httpFallbackKey) != ""
+}
+
+// fallBackToHTTP requests the front page of a domain again as
+// http://domain when its HTTPS fetch failed in a way that shows the domain
+// doesn't serve HTTPS (refused connection, broken certificate), if
+// allow_http_fallback is set. The fallback continues the same fetch, with
+// its context and deadline, so the node only fails if it fails too
+// Returns false if there is no fallback, and the failure stands
+func (c *Crawler) fallBackToHTTP(r *colly.Response, err error) bool {
+	if !c.cfg.AllowHTTPFallback || r == nil || r.Request == nil || innerPage(r.Ctx) || fellBack(r.Ctx) {
+		return false
+	}
+	if r.Request.URL.Scheme != "https" || !httpsRefused(err, r.StatusCode) {
+		return false
+	}
+	domain, extractErr := ExtractDomain(r.Request.URL.String())
+	if extractErr != nil || domain == "" {
+		return false
+	}
+	entry := c.fetchEntry(r.Ctx, domain)
+	if entry == nil {
+		return false
+	}
+
+	fallbackURL := "http://" + entry.DomainName
+	r.Ctx.Put(httpFallbackKey, "1")
+	if reqErr := c.collectors.For(entry.DomainName).Request(http.MethodGet, fallbackURL, nil, r.Ctx, nil); reqErr != nil {
+		logrus.Debugf("HTTP fallback for %s not sent: %v", entry.DomainName, reqErr)
+		r.Ctx.Put(httpFallbackKey, "")
+		return false
+	}
+	logrus.Infof("HTTPS failed for %s (%v), falling back to %s", entry.DomainName, err, fallbackURL)
+	c.httpFallbacks.Add(1)
+	c.schemes.RecordHTTPS(entry.DomainName, false, c.collectors.For(entry.DomainName).UserAgent)
+	return true
+}
+
+// recordScheme records that domain answered over the scheme its fetch in
+// ctx used
+func (c *Crawler) recordScheme(ctx *colly.Context, domain string) {
+	if fellBack(ctx) {
+		c.schemes.RecordHTTP(domain, true)
+		return
+	}
+	c.schemes.RecordHTTPS(domain, true, c.collectors.For(domain).UserAgent)
+}
+
+// HTTPFallbacks returns how many front pages were requested again over
+// plain HTTP after HTTPS failed
+func (c *Crawler) HTTPFallbacks() int {
+	return int(c.httpFallbacks.Load())
+}
@@ -0,0 +1,61 @@
+package crawler
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FanOutLimiter caps how many new domains may be enqueued at each depth, to
+// budget the exponential growth of deeper levels more finely than max_depth
+// Counts cover the current run; entries restored on resume don't count
+type FanOutLimiter struct {
+	limits map[int]int // depth -> max new domains; absent depths are unlimited
+
+	mu      sync.Mutex
+	counts  map[int]int
+	reached map[int]bool // depths whose limit has been logged
+}
+
+// NewFanOutLimiter creates a limiter; an empty limits map disables it
+func NewFanOutLimiter(limits map[int]int) *FanOutLimiter {
+	return &FanOutLimiter{
+		limits:  limits,
+		counts:  make(map[int]int),
+		reached: make(map[int]bool),
+	}
+}
+
+// Reserve claims a slot for a new domain at depth, reporting false once the
+// depth's limit is spent; a slot claimed for a domain that turns out to be
+// queued already must be given back with Release
+func (l *FanOutLimiter) Reserve(depth int) bool {
+	limit, ok := l.limits[depth]
+	if !ok {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.counts[depth] >= limit {
+		if !l.reached[depth] {
+			l.reached[depth] = true
+			logrus.Infof("Fan-out limit reached: %d new domains enqueued at depth %d", limit, depth)
+		}
+		return false
+	}
+	l.counts[depth]++
+	return true
+}
+
+// Release gives back a slot claimed with Reserve
+func (l *FanOutLimiter) Release(depth int) {
+	if _, ok := l.limits[depth]; !ok {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.counts[depth]--
+}
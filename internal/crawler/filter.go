@@ -2,24 +2,10 @@ package crawler
 
 import (
 	"net/url"
-	"regexp"
 	"strings"
-)
 
-// Excluded domain patterns (social media, ads, analytics)
-var excludedPatterns = []*regexp.Regexp{
-	regexp.MustCompile(`(?i)(facebook|fb)\.com`),
-	regexp.MustCompile(`(?i)twitter\.com`),
-	regexp.MustCompile(`(?i)instagram\.com`),
-	regexp.MustCompile(`(?i)linkedin\.com`),
-	regexp.MustCompile(`(?i)youtube\.com`),
-	regexp.MustCompile(`(?i)google-analytics\.com`),
-	regexp.MustCompile(`(?i)doubleclick\.net`),
-	regexp.MustCompile(`(?i)^ads?\.`),
-	regexp.MustCompile(`(?i)^analytics?\.`),
-	regexp.MustCompile(`(?i)googletagmanager\.com`),
-	regexp.MustCompile(`(?i)googleapis\.com`),
-}
+	"github.com/alvmarrod/web-weaver/internal/config"
+)
 
 // ExtractDomain extracts the hostname (domain/subdomain) from a URL string
 func ExtractDomain(urlStr string) (string, error) {
@@ -56,19 +42,44 @@ func ExtractRootDomain(domain string) string {
 	return domain
 }
 
-// IsExcluded checks if a domain matches any excluded pattern
-func IsExcluded(domain string) bool {
-	for _, pattern := range excludedPatterns {
-		if pattern.MatchString(domain) {
-			return true
+// DomainFilter applies the configured exclude and include patterns
+type DomainFilter struct {
+	exclude []config.FilterRule
+	include []config.FilterRule
+}
+
+// NewDomainFilter creates a filter from rules compiled by config.LoadConfig
+func NewDomainFilter(exclude, include []config.FilterRule) *DomainFilter {
+	return &DomainFilter{exclude: exclude, include: include}
+}
+
+// Allows reports whether a domain may be crawled
+func (f *DomainFilter) Allows(domain string) bool {
+	allowed, _ := f.Match(domain)
+	return allowed
+}
+
+// Match reports whether a domain may be crawled and the rule that decided
+// it: the first matching exclusion, or the first matching inclusion
+// A nil rule means no rule matched; the domain is then allowed unless an
+// include list is configured
+func (f *DomainFilter) Match(domain string) (bool, *config.FilterRule) {
+	for i := range f.exclude {
+		if f.exclude[i].Regexp.MatchString(domain) {
+			return false, &f.exclude[i]
+		}
+	}
+	for i := range f.include {
+		if f.include[i].Regexp.MatchString(domain) {
+			return true, &f.include[i]
 		}
 	}
-	return false
+	return len(f.include) == 0, nil
 }
 
 // FilterLinks extracts, filters, and selects up to maxLinks cross-domain links
 // Returns a list of target domains (not full URLs)
-func FilterLinks(sourceURL string, links []string, maxLinks int) []string {
+func (f *DomainFilter) FilterLinks(sourceURL string, links []string, maxLinks int) []string {
 	sourceDomain, err := ExtractDomain(sourceURL)
 	if err != nil || sourceDomain == "" {
 		return []string{}
@@ -94,8 +105,8 @@ func FilterLinks(sourceURL string, links []string, maxLinks int) []string {
 			continue
 		}
 
-		// Skip excluded domains
-		if IsExcluded(targetDomain) {
+		// Skip filtered domains
+		if !f.Allows(targetDomain) {
 			continue
 		}
 
@@ -0,0 +1,563 @@
+package crawler
+
+import (
+	"container/heap"
+	"context"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// backQueuesPerWorker sizes the back-queue pool; Mercator suggests about
+// three hosts per fetcher so workers rarely wait on politeness delays
+const backQueuesPerWorker = 3
+
+// Queue is the crawl frontier: entries wait in it, deduplicated, until a
+// worker pops one whose host politeness allows fetching. Frontier keeps it
+// in memory; RedisQueue shares it between processes
+type Queue interface {
+	// Push adds an entry unless it was pushed before; Requeue adds one again,
+	// e.g. to retry a failed fetch. Both return false once stopped
+	Push(entry storage.QueueEntry) bool
+	Requeue(entry storage.QueueEntry) bool
+
+	// Pop blocks until an entry's host may be fetched, returning false once
+	// stopped or ctx is done
+	Pop(ctx context.Context) (storage.QueueEntry, bool)
+
+	// Done releases an entry from Pop once its fetch settled or it was
+	// skipped; a shared queue hands the entries a process never released
+	// to another one
+	Done(entry storage.QueueEntry)
+
+	// Reserve claims a fetch from domain's host made outside the queue,
+	// blocking until the host's politeness allows it; false once stopped or
+	// ctx is done
+	Reserve(ctx context.Context, domain string, depth int) bool
+
+	// Stop rejects further pushes and releases blocked Pop calls
+	Stop()
+
+	IsEmpty() bool
+	Size() int
+	VisitedCount() int
+
+	// Drained reports whether the crawl ran out of work: the queue is
+	// empty and nothing sharing it is busy; busy tells whether this process
+	// still has fetches in flight or retries pending
+	Drained(busy bool) bool
+
+	// GetAllEntries returns the entries a checkpoint saves
+	GetAllEntries() []storage.QueueEntry
+
+	// Upcoming returns the distinct domains of roughly the next n entries
+	Upcoming(n int) []string
+
+	// Admits, KnowsRoot and Limiter expose the per-root subdomain limiter
+	Admits(domain string) bool
+	KnowsRoot(domain string) bool
+	Limiter() *SubdomainLimiter
+
+	SetDepthPoliteness(p *DepthPoliteness)
+}
+
+// newQueue creates the frontier of the configured backend
+func newQueue(cfg *config.Config, workers int, latency *HostLatency) Queue {
+	delay := time.Duration(cfg.PolitenessDelayMs) * time.Millisecond
+	jitter := time.Duration(cfg.PolitenessJitterMs) * time.Millisecond
+	if cfg.QueueBackend == config.QueueBackendRedis {
+		return NewRedisQueue(cfg.QueueRedis, cfg.MaxDepth, delay, jitter, cfg.MaxSubdomainsPerRoot, latency)
+	}
+	return NewFrontier(cfg.MaxDepth, workers, delay, jitter, cfg.MaxSubdomainsPerRoot, latency)
+}
+
+// Frontier is a Mercator-style two-tier URL frontier
+//
+// Front queues order entries by priority (shallower depth first, keeping the
+// crawl breadth-first, then by the entry's score, then first-in first-out).
+// Back queues hold entries for a single host, where a
+// host is a root domain, and each carries the earliest time it may be
+// fetched again. Workers always pop from the host that becomes ready first,
+// so prioritization and politeness never fight each other.
+type Frontier struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	stopped bool
+
+	front   []frontQueue          // one queue per depth level
+	seq     uint64                // arrival counter, so equal priorities stay FIFO
+	back    map[string]*backQueue // host -> its back queue
+	ready   backQueueHeap         // back queues ordered by next fetch time
+	maxBack int
+	delay   time.Duration // politeness gap between fetches to the same host
+	jitter  time.Duration // random extra gap so fetch timing isn't periodic
+	size    int
+
+	// Last fetch per host, so politeness survives a back queue draining
+	lastFetch  map[string]time.Time
+	latency    *HostLatency     // pushes slow hosts' next fetch further out
+	politeness *DepthPoliteness // scales delay and concurrency by depth; nil leaves them as is
+
+	visited map[visitKey]bool
+	limiter *SubdomainLimiter
+}
+
+// backQueue holds entries for one host
+type backQueue struct {
+	host      string
+	entries   []storage.QueueEntry
+	nextFetch time.Time
+	burst     int // entries popped since the last politeness delay
+	index     int // position in the ready heap
+}
+
+// NewFrontier creates a frontier with priority levels 0..maxDepth
+func NewFrontier(maxDepth, workers int, politenessDelay, politenessJitter time.Duration, maxSubdomainsPerRoot int, latency *HostLatency) *Frontier {
+	f := &Frontier{
+		front:   make([]frontQueue, maxDepth+1),
+		back:    make(map[string]*backQueue),
+		maxBack: max(1, workers*backQueuesPerWorker),
+		delay:   politenessDelay,
+		jitter:  politenessJitter,
+		visited: make(map[visitKey]bool),
+
+		lastFetch: make(map[string]time.Time),
+		latency:   latency,
+		limiter:   NewSubdomainLimiter(maxSubdomainsPerRoot),
+	}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// SetDepthPoliteness scales the politeness delay and per-host concurrency
+// by the depth of the entries popped; call it before the first Push
+func (f *Frontier) SetDepthPoliteness(p *DepthPoliteness) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.politeness = p
+}
+
+// Admits reports whether a domain fits within the per-root subdomain limit
+func (f *Frontier) Admits(domain string) bool {
+	return f.limiter.CanAdd(domain)
+}
+
+// Limiter returns the per-root subdomain limiter so its state can be saved
+func (f *Frontier) Limiter() *SubdomainLimiter {
+	return f.limiter
+}
+
+// KnowsRoot reports whether a host under the domain's root was ever queued
+func (f *Frontier) KnowsRoot(domain string) bool {
+	return f.limiter.KnowsRoot(domain)
+}
+
+// Push adds an entry if not already visited at this depth; inner pages are
+// deduplicated by URL
+// Returns true if added, false if duplicate or stopped
+func (f *Frontier) Push(entry storage.QueueEntry) bool {
+	f.limiter.Add(entry.DomainName)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.stopped {
+		return false
+	}
+
+	key := makeKey(entry.DomainName, entry.Depth)
+	if entry.URL != "" {
+		key = pageVisitKey(entry.URL)
+	}
+	if f.visited[key] {
+		return false
+	}
+	f.visited[key] = true
+
+	f.enqueue(entry)
+	return true
+}
+
+// Requeue adds an entry that was already pushed once, bypassing the
+// visited set, e.g. to retry a failed fetch
+// Returns false if stopped
+func (f *Frontier) Requeue(entry storage.QueueEntry) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.stopped {
+		return false
+	}
+	f.enqueue(entry)
+	return true
+}
+
+// enqueue adds an entry to the front queue of its depth; callers hold mu
+func (f *Frontier) enqueue(entry storage.QueueEntry) {
+	level := min(max(entry.Depth, 0), len(f.front)-1)
+	f.seq++
+	f.front[level].push(frontEntry{entry: entry, seq: f.seq})
+	f.size++
+
+	f.cond.Signal()
+}
+
+// Pop removes the next entry whose host is allowed to be fetched
+// Blocks while the frontier is empty or every host is still cooling down
+// Returns (empty, false) once stopped or ctx is done, whichever comes
+// first; remaining entries stay in place so they can be checkpointed
+func (f *Frontier) Pop(ctx context.Context) (storage.QueueEntry, bool) {
+	// Cancelling ctx wakes the wait below as Stop does; the wake-up is
+	// released before the lock, as deferred calls run in reverse
+	release := context.AfterFunc(ctx, f.wake)
+	defer release()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for {
+		if f.stopped || ctx.Err() != nil {
+			return storage.QueueEntry{}, false
+		}
+
+		f.refill()
+
+		if len(f.ready) == 0 {
+			f.cond.Wait()
+			continue
+		}
+
+		bq := f.ready[0]
+		if wait := time.Until(bq.nextFetch); wait > 0 {
+			// Wake up when the host is ready, or earlier on Push/Stop
+			timer := time.AfterFunc(wait, f.wake)
+			f.cond.Wait()
+			timer.Stop()
+			continue
+		}
+
+		entry := bq.entries[0]
+		bq.entries = bq.entries[1:]
+		f.size--
+
+		now := time.Now()
+		f.lastFetch[bq.host] = now
+
+		// Under depth_politeness, a host's shallow entries may be popped
+		// several at a time; the delay follows the last of them
+		bq.burst++
+		switch {
+		case len(bq.entries) == 0:
+			heap.Remove(&f.ready, bq.index)
+			delete(f.back, bq.host)
+		case bq.burst < f.politeness.Concurrency(bq.entries[0].Depth):
+			bq.nextFetch = now
+			heap.Fix(&f.ready, bq.index)
+		default:
+			bq.burst = 0
+			bq.nextFetch = now.Add(f.hostDelay(bq.host, entry.Depth))
+			heap.Fix(&f.ready, bq.index)
+		}
+
+		return entry, true
+	}
+}
+
+// Done does nothing: popped entries die with the process, and checkpoints
+// save the open fetches' entries
+func (f *Frontier) Done(entry storage.QueueEntry) {}
+
+// Reserve claims the next fetch slot of domain's host for a request made
+// outside the frontier, such as a sitemap read, and waits for it; entries
+// of the host queued meanwhile follow the reserved fetch's delay
+// Returns false once stopped or ctx is done
+func (f *Frontier) Reserve(ctx context.Context, domain string, depth int) bool {
+	host := ExtractRootDomain(domain)
+
+	f.mu.Lock()
+	if f.stopped {
+		f.mu.Unlock()
+		return false
+	}
+	slot := time.Now()
+	if last, ok := f.lastFetch[host]; ok {
+		if next := last.Add(f.hostDelay(host, depth)); next.After(slot) {
+			slot = next
+		}
+	}
+	if bq, ok := f.back[host]; ok {
+		if bq.nextFetch.After(slot) {
+			slot = bq.nextFetch
+		}
+		bq.burst = 0
+		bq.nextFetch = slot.Add(f.hostDelay(host, depth))
+		heap.Fix(&f.ready, bq.index)
+	}
+	f.lastFetch[host] = slot
+	f.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// wake releases every Pop blocked in cond.Wait. It takes the lock, so a
+// wake-up can't land between a Pop's checks and its Wait and be lost
+func (f *Frontier) wake() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cond.Broadcast()
+}
+
+// refill moves entries from the front queues, highest priority first, into
+// back queues until the back-queue pool is full or the front is exhausted
+func (f *Frontier) refill() {
+	for level := range f.front {
+		for len(f.front[level]) > 0 {
+			entry := f.front[level][0].entry
+			host := ExtractRootDomain(entry.DomainName)
+
+			bq, exists := f.back[host]
+			if !exists && len(f.back) >= f.maxBack {
+				return
+			}
+
+			f.front[level].pop()
+			if exists {
+				bq.entries = append(bq.entries, entry)
+				continue
+			}
+
+			bq = &backQueue{host: host, entries: []storage.QueueEntry{entry}, nextFetch: time.Now()}
+			if last, ok := f.lastFetch[host]; ok {
+				bq.nextFetch = last.Add(f.hostDelay(host, entry.Depth))
+			}
+			f.back[host] = bq
+			heap.Push(&f.ready, bq)
+		}
+	}
+}
+
+// hostDelay is the gap after fetching an entry at depth from a host
+func (f *Frontier) hostDelay(host string, depth int) time.Duration {
+	return politenessGap(f.delay, f.jitter, f.politeness, f.latency, host, depth)
+}
+
+// politenessGap is the gap after fetching an entry at depth from a host:
+// the politeness delay plus random jitter, both scaled for the depth, and
+// any slow-host penalty
+func politenessGap(delay, jitter time.Duration, p *DepthPoliteness, latency *HostLatency, host string, depth int) time.Duration {
+	m := p.Multiplier(depth)
+	gap := time.Duration(float64(delay)*m) + latency.Penalty(host)
+	if jitter := time.Duration(float64(jitter) * m); jitter > 0 {
+		gap += rand.N(jitter)
+	}
+	return gap
+}
+
+// IsEmpty returns true if the frontier has no entries
+func (f *Frontier) IsEmpty() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.size == 0
+}
+
+// Size returns the current number of entries in the frontier
+func (f *Frontier) Size() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.size
+}
+
+// VisitedCount returns the number of keys in the dedup set
+func (f *Frontier) VisitedCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.visited)
+}
+
+// Drained reports whether the frontier is empty and the process idle
+func (f *Frontier) Drained(busy bool) bool {
+	return !busy && f.IsEmpty()
+}
+
+// Stop rejects further pushes and releases workers blocked on Pop()
+func (f *Frontier) Stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = true
+	f.cond.Broadcast()
+}
+
+// GetAllEntries returns a snapshot of all current entries, back queues first
+// Used for persisting queue state on checkpoint/shutdown
+func (f *Frontier) GetAllEntries() []storage.QueueEntry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries := make([]storage.QueueEntry, 0, f.size)
+	for _, bq := range f.ready {
+		entries = append(entries, bq.entries...)
+	}
+	for _, level := range f.front {
+		for _, fe := range level {
+			entries = append(entries, fe.entry)
+		}
+	}
+	return entries
+}
+
+// Upcoming returns the distinct domains of roughly the next n entries to be
+// popped: back-queue heads in next-fetch order, then the rest of the back
+// queues, then the front queues by depth (each in heap order, which only
+// approximates score order)
+func (f *Frontier) Upcoming(n int) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	queues := make([]*backQueue, len(f.ready))
+	copy(queues, f.ready)
+	sort.Slice(queues, func(i, j int) bool { return queues[i].nextFetch.Before(queues[j].nextFetch) })
+
+	domains := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	add := func(entry storage.QueueEntry) bool {
+		if !seen[entry.DomainName] {
+			seen[entry.DomainName] = true
+			domains = append(domains, entry.DomainName)
+		}
+		return len(domains) >= n
+	}
+
+	for _, bq := range queues {
+		if len(bq.entries) > 0 && add(bq.entries[0]) {
+			return domains
+		}
+	}
+	for _, bq := range queues {
+		for _, entry := range bq.entries[min(1, len(bq.entries)):] {
+			if add(entry) {
+				return domains
+			}
+		}
+	}
+	for _, level := range f.front {
+		for _, fe := range level {
+			if add(fe.entry) {
+				return domains
+			}
+		}
+	}
+	return domains
+}
+
+// visitKey deduplicates frontier entries: a domain at a depth, or an
+// inner page by URL
+// A struct key spares formatting a string for every pushed link
+type visitKey struct {
+	name  string // domain, or the URL of an inner page
+	depth int    // -1 for inner pages, which are deduplicated at any depth
+}
+
+// makeKey creates a deduplication key from domain and depth
+func makeKey(domain string, depth int) visitKey {
+	return visitKey{name: domain, depth: depth}
+}
+
+// pageVisitKey creates the deduplication key of an inner page
+func pageVisitKey(pageURL string) visitKey {
+	return visitKey{name: pageURL, depth: -1}
+}
+
+// backQueueHeap is a min-heap of back queues by next fetch time
+type backQueueHeap []*backQueue
+
+func (h backQueueHeap) Len() int           { return len(h) }
+func (h backQueueHeap) Less(i, j int) bool { return h[i].nextFetch.Before(h[j].nextFetch) }
+
+func (h backQueueHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *backQueueHeap) Push(x any) {
+	bq := x.(*backQueue)
+	bq.index = len(*h)
+	*h = append(*h, bq)
+}
+
+func (h *backQueueHeap) Pop() any {
+	old := *h
+	bq := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return bq
+}
+
+// frontEntry is a front-queue entry with its arrival number
+type frontEntry struct {
+	entry storage.QueueEntry
+	seq   uint64
+}
+
+// frontQueue is a max-heap of entries by priority, oldest first among equals
+type frontQueue []frontEntry
+
+func (q frontQueue) Len() int { return len(q) }
+func (q frontQueue) Less(i, j int) bool {
+	if q[i].entry.Priority != q[j].entry.Priority {
+		return q[i].entry.Priority > q[j].entry.Priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q frontQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+// push adds fe; unlike heap.Push it doesn't box the entry in an interface,
+// which would allocate for every queued link
+func (q *frontQueue) push(fe frontEntry) {
+	*q = append(*q, fe)
+	h := *q
+	for i := len(h) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if !h.Less(i, parent) {
+			break
+		}
+		h.Swap(i, parent)
+		i = parent
+	}
+}
+
+// pop removes and returns the highest-priority entry, without boxing it
+func (q *frontQueue) pop() frontEntry {
+	h := *q
+	n := len(h) - 1
+	h.Swap(0, n)
+	for i := 0; ; {
+		child := 2*i + 1
+		if child >= n {
+			break
+		}
+		if right := child + 1; right < n && h.Less(right, child) {
+			child = right
+		}
+		if !h.Less(child, i) {
+			break
+		}
+		h.Swap(i, child)
+		i = child
+	}
+	fe := h[n]
+	h[n] = frontEntry{} // release the entry's strings
+	*q = h[:n]
+	return fe
+}
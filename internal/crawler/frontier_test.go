@@ -0,0 +1,106 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// Frontier shutdown stress parameters
+const (
+	shutdownRounds  = 60
+	shutdownPoppers = 32
+	shutdownHosts   = 200
+	shutdownGrace   = time.Second // longest a Pop may stay blocked once stopped
+)
+
+// TestFrontierShutdown stops frontiers under load: workers popping from
+// hosts in and out of their politeness delay while links are still pushed,
+// stopped after a random moment by cancelling the workers' context, by
+// Frontier.Stop, or both in either order. A Pop must not stay blocked after
+// the stop, and every entry must be either popped or left to checkpoint
+func TestFrontierShutdown(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 1))
+	for i := range shutdownRounds {
+		how := i % 3
+		wait := time.Duration(rng.IntN(2000)) * time.Microsecond
+		if err := shutdownFrontier(how, wait); err != nil {
+			t.Fatalf("round %d (stop %d after %s): %v", i, how, wait, err)
+		}
+	}
+}
+
+// shutdownFrontier runs one round of TestFrontierShutdown, stopping after
+// wait in the given way: 0 cancels, 1 stops then cancels, 2 cancels then
+// stops
+func shutdownFrontier(how int, wait time.Duration) error {
+	f := NewFrontier(5, shutdownPoppers, time.Millisecond, time.Millisecond, 1<<30, NewHostLatency(0))
+	defer f.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pushed, popped atomic.Int64
+	push := func(n int) {
+		domain := fmt.Sprintf("sub%d.root%d.com", n, n%shutdownHosts)
+		if f.Push(storage.QueueEntry{DomainName: domain, Depth: n % 6}) {
+			pushed.Add(1)
+		}
+	}
+	for n := range shutdownHosts {
+		push(n)
+	}
+
+	var wg sync.WaitGroup
+	for range shutdownPoppers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if _, ok := f.Pop(ctx); !ok {
+					return
+				}
+				popped.Add(1)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := shutdownHosts; ctx.Err() == nil && n < 6*shutdownHosts; n++ {
+			push(n)
+		}
+	}()
+
+	time.Sleep(wait)
+	switch how {
+	case 0:
+		cancel()
+	case 1:
+		f.Stop()
+		cancel()
+	case 2:
+		cancel()
+		f.Stop()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownGrace):
+		return fmt.Errorf("Pop still blocked %s after the stop", shutdownGrace)
+	}
+	if left := int64(f.Size()); popped.Load()+left != pushed.Load() {
+		return fmt.Errorf("%d entries pushed, but %d popped and %d left", pushed.Load(), popped.Load(), left)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RequestHook prepares every outgoing request right before it is sent, e.g.
+// to sign it, add HMAC headers, or attach a freshly refreshed OAuth token
+// It sees the final request, including redirects and conditional headers,
+// and runs for HTTP scheme probes too. Returning an error fails the fetch
+// without sending it. Hooks are called from every worker, so they must be
+// safe for concurrent use
+type RequestHook interface {
+	PrepareRequest(req *http.Request) error
+}
+
+// RequestHookFunc adapts a function to a RequestHook
+type RequestHookFunc func(req *http.Request) error
+
+// PrepareRequest calls f(req)
+func (f RequestHookFunc) PrepareRequest(req *http.Request) error {
+	return f(req)
+}
+
+// SetRequestHook installs hook on every outgoing request; nil removes it
+// Call it before Start
+func (c *Crawler) SetRequestHook(hook RequestHook) {
+	c.requestHook = hook
+}
+
+// hookTransport runs the crawler's request hook before handing requests
+// to the underlying transport
+type hookTransport struct {
+	base    http.RoundTripper
+	crawler *Crawler
+}
+
+// hooked wraps transport so requests go through the request hook
+func (c *Crawler) hooked(transport http.RoundTripper) http.RoundTripper {
+	return &hookTransport{base: transport, crawler: c}
+}
+
+// RoundTrip sends a copy of req prepared by the hook, if one is set
+func (t *hookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	hook := t.crawler.requestHook
+	if hook == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the request it was given
+	prepared := req.Clone(req.Context())
+	if err := hook.PrepareRequest(prepared); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("request hook: %w", err)
+	}
+	return t.base.RoundTrip(prepared)
+}
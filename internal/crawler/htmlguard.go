@@ -0,0 +1,293 @@
+package crawler
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLGuard bounds the document Colly parses into a DOM for each page, as
+// a pathological page (millions of elements, tags nested thousands deep,
+// megabyte-long attribute values) makes that DOM, and the selectors run
+// over it, cost far more memory than the page's size
+// The page is tokenized in one streaming pass before Colly parses it;
+// pages within the limits are left untouched, others are rewritten without
+// the elements past max_html_elements, the subtrees nested deeper than
+// max_html_depth, and the attributes longer than max_html_attribute_bytes
+type HTMLGuard struct {
+	maxElements  int // 0 means unlimited, as for the other limits
+	maxDepth     int
+	maxAttrBytes int
+}
+
+// NewHTMLGuard creates a guard with the configured limits
+func NewHTMLGuard(cfg *config.Config) *HTMLGuard {
+	return &HTMLGuard{
+		maxElements:  max(cfg.MaxHTMLElements, 0),
+		maxDepth:     max(cfg.MaxHTMLDepth, 0),
+		maxAttrBytes: max(cfg.MaxHTMLAttributeBytes, 0),
+	}
+}
+
+// Enabled reports whether any limit is set
+func (g *HTMLGuard) Enabled() bool {
+	return g.maxElements > 0 || g.maxDepth > 0 || g.maxAttrBytes > 0
+}
+
+// htmlPruning is what the guard removed from a page
+type htmlPruning struct {
+	truncated bool // the document was cut at max_html_elements
+	subtrees  int  // subtrees dropped past max_html_depth
+	attrs     int  // attributes dropped past max_html_attribute_bytes
+}
+
+// any reports whether anything was removed
+func (p htmlPruning) any() bool {
+	return p.truncated || p.subtrees > 0 || p.attrs > 0
+}
+
+// Guard prunes the body of an HTML response in place, before Colly parses
+// it; other responses are left alone
+func (g *HTMLGuard) Guard(r *colly.Response) {
+	if !g.Enabled() || !isHTML(r) {
+		return
+	}
+	body, pruned := g.prune(r.Body)
+	if !pruned.any() {
+		return
+	}
+	logrus.Warnf("Pruned oversized HTML of %s (%d -> %d bytes): truncated=%t, deep subtrees=%d, long attributes=%d",
+		r.Request.URL, len(r.Body), len(body), pruned.truncated, pruned.subtrees, pruned.attrs)
+	r.Body = body
+}
+
+// isHTML reports whether Colly would parse the response as HTML
+func isHTML(r *colly.Response) bool {
+	contentType := ""
+	if r.Headers != nil {
+		contentType = r.Headers.Get("Content-Type")
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(r.Body)
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// prune returns body without what exceeds the limits, and what that was;
+// body itself if nothing does
+// Nesting is tracked as the HTML parser would for well-formed markup: end
+// tags close up to the matching open element, and the elements a sibling
+// implicitly closes (p, li, td, ...) are closed by it
+func (g *HTMLGuard) prune(body []byte) ([]byte, htmlPruning) {
+	var (
+		pruned   htmlPruning
+		out      *bytes.Buffer // nil until something is pruned
+		offset   int           // bytes of body tokenized so far
+		elements int
+		open     []atom.Atom // emitted elements still open, outermost first; 0 for unknown tags
+
+		skipping  bool      // dropping a subtree
+		skipRoot  atom.Atom // its root
+		skipNests int       // elements named as its root open inside it
+	)
+	// startPruning copies the untouched part of body read so far
+	startPruning := func() {
+		if out == nil {
+			out = bytes.NewBuffer(make([]byte, 0, len(body)))
+			out.Write(body[:offset])
+		}
+	}
+
+	z := nethtml.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			if z.Err() != io.EOF {
+				// Unreadable markup; keep what came before it
+				startPruning()
+			}
+			break
+		}
+		raw := z.Raw()
+		rawLen := len(raw)
+
+		switch tt {
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			nameBytes, hasAttrs := z.TagName()
+			name := atom.Lookup(nameBytes)
+			void := tt == nethtml.SelfClosingTagToken || voidElements[name]
+
+			if skipping {
+				if name == skipRoot && !void {
+					skipNests++
+				}
+				break
+			}
+			if g.maxElements > 0 && elements >= g.maxElements {
+				startPruning()
+				pruned.truncated = true
+				return out.Bytes(), pruned
+			}
+			open = closeImplied(open, name)
+			if g.maxDepth > 0 && len(open) >= g.maxDepth {
+				startPruning()
+				pruned.subtrees++
+				if !void {
+					skipping, skipRoot, skipNests = true, name, 0
+				}
+				break
+			}
+			elements++
+			if !void {
+				open = append(open, name)
+			}
+
+			// A tag shorter than the limit can't hold a longer attribute
+			// Reading attributes unescapes them in place, so raw is only
+			// written as is if they weren't read
+			if g.maxAttrBytes > 0 && hasAttrs && rawLen > g.maxAttrBytes {
+				tag, dropped := g.rebuildTag(z, string(nameBytes), tt == nethtml.SelfClosingTagToken)
+				if dropped > 0 {
+					startPruning()
+					pruned.attrs += dropped
+				}
+				if out != nil {
+					out.Write(tag)
+				}
+				break
+			}
+			if out != nil {
+				out.Write(raw)
+			}
+
+		case nethtml.EndTagToken:
+			nameBytes, _ := z.TagName()
+			name := atom.Lookup(nameBytes)
+			if skipping {
+				// The subtree ends with its root, or when an ancestor closes
+				if name == skipRoot {
+					if skipNests > 0 {
+						skipNests--
+					} else {
+						skipping = false
+					}
+					break
+				}
+				if indexOfOpen(open, name) < 0 {
+					break
+				}
+				skipping = false
+			}
+			if i := indexOfOpen(open, name); i >= 0 {
+				open = open[:i]
+			}
+			if out != nil {
+				out.Write(raw)
+			}
+
+		default:
+			if !skipping && out != nil {
+				out.Write(raw)
+			}
+		}
+		offset += rawLen
+	}
+
+	if out == nil {
+		return body, pruned
+	}
+	return out.Bytes(), pruned
+}
+
+// rebuildTag renders the current start tag without attribute values
+// longer than the limit, and how many it dropped
+func (g *HTMLGuard) rebuildTag(z *nethtml.Tokenizer, name string, selfClosing bool) ([]byte, int) {
+	var tag bytes.Buffer
+	dropped := 0
+	tag.WriteString("<" + name)
+	for more := true; more; {
+		var key, val []byte
+		key, val, more = z.TagAttr()
+		if len(val) > g.maxAttrBytes {
+			dropped++
+			continue
+		}
+		tag.WriteString(" " + string(key) + `="` + html.EscapeString(string(val)) + `"`)
+	}
+	if selfClosing {
+		tag.WriteString("/")
+	}
+	tag.WriteString(">")
+	return tag.Bytes(), dropped
+}
+
+// indexOfOpen returns the position of the innermost open element named
+// name, or -1
+func indexOfOpen(open []atom.Atom, name atom.Atom) int {
+	for i := len(open) - 1; i >= 0; i-- {
+		if open[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// closeImplied closes the innermost open elements that a start tag named
+// name implicitly ends, e.g. an open li at a new li, or a p at a div
+func closeImplied(open []atom.Atom, name atom.Atom) []atom.Atom {
+	closes := impliedEnds[name]
+	if closes == nil {
+		if !closesP[name] {
+			return open
+		}
+		closes = []atom.Atom{atom.P}
+	}
+	for len(open) > 0 && slices.Contains(closes, open[len(open)-1]) {
+		open = open[:len(open)-1]
+	}
+	return open
+}
+
+// voidElements never have content or an end tag
+var voidElements = map[atom.Atom]bool{
+	atom.Area: true, atom.Base: true, atom.Br: true, atom.Col: true, atom.Embed: true, atom.Hr: true, atom.Img: true,
+	atom.Input: true, atom.Link: true, atom.Meta: true, atom.Param: true, atom.Source: true, atom.Track: true, atom.Wbr: true,
+}
+
+// impliedEnds lists, by start tag, the open elements it closes while they
+// are the innermost: the common optional end tags of the HTML spec
+var impliedEnds = map[atom.Atom][]atom.Atom{
+	atom.P:        {atom.P},
+	atom.Li:       {atom.Li, atom.P},
+	atom.Dt:       {atom.Dt, atom.Dd, atom.P},
+	atom.Dd:       {atom.Dt, atom.Dd, atom.P},
+	atom.Option:   {atom.Option},
+	atom.Optgroup: {atom.Option, atom.Optgroup},
+	atom.Tr:       {atom.Td, atom.Th, atom.Tr},
+	atom.Td:       {atom.Td, atom.Th},
+	atom.Th:       {atom.Td, atom.Th},
+	atom.Tbody:    {atom.Td, atom.Th, atom.Tr, atom.Tbody, atom.Thead, atom.Tfoot},
+	atom.Tfoot:    {atom.Td, atom.Th, atom.Tr, atom.Tbody, atom.Thead},
+	atom.Rt:       {atom.Rt, atom.Rp},
+	atom.Rp:       {atom.Rt, atom.Rp},
+}
+
+// closesP lists the block elements whose start tag closes an open p
+var closesP = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Details: true,
+	atom.Div: true, atom.Dl: true, atom.Fieldset: true, atom.Figure: true, atom.Footer: true, atom.Form: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Main: true, atom.Menu: true, atom.Nav: true, atom.Ol: true, atom.Pre: true,
+	atom.Section: true, atom.Table: true, atom.Ul: true,
+}
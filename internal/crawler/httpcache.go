@@ -0,0 +1,181 @@
+package crawler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// HTTPCache keeps per-URL freshness and validators in memory so re-crawls
+// can skip provably fresh pages and revalidate stale ones cheaply
+// Changes are flushed to storage together with the graph
+type HTTPCache struct {
+	mu      sync.Mutex
+	entries map[string]storage.HTTPCacheEntry
+	dirty   map[string]bool
+
+	fresh       atomic.Int64 // fetches skipped because the page was fresh
+	notModified atomic.Int64 // conditional fetches answered with 304
+}
+
+// NewHTTPCache creates an empty cache
+func NewHTTPCache() *HTTPCache {
+	return &HTTPCache{
+		entries: make(map[string]storage.HTTPCacheEntry),
+		dirty:   make(map[string]bool),
+	}
+}
+
+// Load replaces the cache contents with entries read from storage
+func (h *HTTPCache) Load(entries []storage.HTTPCacheEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = make(map[string]storage.HTTPCacheEntry, len(entries))
+	for _, entry := range entries {
+		h.entries[entry.URL] = entry
+	}
+}
+
+// Get returns the cache entry for a URL
+func (h *HTTPCache) Get(url string) (storage.HTTPCacheEntry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, ok := h.entries[url]
+	return entry, ok
+}
+
+// IsFresh reports whether the URL can be skipped without contacting the server
+// Only persisted entries qualify: their links were flushed to storage with them
+func (h *HTTPCache) IsFresh(url string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry, ok := h.entries[url]
+	return ok && !h.dirty[url] && time.Now().Before(entry.FreshUntil)
+}
+
+// Update records freshness and validators from a 200 or 304 response
+// A 304 may omit validators, in which case the stored ones are kept
+func (h *HTTPCache) Update(url string, header http.Header) {
+	freshUntil, storable := freshnessFromHeaders(header, time.Now())
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !storable {
+		if _, ok := h.entries[url]; ok {
+			delete(h.entries, url)
+			// Keep the stale row harmless: no freshness, no validators
+			h.dirty[url] = true
+		}
+		return
+	}
+
+	entry := h.entries[url]
+	entry.URL = url
+	entry.FreshUntil = freshUntil
+	if etag := header.Get("ETag"); etag != "" {
+		entry.ETag = etag
+	}
+	if lastModified := header.Get("Last-Modified"); lastModified != "" {
+		entry.LastModified = lastModified
+	}
+
+	h.entries[url] = entry
+	h.dirty[url] = true
+}
+
+// Forget drops the freshness and validators of a URL, so its next fetch is
+// unconditional
+func (h *HTTPCache) Forget(url string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.entries[url]; ok {
+		delete(h.entries, url)
+		h.dirty[url] = true
+	}
+}
+
+// TakeDirty returns entries changed since the last call and clears the set
+func (h *HTTPCache) TakeDirty() []storage.HTTPCacheEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := make([]storage.HTTPCacheEntry, 0, len(h.dirty))
+	for url := range h.dirty {
+		entry, ok := h.entries[url]
+		if !ok {
+			entry = storage.HTTPCacheEntry{URL: url}
+		}
+		entries = append(entries, entry)
+	}
+	h.dirty = make(map[string]bool)
+	return entries
+}
+
+// Stats returns how many fetches were skipped as fresh and how many were
+// answered with 304 Not Modified
+func (h *HTTPCache) Stats() (fresh, notModified int) {
+	return int(h.fresh.Load()), int(h.notModified.Load())
+}
+
+// freshnessFromHeaders derives the freshness lifetime of a response
+// Returns storable=false for no-store responses; a zero time means the
+// response must be revalidated before reuse
+func freshnessFromHeaders(header http.Header, now time.Time) (time.Time, bool) {
+	directives := parseCacheControl(header.Get("Cache-Control"))
+
+	if _, ok := directives["no-store"]; ok {
+		return time.Time{}, false
+	}
+	if _, ok := directives["no-cache"]; ok {
+		return time.Time{}, true
+	}
+
+	// Age already spent in intermediate caches counts against the lifetime
+	age, _ := strconv.Atoi(header.Get("Age"))
+
+	// s-maxage only applies to shared caches; the crawler is a private client
+	if value, ok := directives["max-age"]; ok {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds-age <= 0 {
+			return time.Time{}, true
+		}
+		return now.Add(time.Duration(seconds-age) * time.Second), true
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return time.Time{}, true
+		}
+		// Measure the lifetime against the server clock when possible
+		if date, err := http.ParseTime(header.Get("Date")); err == nil {
+			return now.Add(expiresAt.Sub(date)), true
+		}
+		return expiresAt, true
+	}
+
+	// No explicit freshness: validators only, no heuristic lifetime
+	return time.Time{}, true
+}
+
+// parseCacheControl splits a Cache-Control header into lowercase directives
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+		directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+	}
+	return directives
+}
@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"maps"
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// anyLanguage is the language_quotas key shared by languages without an
+// entry of their own
+const anyLanguage = "*"
+
+// LanguageQuota caps how many domains are crawled per declared language
+// A domain's language is only known once its front page is fetched, so
+// quotas act on what is known: links found on pages of a language whose
+// quota is spent are recorded but not enqueued, and domains of a known
+// language are not fetched once its quota is spent. Domains of a language
+// predicted wrong may still overshoot it
+// Counts cover the current run, like fan-out limits
+type LanguageQuota struct {
+	quotas map[string]int // language or anyLanguage -> max domains; -1 unlimited
+
+	mu        sync.Mutex
+	counts    map[string]int  // by quota key
+	languages map[string]int  // by declared language, for metrics
+	counted   map[string]bool // domains already counted this run
+	reached   map[string]bool // quota keys whose limit has been logged
+}
+
+// NewLanguageQuota creates a quota; an empty quotas map only tallies
+// languages
+func NewLanguageQuota(quotas map[string]int) *LanguageQuota {
+	q := &LanguageQuota{
+		quotas:    make(map[string]int, len(quotas)),
+		counts:    make(map[string]int),
+		languages: make(map[string]int),
+		counted:   make(map[string]bool),
+		reached:   make(map[string]bool),
+	}
+	for language, quota := range quotas {
+		q.quotas[strings.ToLower(language)] = quota
+	}
+	return q
+}
+
+// key returns the quota a language counts against, or "" if unlimited
+func (q *LanguageQuota) key(language string) string {
+	key := language
+	quota, ok := q.quotas[key]
+	if !ok {
+		key = anyLanguage
+		quota, ok = q.quotas[key]
+	}
+	if !ok || quota < 0 {
+		return ""
+	}
+	return key
+}
+
+// Count records the crawl of domain's front page in language, once per
+// domain per run
+func (q *LanguageQuota) Count(domain, language string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.counted[domain] {
+		return
+	}
+	q.counted[domain] = true
+	q.languages[language]++
+
+	key := q.key(language)
+	if key == "" {
+		return
+	}
+	q.counts[key]++
+	if q.counts[key] >= q.quotas[key] && !q.reached[key] {
+		q.reached[key] = true
+		logrus.Infof("Language quota reached: %d domains crawled for %q", q.quotas[key], key)
+	}
+}
+
+// Admits reports whether domain may be crawled as a domain of language:
+// true for domains already counted this run and unknown ("") languages,
+// false once the language's quota is spent
+func (q *LanguageQuota) Admits(domain, language string) bool {
+	if language == "" {
+		return true
+	}
+	key := q.key(language)
+	if key == "" {
+		return true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.counted[domain] || q.counts[key] < q.quotas[key]
+}
+
+// Languages returns how many domains were crawled per declared language
+func (q *LanguageQuota) Languages() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return maps.Clone(q.languages)
+}
+
+// pageLanguage returns the primary subtag of the language a page declares
+// in <html lang>, a Content-Language meta tag, or the Content-Language
+// header, e.g. "en" for "en-US"; "" if none is declared
+func pageLanguage(e *colly.HTMLElement) string {
+	declared := e.Attr("lang")
+	if declared == "" {
+		declared = e.ChildAttr(`meta[http-equiv="content-language" i]`, "content")
+	}
+	return primaryLanguage(declared, e.Response)
+}
+
+// primaryLanguage returns the primary subtag of the language declared in
+// the page, else in r's Content-Language header; "" if none is valid
+func primaryLanguage(declared string, r *colly.Response) string {
+	if declared == "" && r.Headers != nil {
+		declared = r.Headers.Get("Content-Language")
+	}
+
+	// A header may list several languages; the first is the primary one
+	declared, _, _ = strings.Cut(declared, ",")
+	primary, _, _ := strings.Cut(strings.TrimSpace(declared), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	primary = strings.ToLower(primary)
+	if len(primary) < 2 || len(primary) > 3 || strings.Trim(primary, "abcdefghijklmnopqrstuvwxyz") != "" {
+		return ""
+	}
+	return primary
+}
+
+// setLanguage records the language declared by domain's front page
+func (c *Crawler) setLanguage(domain, language string) {
+	if err := c.memGraph.SetLanguage(domain, language); err != nil {
+		logrus.Warnf("Failed to update language of %s: %v", domain, err)
+		return
+	}
+	c.languages.Count(domain, language)
+}
+
+// languageOf returns the known language of domain, or "" if unknown
+func (c *Crawler) languageOf(domain string) string {
+	node, err := c.memGraph.GetNode(domain)
+	if err != nil || node == nil {
+		return ""
+	}
+	return node.Language
+}
+
+// Languages returns how many domains were crawled per declared language
+func (c *Crawler) Languages() map[string]int {
+	return c.languages.Languages()
+}
@@ -0,0 +1,107 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// latencyWeight is the EWMA weight given to each new sample
+	latencyWeight = 0.3
+
+	// minLatencySamples is how many fetches a host needs before it can be
+	// judged slow, so one bad response doesn't penalize it
+	minLatencySamples = 3
+
+	// slowHostPenaltyFactor spaces a slow host's fetches by this multiple of
+	// its average latency, as in Mercator
+	slowHostPenaltyFactor = 10
+)
+
+// HostLatency tracks a rolling average fetch latency per host (root domain)
+// and flags hosts that are consistently close to the request timeout
+type HostLatency struct {
+	threshold time.Duration // zero disables slow-host detection
+
+	mu    sync.Mutex
+	hosts map[string]*hostStats
+}
+
+// hostStats is the rolling latency of one host
+type hostStats struct {
+	average time.Duration
+	samples int
+	slow    bool
+}
+
+// NewHostLatency creates a tracker flagging hosts whose average latency
+// reaches threshold
+func NewHostLatency(threshold time.Duration) *HostLatency {
+	return &HostLatency{
+		threshold: threshold,
+		hosts:     make(map[string]*hostStats),
+	}
+}
+
+// Observe records how long a fetch from domain took
+func (h *HostLatency) Observe(domain string, latency time.Duration) {
+	if h.threshold <= 0 {
+		return
+	}
+
+	host := ExtractRootDomain(domain)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats, ok := h.hosts[host]
+	if !ok {
+		stats = &hostStats{average: latency}
+		h.hosts[host] = stats
+	} else {
+		stats.average = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(stats.average))
+	}
+	stats.samples++
+
+	slow := stats.samples >= minLatencySamples && stats.average >= h.threshold
+	if slow != stats.slow {
+		if slow {
+			logrus.Warnf("Slow host %s (avg %v over %d fetches) - deprioritizing", host, stats.average.Round(time.Millisecond), stats.samples)
+		} else {
+			logrus.Infof("Host %s recovered (avg %v) - restoring priority", host, stats.average.Round(time.Millisecond))
+		}
+		stats.slow = slow
+	}
+}
+
+// Penalty returns the extra gap to leave before fetching from domain again
+func (h *HostLatency) Penalty(domain string) time.Duration {
+	if h.threshold <= 0 {
+		return 0
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats, ok := h.hosts[ExtractRootDomain(domain)]
+	if !ok || !stats.slow {
+		return 0
+	}
+	return slowHostPenaltyFactor * stats.average
+}
+
+// SlowHosts returns how many hosts are currently flagged as slow
+func (h *HostLatency) SlowHosts() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count := 0
+	for _, stats := range h.hosts {
+		if stats.slow {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,138 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// linkStatsKey is the colly request context key holding a page's pageLinks
+const linkStatsKey = "link_stats"
+
+// pageLinks accumulates outbound link statistics and candidate targets while
+// a page is parsed
+// Colly runs a response's HTML callbacks sequentially, so no locking is needed
+type pageLinks struct {
+	sourceRoot      string
+	stats           storage.LinkStats
+	externalDomains map[string]bool
+	targets         []string // followable target domains in DOM order
+	seenTargets     map[string]bool
+	anchors         map[string]linkAnchor // target domain -> its first link with text or rel
+	pages           []string              // same-host page URLs in DOM order, for url crawl mode
+	seenPages       map[string]bool
+}
+
+// linkAnchor is the text and rel attribute of a link
+type linkAnchor struct {
+	text, rel string
+}
+
+// pageLinksPool recycles accumulators, whose maps and slices would
+// otherwise be reallocated and grown for every fetched page
+var pageLinksPool = sync.Pool{
+	New: func() any {
+		return &pageLinks{
+			externalDomains: make(map[string]bool),
+			seenTargets:     make(map[string]bool),
+			anchors:         make(map[string]linkAnchor),
+			seenPages:       make(map[string]bool),
+		}
+	},
+}
+
+// newPageLinks returns an accumulator for a page served by sourceDomain
+func newPageLinks(sourceDomain string) *pageLinks {
+	p := pageLinksPool.Get().(*pageLinks)
+	p.sourceRoot = ExtractRootDomain(sourceDomain)
+	return p
+}
+
+// release resets p and returns it to the pool; p, and the slices it
+// returned, must not be used afterwards
+func (p *pageLinks) release() {
+	p.sourceRoot = ""
+	p.stats = storage.LinkStats{}
+	clear(p.externalDomains)
+	clear(p.seenTargets)
+	clear(p.anchors)
+	clear(p.seenPages)
+	clear(p.targets)
+	p.targets = p.targets[:0]
+	clear(p.pages)
+	p.pages = p.pages[:0]
+	pageLinksPool.Put(p)
+}
+
+// Add classifies an absolute link; non-http(s) links are ignored
+func (p *pageLinks) Add(link string) {
+	parsed, err := url.Parse(link)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return
+	}
+
+	p.stats.Total++
+
+	// The host ExtractDomain would return, without parsing the link again
+	domain := strings.ToLower(parsed.Hostname())
+	if ExtractRootDomain(domain) == p.sourceRoot {
+		p.stats.Internal++
+		return
+	}
+
+	p.stats.External++
+	if !p.externalDomains[domain] {
+		p.externalDomains[domain] = true
+		p.stats.ExternalDomains++
+	}
+}
+
+// AddTarget records a followable target domain, ignoring repeats, with the
+// text and rel attribute of the first link to it that has either
+func (p *pageLinks) AddTarget(domain, text, rel string) {
+	if !p.seenTargets[domain] {
+		p.seenTargets[domain] = true
+		p.targets = append(p.targets, domain)
+	}
+	if _, set := p.anchors[domain]; !set && (text != "" || rel != "") {
+		p.anchors[domain] = linkAnchor{text, rel}
+	}
+}
+
+// Anchor returns the text and rel attribute recorded for a target domain
+func (p *pageLinks) Anchor(domain string) (text, rel string) {
+	a := p.anchors[domain]
+	return a.text, a.rel
+}
+
+// AddPage records an absolute link to another page on host, the one that
+// served the page, without its fragment, ignoring repeats and other hosts
+func (p *pageLinks) AddPage(link, host string) {
+	parsed, err := url.Parse(link)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || strings.ToLower(parsed.Hostname()) != host {
+		return
+	}
+	parsed.Fragment, parsed.RawFragment = "", ""
+	page := parsed.String()
+	if !p.seenPages[page] {
+		p.seenPages[page] = true
+		p.pages = append(p.pages, page)
+	}
+}
+
+// Pages returns the distinct same-host page URLs in DOM order
+func (p *pageLinks) Pages() []string {
+	return p.pages
+}
+
+// Targets returns the distinct target domains in DOM order
+func (p *pageLinks) Targets() []string {
+	return p.targets
+}
+
+// Stats returns the statistics gathered so far
+func (p *pageLinks) Stats() storage.LinkStats {
+	return p.stats
+}
@@ -0,0 +1,90 @@
+package crawler
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// High-frequency log events subject to sampling, in summary order
+const (
+	logScheduled = iota
+	logFetched
+	logRevalidated
+	logFresh
+	logEdge
+	logTooRecent
+	logEventKinds
+)
+
+// logEventNames labels each event kind in interval summaries
+var logEventNames = [logEventKinds]string{"scheduled", "fetched", "revalidated", "fresh", "edges", "fetched too recently"}
+
+// LogSampler keeps Info logs readable on fast crawls: only every Nth
+// high-frequency event is logged at Info, the rest at Debug, and an
+// optional summary counts every event per interval
+type LogSampler struct {
+	every    uint64        // log one in every events of a kind at Info; 0 logs none
+	interval time.Duration // summary interval; 0 disables summaries
+
+	seen    [logEventKinds]atomic.Uint64 // events since start, for sampling
+	pending [logEventKinds]atomic.Int64  // events since the last summary
+}
+
+// NewLogSampler creates a sampler logging the given fraction of events at
+// Info; a rate of 1 logs every event, a negative one none
+func NewLogSampler(rate float64, interval time.Duration) *LogSampler {
+	every := uint64(1)
+	switch {
+	case rate < 0:
+		every = 0
+	case rate > 0 && rate < 1:
+		every = uint64(math.Round(1 / rate))
+	}
+	return &LogSampler{every: every, interval: interval}
+}
+
+// Infof logs an event of the given kind at Info if it is sampled, at Debug
+// otherwise
+func (s *LogSampler) Infof(kind int, format string, args ...any) {
+	s.pending[kind].Add(1)
+	if n := s.seen[kind].Add(1); s.every > 0 && (n-1)%s.every == 0 {
+		logrus.Infof(format, args...)
+		return
+	}
+	if logrus.IsLevelEnabled(logrus.DebugLevel) {
+		logrus.Debugf(format, args...)
+	}
+}
+
+// Run logs a summary of the events seen every interval until stop is closed
+func (s *LogSampler) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if summary := s.summary(); summary != "" {
+				logrus.Infof("Last %s: %s", s.interval, summary)
+			}
+		}
+	}
+}
+
+// summary formats and resets the per-interval counts; "" if nothing happened
+func (s *LogSampler) summary() string {
+	var parts []string
+	for kind := range logEventKinds {
+		if n := s.pending[kind].Swap(0); n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, logEventNames[kind]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,138 @@
+package crawler
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// innerPageKey is the colly request context key marking the fetch of an
+// inner page queued in url crawl mode
+const innerPageKey = "inner_page"
+
+// PageBudget admits the inner pages of domains in url crawl mode: each page
+// once per run, and at most max pages per domain, front page included
+type PageBudget struct {
+	max int // 0 in domain crawl mode, admitting nothing
+
+	mu    sync.Mutex
+	pages map[string]map[string]bool // domain -> admitted page keys
+}
+
+// NewPageBudget creates the budget for cfg's crawl mode
+func NewPageBudget(cfg *config.Config) *PageBudget {
+	b := &PageBudget{pages: make(map[string]map[string]bool)}
+	if cfg.CrawlMode == config.CrawlModeURL {
+		b.max = cfg.MaxPagesPerDomain
+	}
+	return b
+}
+
+// Enabled reports whether inner pages are crawled
+func (b *PageBudget) Enabled() bool {
+	return b.max > 0
+}
+
+// Admit reserves a fetch of pageURL for domain, reporting false if the page
+// was already admitted or the domain's budget is spent
+func (b *PageBudget) Admit(domain, pageURL string) bool {
+	if !b.Enabled() {
+		return false
+	}
+	key := pageKey(pageURL)
+	if key == "" {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pages := b.pages[domain]
+	if pages == nil {
+		pages = make(map[string]bool)
+		b.pages[domain] = pages
+	}
+	if pages[key] || len(pages) >= b.max {
+		return false
+	}
+	pages[key] = true
+	return true
+}
+
+// pageKey identifies a page regardless of scheme, host case, and fragment,
+// or returns "" for links that aren't http(s)
+func pageKey(pageURL string) string {
+	parsed, err := url.Parse(pageURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return ""
+	}
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	key := strings.ToLower(parsed.Host) + path
+	if parsed.RawQuery != "" {
+		key += "?" + parsed.RawQuery
+	}
+	return key
+}
+
+// innerPage reports whether ctx belongs to the fetch of an inner page
+func innerPage(ctx *colly.Context) bool {
+	return ctx != nil && ctx.Get(innerPageKey) != ""
+}
+
+// queueInnerPages queues the same-host pages linked from a page of entry's
+// domain, within the domain's page budget. They keep the domain's depth,
+// as the graph's depth counts hops between domains
+func (c *Crawler) queueInnerPages(entry *storage.QueueEntry, pages []string) {
+	if c.frontierClosed.Load() {
+		return
+	}
+	for _, page := range pages {
+		inner := storage.QueueEntry{
+			NodeID:     entry.NodeID,
+			DomainName: entry.DomainName,
+			URL:        page,
+			Depth:      entry.Depth,
+		}
+		if !c.pages.Admit(entry.DomainName, page) {
+			continue
+		}
+		c.prioritize(&inner)
+		if !c.throttle.Hold(inner) {
+			c.frontier.Push(inner)
+		}
+	}
+}
+
+// fetchInnerPage schedules the fetch of an inner page popped by worker id;
+// it is a page fetch, but not a crawl of the node, whose front page already
+// passed the crawl count and re-fetch checks
+func (c *Crawler) fetchInnerPage(id int, entry storage.QueueEntry) {
+	c.incrementInFlight()
+
+	fetchCtx := c.workers.StartFetch(id, entry.DomainName, entry.URL)
+	c.trackFetch(fetchCtx, entry, entry.URL)
+	fetchCtx.Put(innerPageKey, "1")
+	if err := c.request(entry, entry.URL, fetchCtx); err != nil {
+		logrus.Debugf("Worker %d: visit failed for %s: %v", id, entry.URL, err)
+		// A failed inner page says nothing about the domain, so its
+		// status is left alone
+		var visited *colly.AlreadyVisitedError
+		if errors.As(err, &visited) {
+			c.settleFetch(fetchCtx, nil)
+		} else {
+			c.settleFetch(fetchCtx, err)
+			c.errorLog.Record(entry.DomainName, entry.URL, c.attemptOf(entry.DomainName), 0, err)
+		}
+		return
+	}
+	c.logSampler.Infof(logScheduled, "Worker %d: scheduled visit to %s (depth=%d)", id, entry.URL, entry.Depth)
+}
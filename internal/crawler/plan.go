@@ -0,0 +1,283 @@
+package crawler
+
+import (
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// Where a planned run starts from, mirroring startup
+const (
+	PlanFromQueue  = "saved queue"
+	PlanFromResume = "resumable nodes"
+	PlanFromSeed   = "seeds"
+)
+
+// planPageSize is how many nodes the planner reads per query
+const planPageSize = 5000
+
+// Plan estimates the fetches a run would perform, derived from the stored
+// graph without touching the network
+type Plan struct {
+	Start   string // one of the PlanFrom* values
+	Entries int    // queue entries the run would start with
+
+	Fetches int            // estimated page fetches
+	ByDepth []int          // fetches per depth, 0 through max_depth
+	ByRoot  map[string]int // fetches per root domain
+	// Fetches of pages never crawled, whose links are unknown; the real
+	// run discovers more than the plan from each of them
+	Unexplored int
+
+	// Candidates the run would pass over, by reason
+	AtMaxCrawls  int // crawled max_crawls_per_node times already
+	Filtered     int // TLD, domain filter, or blocklist
+	SubdomainCap int // over max_subdomains_per_root
+	FanOutCap    int // over depth_fanout_limits
+	TooRecent    int // within min_refetch_interval_sec of their last fetch
+	BudgetCapped bool
+}
+
+// planNode is what the planner knows about a stored node
+type planNode struct {
+	id        int
+	domain    string
+	remaining int // fetches left before max_crawls_per_node
+	explored  bool
+}
+
+// PlanCrawl walks the stored graph from the entries the next run would
+// start with, breadth-first like the frontier, applying the configured
+// depth, crawl, filter, subdomain, fan-out, refetch, link, and node
+// budget limits. Known out-links stand in for the pages the run would
+// fetch; pages never crawled end the walk and are counted as unexplored
+func PlanCrawl(cfg *config.Config, store *storage.Storage) (*Plan, error) {
+	nodes, byID, err := loadPlanNodes(store, cfg.MaxCrawlsPerNode)
+	if err != nil {
+		return nil, err
+	}
+	links, err := store.OutLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := store.ListBlockedDomains()
+	if err != nil {
+		return nil, err
+	}
+	blocklist := NewBlocklist()
+	for _, b := range blocked {
+		blocklist.Add(b.Domain)
+	}
+	tlds := NewTLDFilter(cfg.AllowedTLDs, cfg.BlockedTLDs)
+	filter := NewDomainFilter(cfg.ExcludeRules, cfg.IncludeRules)
+	fanOut := NewFanOutLimiter(cfg.DepthFanOutLimits)
+	limiter := NewSubdomainLimiter(cfg.MaxSubdomainsPerRoot)
+	if platforms := NewPlatforms(cfg.PlatformMode, cfg.Platforms); platforms.Enabled() {
+		limiter.SetRootFunc(platforms.Root)
+	}
+	subdomains, err := store.LoadSubdomains()
+	if err != nil {
+		return nil, err
+	}
+	limiter.Restore(subdomains)
+
+	refetch := NewRefetchGuard(time.Duration(cfg.MinRefetchIntervalSec) * time.Second)
+	if refetch.Interval() > 0 {
+		recent, err := store.RecentFetches(time.Now().Add(-refetch.Interval()))
+		if err != nil {
+			return nil, err
+		}
+		refetch.Load(recent)
+	}
+
+	plan := &Plan{ByDepth: make([]int, cfg.MaxDepth+1), ByRoot: make(map[string]int)}
+	levels := make([][]*planNode, cfg.MaxDepth+1)
+	queued := make(map[visitKey]bool)
+	push := func(node *planNode, depth int) bool {
+		key := makeKey(node.domain, depth)
+		if queued[key] {
+			return false
+		}
+		limiter.Add(node.domain)
+		queued[key] = true
+		levels[min(depth, cfg.MaxDepth)] = append(levels[min(depth, cfg.MaxDepth)], node)
+		return true
+	}
+
+	start, err := planStart(cfg, store, nodes)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range start.entries {
+		push(entry.node, entry.depth)
+	}
+	plan.Start, plan.Entries = start.from, len(start.entries)
+
+	for depth := 0; depth <= cfg.MaxDepth; depth++ {
+		for i := 0; i < len(levels[depth]); i++ {
+			node := levels[depth][i]
+			if cfg.NodeBudget > 0 && plan.Fetches >= cfg.NodeBudget {
+				plan.BudgetCapped = true
+				return plan, nil
+			}
+
+			// The worker's checks when popping an entry
+			if node.remaining <= 0 {
+				plan.AtMaxCrawls++
+				continue
+			}
+			if blocklist.IsBlocked(node.domain) {
+				plan.Filtered++
+				continue
+			}
+			if ok, _ := refetch.Acquire(node.domain); !ok {
+				plan.TooRecent++
+				continue
+			}
+			node.remaining--
+			plan.Fetches++
+			plan.ByDepth[depth]++
+			plan.ByRoot[ExtractRootDomain(node.domain)]++
+
+			if !node.explored {
+				plan.Unexplored++
+				continue
+			}
+			if depth+1 > cfg.MaxDepth {
+				continue
+			}
+
+			// Link selection over the known targets, then enqueueing
+			followed := 0
+			seen := make(map[int]bool)
+			for _, targetID := range links[node.id] {
+				target := byID[targetID]
+				if followed >= cfg.MaxOutboundLinks {
+					break
+				}
+				// Edges of several types can point at the same target
+				if target == nil || target.domain == node.domain || seen[targetID] {
+					continue
+				}
+				seen[targetID] = true
+				if allowed, _ := tlds.Match(target.domain); !allowed || !filter.Allows(target.domain) || blocklist.IsBlocked(target.domain) {
+					plan.Filtered++
+					continue
+				}
+				if !limiter.CanAdd(target.domain) {
+					plan.SubdomainCap++
+					continue
+				}
+				followed++
+
+				if !fanOut.Reserve(depth + 1) {
+					plan.FanOutCap++
+					continue
+				}
+				if !push(target, depth+1) {
+					fanOut.Release(depth + 1)
+				}
+			}
+		}
+	}
+	return plan, nil
+}
+
+// plannedEntry is a node queued at a depth
+type plannedEntry struct {
+	node  *planNode
+	depth int
+}
+
+// planStartSet is where a planned run starts
+type planStartSet struct {
+	from    string
+	entries []plannedEntry
+}
+
+// planStart picks the entries the next run starts with, as startup does:
+// the saved queue, else the resumable nodes, else the seeds
+func planStart(cfg *config.Config, store *storage.Storage, nodes map[string]*planNode) (planStartSet, error) {
+	queue, err := store.LoadQueueEntries()
+	if err != nil {
+		return planStartSet{}, err
+	}
+	if len(queue) > 0 {
+		start := planStartSet{from: PlanFromQueue}
+		for _, entry := range queue {
+			// Inner pages of url crawl mode add no domains
+			if entry.URL != "" {
+				continue
+			}
+			start.entries = append(start.entries, plannedEntry{planNodeFor(nodes, entry.DomainName, cfg.MaxCrawlsPerNode), entry.Depth})
+		}
+		return start, nil
+	}
+
+	resumable, err := store.LoadResumableNodes(cfg.MaxCrawlsPerNode)
+	if err != nil {
+		return planStartSet{}, err
+	}
+	if len(resumable) > 0 {
+		start := planStartSet{from: PlanFromResume}
+		for _, node := range resumable {
+			start.entries = append(start.entries, plannedEntry{planNodeFor(nodes, node.DomainName, cfg.MaxCrawlsPerNode), node.LastDepth})
+		}
+		return start, nil
+	}
+
+	admits, err := SeedAdmission(cfg, store)
+	if err != nil {
+		return planStartSet{}, err
+	}
+	seeds, _, err := StartSeeds(cfg, admits)
+	if err != nil {
+		return planStartSet{}, err
+	}
+	start := planStartSet{from: PlanFromSeed}
+	for _, seed := range seeds {
+		node := planNodeFor(nodes, seed, cfg.MaxCrawlsPerNode)
+		// Startup resets the seeds' crawl counts
+		node.remaining = cfg.MaxCrawlsPerNode
+		start.entries = append(start.entries, plannedEntry{node, 0})
+	}
+	return start, nil
+}
+
+// planNodeFor returns the stored node for domain, or a new unexplored one
+func planNodeFor(nodes map[string]*planNode, domain string, maxCrawls int) *planNode {
+	if node, ok := nodes[domain]; ok {
+		return node
+	}
+	node := &planNode{domain: domain, remaining: maxCrawls}
+	nodes[domain] = node
+	return node
+}
+
+// loadPlanNodes reads the session's live nodes, keyed by domain and by ID
+func loadPlanNodes(store *storage.Storage, maxCrawls int) (map[string]*planNode, map[int]*planNode, error) {
+	byDomain := make(map[string]*planNode)
+	byID := make(map[int]*planNode)
+	for afterID := 0; ; {
+		page, err := store.ListNodes(storage.NodeFilter{}, afterID, planPageSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, n := range page {
+			node := &planNode{
+				id:        n.NodeID,
+				domain:    n.DomainName,
+				remaining: maxCrawls - n.CrawlCount,
+				explored:  n.Status == storage.NodeCrawled,
+			}
+			byDomain[node.domain] = node
+			byID[node.id] = node
+			afterID = n.NodeID
+		}
+		if len(page) < planPageSize {
+			return byDomain, byID, nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+)
+
+// PlateauDetector tracks how quickly new root domains are being found and
+// reports a plateau once fewer than minNew appear within a sliding window
+type PlateauDetector struct {
+	window time.Duration
+	minNew int
+
+	mu         sync.Mutex
+	start      time.Time
+	seenRoots  map[string]bool
+	recentHits []time.Time // discovery times of new roots, oldest first
+}
+
+// NewPlateauDetector creates a detector; a zero window disables it
+func NewPlateauDetector(window time.Duration, minNew int) *PlateauDetector {
+	return &PlateauDetector{
+		window:    window,
+		minNew:    minNew,
+		start:     time.Now(),
+		seenRoots: make(map[string]bool),
+	}
+}
+
+// Enabled reports whether a window is configured
+func (p *PlateauDetector) Enabled() bool {
+	return p.window > 0
+}
+
+// Observe records a discovered domain, counting it if its root is new
+func (p *PlateauDetector) Observe(domain string) {
+	if !p.Enabled() {
+		return
+	}
+
+	root := ExtractRootDomain(domain)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.seenRoots[root] {
+		return
+	}
+	p.seenRoots[root] = true
+	p.recentHits = append(p.recentHits, time.Now())
+}
+
+// Plateaued reports whether fewer than minNew roots were found during the
+// last window, along with how many were; never true before one full window
+// has elapsed
+func (p *PlateauDetector) Plateaued() (bool, int) {
+	if !p.Enabled() {
+		return false, 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-p.window)
+
+	// Drop discoveries that slid out of the window
+	keep := 0
+	for keep < len(p.recentHits) && !p.recentHits[keep].After(cutoff) {
+		keep++
+	}
+	p.recentHits = p.recentHits[keep:]
+
+	if now.Sub(p.start) < p.window {
+		return false, len(p.recentHits)
+	}
+	return len(p.recentHits) < p.minNew, len(p.recentHits)
+}
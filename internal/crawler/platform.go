@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"strings"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+)
+
+// Platforms aggregates hosts on multi-tenant platforms such as github.io,
+// whose subdomains belong to unrelated tenants. The regular subdomain
+// handling treats them as one site capped at max_subdomains_per_root, which
+// misrepresents both the platform and its tenants; platform_mode picks one
+// of the two views instead
+type Platforms struct {
+	mode     string
+	suffixes []string
+}
+
+// NewPlatforms creates the aggregation for mode (a config.PlatformMode*
+// value, or "" to disable it) over the given platform domains
+func NewPlatforms(mode string, suffixes []string) *Platforms {
+	return &Platforms{mode: mode, suffixes: suffixes}
+}
+
+// Enabled reports whether platform hosts are aggregated
+func (p *Platforms) Enabled() bool {
+	return p.mode != ""
+}
+
+// Split returns the platform domain is hosted on and its tenant host
+// (e.g. "github.io" and "alice.github.io" for docs.alice.github.io), or
+// empty strings when domain is not on a platform. The platform itself has
+// no tenant
+func (p *Platforms) Split(domain string) (platform, tenant string) {
+	for _, suffix := range p.suffixes {
+		if domain == suffix {
+			return suffix, ""
+		}
+		prefix, ok := strings.CutSuffix(domain, "."+suffix)
+		if !ok {
+			continue
+		}
+		return suffix, prefix[strings.LastIndex(prefix, ".")+1:] + "." + suffix
+	}
+	return "", ""
+}
+
+// Aggregate returns the node domain stands for: its platform in platform
+// mode, its tenant in tenant mode, and domain itself otherwise
+func (p *Platforms) Aggregate(domain string) string {
+	if !p.Enabled() {
+		return domain
+	}
+	platform, tenant := p.Split(domain)
+	switch {
+	case platform == "":
+		return domain
+	case p.mode == config.PlatformModePlatform || tenant == "":
+		return platform
+	default:
+		return tenant
+	}
+}
+
+// Root returns the root domain that max_subdomains_per_root counts domain
+// against: in tenant mode each tenant is a root of its own
+func (p *Platforms) Root(domain string) string {
+	if p.mode == config.PlatformModeTenant {
+		if _, tenant := p.Split(domain); tenant != "" {
+			return tenant
+		}
+	}
+	return ExtractRootDomain(domain)
+}
@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"math"
+	"slices"
+)
+
+// DepthPoliteness scales the politeness toward a host by the depth of the
+// entry fetched from it, so the seeds' own ecosystem can be crawled harder
+// than domains far from them. Each listed depth sets a multiplier for
+// itself and the depths below it, up to the next listed one
+// A multiplier scales politeness_delay_ms and politeness_jitter_ms; one
+// below 1 also lets round(1/multiplier) entries of the host be fetched at
+// once, back to back, before the delay applies
+type DepthPoliteness struct {
+	depths      []int     // listed depths, ascending
+	multipliers []float64 // by position in depths
+}
+
+// NewDepthPoliteness creates a scaler from a depth -> multiplier table; an
+// empty table leaves politeness unscaled
+func NewDepthPoliteness(table map[int]float64) *DepthPoliteness {
+	p := &DepthPoliteness{}
+	for depth := range table {
+		p.depths = append(p.depths, depth)
+	}
+	slices.Sort(p.depths)
+	for _, depth := range p.depths {
+		p.multipliers = append(p.multipliers, table[depth])
+	}
+	return p
+}
+
+// Multiplier returns the multiplier for an entry at depth: that of the
+// deepest listed depth not deeper than it, 1 if there is none
+func (p *DepthPoliteness) Multiplier(depth int) float64 {
+	if p == nil {
+		return 1
+	}
+	i, found := slices.BinarySearch(p.depths, depth)
+	if !found {
+		i--
+	}
+	if i < 0 {
+		return 1
+	}
+	return p.multipliers[i]
+}
+
+// Concurrency returns how many entries of a host may be fetched back to
+// back when the next one is at depth
+func (p *DepthPoliteness) Concurrency(depth int) int {
+	m := p.Multiplier(depth)
+	if m >= 1 {
+		return 1
+	}
+	return max(1, int(math.Round(1/m)))
+}
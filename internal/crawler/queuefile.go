@@ -0,0 +1,82 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// queueFileHeader is the first line of a written queue file
+const queueFileHeader = "domain,depth,priority"
+
+// ParseQueueFile reads a crawl plan in the queue file format: CSV lines of
+// domain,depth,priority where depth and priority are optional (default 0)
+// and the domain may also be a URL. Entries keep file order; repeats of a
+// domain at the same depth are dropped
+// Blank lines, # comments, and lines without a domain (e.g. the header)
+// are skipped and counted; a malformed depth or priority is an error
+func ParseQueueFile(r io.Reader) (entries []storage.QueueEntry, skipped int, err error) {
+	seen := make(map[visitKey]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, ",")
+		for i := range fields {
+			fields[i] = strings.Trim(strings.TrimSpace(fields[i]), `"`)
+		}
+		if len(fields) > 3 {
+			return nil, skipped, fmt.Errorf("line %d: expected domain,depth,priority", line)
+		}
+
+		domain := seedHost(fields[0])
+		if domain == "" {
+			skipped++
+			continue
+		}
+		entry := storage.QueueEntry{DomainName: domain}
+		if len(fields) > 1 && fields[1] != "" {
+			if entry.Depth, err = strconv.Atoi(fields[1]); err != nil || entry.Depth < 0 {
+				return nil, skipped, fmt.Errorf("line %d: invalid depth %q", line, fields[1])
+			}
+		}
+		if len(fields) > 2 && fields[2] != "" {
+			if entry.Priority, err = strconv.ParseFloat(fields[2], 64); err != nil {
+				return nil, skipped, fmt.Errorf("line %d: invalid priority %q", line, fields[2])
+			}
+		}
+
+		if key := makeKey(domain, entry.Depth); !seen[key] {
+			seen[key] = true
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, skipped, fmt.Errorf("failed to read queue file: %w", err)
+	}
+	return entries, skipped, nil
+}
+
+// WriteQueueFile writes entries in the queue file format, header first
+func WriteQueueFile(w io.Writer, entries []storage.QueueEntry) error {
+	if _, err := fmt.Fprintln(w, queueFileHeader); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		priority := strconv.FormatFloat(entry.Priority, 'g', -1, 64)
+		if _, err := fmt.Fprintf(w, "%s,%d,%s\n", entry.DomainName, entry.Depth, priority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
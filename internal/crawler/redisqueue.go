@@ -0,0 +1,682 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// redisScanRoots bounds the hosts of each depth a pop looks at; a host
+	// found cooling down leaves the ready set until it may be fetched, so
+	// it is looked at once per politeness delay
+	redisScanRoots = 64
+
+	// redisPollInterval is how long Pop waits before looking again when
+	// every host is cooling down or the queue is empty; other processes'
+	// pushes can't wake it
+	redisPollInterval = 200 * time.Millisecond
+
+	// redisRetryInterval spaces attempts, and warnings, while Redis fails
+	redisRetryInterval = time.Second
+
+	// redisBusyTTL is how long a process counts as busy after it last said
+	// so, which bounds how long a crashed one holds back the others' end
+	redisBusyTTL = 10 * time.Second
+
+	// redisLeaseTTL is how long a popped entry stays leased to its process
+	// after the process last renewed its leases; then any pop requeues it
+	redisLeaseTTL = 30 * time.Second
+)
+
+// redisLib holds the functions the scripts share. Keys are built from the
+// prefix in ARGV[1], so a shared queue needs one Redis server, not a
+// cluster
+//
+// Each depth d keeps an entry queue per root, queue:<d>:<root>, and a ready
+// set, ready:<d>, of the roots not cooling down, scored by their first
+// entry and ordered on by its arrival number; heads:<d> maps each ready
+// root to its member there. Roots found cooling down wait in cooling:<d>,
+// scored by when they may be fetched
+const redisLib = `
+local function promote(prefix, d, root)
+	local heads = prefix .. 'heads:' .. d
+	local old = redis.call('HGET', heads, root)
+	if old then
+		redis.call('ZREM', prefix .. 'ready:' .. d, old)
+	end
+	local head = redis.call('ZRANGE', prefix .. 'queue:' .. d .. ':' .. root, 0, 0, 'WITHSCORES')
+	if #head == 0 then
+		redis.call('HDEL', heads, root)
+		return
+	end
+	local member = string.sub(head[1], 1, 16) .. '\t' .. root
+	redis.call('ZADD', prefix .. 'ready:' .. d, head[2], member)
+	redis.call('HSET', heads, root, member)
+end
+
+local function enqueue(prefix, d, root, score, member)
+	redis.call('ZADD', prefix .. 'queue:' .. d .. ':' .. root, score, member)
+	redis.call('INCR', prefix .. 'size')
+	if not redis.call('ZSCORE', prefix .. 'cooling:' .. d, root) then
+		promote(prefix, d, root)
+	end
+end
+
+local function reclaim(prefix, now, maxDepth)
+	for _, process in ipairs(redis.call('SMEMBERS', prefix .. 'leases')) do
+		local key = prefix .. 'lease:' .. process
+		for _, member in ipairs(redis.call('ZRANGEBYSCORE', key, '-inf', now)) do
+			local root, fetch, data = string.match(member, '^[^\t]*\t([^\t]*)\t([^\t]*)\t(.*)$')
+			local entry = cjson.decode(data)
+			redis.call('ZREM', key, member)
+			if fetch ~= '' then
+				redis.call('SREM', prefix .. 'fetched', fetch)
+			end
+			local d = math.min(math.max(entry.n or 0, 0), maxDepth)
+			enqueue(prefix, string.format('%d', d), root, -(entry.p or 0), member)
+		end
+		if redis.call('EXISTS', key) == 0 then
+			redis.call('SREM', prefix .. 'leases', process)
+		end
+	end
+end
+`
+
+// redisPush adds an entry to its root's queue at its depth unless its visit
+// key is already in the dedup set; an empty visit key skips the check.
+// Members are the arrival number, host, fetch key and encoded entry,
+// tab-separated
+// ARGV: key prefix, visit key, depth, score, host, fetch key, encoded entry
+var redisPush = redis.NewScript(redisLib + `
+local prefix = ARGV[1]
+if ARGV[2] ~= '' and redis.call('SADD', prefix .. 'visited', ARGV[2]) == 0 then
+	return 0
+end
+local seq = redis.call('INCR', prefix .. 'seq')
+enqueue(prefix, ARGV[3], ARGV[5], ARGV[4], string.format('%016d', seq) .. '\t' .. ARGV[5] .. '\t' .. ARGV[6] .. '\t' .. ARGV[7])
+return 1
+`)
+
+// redisPop first requeues the entries of expired leases, then removes the
+// first entry, shallowest depth first, of a ready host: it claims the host
+// for the politeness delay, leases the entry to the process, and marks the
+// process busy. Ready hosts found cooling down move to the cooling set
+// Entries whose page some process already fetched are dropped on the way,
+// as a collector refuses revisits within one process
+// ARGV: key prefix, max depth, now (unix milliseconds), claim in
+// milliseconds, hosts scanned per depth, process, busy until, lease until
+var redisPop = redis.NewScript(redisLib + `
+local prefix, maxDepth, now, claim = ARGV[1], tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+reclaim(prefix, now, maxDepth)
+for d = 0, maxDepth do
+	local ready, cooling, heads = prefix .. 'ready:' .. d, prefix .. 'cooling:' .. d, prefix .. 'heads:' .. d
+	for _, root in ipairs(redis.call('ZRANGEBYSCORE', cooling, '-inf', now)) do
+		redis.call('ZREM', cooling, root)
+		promote(prefix, d, root)
+	end
+	for _ = 1, tonumber(ARGV[5]) do
+		local head = redis.call('ZRANGE', ready, 0, 0)[1]
+		if not head then
+			break
+		end
+		local root = string.sub(head, 18)
+		local queue = prefix .. 'queue:' .. d .. ':' .. root
+		local wait = redis.call('PTTL', prefix .. 'host:' .. root)
+		local member = redis.call('ZRANGE', queue, 0, 0)[1]
+		if wait ~= -2 or not member then
+			redis.call('ZREM', ready, head)
+			redis.call('HDEL', heads, root)
+			if member then
+				if wait < 0 then
+					wait = claim
+				end
+				redis.call('ZADD', cooling, now + wait, root)
+			end
+		else
+			redis.call('ZREM', queue, member)
+			redis.call('DECR', prefix .. 'size')
+			local fetch = string.match(member, '^[^\t]*\t[^\t]*\t([^\t]*)\t')
+			if fetch ~= '' and redis.call('SISMEMBER', prefix .. 'fetched', fetch) == 1 then
+				promote(prefix, d, root)
+			else
+				if fetch ~= '' then
+					redis.call('SADD', prefix .. 'fetched', fetch)
+				end
+				redis.call('SET', prefix .. 'host:' .. root, '1', 'PX', claim)
+				redis.call('ZREM', ready, head)
+				redis.call('HDEL', heads, root)
+				if redis.call('EXISTS', queue) == 1 then
+					redis.call('ZADD', cooling, now + claim, root)
+				end
+				redis.call('ZADD', prefix .. 'busy', ARGV[7], ARGV[6])
+				redis.call('ZADD', prefix .. 'lease:' .. ARGV[6], ARGV[8], member)
+				redis.call('SADD', prefix .. 'leases', ARGV[6])
+				return member
+			end
+		end
+	end
+end
+return false
+`)
+
+// redisRenew extends every lease of a process
+// KEYS: the process's lease set
+// ARGV: lease until (unix milliseconds)
+var redisRenew = redis.NewScript(`
+for _, member in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+	redis.call('ZADD', KEYS[1], 'XX', ARGV[1], member)
+end
+return 0
+`)
+
+// redisLeased counts the entries leased to any process
+// ARGV: key prefix
+var redisLeased = redis.NewScript(`
+local leased = 0
+for _, process in ipairs(redis.call('SMEMBERS', ARGV[1] .. 'leases')) do
+	leased = leased + redis.call('ZCARD', ARGV[1] .. 'lease:' .. process)
+end
+return leased
+`)
+
+// RedisQueue is a frontier kept in Redis, so processes on several machines
+// crawl one frontier with one dedup set
+//
+// Each depth queues entries per host, ordered by priority, then arrival,
+// and each host fetched holds a key that expires with its politeness
+// delay, so hosts stay polite across processes. The subdomain limiter and
+// slow-host penalties stay per process, and a process's Size and IsEmpty
+// report the shared queue. A popped entry stays leased to its process
+// until Done; the process renews its leases while it runs, and those of a
+// process that stopped renewing them go back to the queue
+//
+// Keys, under the configured prefix: visited (dedup set), fetched (pages
+// popped, so no two processes fetch one), seq (arrival counter), size
+// (entries queued), queue:<depth>:<root domain>, ready:<depth>,
+// heads:<depth>, cooling:<depth> (see redisLib), host:<root domain>, busy
+// (processes working on popped entries, by expiry), lease:<process>
+// (entries popped, by expiry), and leases (processes holding leases)
+type RedisQueue struct {
+	client   *redis.Client
+	prefix   string
+	process  string // member of the busy set: host name and process ID
+	maxDepth int
+	delay    time.Duration
+	jitter   time.Duration
+	latency  *HostLatency
+
+	mu         sync.Mutex
+	politeness *DepthPoliteness
+	leased     map[redisEntry][]string // members popped and not yet done
+
+	stopped  atomic.Bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	lastWarn atomic.Int64 // unix nanoseconds of the last logged failure
+
+	limiter *SubdomainLimiter
+}
+
+// redisEntry is a queue entry as stored in Redis; node IDs are local to
+// each process's database, so they are left out
+type redisEntry struct {
+	Domain   string  `json:"d"`
+	URL      string  `json:"u,omitempty"`
+	Depth    int     `json:"n"`
+	Priority float64 `json:"p,omitempty"`
+	Retries  int     `json:"r,omitempty"`
+}
+
+// redisLogger sends the client's own messages, such as each failed dial,
+// to the debug log; failures reach the log through RedisQueue.warn
+type redisLogger struct{}
+
+func (redisLogger) Printf(ctx context.Context, format string, v ...any) {
+	logrus.Debugf("redis: "+format, v...)
+}
+
+// NewRedisQueue creates a frontier on the Redis server of cfg with priority
+// levels 0..maxDepth; it connects on first use (see Ping)
+func NewRedisQueue(cfg config.QueueRedis, maxDepth int, politenessDelay, politenessJitter time.Duration, maxSubdomainsPerRoot int, latency *HostLatency) *RedisQueue {
+	redis.SetLogger(redisLogger{})
+	q := &RedisQueue{
+		client: redis.NewClient(&redis.Options{
+			Addr:     cfg.Addr,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}),
+		prefix:   cfg.KeyPrefix + ":",
+		process:  processName(),
+		maxDepth: maxDepth,
+		delay:    politenessDelay,
+		jitter:   politenessJitter,
+		latency:  latency,
+		leased:   make(map[redisEntry][]string),
+		stop:     make(chan struct{}),
+		limiter:  NewSubdomainLimiter(maxSubdomainsPerRoot),
+	}
+	go q.renewLeases()
+	return q
+}
+
+// Ping checks that the Redis server answers
+func (q *RedisQueue) Ping(ctx context.Context) error {
+	if err := q.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis at %s: %w", q.client.Options().Addr, err)
+	}
+	return nil
+}
+
+// SetDepthPoliteness scales the politeness delay by the depth of the
+// entries popped; unlike Frontier, hosts are never fetched several at once
+func (q *RedisQueue) SetDepthPoliteness(p *DepthPoliteness) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.politeness = p
+}
+
+// Admits reports whether a domain fits within the per-root subdomain limit
+func (q *RedisQueue) Admits(domain string) bool {
+	return q.limiter.CanAdd(domain)
+}
+
+// Limiter returns the per-root subdomain limiter so its state can be saved
+func (q *RedisQueue) Limiter() *SubdomainLimiter {
+	return q.limiter
+}
+
+// KnowsRoot reports whether this process ever queued a host under the
+// domain's root
+func (q *RedisQueue) KnowsRoot(domain string) bool {
+	return q.limiter.KnowsRoot(domain)
+}
+
+// Push adds an entry unless any process pushed it at this depth before;
+// inner pages are deduplicated by URL
+// Returns true if added, false if duplicate, stopped, or Redis failed
+func (q *RedisQueue) Push(entry storage.QueueEntry) bool {
+	q.limiter.Add(entry.DomainName)
+
+	visit := "d:" + strconv.Itoa(entry.Depth) + ":" + entry.DomainName
+	if entry.URL != "" {
+		visit = "p:" + entry.URL
+	}
+	return q.push(visit, entry)
+}
+
+// Requeue adds an entry that was already pushed once, bypassing the
+// dedup set, e.g. to retry a failed fetch
+// Returns false if stopped or Redis failed
+func (q *RedisQueue) Requeue(entry storage.QueueEntry) bool {
+	return q.push("", entry)
+}
+
+// push runs redisPush for entry with the given visit key
+func (q *RedisQueue) push(visit string, entry storage.QueueEntry) bool {
+	if q.stopped.Load() {
+		return false
+	}
+
+	data, err := json.Marshal(redisEntryOf(entry))
+	if err != nil {
+		q.warn("Failed to encode queue entry %s: %v", entry.DomainName, err)
+		return false
+	}
+
+	// Retries fetch their page again
+	fetch := entry.DomainName
+	if entry.URL != "" {
+		fetch = entry.URL
+	}
+	if entry.Retries > 0 {
+		fetch = ""
+	}
+
+	depth := strconv.Itoa(min(max(entry.Depth, 0), q.maxDepth))
+	score := strconv.FormatFloat(-entry.Priority, 'g', -1, 64)
+	added, err := redisPush.Run(context.Background(), q.client, nil, q.prefix, visit, depth, score, ExtractRootDomain(entry.DomainName), fetch, data).Int()
+	if err != nil {
+		q.warn("Failed to push %s to the Redis queue: %v", entry.DomainName, err)
+		return false
+	}
+	return added == 1
+}
+
+// Pop removes the next entry whose host is allowed to be fetched, by any
+// process, and leases it until Done; its NodeID is 0, as it may have been
+// discovered elsewhere
+// Blocks while the queue is empty, every scanned host is cooling down, or
+// Redis fails
+// Returns (empty, false) once stopped or ctx is done, whichever comes first
+func (q *RedisQueue) Pop(ctx context.Context) (storage.QueueEntry, bool) {
+	claim := max(q.delay, time.Millisecond).Milliseconds()
+
+	for {
+		if q.stopped.Load() || ctx.Err() != nil {
+			return storage.QueueEntry{}, false
+		}
+
+		wait := redisPollInterval
+		now := time.Now()
+		member, err := redisPop.Run(ctx, q.client, nil, q.prefix, q.maxDepth, now.UnixMilli(), claim, redisScanRoots,
+			q.process, now.Add(redisBusyTTL).UnixMilli(), now.Add(redisLeaseTTL).UnixMilli()).Text()
+		switch {
+		case err == nil:
+			host, entry, err := decodeRedisMember(member)
+			if err != nil {
+				q.warn("Dropping malformed Redis queue entry %q: %v", member, err)
+				q.release(member)
+				continue
+			}
+			// The host was claimed for the unscaled delay; hold it, and
+			// park it at this depth, for this entry's
+			key := redisEntryOf(entry)
+			q.mu.Lock()
+			q.leased[key] = append(q.leased[key], member)
+			gap := politenessGap(q.delay, q.jitter, q.politeness, q.latency, host, entry.Depth)
+			q.mu.Unlock()
+			depth := strconv.Itoa(min(max(entry.Depth, 0), q.maxDepth))
+			pipe := q.client.Pipeline()
+			pipe.PExpire(ctx, q.prefix+"host:"+host, gap)
+			pipe.ZAddXX(ctx, q.prefix+"cooling:"+depth, redis.Z{Score: float64(now.Add(gap).UnixMilli()), Member: host})
+			if _, err := pipe.Exec(ctx); err != nil {
+				q.warn("Failed to set the politeness delay of %s: %v", host, err)
+			}
+			return entry, true
+		case errors.Is(err, redis.Nil):
+		case ctx.Err() == nil:
+			q.warn("Failed to pop from the Redis queue: %v", err)
+			wait = redisRetryInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-q.stop:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+}
+
+// Done ends the lease of an entry from Pop
+func (q *RedisQueue) Done(entry storage.QueueEntry) {
+	key := redisEntryOf(entry)
+	q.mu.Lock()
+	members := q.leased[key]
+	if len(members) == 0 {
+		q.mu.Unlock()
+		return
+	}
+	member := members[0]
+	if len(members) == 1 {
+		delete(q.leased, key)
+	} else {
+		q.leased[key] = members[1:]
+	}
+	q.mu.Unlock()
+	q.release(member)
+}
+
+// release removes member from this process's leases
+func (q *RedisQueue) release(member string) {
+	if err := q.client.ZRem(context.Background(), q.leaseKey(), member).Err(); err != nil {
+		q.warn("Failed to release a Redis queue lease: %v", err)
+	}
+}
+
+// renewLeases extends this process's leases every third of redisLeaseTTL
+// until Stop; after that, entries it never finished go back to the queue
+// once their lease runs out
+func (q *RedisQueue) renewLeases() {
+	ticker := time.NewTicker(redisLeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			until := time.Now().Add(redisLeaseTTL).UnixMilli()
+			if err := redisRenew.Run(context.Background(), q.client, []string{q.leaseKey()}, until).Err(); err != nil {
+				q.warn("Failed to renew Redis queue leases: %v", err)
+			}
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// leaseKey returns the key of the sorted set holding this process's leases
+func (q *RedisQueue) leaseKey() string {
+	return q.prefix + "lease:" + q.process
+}
+
+// Reserve claims domain's host for a request made outside the queue, such
+// as a sitemap read, waiting while any process holds it
+// Returns false once stopped or ctx is done
+func (q *RedisQueue) Reserve(ctx context.Context, domain string, depth int) bool {
+	host := ExtractRootDomain(domain)
+	for {
+		if q.stopped.Load() || ctx.Err() != nil {
+			return false
+		}
+
+		q.mu.Lock()
+		gap := politenessGap(q.delay, q.jitter, q.politeness, q.latency, host, depth)
+		q.mu.Unlock()
+
+		wait := redisPollInterval
+		claimed, err := q.client.SetNX(ctx, q.prefix+"host:"+host, "1", max(gap, time.Millisecond)).Result()
+		switch {
+		case err == nil && claimed:
+			return true
+		case err != nil && ctx.Err() == nil:
+			q.warn("Failed to claim %s in Redis: %v", host, err)
+			wait = redisRetryInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-q.stop:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+}
+
+// decodeRedisMember splits a queue member into its host and entry
+func decodeRedisMember(member string) (string, storage.QueueEntry, error) {
+	parts := strings.SplitN(member, "\t", 4)
+	if len(parts) != 4 {
+		return "", storage.QueueEntry{}, errors.New("missing fields")
+	}
+	var e redisEntry
+	if err := json.Unmarshal([]byte(parts[3]), &e); err != nil {
+		return "", storage.QueueEntry{}, err
+	}
+	return parts[1], storage.QueueEntry{
+		DomainName: e.Domain,
+		URL:        e.URL,
+		Depth:      e.Depth,
+		Priority:   e.Priority,
+		Retries:    e.Retries,
+	}, nil
+}
+
+// redisEntryOf returns entry as stored in Redis
+func redisEntryOf(entry storage.QueueEntry) redisEntry {
+	return redisEntry{
+		Domain:   entry.DomainName,
+		URL:      entry.URL,
+		Depth:    entry.Depth,
+		Priority: entry.Priority,
+		Retries:  entry.Retries,
+	}
+}
+
+// IsEmpty returns true if the shared queue has no entries; false if Redis
+// can't tell, so a failing server doesn't end the crawl
+func (q *RedisQueue) IsEmpty() bool {
+	size, err := q.size()
+	return err == nil && size == 0
+}
+
+// Size returns the number of entries in the shared queue, 0 if Redis fails
+func (q *RedisQueue) Size() int {
+	size, _ := q.size()
+	return size
+}
+
+// size reads the count of queued entries the scripts keep
+func (q *RedisQueue) size() (int, error) {
+	size, err := q.client.Get(context.Background(), q.prefix+"size").Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		q.warn("Failed to read the Redis queue size: %v", err)
+		return 0, err
+	}
+	return size, nil
+}
+
+// VisitedCount returns the number of keys in the shared dedup set
+func (q *RedisQueue) VisitedCount() int {
+	count, err := q.client.SCard(context.Background(), q.prefix+"visited").Result()
+	if err != nil {
+		q.warn("Failed to read the Redis dedup set size: %v", err)
+	}
+	return int(count)
+}
+
+// Drained reports whether the shared queue is empty, no process is busy,
+// and no entry is leased; busy marks this one as such until redisBusyTTL,
+// or clears its mark
+// Any process popping an entry counts as busy until it says otherwise, so
+// one whose fetch will push more links doesn't let the others end, and the
+// leases of one that crashed hold the others until a pop requeues them
+func (q *RedisQueue) Drained(busy bool) bool {
+	ctx := context.Background()
+	now := time.Now()
+	key := q.prefix + "busy"
+
+	pipe := q.client.Pipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+	if busy {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.Add(redisBusyTTL).UnixMilli()), Member: q.process})
+	} else {
+		pipe.ZRem(ctx, key, q.process)
+	}
+	others := pipe.ZCard(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		q.warn("Failed to update the Redis busy set: %v", err)
+		return false
+	}
+	if busy || others.Val() > 0 {
+		return false
+	}
+	leased, err := redisLeased.Run(ctx, q.client, nil, q.prefix).Int()
+	if err != nil {
+		q.warn("Failed to count the Redis queue leases: %v", err)
+		return false
+	}
+	return leased == 0 && q.IsEmpty()
+}
+
+// Stop rejects further pushes and releases workers blocked on Pop(); the
+// shared queue stays in Redis for the other processes, which no longer
+// wait on this one
+func (q *RedisQueue) Stop() {
+	q.stopped.Store(true)
+	q.stopOnce.Do(func() {
+		close(q.stop)
+		if err := q.client.ZRem(context.Background(), q.prefix+"busy", q.process).Err(); err != nil {
+			q.warn("Failed to leave the Redis busy set: %v", err)
+		}
+	})
+}
+
+// processName identifies this process among those sharing a queue
+func processName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return host + ":" + strconv.Itoa(os.Getpid())
+}
+
+// GetAllEntries returns nothing: the shared queue outlives the process in
+// Redis, and saving it in every process's database would only duplicate it
+func (q *RedisQueue) GetAllEntries() []storage.QueueEntry {
+	return nil
+}
+
+// Upcoming returns the distinct domains of roughly the next n entries to be
+// popped: by depth, the first entries of the ready hosts by priority, then
+// of the cooling hosts by when they are ready
+func (q *RedisQueue) Upcoming(n int) []string {
+	ctx := context.Background()
+	domains := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for depth := 0; depth <= q.maxDepth && len(domains) < n; depth++ {
+		d := strconv.Itoa(depth)
+		ready, err := q.client.ZRange(ctx, q.prefix+"ready:"+d, 0, int64(n-1)).Result()
+		if err != nil {
+			q.warn("Failed to read the Redis queue: %v", err)
+			return domains
+		}
+		cooling, err := q.client.ZRange(ctx, q.prefix+"cooling:"+d, 0, int64(n-1)).Result()
+		if err != nil {
+			q.warn("Failed to read the Redis queue: %v", err)
+			return domains
+		}
+		roots := make([]string, 0, len(ready)+len(cooling))
+		for _, member := range ready {
+			_, root, _ := strings.Cut(member, "\t")
+			roots = append(roots, root)
+		}
+		roots = append(roots, cooling...)
+		for _, root := range roots {
+			members, err := q.client.ZRange(ctx, q.prefix+"queue:"+d+":"+root, 0, 0).Result()
+			if err != nil || len(members) == 0 {
+				continue
+			}
+			_, entry, err := decodeRedisMember(members[0])
+			if err != nil || seen[entry.DomainName] {
+				continue
+			}
+			seen[entry.DomainName] = true
+			domains = append(domains, entry.DomainName)
+			if len(domains) >= n {
+				break
+			}
+		}
+	}
+	return domains
+}
+
+// warn logs a Redis failure, at most once per redisRetryInterval so an
+// outage doesn't flood the log
+func (q *RedisQueue) warn(format string, args ...any) {
+	now := time.Now().UnixNano()
+	last := q.lastWarn.Load()
+	if now-last < int64(redisRetryInterval) || !q.lastWarn.CompareAndSwap(last, now) {
+		return
+	}
+	logrus.Warnf(format, args...)
+}
@@ -0,0 +1,108 @@
+package crawler
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// RefetchGuard keeps a domain from being fetched more often than
+// min_refetch_interval_sec, counting fetches by earlier runs and other
+// sessions of the same database, so frequently re-seeded domains aren't
+// hammered. Fetch times are recorded even when no interval is set
+type RefetchGuard struct {
+	interval time.Duration // 0 records fetches without refusing any
+
+	mu       sync.Mutex
+	last     map[string]time.Time          // domain -> latest known fetch
+	dirty    map[string]time.Time          // fetches since the last flush
+	deferred map[string]storage.QueueEntry // refused entries not back in the frontier, by domain
+}
+
+// NewRefetchGuard creates a guard enforcing interval between fetches
+func NewRefetchGuard(interval time.Duration) *RefetchGuard {
+	return &RefetchGuard{
+		interval: interval,
+		last:     make(map[string]time.Time),
+		dirty:    make(map[string]time.Time),
+		deferred: make(map[string]storage.QueueEntry),
+	}
+}
+
+// Interval returns the enforced minimum interval between fetches
+func (g *RefetchGuard) Interval() time.Duration {
+	return g.interval
+}
+
+// Load merges fetch times recorded by previous runs
+func (g *RefetchGuard) Load(times map[string]time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for domain, at := range times {
+		if at.After(g.last[domain]) {
+			g.last[domain] = at
+		}
+	}
+}
+
+// Acquire records a fetch of domain now, unless the previous one was less
+// than the interval ago; then it returns false and how long until the
+// domain may be fetched again
+func (g *RefetchGuard) Acquire(domain string) (bool, time.Duration) {
+	now := time.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if last, ok := g.last[domain]; ok && g.interval > 0 {
+		if wait := g.interval - now.Sub(last); wait > 0 {
+			return false, wait
+		}
+	}
+	g.last[domain] = now
+	g.dirty[domain] = now
+	return true, 0
+}
+
+// TakeDirty returns the fetches recorded since the last call and clears them
+func (g *RefetchGuard) TakeDirty() map[string]time.Time {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	dirty := g.dirty
+	g.dirty = make(map[string]time.Time)
+	return dirty
+}
+
+// Defer re-enqueues entry through requeue once wait has passed, for an entry
+// Acquire refused. A deferred entry doesn't hold the crawl open, as the
+// interval may well outlast it; until requeued it is kept for checkpoints,
+// so the next run fetches it instead of the crawl dropping it
+func (g *RefetchGuard) Defer(entry storage.QueueEntry, wait time.Duration, requeue func(storage.QueueEntry) bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.deferred[entry.DomainName]; ok {
+		return
+	}
+	g.deferred[entry.DomainName] = entry
+
+	time.AfterFunc(wait, func() {
+		// An entry a stopped frontier refuses stays deferred, so the
+		// shutdown checkpoint saves it for the next run
+		if !requeue(entry) {
+			return
+		}
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		delete(g.deferred, entry.DomainName)
+	})
+}
+
+// Deferred returns the refused entries not back in the frontier, for
+// checkpoints; one being requeued may be in both
+func (g *RefetchGuard) Deferred() []storage.QueueEntry {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Collect(maps.Values(g.deferred))
+}
@@ -0,0 +1,403 @@
+package crawler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// ReputationSource rates domains from 0 (bad) to 1 (good); rated is false
+// when the source knows nothing about the domain
+// Sources are called from every worker, so they must be safe for
+// concurrent use; lookups are bounded by the request timeout
+type ReputationSource interface {
+	Lookup(ctx context.Context, domain string) (score float64, rated bool, err error)
+}
+
+// Reputation prefetch bounds: the upcoming frontier domains looked up in
+// the background, and the lookups running at once
+const (
+	reputationPrefetchAhead   = 64
+	reputationPrefetchWorkers = 8
+	reputationPrefetchEvery   = 100 * time.Millisecond
+)
+
+// Reputation consults the reputation sources for each domain about to be
+// crawled once per run, caching the verdict
+type Reputation struct {
+	sources  []ReputationSource
+	minScore float64
+	timeout  time.Duration
+
+	mu       sync.Mutex
+	verdicts map[string]string        // domain -> storage.Reputation* verdict
+	inflight map[string]chan struct{} // domain -> closed once its lookup is done
+
+	lookups atomic.Int64
+	low     atomic.Int64
+}
+
+// NewReputation creates a lookup without sources, judging scores below
+// minScore as low reputation
+func NewReputation(minScore float64, timeout time.Duration) *Reputation {
+	return &Reputation{
+		minScore: minScore,
+		timeout:  timeout,
+		verdicts: make(map[string]string),
+		inflight: make(map[string]chan struct{}),
+	}
+}
+
+// Add appends a source; call it before the crawl starts
+func (r *Reputation) Add(source ReputationSource) {
+	r.sources = append(r.sources, source)
+}
+
+// Enabled reports whether any source is configured
+func (r *Reputation) Enabled() bool {
+	return len(r.sources) > 0
+}
+
+// Verdict returns the reputation verdict of domain, looking it up on first
+// use, or "" when no source is configured; a lookup already running is
+// waited for rather than repeated
+// A domain no source rates, or whose every lookup failed, is unknown, and
+// is crawled like one of good reputation
+func (r *Reputation) Verdict(domain string) string {
+	if !r.Enabled() {
+		return ""
+	}
+
+	r.mu.Lock()
+	if verdict, ok := r.verdicts[domain]; ok {
+		r.mu.Unlock()
+		return verdict
+	}
+	if done, ok := r.inflight[domain]; ok {
+		r.mu.Unlock()
+		<-done
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.verdicts[domain]
+	}
+	done := make(chan struct{})
+	r.inflight[domain] = done
+	r.mu.Unlock()
+
+	verdict := r.lookup(domain)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.verdicts[domain] = verdict
+	if verdict == storage.ReputationLow {
+		r.low.Add(1)
+	}
+	delete(r.inflight, domain)
+	close(done)
+	return verdict
+}
+
+// Cached returns the verdict of domain if it has been looked up, without
+// waiting on the sources
+func (r *Reputation) Cached(domain string) (verdict string, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	verdict, ok = r.verdicts[domain]
+	return verdict, ok
+}
+
+// pending reports whether domain is neither looked up nor being looked up
+func (r *Reputation) pending(domain string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, known := r.verdicts[domain]
+	_, running := r.inflight[domain]
+	return !known && !running
+}
+
+// Run looks up the domains of upcoming frontier entries in the background
+// until stop is closed, so workers rarely wait on the sources when they
+// pop them
+func (r *Reputation) Run(upcoming func(int) []string, stop <-chan struct{}) {
+	ticker := time.NewTicker(reputationPrefetchEvery)
+	defer ticker.Stop()
+
+	slots := make(chan struct{}, reputationPrefetchWorkers)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		// Domains left over once every slot is busy wait for a later tick
+	domains:
+		for _, domain := range upcoming(reputationPrefetchAhead) {
+			if !r.pending(domain) {
+				continue
+			}
+			select {
+			case slots <- struct{}{}:
+			default:
+				break domains
+			}
+			go func() {
+				defer func() { <-slots }()
+				r.Verdict(domain)
+			}()
+		}
+	}
+}
+
+// lookup asks every source and judges the lowest score given
+func (r *Reputation) lookup(domain string) string {
+	r.lookups.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	lowest, rated := 1.0, false
+	for _, source := range r.sources {
+		score, ok, err := source.Lookup(ctx, domain)
+		if err != nil {
+			logrus.Debugf("Reputation lookup for %s failed: %v", domain, err)
+			continue
+		}
+		if ok {
+			lowest, rated = min(lowest, score), true
+		}
+	}
+
+	switch {
+	case !rated:
+		return storage.ReputationUnknown
+	case lowest < r.minScore:
+		return storage.ReputationLow
+	default:
+		return storage.ReputationOK
+	}
+}
+
+// Stats returns the domains looked up and those judged low reputation
+func (r *Reputation) Stats() (lookups, low int) {
+	return int(r.lookups.Load()), int(r.low.Load())
+}
+
+// CSVReputation rates domains from a local feed of "domain,score" lines;
+// a listed domain also rates its subdomains, the most specific entry winning
+type CSVReputation struct {
+	scores map[string]float64
+}
+
+// NewCSVReputation loads a feed; blank lines and lines starting with #
+// are skipped, as is a header line whose score isn't a number
+func NewCSVReputation(path string) (*CSVReputation, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reputation feed: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	scores := make(map[string]float64)
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read reputation feed: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) < 2 {
+			return nil, fmt.Errorf("reputation feed line %d: expected domain,score", line)
+		}
+		score, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil && first {
+			continue
+		}
+		if err != nil || score < 0 || score > 1 {
+			return nil, fmt.Errorf("reputation feed line %d: score must be a number between 0 and 1", line)
+		}
+		domain := strings.ToLower(strings.Trim(strings.TrimSpace(record[0]), "."))
+		if domain == "" {
+			return nil, fmt.Errorf("reputation feed line %d: domain must not be empty", line)
+		}
+		scores[domain] = score
+	}
+	return &CSVReputation{scores: scores}, nil
+}
+
+// Lookup rates domain by its own entry or its closest listed parent
+func (s *CSVReputation) Lookup(_ context.Context, domain string) (float64, bool, error) {
+	for {
+		if score, ok := s.scores[domain]; ok {
+			return score, true, nil
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return 0, false, nil
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// DNSBLReputation rates domains against a domain blocklist zone: a domain
+// with an address under the zone is listed and scores 0, one without
+// scores 1
+type DNSBLReputation struct {
+	zone     string
+	resolver *net.Resolver
+}
+
+// NewDNSBLReputation creates a source querying zone, e.g. "dbl.example.org"
+func NewDNSBLReputation(zone string) *DNSBLReputation {
+	return &DNSBLReputation{
+		zone:     strings.Trim(zone, "."),
+		resolver: net.DefaultResolver,
+	}
+}
+
+// Lookup resolves <domain>.<zone>
+func (s *DNSBLReputation) Lookup(ctx context.Context, domain string) (float64, bool, error) {
+	_, err := s.resolver.LookupHost(ctx, domain+"."+s.zone)
+	if err == nil {
+		return 0, true, nil
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return 1, true, nil
+	}
+	return 0, false, err
+}
+
+// HTTPReputation rates domains through an HTTP API answering
+// {"score": n}; a 404, or an answer without a score, leaves the domain
+// unrated
+type HTTPReputation struct {
+	url    string // with a {domain} placeholder
+	client *http.Client
+}
+
+// NewHTTPReputation creates a source querying urlTemplate with {domain}
+// replaced by the domain
+func NewHTTPReputation(urlTemplate string, timeout time.Duration) *HTTPReputation {
+	return &HTTPReputation{
+		url:    urlTemplate,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Lookup asks the API about domain
+func (s *HTTPReputation) Lookup(ctx context.Context, domain string) (float64, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.ReplaceAll(s.url, "{domain}", url.QueryEscape(domain)), nil)
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, false, fmt.Errorf("reputation API returned %s", resp.Status)
+	}
+
+	var body struct {
+		Score *float64 `json:"score"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return 0, false, fmt.Errorf("failed to decode reputation API response: %w", err)
+	}
+	if body.Score == nil {
+		return 0, false, nil
+	}
+	return min(max(*body.Score, 0), 1), true, nil
+}
+
+// LoadReputationSources sets up the reputation sources configured in
+// domain_reputation, reading the CSV feed if any
+func (c *Crawler) LoadReputationSources() error {
+	rep := c.cfg.DomainReputation
+	if rep.CSVPath != "" {
+		source, err := NewCSVReputation(rep.CSVPath)
+		if err != nil {
+			return err
+		}
+		c.reputation.Add(source)
+		logrus.Infof("Loaded %d reputation feed entries from %s", len(source.scores), rep.CSVPath)
+	}
+	if rep.DNSBLZone != "" {
+		c.reputation.Add(NewDNSBLReputation(rep.DNSBLZone))
+	}
+	if rep.HTTPURL != "" {
+		c.reputation.Add(NewHTTPReputation(rep.HTTPURL, time.Duration(c.cfg.RequestTimeoutMs)*time.Millisecond))
+	}
+	return nil
+}
+
+// AddReputationSource adds a reputation source, e.g. a custom
+// implementation when embedding the crawler
+// Call it before Start
+func (c *Crawler) AddReputationSource(source ReputationSource) {
+	c.reputation.Add(source)
+}
+
+// ReputationStats returns the domains looked up and those judged low
+// reputation
+func (c *Crawler) ReputationStats() (lookups, low int) {
+	return c.reputation.Stats()
+}
+
+// checkReputation returns the verdict for a link target, and whether the
+// link is dropped under the "skip" action; low-reputation targets that are
+// kept are recorded but never enqueued
+// Only verdicts already looked up count, so handling a link never waits on
+// the sources; targets not looked up yet are enqueued, and settled by
+// reputationAdmits once popped
+func (c *Crawler) checkReputation(domain string) (verdict string, skip bool) {
+	verdict, _ = c.reputation.Cached(domain)
+	if verdict != storage.ReputationLow {
+		return verdict, false
+	}
+	return verdict, c.cfg.DomainReputation.Action == config.ReputationActionSkip
+}
+
+// reputationAdmits looks up the domain of a popped entry, waiting on the
+// sources only when the prefetch has not reached it yet, records the
+// verdict, and reports whether the domain may be fetched
+func (c *Crawler) reputationAdmits(domain string) bool {
+	verdict := c.reputation.Verdict(domain)
+	if verdict == "" {
+		return true
+	}
+	c.memGraph.SetReputation(domain, verdict)
+	return verdict != storage.ReputationLow
+}
@@ -0,0 +1,152 @@
+package crawler
+
+import (
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// Retries re-enqueues entries whose fetch failed transiently (timeouts,
+// 5xx, DNS and connection errors), up to retry_attempts times per entry,
+// each after an exponentially growing, jittered delay
+// Colly refuses to request a URL twice, so a retry replays the failed
+// request, which Colly lets through
+type Retries struct {
+	attempts int
+	delay    time.Duration // before the first retry; doubles for each next one
+
+	mu       sync.Mutex
+	requests map[string]*colly.Request     // failed request, by retryKey, until retried
+	waiting  map[string]storage.QueueEntry // entries not back in the frontier yet, by retryKey
+
+	pending atomic.Int64 // retries waiting out their delay
+	count   atomic.Int64
+}
+
+// NewRetries creates a retry scheduler; zero attempts disables retries
+func NewRetries(attempts int, delay time.Duration) *Retries {
+	return &Retries{
+		attempts: attempts,
+		delay:    delay,
+		requests: make(map[string]*colly.Request),
+		waiting:  make(map[string]storage.QueueEntry),
+	}
+}
+
+// Schedule re-enqueues entry through requeue once its backoff has passed,
+// if a fetch that failed with err and status is worth retrying and entry
+// has retries left. req is the failed request, replayed by Take
+// Returns false if the entry won't be retried
+func (r *Retries) Schedule(entry storage.QueueEntry, req *colly.Request, err error, status int, requeue func(storage.QueueEntry) bool) bool {
+	if req == nil || entry.Retries >= r.attempts || failureStatus(err, status) != storage.NodeFailedTransient {
+		return false
+	}
+	entry.Retries++
+	key := retryKey(entry)
+
+	r.mu.Lock()
+	r.requests[key] = req
+	r.waiting[key] = entry
+	r.mu.Unlock()
+	r.pending.Add(1)
+	r.count.Add(1)
+
+	delay := backoff(r.delay, entry.Retries)
+	logrus.Infof("Retrying %s in %s (retry %d of %d)", req.URL, delay.Round(time.Millisecond), entry.Retries, r.attempts)
+	time.AfterFunc(delay, func() {
+		defer r.pending.Add(-1)
+		requeued := requeue(entry)
+
+		// An entry a stopped frontier refuses keeps waiting, so the
+		// shutdown checkpoint saves it for the next run
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if requeued {
+			delete(r.waiting, key)
+		} else {
+			delete(r.requests, key)
+		}
+	})
+	return true
+}
+
+// Waiting returns the entries scheduled for a retry that are not back in
+// the frontier, for checkpoints; one being requeued may be in both
+func (r *Retries) Waiting() []storage.QueueEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Collect(maps.Values(r.waiting))
+}
+
+// Take returns the failed request of a retried entry, or nil if entry isn't
+// a retry; each request is returned once
+func (r *Retries) Take(entry storage.QueueEntry) *colly.Request {
+	if entry.Retries == 0 {
+		return nil
+	}
+	key := retryKey(entry)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	req := r.requests[key]
+	delete(r.requests, key)
+	return req
+}
+
+// Pending returns how many retries are waiting out their delay, and so are
+// in neither the frontier nor in flight
+func (r *Retries) Pending() int {
+	return int(r.pending.Load())
+}
+
+// Count returns how many failed fetches were scheduled for a retry
+func (r *Retries) Count() int {
+	return int(r.count.Load())
+}
+
+// retryKey identifies the fetch of an entry: the inner page, or the front
+// page of the domain
+func retryKey(entry storage.QueueEntry) string {
+	if entry.URL != "" {
+		return entry.URL
+	}
+	return entry.DomainName
+}
+
+// backoff returns the delay before the given retry, counting from 1: base
+// doubled for each earlier retry, plus up to half of that again at random
+// so retries of hosts that failed together spread out
+func backoff(base time.Duration, retry int) time.Duration {
+	delay := base << min(retry-1, 16)
+	return delay + rand.N(delay/2+1)
+}
+
+// request sends the fetch of entry with ctx: a retry replays its failed
+// request, anything else is a new request for targetURL
+func (c *Crawler) request(entry storage.QueueEntry, targetURL string, ctx *colly.Context) error {
+	if req := c.retries.Take(entry); req != nil {
+		// A retried HTTP fallback is still one
+		if fellBack(req.Ctx) {
+			ctx.Put(httpFallbackKey, "1")
+		}
+		// Callbacks of an abandoned fetch may still read the failed
+		// request, so the replay gets a copy with the new context
+		retry := *req
+		retry.Ctx = ctx
+		return retry.Retry()
+	}
+	return c.collectors.For(entry.DomainName).Request(http.MethodGet, targetURL, nil, ctx, nil)
+}
+
+// RetriedFetches returns how many failed fetches were scheduled for a retry
+func (c *Crawler) RetriedFetches() int {
+	return c.retries.Count()
+}
@@ -0,0 +1,179 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// maxSchemeProbes bounds the HTTP probes running at once
+const maxSchemeProbes = 4
+
+// SchemeLog buffers scheme support observations until the next flush
+// writes them to the scheme_support table, and optionally probes whether
+// each fetched domain also answers over plain HTTP
+type SchemeLog struct {
+	mu      sync.Mutex
+	pending map[string]*storage.SchemeSupport
+	probed  map[string]bool // domains probed over HTTP this run
+
+	client *http.Client // nil disables HTTP probing
+	slots  chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewSchemeLog creates an empty scheme log; probe enables HTTP probing
+// with requests limited to timeout
+func NewSchemeLog(probe bool, timeout time.Duration) *SchemeLog {
+	l := &SchemeLog{
+		pending: make(map[string]*storage.SchemeSupport),
+		probed:  make(map[string]bool),
+	}
+	if probe {
+		l.client = &http.Client{
+			Timeout: timeout,
+			// The redirect target is the answer; following it would fetch HTTPS
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		l.slots = make(chan struct{}, maxSchemeProbes)
+	}
+	return l
+}
+
+// SetTransport replaces the transport HTTP probes are sent through
+func (l *SchemeLog) SetTransport(transport http.RoundTripper) {
+	if l.client != nil {
+		l.client.Transport = transport
+	}
+}
+
+// RecordHTTPS records whether domain answered over HTTPS, then probes HTTP
+// as userAgent if enabled and not yet done this run
+func (l *SchemeLog) RecordHTTPS(domain string, ok bool, userAgent string) {
+	l.update(domain, func(r *storage.SchemeSupport) { r.HTTPS = &ok })
+
+	if l.client == nil {
+		return
+	}
+	l.mu.Lock()
+	if l.probed[domain] {
+		l.mu.Unlock()
+		return
+	}
+	l.probed[domain] = true
+	l.mu.Unlock()
+
+	l.wg.Add(1)
+	go l.probeHTTP(domain, userAgent)
+}
+
+// RecordHTTP records whether domain answered over plain HTTP when fetched
+// over it
+func (l *SchemeLog) RecordHTTP(domain string, ok bool) {
+	l.update(domain, func(r *storage.SchemeSupport) { r.HTTP = &ok })
+}
+
+// probeHTTP requests http://domain/ as userAgent without following
+// redirects
+func (l *SchemeLog) probeHTTP(domain, userAgent string) {
+	defer l.wg.Done()
+	l.slots <- struct{}{}
+	defer func() { <-l.slots }()
+
+	req, err := http.NewRequest(http.MethodHead, "http://"+domain+"/", nil)
+	if err != nil {
+		logrus.Debugf("HTTP probe of %s not sent: %v", domain, err)
+		return
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := l.client.Do(req)
+	if err != nil {
+		// Only a refusal says anything about the scheme; timeouts and DNS
+		// failures leave it unknown
+		if categorizeFetchError(err, 0) == storage.ErrorConnection {
+			no := false
+			l.update(domain, func(r *storage.SchemeSupport) { r.HTTP = &no })
+		} else {
+			logrus.Debugf("HTTP probe of %s inconclusive: %v", domain, err)
+		}
+		return
+	}
+	resp.Body.Close()
+
+	yes, redirects := true, redirectsToHTTPS(resp)
+	l.update(domain, func(r *storage.SchemeSupport) {
+		r.HTTP = &yes
+		r.HTTPToHTTPS = &redirects
+	})
+}
+
+// redirectsToHTTPS reports whether resp redirects to an https:// URL
+func redirectsToHTTPS(resp *http.Response) bool {
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return false
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(location.Scheme, "https")
+}
+
+// update applies fn to domain's pending record
+func (l *SchemeLog) update(domain string, fn func(*storage.SchemeSupport)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, ok := l.pending[domain]
+	if !ok {
+		r = &storage.SchemeSupport{Domain: domain}
+		l.pending[domain] = r
+	}
+	fn(r)
+	r.CheckedAt = time.Now()
+}
+
+// Wait blocks until running HTTP probes finish or timeout elapses
+func (l *SchemeLog) Wait(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		logrus.Warn("Timeout waiting for HTTP scheme probes")
+	}
+}
+
+// Take returns the observations recorded since the last call and clears them
+func (l *SchemeLog) Take() []storage.SchemeSupport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	records := make([]storage.SchemeSupport, 0, len(l.pending))
+	for _, r := range l.pending {
+		records = append(records, *r)
+	}
+	clear(l.pending)
+	return records
+}
+
+// httpsRefused reports whether a failed HTTPS fetch shows the domain does
+// not serve HTTPS, rather than being unreachable altogether
+func httpsRefused(err error, status int) bool {
+	if status != 0 {
+		return false
+	}
+	switch categorizeFetchError(err, status) {
+	case storage.ErrorTLS, storage.ErrorConnection:
+		return true
+	}
+	// Plain HTTP servers on port 443 answer the handshake with an HTTP response
+	return strings.Contains(err.Error(), "server gave HTTP response to HTTPS client")
+}
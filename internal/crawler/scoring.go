@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// Candidate describes a domain about to be enqueued, for scoring
+type Candidate struct {
+	Domain   string
+	TLD      string   // last label of the domain, e.g. "org"
+	Depth    int      // depth it would be enqueued at
+	InDegree int      // distinct nodes linking to it so far this run
+	Tokens   []string // lowercase words of the domain name, e.g. "dev", "blog"
+}
+
+// DomainScorer assigns a frontier priority to a candidate domain; within a
+// depth, higher scores are fetched first and equal scores in arrival order
+// Scorers are called from every worker, so they must be safe for
+// concurrent use and fast
+type DomainScorer interface {
+	Score(candidate Candidate) float64
+}
+
+// RuleScorer scores candidates with the weights in domain_scoring
+type RuleScorer struct {
+	tlds     map[string]float64
+	tokens   map[string]float64
+	inDegree float64
+}
+
+// NewRuleScorer creates a scorer from configured weights; nil when none are
+// configured, so the frontier stays first-in first-out within a depth
+func NewRuleScorer(rules config.DomainScoring) DomainScorer {
+	if !rules.Enabled() {
+		return nil
+	}
+
+	s := &RuleScorer{
+		tlds:     make(map[string]float64, len(rules.TLDWeights)),
+		tokens:   make(map[string]float64, len(rules.TokenWeights)),
+		inDegree: rules.InDegreeWeight,
+	}
+	for tld, weight := range rules.TLDWeights {
+		s.tlds[strings.ToLower(strings.Trim(tld, "."))] = weight
+	}
+	for token, weight := range rules.TokenWeights {
+		s.tokens[strings.ToLower(token)] = weight
+	}
+	return s
+}
+
+// Score sums the weights of the candidate's TLD, tokens, and in-degree
+func (s *RuleScorer) Score(candidate Candidate) float64 {
+	score := s.tlds[candidate.TLD] + s.inDegree*float64(candidate.InDegree)
+	for _, token := range candidate.Tokens {
+		score += s.tokens[token]
+	}
+	return score
+}
+
+// SetScorer replaces the domain scorer, e.g. with a custom implementation
+// when embedding the crawler; nil turns scoring off
+// Call it before Start
+func (c *Crawler) SetScorer(scorer DomainScorer) {
+	c.scorer = scorer
+}
+
+// prioritize sets entry's frontier priority from the scorer, if any
+func (c *Crawler) prioritize(entry *storage.QueueEntry) {
+	if c.scorer == nil {
+		return
+	}
+	entry.Priority = c.scorer.Score(c.candidate(entry))
+}
+
+// candidate gathers the scoring features of a queue entry
+func (c *Crawler) candidate(entry *storage.QueueEntry) Candidate {
+	domain := strings.ToLower(entry.DomainName)
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	return Candidate{
+		Domain:   domain,
+		TLD:      tld,
+		Depth:    entry.Depth,
+		InDegree: c.memGraph.InDegree(entry.NodeID),
+		Tokens:   domainTokens(strings.TrimSuffix(domain, "."+tld)),
+	}
+}
+
+// domainTokens splits a domain name into its alphanumeric words
+func domainTokens(domain string) []string {
+	return strings.FieldsFunc(domain, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,241 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+	"github.com/temoto/robotstxt"
+)
+
+// Seed check limits
+const (
+	maxSeedChecks    = 20 // seeds test-fetched per run; seed files can list millions
+	maxSeedRedirects = 10 // as net/http follows by default
+)
+
+// SeedReport is what test-fetching a seed found
+type SeedReport struct {
+	URL        string
+	Redirects  []string // URLs redirected to, in order
+	StatusCode int      // 0 if the fetch failed before a response
+	Duration   time.Duration
+	TLS        string   // protocol and certificate expiry; "" over plain HTTP or on failure
+	Robots     string   // robots.txt verdict for the crawler's user agent
+	Problems   []string // what will keep the seed from yielding links, with what to try
+}
+
+// Healthy reports whether nothing was found wrong with the seed
+func (r SeedReport) Healthy() bool {
+	return len(r.Problems) == 0
+}
+
+// SeedChecker test-fetches seeds before they are enqueued, following their
+// redirects, so a seed that fails (a 403 to the crawler's user agent, a
+// broken certificate, a redirect to a non-HTML page) is reported with a
+// diagnosis instead of surfacing as a crawl that ends seconds after it
+// started
+type SeedChecker struct {
+	client *http.Client // nil disables checks
+
+	checked   atomic.Int64
+	unhealthy atomic.Int64
+}
+
+// NewSeedChecker creates a checker; enabled turns checks on, with requests
+// limited to timeout
+func NewSeedChecker(enabled bool, timeout time.Duration) *SeedChecker {
+	s := &SeedChecker{}
+	if enabled {
+		s.client = &http.Client{Timeout: timeout}
+	}
+	return s
+}
+
+// SetTransport replaces the transport seed checks are sent through
+func (s *SeedChecker) SetTransport(transport http.RoundTripper) {
+	if s.client != nil {
+		s.client.Transport = transport
+	}
+}
+
+// Check fetches seedURL as userAgent, then its robots.txt; ok is false if
+// checks are disabled or the run's maxSeedChecks are used up
+// httpFallback tells whether a failed TLS handshake is retried over HTTP
+func (s *SeedChecker) Check(seedURL, userAgent string, httpFallback bool) (report SeedReport, ok bool) {
+	if s.client == nil || s.checked.Add(1) > maxSeedChecks {
+		return report, false
+	}
+	report.URL = seedURL
+
+	client := *s.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxSeedRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxSeedRedirects)
+		}
+		report.Redirects = append(report.Redirects, req.URL.String())
+		return nil
+	}
+
+	start := time.Now()
+	resp, err := fetchAs(&client, seedURL, userAgent)
+	report.Duration = time.Since(start)
+	if err != nil {
+		report.Problems = append(report.Problems, fetchProblem(err, httpFallback))
+		s.unhealthy.Add(1)
+		return report, true
+	}
+	resp.Body.Close()
+
+	report.StatusCode = resp.StatusCode
+	if state := resp.TLS; state != nil && len(state.PeerCertificates) > 0 {
+		report.TLS = fmt.Sprintf("%s, certificate valid until %s",
+			tls.VersionName(state.Version), state.PeerCertificates[0].NotAfter.Format(time.DateOnly))
+	}
+	if problem := statusProblem(resp); problem != "" {
+		report.Problems = append(report.Problems, problem)
+	}
+
+	report.Robots = robotsVerdict(&client, resp.Request.URL, userAgent)
+	if strings.HasPrefix(report.Robots, "disallowed") {
+		report.Problems = append(report.Problems,
+			"robots.txt asks this user agent not to crawl the front page; the crawler doesn't enforce robots.txt, but a site that asks is likely to block it too")
+	}
+	if !report.Healthy() {
+		s.unhealthy.Add(1)
+	}
+	return report, true
+}
+
+// Stats returns how many seeds were checked and how many had problems
+func (s *SeedChecker) Stats() (checked, unhealthy int) {
+	return int(min(s.checked.Load(), maxSeedChecks)), int(s.unhealthy.Load())
+}
+
+// fetchAs sends a GET for target as userAgent
+func fetchAs(client *http.Client, target, userAgent string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	return client.Do(req)
+}
+
+// robotsVerdict reads the robots.txt of the host page is on and tells
+// whether it lets userAgent fetch page
+func robotsVerdict(client *http.Client, page *url.URL, userAgent string) string {
+	robotsURL := page.Scheme + "://" + page.Host + "/robots.txt"
+	resp, err := fetchAs(client, robotsURL, userAgent)
+	if err != nil {
+		return fmt.Sprintf("unknown, %s not fetched: %v", robotsURL, err)
+	}
+	defer resp.Body.Close()
+	robots, err := robotstxt.FromResponse(resp)
+	if err != nil {
+		return fmt.Sprintf("unknown, %s unreadable: %v", robotsURL, err)
+	}
+	path := page.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if !robots.TestAgent(path, userAgent) {
+		return "disallowed for " + path
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Sprintf("allowed, no robots.txt (status %d)", resp.StatusCode)
+	}
+	return "allowed"
+}
+
+// fetchProblem explains a seed fetch that got no response
+func fetchProblem(err error, httpFallback bool) string {
+	switch categorizeFetchError(err, 0) {
+	case storage.ErrorDNS:
+		return fmt.Sprintf("the host doesn't resolve (%v); check the seed for typos", err)
+	case storage.ErrorTLS:
+		if httpFallback {
+			return fmt.Sprintf("the TLS handshake failed (%v); the crawl will fetch the front page over HTTP instead", err)
+		}
+		return fmt.Sprintf("the TLS handshake failed (%v); set allow_http_fallback to fetch such front pages over HTTP", err)
+	case storage.ErrorTimeout:
+		return fmt.Sprintf("no answer in time (%v); check the host is up, or raise request_timeout_ms", err)
+	case storage.ErrorConnection:
+		return fmt.Sprintf("the connection failed (%v); check the host is up and reachable from this network", err)
+	default:
+		return fmt.Sprintf("the fetch failed: %v", err)
+	}
+}
+
+// statusProblem explains a seed response the crawl can't take links from,
+// or returns ""
+func statusProblem(resp *http.Response) string {
+	status := resp.StatusCode
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return fmt.Sprintf("status %d: the site refuses this user agent or wants a login; try another seed, or a per-root user_agent in root_collectors", status)
+	case status == http.StatusTooManyRequests:
+		return "status 429: the site is rate limiting this address; raise politeness_delay_ms or try later"
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return fmt.Sprintf("status %d: the front page doesn't exist; seed another URL of the site", status)
+	case status >= 500:
+		return fmt.Sprintf("status %d: the site is failing; the crawl retries it up to retry_attempts times, or try later", status)
+	case status >= 400:
+		return fmt.Sprintf("status %d: the front page can't be fetched", status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			return fmt.Sprintf("the front page is %s, not HTML, so it has no links to follow", mediaType)
+		}
+	}
+	return ""
+}
+
+// checkSeed test-fetches a seed and logs what it found; unhealthy seeds are
+// still enqueued, as the problem may be passing
+func (c *Crawler) checkSeed(seedURL, seedDomain string) {
+	userAgent := c.collectors.For(seedDomain).UserAgent
+	report, ok := c.seeds.Check(seedURL, userAgent, c.cfg.AllowHTTPFallback)
+	if !ok {
+		return
+	}
+
+	summary := report.URL
+	for _, redirect := range report.Redirects {
+		summary += " -> " + redirect
+	}
+	if report.StatusCode > 0 {
+		summary += fmt.Sprintf(": status %d in %s", report.StatusCode, report.Duration.Round(time.Millisecond))
+	}
+	if report.TLS != "" {
+		summary += "; " + report.TLS
+	}
+	if report.Robots != "" {
+		summary += "; robots.txt " + report.Robots
+	}
+
+	if report.Healthy() {
+		logrus.Infof("Seed check %s", summary)
+		return
+	}
+	logrus.Warnf("Seed check %s", summary)
+	for _, problem := range report.Problems {
+		logrus.Warnf("Seed %s: %s", seedDomain, problem)
+	}
+}
+
+// SeedChecks returns how many seeds were test-fetched and how many of them
+// had problems
+func (c *Crawler) SeedChecks() (checked, unhealthy int) {
+	return c.seeds.Stats()
+}
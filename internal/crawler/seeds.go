@@ -0,0 +1,186 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// ParseSeedDomains reads seed hosts from an external index dump, one record
+// per line, and returns them deduplicated in first-seen order
+// Accepted lines: Common Crawl index JSON ({"url": ...}), CDXJ
+// (urlkey timestamp {json}), classic CDX (urlkey timestamp url ...), and
+// CSV or plain lists whose first column holding a URL or bare host is
+// taken, e.g. the domain of Tranco's rank,domain lines
+// Blank lines, # comments, and unparsable lines (e.g. CSV headers) are
+// skipped and counted
+func ParseSeedDomains(r io.Reader) (domains []string, skipped int, err error) {
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		domain := seedLineDomain(line)
+		if domain == "" {
+			skipped++
+			continue
+		}
+		if !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, skipped, fmt.Errorf("failed to read seeds: %w", err)
+	}
+	return domains, skipped, nil
+}
+
+// seedLineDomain extracts the host from one seed record, or "" if none
+func seedLineDomain(line string) string {
+	// JSON record, either the whole line or the CDXJ trailer
+	if i := strings.IndexByte(line, '{'); i == 0 || (i > 0 && len(strings.Fields(line[:i])) == 2) {
+		var record struct {
+			URL string `json:"url"`
+		}
+		if err := json.Unmarshal([]byte(line[i:]), &record); err != nil {
+			return ""
+		}
+		return seedHost(record.URL)
+	}
+
+	// Classic CDX: the original URL is the third field
+	if fields := strings.Fields(line); len(fields) >= 3 && strings.Contains(fields[2], "://") {
+		return seedHost(fields[2])
+	}
+
+	// CSV or plain list: the first column holding a host, skipping ranks
+	for column := range strings.SplitSeq(line, ",") {
+		if host := seedHost(strings.Trim(strings.TrimSpace(column), `"`)); host != "" {
+			return host
+		}
+	}
+	return ""
+}
+
+// seedHost normalizes a URL or bare host to a lowercase hostname
+func seedHost(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t") {
+		return ""
+	}
+	if !strings.Contains(value, "://") && !strings.HasPrefix(value, "//") {
+		value = "https://" + value
+	}
+	host, err := ExtractDomain(value)
+	if err != nil || !strings.Contains(host, ".") {
+		return ""
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// ReadSeedFile parses a seed file, "-" for stdin; .gz files are
+// decompressed
+func ReadSeedFile(path string) ([]string, int, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to open seed file: %w", err)
+		}
+		defer f.Close()
+		r = f
+
+		if strings.HasSuffix(path, ".gz") {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to decompress seed file: %w", err)
+			}
+			defer gz.Close()
+			r = gz
+		}
+	}
+
+	return ParseSeedDomains(r)
+}
+
+// SeedAdmission returns a check applying the same admission rules as
+// discovered links: the TLD and domain filters and the stored blocklist
+func SeedAdmission(cfg *config.Config, store *storage.Storage) (func(domain string) bool, error) {
+	blocked, err := store.ListBlockedDomains()
+	if err != nil {
+		return nil, err
+	}
+	blocklist := NewBlocklist()
+	for _, b := range blocked {
+		blocklist.Add(b.Domain)
+	}
+
+	tlds := NewTLDFilter(cfg.AllowedTLDs, cfg.BlockedTLDs)
+	filter := NewDomainFilter(cfg.ExcludeRules, cfg.IncludeRules)
+	return func(domain string) bool {
+		allowed, _ := tlds.Match(domain)
+		return allowed && filter.Allows(domain) && !blocklist.IsBlocked(domain)
+	}, nil
+}
+
+// StartSeeds returns the domains a fresh crawl starts from: those of
+// seed_urls, then those listed in seed_file that admits accepts (all if
+// admits is nil), without duplicates. skipped counts the seed_file lines
+// that were unparsable or not admitted
+func StartSeeds(cfg *config.Config, admits func(domain string) bool) (seeds []string, skipped int, err error) {
+	seen := make(map[string]bool)
+	for _, seedURL := range cfg.SeedURLs {
+		domain, err := ExtractDomain(seedURL)
+		if err != nil || domain == "" {
+			return nil, 0, fmt.Errorf("invalid seed URL %q", seedURL)
+		}
+		if !seen[domain] {
+			seen[domain] = true
+			seeds = append(seeds, domain)
+		}
+	}
+	if cfg.SeedFile == "" {
+		return seeds, 0, nil
+	}
+
+	listed, skipped, err := ReadSeedFile(cfg.SeedFile)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, domain := range listed {
+		switch {
+		case seen[domain]:
+		case admits != nil && !admits(domain):
+			skipped++
+		default:
+			seen[domain] = true
+			seeds = append(seeds, domain)
+		}
+	}
+	return seeds, skipped, nil
+}
+
+// SeedURL returns the URL a fresh crawl enqueues the seed domain with: the
+// first seed_urls entry on the domain, as configured, or https://domain/
+// for a domain listed only in seed_file
+func SeedURL(cfg *config.Config, domain string) string {
+	for _, seedURL := range cfg.SeedURLs {
+		if d, err := ExtractDomain(seedURL); err == nil && d == domain {
+			return seedURL
+		}
+	}
+	return "https://" + domain + "/"
+}
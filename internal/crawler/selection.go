@@ -0,0 +1,53 @@
+package crawler
+
+import (
+	"math/rand/v2"
+	"slices"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+)
+
+// selectTargets caps a page's candidate target domains at max_outbound_links
+// using the configured link_selection strategy; candidates are in DOM order
+func (c *Crawler) selectTargets(candidates []string) []string {
+	limit := c.cfg.MaxOutboundLinks
+	if len(candidates) <= limit {
+		return candidates
+	}
+
+	switch c.cfg.LinkSelection {
+	case config.LinkSelectionRandom:
+		shuffled := slices.Clone(candidates)
+		rand.Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		return shuffled[:limit]
+
+	case config.LinkSelectionPriority:
+		ranked := slices.Clone(candidates)
+		rank := make(map[string]int, len(ranked))
+		for _, domain := range ranked {
+			rank[domain] = c.linkPriority(domain)
+		}
+		// Stable, so DOM order breaks ties
+		slices.SortStableFunc(ranked, func(a, b string) int {
+			return rank[a] - rank[b]
+		})
+		return ranked[:limit]
+
+	default:
+		return candidates[:limit]
+	}
+}
+
+// linkPriority ranks a target for priority selection, lower first: hosts
+// under a root never queued, then unseen hosts, then already known hosts
+func (c *Crawler) linkPriority(domain string) int {
+	if node, err := c.memGraph.GetNode(domain); err == nil && node != nil {
+		return 2
+	}
+	if c.frontier.KnowsRoot(domain) {
+		return 1
+	}
+	return 0
+}
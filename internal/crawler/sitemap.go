@@ -0,0 +1,273 @@
+package crawler
+
+import (
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// Sitemap reading limits
+const (
+	maxSitemapReads = 4                // sitemaps read at once
+	maxSitemapFiles = 20               // files read per domain, index included
+	maxSitemapBytes = 50 * 1024 * 1024 // per file, the limit sitemaps.org sets uncompressed
+)
+
+// SitemapReader reads https://domain/sitemap.xml, following sitemap index
+// files, the first time a domain is crawled. Sites that hide their
+// navigation behind JavaScript often list their pages there
+// Each file is requested with the crawl's user agent once the host's
+// politeness allows it
+type SitemapReader struct {
+	client  *http.Client // nil disables reading
+	maxURLs int
+	slots   chan struct{}
+	wg      sync.WaitGroup
+	pending atomic.Int64 // reads queued or running
+
+	mu   sync.Mutex
+	read map[string]bool // domains read this run
+
+	files atomic.Int64
+	urls  atomic.Int64
+}
+
+// NewSitemapReader creates a reader; enabled turns reading on, with
+// requests limited to timeout and at most maxURLs URLs taken per domain
+func NewSitemapReader(enabled bool, maxURLs int, timeout time.Duration) *SitemapReader {
+	r := &SitemapReader{maxURLs: maxURLs, read: make(map[string]bool)}
+	if enabled {
+		r.client = &http.Client{Timeout: timeout}
+		r.slots = make(chan struct{}, maxSitemapReads)
+	}
+	return r
+}
+
+// SetTransport replaces the transport sitemap requests are sent through
+func (r *SitemapReader) SetTransport(transport http.RoundTripper) {
+	if r.client != nil {
+		r.client.Transport = transport
+	}
+}
+
+// Read reads domain's sitemap in the background, once per run, and passes
+// the URLs it lists to found. Each file is requested as userAgent once
+// reserve, given the file's host, allows it
+func (r *SitemapReader) Read(domain, userAgent string, reserve func(host string) bool, found func(sitemapURL string, urls []string)) {
+	if r.client == nil {
+		return
+	}
+	r.mu.Lock()
+	if r.read[domain] {
+		r.mu.Unlock()
+		return
+	}
+	r.read[domain] = true
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	r.pending.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer r.pending.Add(-1)
+		r.slots <- struct{}{}
+		defer func() { <-r.slots }()
+
+		sitemapURL := "https://" + domain + "/sitemap.xml"
+		urls := r.collect(sitemapURL, userAgent, reserve)
+		if len(urls) > 0 {
+			r.urls.Add(int64(len(urls)))
+			logrus.Debugf("Sitemap of %s lists %d URLs", domain, len(urls))
+			found(sitemapURL, urls)
+		}
+	}()
+}
+
+// Pending returns how many sitemap reads are queued or running; the pages
+// and domains they will find are in neither the frontier nor in flight
+func (r *SitemapReader) Pending() int {
+	return int(r.pending.Load())
+}
+
+// collect gathers the page URLs of the sitemap at sitemapURL, descending
+// into the sitemaps an index lists until the file or URL limit is reached
+func (r *SitemapReader) collect(sitemapURL, userAgent string, reserve func(host string) bool) []string {
+	var urls []string
+	pending := []string{sitemapURL}
+	for files := 0; len(pending) > 0 && files < maxSitemapFiles && len(urls) < r.maxURLs; files++ {
+		next := pending[0]
+		pending = pending[1:]
+
+		pages, children, err := r.fetch(next, userAgent, reserve)
+		if err != nil {
+			logrus.Debugf("Failed to read sitemap %s: %v", next, err)
+			continue
+		}
+		r.files.Add(1)
+		urls = append(urls, pages[:min(len(pages), r.maxURLs-len(urls))]...)
+		pending = append(pending, children...)
+	}
+	return urls
+}
+
+// fetch reads one sitemap file, returning the page URLs of a urlset or the
+// sitemap URLs of an index
+func (r *SitemapReader) fetch(sitemapURL, userAgent string, reserve func(host string) bool) (pages, children []string, err error) {
+	req, err := http.NewRequest(http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	if !reserve(req.URL.Hostname()) {
+		return nil, nil, errors.New("crawl stopped")
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var body io.Reader = io.LimitReader(resp.Body, maxSitemapBytes)
+	if strings.HasSuffix(resp.Request.URL.Path, ".gz") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer gz.Close()
+		body = io.LimitReader(gz, maxSitemapBytes)
+	}
+	return parseSitemap(body)
+}
+
+// parseSitemap reads a sitemaps.org urlset or sitemap index, returning the
+// <loc> of each <url> as pages and of each <sitemap> as children
+func parseSitemap(r io.Reader) (pages, children []string, err error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+
+	var parent string // "url" or "sitemap" while inside one
+	for root := true; ; {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Keep what was read before a truncated or malformed tail
+			if len(pages) > 0 || len(children) > 0 {
+				break
+			}
+			return nil, nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			if end, ok := token.(xml.EndElement); ok && end.Name.Local == parent {
+				parent = ""
+			}
+			continue
+		}
+		if root {
+			// Pages served in place of a missing sitemap are no sitemap
+			if start.Name.Local != "urlset" && start.Name.Local != "sitemapindex" {
+				return nil, nil, fmt.Errorf("not a sitemap: <%s>", start.Name.Local)
+			}
+			root = false
+		}
+		switch start.Name.Local {
+		case "url", "sitemap":
+			parent = start.Name.Local
+		case "loc":
+			var loc string
+			if err := decoder.DecodeElement(&loc, &start); err != nil {
+				continue
+			}
+			if loc = strings.TrimSpace(loc); loc == "" {
+				continue
+			}
+			switch parent {
+			case "url":
+				pages = append(pages, loc)
+			case "sitemap":
+				children = append(children, loc)
+			}
+		}
+	}
+	return pages, children, nil
+}
+
+// Wait blocks until running sitemap reads finish or timeout elapses
+func (r *SitemapReader) Wait(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		logrus.Warn("Timeout waiting for sitemap reads")
+	}
+}
+
+// Stats returns the sitemap files read and the page URLs they listed
+func (r *SitemapReader) Stats() (files, urls int) {
+	return int(r.files.Load()), int(r.urls.Load())
+}
+
+// readSitemap reads the sitemap of a domain crawled for the first time and
+// records sitemap edges to the other domains it lists, selected like the
+// links of a page. Pages on the domain itself are queued in url crawl mode
+// and counted on the node otherwise
+func (c *Crawler) readSitemap(entry storage.QueueEntry) {
+	node, err := c.memGraph.GetNode(entry.DomainName)
+	if err != nil || node == nil || node.CrawlCount > 1 {
+		return
+	}
+
+	userAgent := c.collectors.For(entry.DomainName).UserAgent
+	reserve := func(host string) bool { return c.frontier.Reserve(c.ctx, host, entry.Depth) }
+	c.sitemaps.Read(entry.DomainName, userAgent, reserve, func(sitemapURL string, urls []string) {
+		links := newPageLinks(entry.DomainName)
+		defer links.release()
+
+		seen := make(map[string]bool)
+		var candidates []string
+		for _, u := range urls {
+			if target := c.linkTarget(&entry, u); target != "" && !seen[target] {
+				seen[target] = true
+				candidates = append(candidates, target)
+			}
+			links.AddPage(u, entry.DomainName)
+		}
+
+		if pages := links.Pages(); c.pages.Enabled() {
+			c.queueInnerPages(&entry, pages)
+		} else if len(pages) > 0 {
+			if err := c.memGraph.SetSitemapPages(entry.DomainName, len(pages)); err != nil {
+				logrus.Warnf("Failed to record sitemap pages of %s: %v", entry.DomainName, err)
+			}
+		}
+
+		targets := c.selectTargets(candidates)
+		c.preloadOrigins(entry.DomainName, targets)
+		for _, target := range targets {
+			c.handleLink(&entry, sitemapURL, target, storage.EdgeSitemap, true)
+		}
+	})
+}
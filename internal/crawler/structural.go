@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/gocolly/colly/v2"
+)
+
+// requestedURLKey is the colly request context key holding the URL a fetch
+// was issued for, before any redirects
+const requestedURLKey = "requested_url"
+
+// feedTypes are the <link type> values that announce a syndication feed
+var feedTypes = []string{"application/rss+xml", "application/atom+xml", "application/feed+json"}
+
+// structuralEdgeType classifies a <link> element by its rel, hreflang and
+// type attributes; "" means the element is not followed
+func structuralEdgeType(rel, hreflang, mimeType string) string {
+	rels := strings.Fields(strings.ToLower(rel))
+	switch {
+	case slices.Contains(rels, "canonical"):
+		return storage.EdgeCanonical
+	case !slices.Contains(rels, "alternate"):
+		return ""
+	case hreflang != "":
+		return storage.EdgeHreflang
+	case slices.Contains(feedTypes, strings.ToLower(strings.TrimSpace(mimeType))):
+		return storage.EdgeFeed
+	}
+	return ""
+}
+
+// metaRefreshTarget returns the URL a <meta http-equiv="refresh"> content
+// value ("5; url=https://example.com/") redirects to, or "" for a plain
+// reload. The url= prefix and quotes around the URL are optional
+func metaRefreshTarget(content string) string {
+	// The delay comes first, separated by ';' or ','
+	i := strings.IndexAny(content, ";,")
+	if i < 0 {
+		return ""
+	}
+	target := strings.TrimSpace(content[i+1:])
+	if len(target) >= 4 && strings.EqualFold(target[:3], "url") {
+		if rest := strings.TrimSpace(target[3:]); strings.HasPrefix(rest, "=") {
+			target = strings.TrimSpace(rest[1:])
+		}
+	}
+	return strings.TrimSpace(strings.Trim(target, `"'`))
+}
+
+// recordRedirect records a redirect edge when the fetch in ctx, of
+// requestedURL, ended up on finalURL in another root domain
+func (c *Crawler) recordRedirect(ctx *colly.Context, requestedURL, finalURL string) {
+	requested, err := ExtractDomain(requestedURL)
+	if err != nil {
+		return
+	}
+	final, err := ExtractDomain(finalURL)
+	if err != nil || redirectTarget(requested, final) == "" {
+		return
+	}
+
+	source := c.fetchEntry(ctx, requested)
+	if source == nil {
+		return
+	}
+	if target := c.linkTarget(source, finalURL); target != "" {
+		c.handleLink(source, requestedURL, target, storage.EdgeRedirect, true)
+	}
+}
+
+// recordPageEdge records an edge of edgeType from the page at pageURL,
+// fetched in ctx, to targetURL, enqueueing the target if enqueue is set. With offsite, only
+// targets in another root domain count, as for meta refreshes
+func (c *Crawler) recordPageEdge(ctx *colly.Context, pageURL, targetURL, edgeType string, offsite, enqueue bool) {
+	domain, err := ExtractDomain(pageURL)
+	if err != nil || domain == "" {
+		return
+	}
+	if offsite {
+		target, err := ExtractDomain(targetURL)
+		if err != nil || redirectTarget(domain, target) == "" {
+			return
+		}
+	}
+
+	source := c.fetchEntry(ctx, domain)
+	if source == nil {
+		return
+	}
+	if target := c.linkTarget(source, targetURL); target != "" {
+		c.handleLink(source, pageURL, target, edgeType, enqueue)
+	}
+}
+
+// redirectTarget returns the domain a fetch of requested ended up on when the
+// redirect left its root domain, or "" otherwise
+// Redirects within a root domain (e.g. to www) are the same site and not edges
+func redirectTarget(requested, final string) string {
+	if requested == "" || final == "" || requested == final {
+		return ""
+	}
+	if ExtractRootDomain(requested) == ExtractRootDomain(final) {
+		return ""
+	}
+	return final
+}
@@ -7,6 +7,7 @@ import (
 // SubdomainLimiter enforces max subdomains per root domain
 type SubdomainLimiter struct {
 	maxPerRoot int
+	rootOf     func(domain string) string
 	mu         sync.RWMutex
 	// Map: rootDomain -> set of subdomains
 	subdomains map[string]map[string]bool
@@ -16,14 +17,22 @@ type SubdomainLimiter struct {
 func NewSubdomainLimiter(maxPerRoot int) *SubdomainLimiter {
 	return &SubdomainLimiter{
 		maxPerRoot: maxPerRoot,
+		rootOf:     ExtractRootDomain,
 		subdomains: make(map[string]map[string]bool),
 	}
 }
 
+// SetRootFunc replaces how a domain's root is found, e.g. to count each
+// tenant of a hosting platform as a root of its own
+// Call it before any domain is added
+func (sl *SubdomainLimiter) SetRootFunc(rootOf func(domain string) string) {
+	sl.rootOf = rootOf
+}
+
 // CanAdd checks if a domain can be added without exceeding the limit
 // Does NOT modify state - use Add() to register the domain
 func (sl *SubdomainLimiter) CanAdd(domain string) bool {
-	rootDomain := ExtractRootDomain(domain)
+	rootDomain := sl.rootOf(domain)
 
 	sl.mu.RLock()
 	defer sl.mu.RUnlock()
@@ -47,7 +56,7 @@ func (sl *SubdomainLimiter) CanAdd(domain string) bool {
 // Add registers a domain with the limiter
 // Returns true if added successfully, false if limit exceeded
 func (sl *SubdomainLimiter) Add(domain string) bool {
-	rootDomain := ExtractRootDomain(domain)
+	rootDomain := sl.rootOf(domain)
 
 	sl.mu.Lock()
 	defer sl.mu.Unlock()
@@ -84,3 +93,58 @@ func (sl *SubdomainLimiter) Count(rootDomain string) int {
 	}
 	return 0
 }
+
+// KnowsRoot reports whether any subdomain of the domain's root is registered
+func (sl *SubdomainLimiter) KnowsRoot(domain string) bool {
+	rootDomain := sl.rootOf(domain)
+
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+
+	return len(sl.subdomains[rootDomain]) > 0
+}
+
+// Snapshot returns the registered subdomains keyed by root domain
+func (sl *SubdomainLimiter) Snapshot() map[string][]string {
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+
+	snapshot := make(map[string][]string, len(sl.subdomains))
+	for root, subdomainSet := range sl.subdomains {
+		for domain := range subdomainSet {
+			snapshot[root] = append(snapshot[root], domain)
+		}
+	}
+	return snapshot
+}
+
+// Restore registers previously counted subdomains, e.g. on resume
+// Restored sets may exceed the limit if it was lowered; they are kept as is
+func (sl *SubdomainLimiter) Restore(subdomains map[string][]string) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	for root, domains := range subdomains {
+		if sl.subdomains[root] == nil {
+			sl.subdomains[root] = make(map[string]bool)
+		}
+		for _, domain := range domains {
+			sl.subdomains[root][domain] = true
+		}
+	}
+}
+
+// Stats returns the number of root domains tracked, subdomains registered,
+// and root domains that have reached the limit
+func (sl *SubdomainLimiter) Stats() (roots, subdomains, saturated int) {
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+
+	for _, subdomainSet := range sl.subdomains {
+		subdomains += len(subdomainSet)
+		if len(subdomainSet) >= sl.maxPerRoot {
+			saturated++
+		}
+	}
+	return len(sl.subdomains), subdomains, saturated
+}
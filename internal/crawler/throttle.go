@@ -0,0 +1,254 @@
+package crawler
+
+import (
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// clockTicksPerSecond is the USER_HZ used by /proc/self/stat on Linux
+const clockTicksPerSecond = 100
+
+// ResourceThrottle shrinks effective worker parallelism under memory or CPU
+// pressure and restores it gradually once pressure drops; frontier entries
+// found meanwhile are held back until then
+type ResourceThrottle struct {
+	maxRSSBytes   uint64
+	maxCPUPercent float64
+	maxWorkers    int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int                  // workers with id > limit are parked
+	paused  bool                 // true while under pressure: no new frontier entries
+	held    []storage.QueueEntry // entries found while paused, in order
+	stopped bool
+
+	lastCPU    time.Duration
+	lastSample time.Time
+}
+
+// NewResourceThrottle creates a throttle; zero thresholds disable that check
+func NewResourceThrottle(maxRSSMB int, maxCPUPercent float64, maxWorkers int) *ResourceThrottle {
+	t := &ResourceThrottle{
+		maxRSSBytes:   uint64(maxRSSMB) * 1024 * 1024,
+		maxCPUPercent: maxCPUPercent,
+		maxWorkers:    maxWorkers,
+		limit:         maxWorkers,
+	}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+// Enabled reports whether any threshold is configured
+func (t *ResourceThrottle) Enabled() bool {
+	return t.maxRSSBytes > 0 || t.maxCPUPercent > 0
+}
+
+// Run samples resource usage at the given interval until stop is closed,
+// handing the held entries to release once pressure eases, or once starved
+// reports the workers have nothing else left to fetch
+func (t *ResourceThrottle) Run(interval time.Duration, release func(storage.QueueEntry), starved func() bool, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if t.sample() || starved() {
+				t.release(release)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// release hands the held entries to fn; they stay held until handed over,
+// so the crawl doesn't look drained in between
+func (t *ResourceThrottle) release(fn func(storage.QueueEntry)) {
+	t.mu.Lock()
+	held := t.held
+	t.mu.Unlock()
+	if len(held) == 0 {
+		return
+	}
+
+	logrus.Infof("Resource throttle: enqueueing %d entries held back under pressure", len(held))
+	for _, entry := range held {
+		fn(entry)
+	}
+
+	// Entries held meanwhile, if pressure is back, were appended after them
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.held = t.held[len(held):]
+}
+
+// sample checks current usage and adjusts the worker limit, reporting
+// whether frontier growth is allowed
+func (t *ResourceThrottle) sample() bool {
+	rss := readRSS()
+	cpu := t.cpuPercent()
+
+	overRSS := t.maxRSSBytes > 0 && rss > t.maxRSSBytes
+	overCPU := t.maxCPUPercent > 0 && cpu > t.maxCPUPercent
+
+	// Recover only once comfortably below thresholds to avoid flapping
+	calmRSS := t.maxRSSBytes == 0 || float64(rss) < 0.8*float64(t.maxRSSBytes)
+	calmCPU := t.maxCPUPercent == 0 || cpu < 0.8*t.maxCPUPercent
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous := t.limit
+	switch {
+	case overRSS || overCPU:
+		t.limit = max(1, t.limit/2)
+		t.paused = true
+		if overRSS {
+			// Give the GC a chance to return memory before the next sample
+			runtime.GC()
+		}
+	case calmRSS && calmCPU:
+		t.limit = min(t.maxWorkers, t.limit+1)
+		t.paused = false
+	}
+
+	if t.limit != previous {
+		logrus.Warnf("Resource throttle: workers %d -> %d (rss=%dMB, cpu=%.0f%%, enqueue paused=%v)",
+			previous, t.limit, rss/(1024*1024), cpu, t.paused)
+		t.cond.Broadcast()
+	}
+	return !t.paused
+}
+
+// Wait blocks worker id while it is above the current limit
+// Returns false if the throttle was stopped
+func (t *ResourceThrottle) Wait(id int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id > t.limit && !t.stopped {
+		t.cond.Wait()
+	}
+	return !t.stopped
+}
+
+// Paused reports whether frontier growth is currently suspended
+func (t *ResourceThrottle) Paused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
+
+// Hold keeps entry back while frontier growth is paused, reporting whether
+// it did; Run hands held entries back once pressure eases
+func (t *ResourceThrottle) Hold(entry storage.QueueEntry) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.paused {
+		return false
+	}
+	t.held = append(t.held, entry)
+	return true
+}
+
+// Held returns the entries held back, for checkpoints; one being handed
+// back may be in the frontier too
+func (t *ResourceThrottle) Held() []storage.QueueEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.held)
+}
+
+// Holding returns how many entries are held back; they are in neither the
+// frontier nor in flight
+func (t *ResourceThrottle) Holding() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.held)
+}
+
+// Stop releases all parked workers
+func (t *ResourceThrottle) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	t.cond.Broadcast()
+}
+
+// cpuPercent returns process CPU usage since the previous sample
+// 100% equals one fully busy core; returns 0 when unavailable
+func (t *ResourceThrottle) cpuPercent() float64 {
+	now := time.Now()
+	cpu, ok := readCPUTime()
+	if !ok {
+		return 0
+	}
+
+	var percent float64
+	if !t.lastSample.IsZero() {
+		elapsed := now.Sub(t.lastSample)
+		if elapsed > 0 {
+			percent = float64(cpu-t.lastCPU) / float64(elapsed) * 100
+		}
+	}
+
+	t.lastCPU = cpu
+	t.lastSample = now
+	return percent
+}
+
+// readRSS returns the resident set size from /proc, falling back to the
+// memory obtained from the OS by the Go runtime
+func readRSS() uint64 {
+	if data, err := os.ReadFile("/proc/self/statm"); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) >= 2 {
+			if pages, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+				return pages * uint64(os.Getpagesize())
+			}
+		}
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.Sys
+}
+
+// readCPUTime returns total user+system CPU time consumed by the process
+func readCPUTime() (time.Duration, bool) {
+	data, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return 0, false
+	}
+
+	// The command name may contain spaces; fields start after the last ')'
+	stat := string(data)
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(stat[end+1:])
+	// utime and stime are fields 14 and 15 overall, 12 and 13 here
+	if len(fields) < 13 {
+		return 0, false
+	}
+
+	utime, err1 := strconv.ParseUint(fields[11], 10, 64)
+	stime, err2 := strconv.ParseUint(fields[12], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+
+	ticks := utime + stime
+	return time.Duration(ticks) * time.Second / clockTicksPerSecond, true
+}
@@ -0,0 +1,73 @@
+package crawler
+
+import (
+	"maps"
+	"strings"
+	"sync"
+)
+
+// TLDFilter admits domains by top-level domain: with an allow list only the
+// listed TLDs pass, and blocked TLDs never do
+// A TLD entry matches the domain's last labels, so "uk" matches every
+// .uk domain and "co.uk" only those under co.uk
+type TLDFilter struct {
+	allowed []string
+	blocked []string
+
+	mu    sync.Mutex
+	skips map[string]int // TLD -> links skipped
+}
+
+// NewTLDFilter creates a filter from TLDs normalized by config.LoadConfig
+func NewTLDFilter(allowed, blocked []string) *TLDFilter {
+	return &TLDFilter{allowed: allowed, blocked: blocked, skips: make(map[string]int)}
+}
+
+// Allows reports whether a domain may be crawled, counting a skip if not
+func (f *TLDFilter) Allows(domain string) bool {
+	allowed, tld := f.Match(domain)
+	if !allowed {
+		f.mu.Lock()
+		f.skips[tld]++
+		f.mu.Unlock()
+	}
+	return allowed
+}
+
+// Match reports whether a domain may be crawled and the TLD that decided
+// it: the matching blocked or allowed entry, or the domain's last label
+// when it matched none
+func (f *TLDFilter) Match(domain string) (bool, string) {
+	if tld := matchTLD(domain, f.blocked); tld != "" {
+		return false, tld
+	}
+	if len(f.allowed) == 0 {
+		return true, lastLabel(domain)
+	}
+	if tld := matchTLD(domain, f.allowed); tld != "" {
+		return true, tld
+	}
+	return false, lastLabel(domain)
+}
+
+// Skips returns how many links were skipped per TLD
+func (f *TLDFilter) Skips() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return maps.Clone(f.skips)
+}
+
+// matchTLD returns the first of tlds that domain ends with, or ""
+func matchTLD(domain string, tlds []string) string {
+	for _, tld := range tlds {
+		if domain == tld || strings.HasSuffix(domain, "."+tld) {
+			return tld
+		}
+	}
+	return ""
+}
+
+// lastLabel returns the domain's top-level label
+func lastLabel(domain string) string {
+	return domain[strings.LastIndex(domain, ".")+1:]
+}
@@ -0,0 +1,248 @@
+package crawler
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/gocolly/colly/v2"
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// PageSummary is what the tokenizer html_parser reads from a page besides
+// its links
+type PageSummary struct {
+	Title       string
+	Description string // meta description, else the Open Graph one
+	Language    string // as declared by <html lang> or a Content-Language meta tag
+	Refs        []Ref  // canonical, hreflang, feed and meta refresh targets, in page order
+}
+
+// Ref is a structural reference read by ScanHTML: a <link> followed as a
+// canonical, hreflang or feed edge, or a meta refresh redirect
+type Ref struct {
+	Href string // as written
+	Base string // the page's <base href> if one came before the reference
+	Type string // storage.EdgeCanonical, EdgeHreflang, EdgeFeed or EdgeRedirect
+}
+
+// Link is an <a href> read by ScanHTML
+type Link struct {
+	Href string // as written
+	Base string // the page's <base href> if one came before the link
+	Text string // raw text inside the link, at most maxLinkTextBytes of it
+	Rel  string
+}
+
+// maxLinkTextBytes bounds the text ScanHTML collects for a link, so an
+// unclosed <a> doesn't gather the rest of the page
+const maxLinkTextBytes = 4096
+
+// ScanHTML reads a page in one pass of the HTML tokenizer, without building
+// a DOM: link is called for every <a href> once its text has been read, at
+// its end tag or wherever the next <a> or the end of the page closes it.
+// The title and description are those the default selectors would find;
+// configured selectors don't apply. Structural references are collected in
+// the summary, as the goquery callbacks would follow them
+func ScanHTML(body []byte, link func(Link)) PageSummary {
+	var (
+		page          PageSummary
+		base          string
+		ogDescription string
+		titleSeen     bool
+		inTitle       bool
+		current       *Link // the <a href> being read
+	)
+	endLink := func() {
+		if current != nil {
+			link(*current)
+			current = nil
+		}
+	}
+	z := nethtml.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		switch tt {
+		case nethtml.ErrorToken:
+			endLink()
+			if page.Description == "" {
+				page.Description = ogDescription
+			}
+			return page
+
+		case nethtml.TextToken:
+			if inTitle {
+				page.Title += string(z.Text())
+			}
+			if current != nil && len(current.Text) < maxLinkTextBytes {
+				current.Text += string(z.Text())
+			}
+
+		case nethtml.EndTagToken:
+			switch name, _ := z.TagName(); atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.A:
+				endLink()
+			}
+
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.A:
+				// Links don't nest; a new one closes the last
+				endLink()
+				var (
+					href, rel string
+					hasHref   bool
+				)
+				for more := hasAttr; more; {
+					var key, val []byte
+					key, val, more = z.TagAttr()
+					switch string(key) {
+					case "href":
+						if !hasHref {
+							href, hasHref = string(val), true
+						}
+					case "rel":
+						rel = string(val)
+					}
+				}
+				if hasHref {
+					current = &Link{Href: href, Base: base, Rel: rel}
+				}
+			case atom.Title:
+				// The first title is the page's; later ones belong to
+				// inline SVGs and the like
+				inTitle = !titleSeen && tt == nethtml.StartTagToken
+				titleSeen = true
+			case atom.Link:
+				var href, rel, hreflang, mimeType string
+				var hasHref bool
+				for more := hasAttr; more; {
+					var key, val []byte
+					key, val, more = z.TagAttr()
+					switch string(key) {
+					case "href":
+						if !hasHref {
+							href, hasHref = string(val), true
+						}
+					case "rel":
+						rel = string(val)
+					case "hreflang":
+						hreflang = string(val)
+					case "type":
+						mimeType = string(val)
+					}
+				}
+				if edgeType := structuralEdgeType(rel, hreflang, mimeType); hasHref && edgeType != "" {
+					page.Refs = append(page.Refs, Ref{Href: href, Base: base, Type: edgeType})
+				}
+			case atom.Base:
+				if base == "" {
+					base = tagAttr(z, hasAttr, "href")
+				}
+			case atom.Html:
+				if page.Language == "" {
+					page.Language = tagAttr(z, hasAttr, "lang")
+				}
+			case atom.Meta:
+				var name, property, httpEquiv, content string
+				for more := hasAttr; more; {
+					var key, val []byte
+					key, val, more = z.TagAttr()
+					switch string(key) {
+					case "name":
+						name = string(val)
+					case "property":
+						property = string(val)
+					case "http-equiv":
+						httpEquiv = string(val)
+					case "content":
+						content = string(val)
+					}
+				}
+				switch {
+				case strings.EqualFold(name, "description") && page.Description == "":
+					page.Description = content
+				case property == "og:description" && ogDescription == "":
+					ogDescription = content
+				case strings.EqualFold(httpEquiv, "content-language") && page.Language == "":
+					page.Language = content
+				case strings.EqualFold(strings.TrimSpace(httpEquiv), "refresh"):
+					if target := metaRefreshTarget(content); target != "" {
+						page.Refs = append(page.Refs, Ref{Href: target, Base: base, Type: storage.EdgeRedirect})
+					}
+				}
+			}
+		}
+	}
+}
+
+// tagAttr returns the value of the current tag's attribute key, or ""
+func tagAttr(z *nethtml.Tokenizer, hasAttr bool, key string) string {
+	for more := hasAttr; more; {
+		var k, v []byte
+		k, v, more = z.TagAttr()
+		if string(k) == key {
+			return string(v)
+		}
+	}
+	return ""
+}
+
+// scanPage reads an HTML response with the tokenizer html_parser: its
+// links go through the same selection as those of the goquery callbacks,
+// its structural references become edges as they do there, and the front
+// page's title, description and language are stored
+func (c *Crawler) scanPage(r *colly.Response) {
+	if !isHTML(r) {
+		return
+	}
+	domain, err := ExtractDomain(r.Request.URL.String())
+	if err != nil || domain == "" {
+		return
+	}
+	ctx := c.fetchEntry(r.Ctx, domain)
+	if ctx == nil {
+		return
+	}
+	links, ok := r.Ctx.GetAny(linkStatsKey).(*pageLinks)
+	if !ok {
+		return
+	}
+
+	page := ScanHTML(r.Body, func(l Link) {
+		c.addLink(ctx, links, domain, l.Href, absoluteURL(r.Request, l.Href, l.Base), l.Text, l.Rel)
+	})
+	// Meta refreshes only count when they leave the root domain
+	pageURL := r.Request.URL.String()
+	for _, ref := range page.Refs {
+		c.recordPageEdge(r.Ctx, pageURL, absoluteURL(r.Request, ref.Href, ref.Base), ref.Type, ref.Type == storage.EdgeRedirect, true)
+	}
+	// A node's title and description are its front page's
+	if !innerPage(r.Ctx) {
+		c.setPageText(ctx.DomainName, page.Title, page.Description, primaryLanguage(page.Language, r))
+	}
+}
+
+// absoluteURL resolves href as Colly does for the goquery callbacks: against
+// the page's <base href> if it has one, else the page URL
+func absoluteURL(r *colly.Request, href, base string) string {
+	if base == "" {
+		return r.AbsoluteURL(href)
+	}
+	baseURL, err := url.Parse(r.AbsoluteURL(base))
+	if err != nil || baseURL.Host == "" {
+		return r.AbsoluteURL(href)
+	}
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	resolved := baseURL.ResolveReference(ref)
+	resolved.Fragment = ""
+	return resolved.String()
+}
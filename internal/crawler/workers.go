@@ -0,0 +1,155 @@
+package crawler
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Worker states reported by Crawler.Workers
+const (
+	WorkerIdle        = "idle"        // waiting for the frontier to hand out a ready host
+	WorkerParked      = "parked"      // held back by the resource throttle or autoscaler
+	WorkerDispatching = "dispatching" // checking a popped entry and scheduling its fetch
+	WorkerStopped     = "stopped"
+)
+
+// fetchIDKey is the colly request context key identifying a tracked fetch
+const fetchIDKey = "worker_fetch"
+
+// WorkerStatus is a snapshot of one worker. Fetches run asynchronously, so
+// a worker keeps popping entries while its earlier fetches are in flight;
+// those are listed oldest first, and one that stays there long past the
+// request timeout points at a hanging connection
+type WorkerStatus struct {
+	ID     int
+	State  string // one of the Worker* states
+	Domain string // entry being dispatched
+	Since  time.Time
+
+	Fetching []WorkerFetch
+	Fetches  int // fetches scheduled since the start
+
+	LastError       string
+	LastErrorDomain string
+	LastErrorAt     time.Time
+}
+
+// WorkerFetch is an in-flight fetch scheduled by a worker
+type WorkerFetch struct {
+	Domain string
+	URL    string
+	Since  time.Time
+}
+
+// workerSlot is the board's record of one worker
+type workerSlot struct {
+	status   WorkerStatus
+	fetching map[uint64]WorkerFetch
+}
+
+// WorkerBoard tracks what each worker is doing, for status reporting
+type WorkerBoard struct {
+	mu      sync.Mutex
+	slots   map[int]*workerSlot
+	fetches map[uint64]int // fetch ID -> worker ID
+	nextID  uint64
+}
+
+// NewWorkerBoard creates an empty board
+func NewWorkerBoard() *WorkerBoard {
+	return &WorkerBoard{
+		slots:   make(map[int]*workerSlot),
+		fetches: make(map[uint64]int),
+	}
+}
+
+// slot returns worker id's record, creating it; the caller holds mu
+func (b *WorkerBoard) slot(id int) *workerSlot {
+	s, ok := b.slots[id]
+	if !ok {
+		s = &workerSlot{status: WorkerStatus{ID: id}, fetching: make(map[uint64]WorkerFetch)}
+		b.slots[id] = s
+	}
+	return s
+}
+
+// Set records that worker id entered state, about domain if any. Since
+// only moves when the state or domain changes
+func (b *WorkerBoard) Set(id int, state, domain string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.slot(id)
+	if s.status.State == state && s.status.Domain == domain {
+		return
+	}
+	s.status.State, s.status.Domain, s.status.Since = state, domain, time.Now()
+}
+
+// StartFetch records a fetch of url scheduled by worker id and returns the
+// request context that identifies it to FinishFetch
+func (b *WorkerBoard) StartFetch(id int, domain, url string) *colly.Context {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	s := b.slot(id)
+	s.fetching[b.nextID] = WorkerFetch{Domain: domain, URL: url, Since: time.Now()}
+	s.status.Fetches++
+	b.fetches[b.nextID] = id
+
+	ctx := colly.NewContext()
+	ctx.Put(fetchIDKey, b.nextID)
+	return ctx
+}
+
+// FinishFetch records the end of the fetch identified by ctx, failed with
+// err unless it is nil. Contexts of untracked requests are ignored
+func (b *WorkerBoard) FinishFetch(ctx *colly.Context, err error) {
+	if ctx == nil {
+		return
+	}
+	fetchID, ok := ctx.GetAny(fetchIDKey).(uint64)
+	if !ok {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id, ok := b.fetches[fetchID]
+	if !ok {
+		return
+	}
+	delete(b.fetches, fetchID)
+	s := b.slots[id]
+	fetch := s.fetching[fetchID]
+	delete(s.fetching, fetchID)
+	if err != nil {
+		s.status.LastError = err.Error()
+		s.status.LastErrorDomain = fetch.Domain
+		s.status.LastErrorAt = time.Now()
+	}
+}
+
+// Snapshot returns every worker's status, by worker ID
+func (b *WorkerBoard) Snapshot() []WorkerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	statuses := make([]WorkerStatus, 0, len(b.slots))
+	for _, s := range b.slots {
+		status := s.status
+		status.Fetching = make([]WorkerFetch, 0, len(s.fetching))
+		for _, fetch := range s.fetching {
+			status.Fetching = append(status.Fetching, fetch)
+		}
+		slices.SortFunc(status.Fetching, func(a, b WorkerFetch) int { return a.Since.Compare(b.Since) })
+		statuses = append(statuses, status)
+	}
+	slices.SortFunc(statuses, func(a, b WorkerStatus) int { return a.ID - b.ID })
+	return statuses
+}
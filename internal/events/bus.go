@@ -0,0 +1,81 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published by the crawler
+const (
+	NodeDiscovered = "node_discovered"
+	EdgeRecorded   = "edge_recorded"
+	PageFetched    = "page_fetched"
+	FetchFailed    = "fetch_failed"
+)
+
+// subscriberBuffer is how many events a slow subscriber may lag behind
+// before further events are dropped for it
+const subscriberBuffer = 256
+
+// Event is a single crawl occurrence streamed to live consumers
+type Event struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	Domain string    `json:"domain"`
+	Target string    `json:"target,omitempty"`
+	Edge   string    `json:"edge_type,omitempty"`
+	Depth  int       `json:"depth"`
+	Status int       `json:"status,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Bus fans events out to subscribers without ever blocking the publisher
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+}
+
+// NewBus creates an event bus with no subscribers
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish delivers an event to every subscriber with room in its buffer
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Subscriber is lagging; drop rather than stall the crawl
+		}
+	}
+}
+
+// Subscribe returns a channel of future events and a function to release it
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
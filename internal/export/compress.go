@@ -0,0 +1,46 @@
+package export
+
+import (
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// nopWriteCloser adapts a writer that needs no closing
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// Compression returns the compression implied by a file name's extension:
+// "gzip" for .gz, "zstd" for .zst/.zstd, or "" for none
+func Compression(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		return "gzip"
+	case ".zst", ".zstd":
+		return "zstd"
+	default:
+		return ""
+	}
+}
+
+// NewCompressedWriter wraps w with the compression implied by path
+// Closing the returned writer flushes the compressed stream but not w
+func NewCompressedWriter(w io.Writer, path string) (io.WriteCloser, error) {
+	switch Compression(path) {
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "zstd":
+		return zstd.NewWriter(w)
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// cytoNode is a Cytoscape.js node element
+type cytoNode struct {
+	Data struct {
+		ID          string `json:"id"`
+		Label       string `json:"label"`
+		Description string `json:"description"`
+		CrawlCount  int    `json:"crawl_count"`
+		Depth       int    `json:"depth"`
+		Category    string `json:"category,omitempty"`
+	} `json:"data"`
+	Position struct {
+		X float64 `json:"x"`
+		Y float64 `json:"y"`
+	} `json:"position"`
+}
+
+// cytoEdge is a Cytoscape.js edge element
+type cytoEdge struct {
+	Data struct {
+		ID         string  `json:"id"`
+		Source     string  `json:"source"`
+		Target     string  `json:"target"`
+		Type       string  `json:"type"`
+		Weight     float64 `json:"weight"`
+		AnchorText string  `json:"anchor_text,omitempty"`
+		Rel        string  `json:"rel,omitempty"`
+	} `json:"data"`
+}
+
+// writeCytoscape writes {"elements": {"nodes": [...], "edges": [...]}},
+// accepted directly by cytoscape({elements: ...})
+func writeCytoscape(w io.Writer, g Graph, opts Options) error {
+	if _, err := io.WriteString(w, `{"elements":{"nodes":[`); err != nil {
+		return err
+	}
+
+	nodes := jsonArray{w: w}
+	err := g.ForEachNode(func(node *storage.Node) error {
+		var el cytoNode
+		el.Data.ID = "n" + strconv.Itoa(node.NodeID)
+		el.Data.Label = node.DomainName
+		el.Data.Description = node.Description
+		el.Data.CrawlCount = node.CrawlCount
+		el.Data.Depth = node.LastDepth
+		el.Data.Category = node.Category
+		el.Position.X, el.Position.Y = spiralPosition(nodes.count)
+		return nodes.add(el)
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, `],"edges":[`); err != nil {
+		return err
+	}
+
+	edges := jsonArray{w: w}
+	err = g.ForEachEdge(func(edge *storage.Edge) error {
+		var el cytoEdge
+		el.Data.ID = "e" + strconv.Itoa(edge.EdgeID)
+		el.Data.Source = "n" + strconv.Itoa(edge.FromNodeID)
+		el.Data.Target = "n" + strconv.Itoa(edge.ToNodeID)
+		el.Data.Type = edge.Type
+		el.Data.Weight = opts.weight(edge)
+		el.Data.AnchorText = edge.AnchorText
+		el.Data.Rel = edge.Rel
+		return edges.add(el)
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}}\n")
+	return err
+}
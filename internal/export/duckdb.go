@@ -0,0 +1,147 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// duckDBScript builds an analytical DuckDB database from the crawl database
+// through DuckDB's sqlite extension, then adds views for common questions
+// Every column is read as text (sqlite_all_varchar) and cast explicitly, as
+// SQLite's loose typing otherwise trips the scanner on mixed-type columns
+var duckDBScript = template.Must(template.New("duckdb").Parse(`-- Web Weaver analytics export for DuckDB
+-- Usage: duckdb analytics.duckdb < this-file.sql
+-- Reads {{.DBPath}} (session {{.Session}}); re-run to refresh
+
+INSTALL sqlite;
+LOAD sqlite;
+SET sqlite_all_varchar = true;
+ATTACH {{.DBPathLiteral}} AS crawl (TYPE sqlite, READ_ONLY);
+
+CREATE OR REPLACE TABLE nodes AS
+SELECT
+    CAST(node_id AS BIGINT) AS node_id,
+    domain_name AS domain,
+    regexp_extract(domain_name, '([^.]+\.[^.]+)$', 1) AS root_domain,
+    NULLIF(title, '') AS title,
+    NULLIF(meta_description, '') AS meta_description,
+    COALESCE(NULLIF(title, ''), NULLIF(meta_description, ''), description) AS description,
+    CAST(crawl_count AS INTEGER) AS crawl_count,
+    CAST(last_depth AS INTEGER) AS last_depth,
+    CAST(links_total AS INTEGER) AS links_total,
+    CAST(links_internal AS INTEGER) AS links_internal,
+    CAST(links_external AS INTEGER) AS links_external,
+    CAST(external_domains AS INTEGER) AS external_domains,
+    NULLIF(category, '') AS category,
+    TRY_CAST(created_at AS TIMESTAMP) AS created_at
+FROM crawl.nodes
+WHERE session = {{.SessionLiteral}} AND CAST(tombstoned AS INTEGER) = 0{{if .CategoriesLiteral}}
+  AND category IN ({{.CategoriesLiteral}}){{end}};
+
+CREATE OR REPLACE TABLE edges AS
+SELECT
+    CAST(e.edge_id AS BIGINT) AS edge_id,
+    CAST(e.from_node_id AS BIGINT) AS from_node_id,
+    CAST(e.to_node_id AS BIGINT) AS to_node_id,
+    e.edge_type,
+    CAST(e.weight AS INTEGER) AS weight,
+    e.anchor_text,
+    e.rel
+FROM crawl.edges e
+WHERE CAST(e.from_node_id AS BIGINT) IN (SELECT node_id FROM nodes)
+  AND CAST(e.to_node_id AS BIGINT) IN (SELECT node_id FROM nodes){{if .EdgeTypesLiteral}}
+  AND e.edge_type IN ({{.EdgeTypesLiteral}}){{end}};
+
+DETACH crawl;
+
+-- Edges with domain names instead of IDs
+CREATE OR REPLACE VIEW edges_named AS
+SELECT f.domain AS from_domain, t.domain AS to_domain, e.edge_type, e.weight, e.anchor_text, e.rel
+FROM edges e
+JOIN nodes f ON f.node_id = e.from_node_id
+JOIN nodes t ON t.node_id = e.to_node_id;
+
+-- In, out, and weighted degree per node
+CREATE OR REPLACE VIEW node_degree AS
+SELECT
+    n.node_id,
+    n.domain,
+    n.root_domain,
+    COALESCE(o.out_degree, 0) AS out_degree,
+    COALESCE(i.in_degree, 0) AS in_degree,
+    COALESCE(o.out_degree, 0) + COALESCE(i.in_degree, 0) AS degree,
+    COALESCE(i.in_weight, 0) AS in_weight
+FROM nodes n
+LEFT JOIN (SELECT from_node_id, COUNT(*) AS out_degree FROM edges GROUP BY from_node_id) o
+    ON o.from_node_id = n.node_id
+LEFT JOIN (SELECT to_node_id, COUNT(*) AS in_degree, SUM(weight) AS in_weight FROM edges GROUP BY to_node_id) i
+    ON i.to_node_id = n.node_id;
+
+-- Root domains ranked by how many distinct nodes link to them
+CREATE OR REPLACE VIEW top_domains AS
+SELECT
+    t.root_domain,
+    COUNT(DISTINCT t.node_id) AS subdomains,
+    COUNT(DISTINCT e.from_node_id) FILTER (WHERE f.root_domain <> t.root_domain) AS referring_nodes,
+    COALESCE(SUM(e.weight) FILTER (WHERE f.root_domain <> t.root_domain), 0) AS inbound_links
+FROM nodes t
+LEFT JOIN edges e ON e.to_node_id = t.node_id
+LEFT JOIN nodes f ON f.node_id = e.from_node_id
+GROUP BY t.root_domain
+ORDER BY referring_nodes DESC, inbound_links DESC;
+
+-- Node counts and connectivity per crawl depth
+CREATE OR REPLACE VIEW depth_distribution AS
+SELECT
+    n.last_depth AS depth,
+    COUNT(*) AS nodes,
+    COUNT(*) FILTER (WHERE n.crawl_count > 0) AS crawled,
+    AVG(d.out_degree) AS avg_out_degree,
+    AVG(d.in_degree) AS avg_in_degree
+FROM nodes n
+JOIN node_degree d ON d.node_id = n.node_id
+GROUP BY n.last_depth
+ORDER BY n.last_depth;
+`))
+
+// WriteDuckDBScript writes a DuckDB SQL script that imports the given
+// session of the SQLite database at dbPath and creates analysis views
+// Only edges of edgeTypes are imported, and only nodes of categories with
+// the edges between them; empty means all
+// Unlike the graph formats it references the database instead of
+// streaming it, so DuckDB does the bulk copy
+func WriteDuckDBScript(w io.Writer, dbPath, session string, edgeTypes, categories []string) error {
+	if err := duckDBScript.Execute(w, map[string]string{
+		"DBPath":            sqlComment(dbPath),
+		"Session":           sqlComment(session),
+		"DBPathLiteral":     sqlLiteral(dbPath),
+		"SessionLiteral":    sqlLiteral(session),
+		"EdgeTypesLiteral":  sqlList(edgeTypes),
+		"CategoriesLiteral": sqlList(categories),
+	}); err != nil {
+		return fmt.Errorf("failed to write duckdb script: %w", err)
+	}
+	return nil
+}
+
+// sqlList quotes values as a comma-separated list of SQL string literals
+func sqlList(values []string) string {
+	literals := make([]string, len(values))
+	for i, value := range values {
+		literals[i] = sqlLiteral(value)
+	}
+	return strings.Join(literals, ", ")
+}
+
+// sqlComment escapes the line breaks of s, so it can't end the -- comment
+// it is written into and have the rest run as SQL
+func sqlComment(s string) string {
+	return strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(s)
+}
+
+// sqlLiteral quotes s as a SQL string literal
+func sqlLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,132 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// pageSize is how many rows are read from storage per query while exporting
+const pageSize = 1000
+
+// Graph is a read-only view of the crawl graph consumed by exporters
+// Nodes are visited before edges so formats can reference node keys
+type Graph interface {
+	ForEachNode(fn func(*storage.Node) error) error
+	ForEachEdge(fn func(*storage.Edge) error) error
+}
+
+// Exporter writes a graph in one output format, rendering edge weights
+// as opts asks
+type Exporter func(w io.Writer, g Graph, opts Options) error
+
+// formats maps format names to their exporters
+var formats = map[string]Exporter{
+	"cytoscape": writeCytoscape,
+	"graphml":   writeGraphML,
+	"sigma":     writeSigma,
+}
+
+// Formats returns the supported format names, sorted
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Write exports g to w in the named format, applying the transforms in opts
+func Write(w io.Writer, format string, g Graph, opts Options) error {
+	exporter, ok := formats[format]
+	if !ok {
+		return fmt.Errorf("unknown export format %q (supported: %v)", format, Formats())
+	}
+	return exporter(w, opts.filter(g), opts)
+}
+
+// StoreGraph streams the whole graph from storage page by page
+type StoreGraph struct {
+	Store      *storage.Storage
+	EdgeTypes  []string // edge types to include; empty means all
+	Categories []string // node categories to include, with the edges between them; empty means all
+}
+
+// ForEachNode visits every stored node of the selected categories in ID
+// order
+func (g StoreGraph) ForEachNode(fn func(*storage.Node) error) error {
+	afterID := 0
+	for {
+		nodes, err := g.Store.ListNodes(storage.NodeFilter{Categories: g.Categories}, afterID, pageSize)
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			if err := fn(node); err != nil {
+				return err
+			}
+		}
+		if len(nodes) < pageSize {
+			return nil
+		}
+		afterID = nodes[len(nodes)-1].NodeID
+	}
+}
+
+// ForEachEdge visits every stored edge of the selected types between
+// nodes of the selected categories in ID order
+func (g StoreGraph) ForEachEdge(fn func(*storage.Edge) error) error {
+	afterID := 0
+	for {
+		edges, err := g.Store.ListEdges(storage.EdgeFilter{Types: g.EdgeTypes, Categories: g.Categories}, afterID, pageSize)
+		if err != nil {
+			return err
+		}
+		for _, edge := range edges {
+			if err := fn(edge); err != nil {
+				return err
+			}
+		}
+		if len(edges) < pageSize {
+			return nil
+		}
+		afterID = edges[len(edges)-1].EdgeID
+	}
+}
+
+// jsonArray streams JSON array elements without holding them in memory
+type jsonArray struct {
+	w     io.Writer
+	count int
+}
+
+// add encodes one element, prefixing a separator when needed
+func (a *jsonArray) add(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if a.count > 0 {
+		if _, err := io.WriteString(a.w, ","); err != nil {
+			return err
+		}
+	}
+	a.count++
+	_, err = a.w.Write(data)
+	return err
+}
+
+// spiralPosition spreads nodes on a golden-angle spiral as a placeholder
+// layout until the front-end runs its own; it needs no total node count,
+// so it works while streaming
+func spiralPosition(index int) (float64, float64) {
+	const goldenAngle = 2.399963229728653
+	radius := 10 * math.Sqrt(float64(index))
+	angle := float64(index) * goldenAngle
+	return math.Round(radius*math.Cos(angle)*100) / 100, math.Round(radius*math.Sin(angle)*100) / 100
+}
@@ -0,0 +1,255 @@
+package export
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// Sampling methods
+const (
+	SampleForestFire = "forest-fire"
+	SampleRandomWalk = "random-walk"
+	SampleEgo        = "ego"
+)
+
+// SampleMethods lists the sampling methods, for validation and help text
+var SampleMethods = []string{SampleForestFire, SampleRandomWalk, SampleEgo}
+
+const (
+	// burnProbability is the forest fire forward burning probability; each
+	// burning node ignites a geometric number of neighbors with mean p/(1-p)
+	burnProbability = 0.7
+	// restartProbability is how often a random walk jumps back to its start
+	restartProbability = 0.15
+	// maxStaleSteps is how many steps a random walk may take without
+	// reaching a new node before it restarts from a random node
+	maxStaleSteps = 100
+)
+
+// SampleOptions selects a subgraph small enough to render
+type SampleOptions struct {
+	Method string
+	Nodes  int   // target sample size for forest-fire and random-walk
+	Seed   int64 // RNG seed; the same seed and graph give the same sample
+	Center int   // node ID at the center of an ego network
+	Radius int   // hops from Center included in an ego network
+}
+
+// Sample returns the subgraph of g induced by the nodes opts selects: a
+// forest fire or random walk sample of about opts.Nodes nodes, or the ego
+// network within opts.Radius hops of opts.Center. Edges are followed in
+// both directions. The graph structure is loaded into memory to sample it;
+// node and edge data is still streamed from g when exporting.
+func Sample(g Graph, opts SampleOptions) (Graph, error) {
+	switch opts.Method {
+	case SampleForestFire, SampleRandomWalk:
+		if opts.Nodes <= 0 {
+			return nil, fmt.Errorf("sample size must be > 0")
+		}
+	case SampleEgo:
+		if opts.Radius < 0 {
+			return nil, fmt.Errorf("ego radius must be >= 0")
+		}
+	default:
+		return nil, fmt.Errorf("unknown sampling method %q (supported: %v)", opts.Method, SampleMethods)
+	}
+
+	adj, err := loadAdjacency(g)
+	if err != nil {
+		return nil, err
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	var nodes map[int]bool
+	switch opts.Method {
+	case SampleForestFire:
+		nodes = adj.forestFire(min(opts.Nodes, len(adj.ids)), rng)
+	case SampleRandomWalk:
+		nodes = adj.randomWalk(min(opts.Nodes, len(adj.ids)), rng)
+	case SampleEgo:
+		if _, ok := adj.neighbors[opts.Center]; !ok {
+			return nil, fmt.Errorf("node %d is not in the graph", opts.Center)
+		}
+		nodes = adj.ego(opts.Center, opts.Radius)
+	}
+	return sampledGraph{Graph: g, nodes: nodes}, nil
+}
+
+// adjacency is the undirected structure of a graph, for sampling
+type adjacency struct {
+	ids       []int         // node IDs in ID order
+	neighbors map[int][]int // node ID -> sorted, distinct neighbor IDs
+}
+
+// loadAdjacency reads the node IDs and edge endpoints of g; edges to nodes
+// g does not list are ignored
+func loadAdjacency(g Graph) (*adjacency, error) {
+	adj := &adjacency{neighbors: make(map[int][]int)}
+	err := g.ForEachNode(func(node *storage.Node) error {
+		adj.ids = append(adj.ids, node.NodeID)
+		adj.neighbors[node.NodeID] = nil
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.ForEachEdge(func(edge *storage.Edge) error {
+		from, to := edge.FromNodeID, edge.ToNodeID
+		if from == to {
+			return nil
+		}
+		if _, ok := adj.neighbors[from]; !ok {
+			return nil
+		}
+		if _, ok := adj.neighbors[to]; !ok {
+			return nil
+		}
+		adj.neighbors[from] = append(adj.neighbors[from], to)
+		adj.neighbors[to] = append(adj.neighbors[to], from)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Edges of several types between the same pair would otherwise weigh
+	// that neighbor more than once
+	for id, neighbors := range adj.neighbors {
+		slices.Sort(neighbors)
+		adj.neighbors[id] = slices.Compact(neighbors)
+	}
+	return adj, nil
+}
+
+// randomUnsampled picks a node not in sampled; len(sampled) must be less
+// than the number of nodes
+func (a *adjacency) randomUnsampled(sampled map[int]bool, rng *rand.Rand) int {
+	for {
+		if id := a.ids[rng.Intn(len(a.ids))]; !sampled[id] {
+			return id
+		}
+	}
+}
+
+// forestFire burns through the graph from random seeds until n nodes are
+// sampled (Leskovec & Faloutsos, "Sampling from Large Graphs")
+func (a *adjacency) forestFire(n int, rng *rand.Rand) map[int]bool {
+	sampled := make(map[int]bool, n)
+	for len(sampled) < n {
+		// A fresh seed reignites the fire whenever it dies out
+		seed := a.randomUnsampled(sampled, rng)
+		sampled[seed] = true
+		burning := []int{seed}
+
+		for len(burning) > 0 && len(sampled) < n {
+			node := burning[0]
+			burning = burning[1:]
+
+			var unburned []int
+			for _, neighbor := range a.neighbors[node] {
+				if !sampled[neighbor] {
+					unburned = append(unburned, neighbor)
+				}
+			}
+			rng.Shuffle(len(unburned), func(i, j int) { unburned[i], unburned[j] = unburned[j], unburned[i] })
+
+			burn := 0
+			for rng.Float64() < burnProbability {
+				burn++
+			}
+			for _, neighbor := range unburned[:min(burn, len(unburned))] {
+				if len(sampled) >= n {
+					break
+				}
+				sampled[neighbor] = true
+				burning = append(burning, neighbor)
+			}
+		}
+	}
+	return sampled
+}
+
+// randomWalk walks the graph with restarts until n nodes are sampled,
+// jumping to a new random start when the walk is stuck
+func (a *adjacency) randomWalk(n int, rng *rand.Rand) map[int]bool {
+	sampled := make(map[int]bool, n)
+	if n == 0 {
+		return sampled
+	}
+
+	start := a.randomUnsampled(sampled, rng)
+	sampled[start] = true
+	current, stale := start, 0
+	for len(sampled) < n {
+		neighbors := a.neighbors[current]
+		if len(neighbors) == 0 || stale >= maxStaleSteps {
+			start = a.randomUnsampled(sampled, rng)
+			sampled[start] = true
+			current, stale = start, 0
+			continue
+		}
+		if rng.Float64() < restartProbability {
+			current = start
+			stale++
+			continue
+		}
+
+		current = neighbors[rng.Intn(len(neighbors))]
+		if sampled[current] {
+			stale++
+		} else {
+			sampled[current] = true
+			stale = 0
+		}
+	}
+	return sampled
+}
+
+// ego returns the nodes within radius hops of center
+func (a *adjacency) ego(center, radius int) map[int]bool {
+	sampled := map[int]bool{center: true}
+	frontier := []int{center}
+	for hop := 0; hop < radius && len(frontier) > 0; hop++ {
+		var next []int
+		for _, node := range frontier {
+			for _, neighbor := range a.neighbors[node] {
+				if !sampled[neighbor] {
+					sampled[neighbor] = true
+					next = append(next, neighbor)
+				}
+			}
+		}
+		frontier = next
+	}
+	return sampled
+}
+
+// sampledGraph is the subgraph of Graph induced by nodes
+type sampledGraph struct {
+	Graph
+	nodes map[int]bool
+}
+
+// ForEachNode visits the sampled nodes in ID order
+func (g sampledGraph) ForEachNode(fn func(*storage.Node) error) error {
+	return g.Graph.ForEachNode(func(node *storage.Node) error {
+		if !g.nodes[node.NodeID] {
+			return nil
+		}
+		return fn(node)
+	})
+}
+
+// ForEachEdge visits the edges between sampled nodes in ID order
+func (g sampledGraph) ForEachEdge(fn func(*storage.Edge) error) error {
+	return g.Graph.ForEachEdge(func(edge *storage.Edge) error {
+		if !g.nodes[edge.FromNodeID] || !g.nodes[edge.ToNodeID] {
+			return nil
+		}
+		return fn(edge)
+	})
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// sigmaNode is a graphology serialized node, as loaded by sigma.js
+type sigmaNode struct {
+	Key        string `json:"key"`
+	Attributes struct {
+		Label       string  `json:"label"`
+		Description string  `json:"description"`
+		CrawlCount  int     `json:"crawl_count"`
+		Depth       int     `json:"depth"`
+		Category    string  `json:"category,omitempty"`
+		X           float64 `json:"x"`
+		Y           float64 `json:"y"`
+		Size        int     `json:"size"`
+	} `json:"attributes"`
+}
+
+// sigmaEdge is a graphology serialized edge
+type sigmaEdge struct {
+	Key        string `json:"key"`
+	Source     string `json:"source"`
+	Target     string `json:"target"`
+	Attributes struct {
+		EdgeType   string  `json:"edge_type"` // "type" selects sigma's edge renderer
+		Weight     float64 `json:"weight"`
+		Size       int     `json:"size"`
+		AnchorText string  `json:"anchor_text,omitempty"`
+		Rel        string  `json:"rel,omitempty"`
+	} `json:"attributes"`
+}
+
+// writeSigma writes the graphology JSON format, loadable with
+// graph.import(data) before handing the graph to sigma.js
+func writeSigma(w io.Writer, g Graph, opts Options) error {
+	if _, err := io.WriteString(w, `{"attributes":{},"options":{"type":"directed","multi":false,"allowSelfLoops":false},"nodes":[`); err != nil {
+		return err
+	}
+
+	nodes := jsonArray{w: w}
+	err := g.ForEachNode(func(node *storage.Node) error {
+		var el sigmaNode
+		el.Key = strconv.Itoa(node.NodeID)
+		el.Attributes.Label = node.DomainName
+		el.Attributes.Description = node.Description
+		el.Attributes.CrawlCount = node.CrawlCount
+		el.Attributes.Depth = node.LastDepth
+		el.Attributes.Category = node.Category
+		el.Attributes.X, el.Attributes.Y = spiralPosition(nodes.count)
+		el.Attributes.Size = 1
+		return nodes.add(el)
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, `],"edges":[`); err != nil {
+		return err
+	}
+
+	edges := jsonArray{w: w}
+	err = g.ForEachEdge(func(edge *storage.Edge) error {
+		var el sigmaEdge
+		el.Key = strconv.Itoa(edge.EdgeID)
+		el.Source = strconv.Itoa(edge.FromNodeID)
+		el.Target = strconv.Itoa(edge.ToNodeID)
+		el.Attributes.EdgeType = edge.Type
+		el.Attributes.Weight = opts.weight(edge)
+		el.Attributes.Size = 1
+		el.Attributes.AnchorText = edge.AnchorText
+		el.Attributes.Rel = edge.Rel
+		return edges.add(el)
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
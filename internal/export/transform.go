@@ -0,0 +1,96 @@
+package export
+
+import (
+	"math"
+	"sort"
+
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// Options are export-time transforms that keep visualizations readable
+// when a few heavy edges (footer links, blogrolls) dominate the graph
+type Options struct {
+	LogWeights bool // export 1 + log2(weight) instead of raw weights
+	MinWeight  int  // drop edges lighter than this (0 keeps all)
+	TopK       int  // keep only each node's K heaviest out-edges (0 keeps all)
+}
+
+// weight returns the exported weight of edge
+func (o Options) weight(edge *storage.Edge) float64 {
+	if !o.LogWeights {
+		return float64(edge.Weight)
+	}
+	return math.Round((1+math.Log2(float64(max(edge.Weight, 1))))*100) / 100
+}
+
+// filter wraps g so only edges passing MinWeight and TopK are visited
+func (o Options) filter(g Graph) Graph {
+	if o.MinWeight <= 1 && o.TopK <= 0 {
+		return g
+	}
+	return filteredGraph{Graph: g, minWeight: o.MinWeight, topK: o.TopK}
+}
+
+// filteredGraph drops light edges and trims each node to its heaviest
+// out-edges; nodes are kept even if they lose all their edges
+type filteredGraph struct {
+	Graph
+	minWeight int
+	topK      int
+}
+
+// ForEachEdge visits the edges that survive filtering in ID order
+// Trimming to the top K takes a first pass over the edges to rank them
+func (g filteredGraph) ForEachEdge(fn func(*storage.Edge) error) error {
+	var kept map[int]bool
+	if g.topK > 0 {
+		var err error
+		if kept, err = g.topEdges(); err != nil {
+			return err
+		}
+	}
+
+	return g.Graph.ForEachEdge(func(edge *storage.Edge) error {
+		if edge.Weight < g.minWeight || (kept != nil && !kept[edge.EdgeID]) {
+			return nil
+		}
+		return fn(edge)
+	})
+}
+
+// rankedEdge is an edge's ID and weight, for ranking a node's out-edges
+type rankedEdge struct {
+	id     int
+	weight int
+}
+
+// topEdges returns the IDs of each node's topK heaviest out-edges at or
+// above minWeight; ties go to the older edge
+func (g filteredGraph) topEdges() (map[int]bool, error) {
+	byNode := make(map[int][]rankedEdge)
+	err := g.Graph.ForEachEdge(func(edge *storage.Edge) error {
+		if edge.Weight < g.minWeight {
+			return nil
+		}
+		ranked := append(byNode[edge.FromNodeID], rankedEdge{id: edge.EdgeID, weight: edge.Weight})
+		// Edges arrive in ID order, so a stable sort keeps older edges first
+		// among equals; trimming as we go bounds memory to K per node
+		sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].weight > ranked[j].weight })
+		if len(ranked) > g.topK {
+			ranked = ranked[:g.topK]
+		}
+		byNode[edge.FromNodeID] = ranked
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make(map[int]bool)
+	for _, ranked := range byNode {
+		for _, e := range ranked {
+			kept[e.id] = true
+		}
+	}
+	return kept, nil
+}
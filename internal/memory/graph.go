@@ -2,6 +2,7 @@ package memory
 
 import (
 	"fmt"
+	"maps"
 	"sync"
 	"time"
 
@@ -13,9 +14,37 @@ import (
 type MemoryGraph struct {
 	nodes       map[string]*storage.Node // domain -> node
 	nodesById   map[int]*storage.Node    // nodeID -> node
-	edges       map[string]int           // "fromID-toID" -> weight
+	edges       map[edgeKey]int          // typed edge -> weight
+	inDegree    map[int]int              // nodeID -> distinct linking nodes this run
+	origins     map[string]origin        // domain -> how it was first reached
+	anchors     map[edgeKey]anchor       // link edge -> its first link, until flushed
 	nodeCounter int                      // auto-increment for node IDs
+	edgeTypes   []string                 // edge type names by edgeKey.edgeType
+	edgeTypeIDs map[string]uint16        // edge type name -> index in edgeTypes
 	mu          sync.RWMutex
+
+	flushMu sync.Mutex      // serializes flushes
+	flushed map[edgeKey]int // edge weight already written to storage
+}
+
+// edgeKey identifies an edge; a node pair may be linked once per type
+// Types are interned so keys hash as plain integers
+type edgeKey struct {
+	fromID, toID int
+	edgeType     uint16
+}
+
+// origin records the parent and seed domains that first led to a node
+// Domains are kept instead of IDs because memory IDs differ from DB IDs
+type origin struct {
+	parent    string // "" for seeds
+	seed      string // "" if unattributed
+	sourceURL string // page of parent the node was found on
+}
+
+// anchor is the text and rel attribute of the link that created a link edge
+type anchor struct {
+	text, rel string
 }
 
 // NewMemoryGraph creates a new in-memory graph
@@ -23,51 +52,187 @@ func NewMemoryGraph() *MemoryGraph {
 	return &MemoryGraph{
 		nodes:       make(map[string]*storage.Node),
 		nodesById:   make(map[int]*storage.Node),
-		edges:       make(map[string]int),
+		edges:       make(map[edgeKey]int),
+		inDegree:    make(map[int]int),
+		origins:     make(map[string]origin),
+		anchors:     make(map[edgeKey]anchor),
 		nodeCounter: 0,
+		edgeTypeIDs: make(map[string]uint16),
+		flushed:     make(map[edgeKey]int),
 	}
 }
 
-// UpsertNode inserts or updates a node in memory
-// Returns the node_id of the inserted/existing node
-func (mg *MemoryGraph) UpsertNode(domain, description string) (int, error) {
-	return mg.UpsertNodeWithDepth(domain, description, 0)
-}
-
 // UpsertNodeWithDepth inserts or updates a node in memory with depth tracking
-// Returns the node_id of the inserted/existing node
-func (mg *MemoryGraph) UpsertNodeWithDepth(domain, description string, depth int) (int, error) {
+// Returns the node_id of the inserted/existing node, and whether it was
+// inserted
+func (mg *MemoryGraph) UpsertNodeWithDepth(domain string, depth int) (int, bool, error) {
 	mg.mu.Lock()
 	defer mg.mu.Unlock()
 
 	// Check if node exists
 	if node, exists := mg.nodes[domain]; exists {
-		// Update description if provided and current is empty
-		if description != "" && node.Description == "" {
-			node.Description = description
-		}
 		// Update depth (keep the most recent/deepest)
 		if depth > node.LastDepth {
 			node.LastDepth = depth
 		}
-		return node.NodeID, nil
+		return node.NodeID, false, nil
 	}
 
 	// Create new node
 	mg.nodeCounter++
 	node := &storage.Node{
-		NodeID:      mg.nodeCounter,
-		DomainName:  domain,
-		Description: description,
-		CrawlCount:  0,
-		LastDepth:   depth,
-		CreatedAt:   time.Now(),
+		NodeID:     mg.nodeCounter,
+		DomainName: domain,
+		CrawlCount: 0,
+		LastDepth:  depth,
+		CreatedAt:  time.Now(),
 	}
 
 	mg.nodes[domain] = node
 	mg.nodesById[node.NodeID] = node
 
-	return node.NodeID, nil
+	return node.NodeID, true, nil
+}
+
+// SetTitle records the page title of an existing node
+func (mg *MemoryGraph) SetTitle(domain, title string) error {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	node, exists := mg.nodes[domain]
+	if !exists {
+		return fmt.Errorf("node %s not found", domain)
+	}
+	node.Title = title
+	return nil
+}
+
+// SetMetaDescription records the meta description of an existing node
+func (mg *MemoryGraph) SetMetaDescription(domain, description string) error {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	node, exists := mg.nodes[domain]
+	if !exists {
+		return fmt.Errorf("node %s not found", domain)
+	}
+	node.MetaDescription = description
+	return nil
+}
+
+// SetLinkStats records the outbound link statistics of an existing node
+func (mg *MemoryGraph) SetLinkStats(domain string, stats storage.LinkStats) error {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	node, exists := mg.nodes[domain]
+	if !exists {
+		return fmt.Errorf("node %s not found", domain)
+	}
+	node.LinkStats = &stats
+	return nil
+}
+
+// SetStatus records the outcome of a node's latest crawl attempt
+// Nodes without a status this run keep the one already stored
+func (mg *MemoryGraph) SetStatus(domain, status string) error {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	node, exists := mg.nodes[domain]
+	if !exists {
+		return fmt.Errorf("node %s not found", domain)
+	}
+	node.Status = status
+	return nil
+}
+
+// SetResponse records the latest answer to a fetch of a node's front page
+func (mg *MemoryGraph) SetResponse(domain string, response storage.Response) error {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	node, exists := mg.nodes[domain]
+	if !exists {
+		return fmt.Errorf("node %s not found", domain)
+	}
+	node.Response = &response
+	return nil
+}
+
+// SetLanguage records the language declared by a node's front page
+func (mg *MemoryGraph) SetLanguage(domain, language string) error {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	node, exists := mg.nodes[domain]
+	if !exists {
+		return fmt.Errorf("node %s not found", domain)
+	}
+	node.Language = language
+	return nil
+}
+
+// SetReputation records the verdict of a node's reputation lookup
+func (mg *MemoryGraph) SetReputation(domain, verdict string) error {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	node, exists := mg.nodes[domain]
+	if !exists {
+		return fmt.Errorf("node %s not found", domain)
+	}
+	node.Reputation = verdict
+	return nil
+}
+
+// SetCategory records the category of a node's front page
+func (mg *MemoryGraph) SetCategory(domain, category string) error {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	node, exists := mg.nodes[domain]
+	if !exists {
+		return fmt.Errorf("node %s not found", domain)
+	}
+	node.Category = category
+	return nil
+}
+
+// SetSitemapPages records how many pages on a node's own host its sitemaps
+// list
+func (mg *MemoryGraph) SetSitemapPages(domain string, pages int) error {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	node, exists := mg.nodes[domain]
+	if !exists {
+		return fmt.Errorf("node %s not found", domain)
+	}
+	node.SitemapPages = pages
+	return nil
+}
+
+// SetOrigin records the parent, seed and source page URL that first led to
+// domain; parent and sourceURL are "" for seeds and seed is "" for
+// unattributed nodes
+// Once a node is attributed to a seed its origin is never replaced
+func (mg *MemoryGraph) SetOrigin(domain, parent, seed, sourceURL string) {
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	if o, exists := mg.origins[domain]; !exists || o.seed == "" {
+		mg.origins[domain] = origin{parent: parent, seed: seed, sourceURL: sourceURL}
+	}
+}
+
+// Seed returns the seed that first led to domain, if its origin is known
+func (mg *MemoryGraph) Seed(domain string) (string, bool) {
+	mg.mu.RLock()
+	defer mg.mu.RUnlock()
+
+	o, exists := mg.origins[domain]
+	return o.seed, exists
 }
 
 // GetNode retrieves a node by domain name
@@ -98,8 +263,9 @@ func (mg *MemoryGraph) IncrementCrawlCount(nodeID int) error {
 	return nil
 }
 
-// UpsertEdge inserts a new edge or increments weight if it exists
-func (mg *MemoryGraph) UpsertEdge(fromID, toID int) error {
+// UpsertEdge inserts a new edge of the given type or increments its weight
+// Callers add weight once per fetched page, not once per link
+func (mg *MemoryGraph) UpsertEdge(fromID, toID int, edgeType string) error {
 	mg.mu.Lock()
 	defer mg.mu.Unlock()
 
@@ -111,13 +277,69 @@ func (mg *MemoryGraph) UpsertEdge(fromID, toID int) error {
 		return fmt.Errorf("target node %d not found", toID)
 	}
 
+	// A pair's first edge of any type adds a linking node
+	if !mg.linked(fromID, toID) {
+		mg.inDegree[toID]++
+	}
+
 	// Create or increment edge
-	edgeKey := fmt.Sprintf("%d-%d", fromID, toID)
-	mg.edges[edgeKey]++
+	mg.edges[edgeKey{fromID, toID, mg.edgeTypeID(edgeType)}]++
 
 	return nil
 }
 
+// SetAnchor records the text and rel attribute of the link from one domain
+// to another, if their link edge exists and has none yet; empty ones are
+// ignored. Storage keeps an edge's first anchor across flushes
+func (mg *MemoryGraph) SetAnchor(from, to, text, rel string) {
+	if text == "" && rel == "" {
+		return
+	}
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	fromNode, fromExists := mg.nodes[from]
+	toNode, toExists := mg.nodes[to]
+	if !fromExists || !toExists {
+		return
+	}
+	key := edgeKey{fromNode.NodeID, toNode.NodeID, mg.edgeTypeID(storage.EdgeLink)}
+	if _, set := mg.anchors[key]; set || mg.edges[key] == 0 {
+		return
+	}
+	mg.anchors[key] = anchor{text, rel}
+}
+
+// linked reports whether fromID has an edge of any type to toID; the
+// caller holds mg.mu
+func (mg *MemoryGraph) linked(fromID, toID int) bool {
+	for id := range mg.edgeTypes {
+		if mg.edges[edgeKey{fromID, toID, uint16(id)}] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// edgeTypeID returns the interned ID of edgeType, assigning one on first
+// use; the caller holds mg.mu for writing
+func (mg *MemoryGraph) edgeTypeID(edgeType string) uint16 {
+	id, ok := mg.edgeTypeIDs[edgeType]
+	if !ok {
+		id = uint16(len(mg.edgeTypes))
+		mg.edgeTypes = append(mg.edgeTypes, edgeType)
+		mg.edgeTypeIDs[edgeType] = id
+	}
+	return id
+}
+
+// InDegree returns how many distinct nodes have linked to a node this run
+func (mg *MemoryGraph) InDegree(nodeID int) int {
+	mg.mu.RLock()
+	defer mg.mu.RUnlock()
+	return mg.inDegree[nodeID]
+}
+
 // GetStats returns current graph statistics
 func (mg *MemoryGraph) GetStats() (nodeCount, edgeCount int) {
 	mg.mu.RLock()
@@ -126,96 +348,83 @@ func (mg *MemoryGraph) GetStats() (nodeCount, edgeCount int) {
 	return len(mg.nodes), len(mg.edges)
 }
 
-// Flush writes all in-memory data to SQLite storage
+// Flush writes all in-memory data to SQLite storage in one transaction
+// Edges only add the weight gained since the last flush, so flushing again,
+// e.g. an emergency flush after a checkpoint, doesn't count links twice
 func (mg *MemoryGraph) Flush(store *storage.Storage) error {
-	mg.mu.RLock()
-	defer mg.mu.RUnlock()
+	mg.flushMu.Lock()
+	defer mg.flushMu.Unlock()
 
 	startTime := time.Now()
 	logrus.Info("Starting flush to database...")
 
-	// Track statistics
-	nodesWritten := 0
-	edgesWritten := 0
-	var firstErr error
-
-	// Flush nodes
+	mg.mu.RLock()
+	nodes := make([]*storage.Node, 0, len(mg.nodes))
 	for _, node := range mg.nodes {
-		// Upsert node with current description and depth
-		_, err := store.UpsertNodeWithDepth(node.DomainName, node.Description, node.LastDepth)
-		if err != nil {
-			if firstErr == nil {
-				firstErr = err
-			}
-			logrus.Warnf("Failed to flush node %s: %v", node.DomainName, err)
-			continue
-		}
+		copied := *node
+		nodes = append(nodes, &copied)
+	}
 
-		// Get the node from DB to get its actual ID
-		dbNode, err := store.GetNode(node.DomainName)
-		if err != nil {
-			logrus.Warnf("Failed to retrieve node %s after upsert: %v", node.DomainName, err)
+	// Edges refer to nodes by domain, as memory IDs differ from DB IDs
+	weights := make(map[edgeKey]int)
+	edges := make([]storage.GraphEdge, 0, len(mg.edges))
+	for key, weight := range mg.edges {
+		delta := weight - mg.flushed[key]
+		if delta <= 0 {
 			continue
 		}
-
-		// Update crawl count in DB (direct SQL update to match memory)
-		if err := store.ResetCrawlCount(dbNode.NodeID); err != nil {
-			logrus.Warnf("Failed to reset crawl count for %s: %v", node.DomainName, err)
-		}
-
-		// Set to actual crawl count
-		for i := 0; i < node.CrawlCount; i++ {
-			if err := store.IncrementCrawlCount(dbNode.NodeID); err != nil {
-				logrus.Warnf("Failed to set crawl count for %s: %v", node.DomainName, err)
-				break
-			}
-		}
-
-		nodesWritten++
-	}
-
-	// Flush edges (need to map memory IDs to DB IDs)
-	// Build ID mapping: memory ID -> DB ID
-	idMap := make(map[int]int)
-	for domain, memNode := range mg.nodes {
-		dbNode, err := store.GetNode(domain)
-		if err != nil || dbNode == nil {
+		from, fromExists := mg.nodesById[key.fromID]
+		to, toExists := mg.nodesById[key.toID]
+		if !fromExists || !toExists {
+			logrus.Warnf("Skipping %s edge %d->%d: node not found", mg.edgeTypes[key.edgeType], key.fromID, key.toID)
 			continue
 		}
-		idMap[memNode.NodeID] = dbNode.NodeID
+		weights[key] = weight
+		a := mg.anchors[key]
+		edges = append(edges, storage.GraphEdge{
+			From:       from.DomainName,
+			To:         to.DomainName,
+			Type:       mg.edgeTypes[key.edgeType],
+			Weight:     delta,
+			AnchorText: a.text,
+			Rel:        a.rel,
+		})
 	}
 
-	// Write edges with mapped IDs
-	for edgeKey, weight := range mg.edges {
-		var memFromID, memToID int
-		fmt.Sscanf(edgeKey, "%d-%d", &memFromID, &memToID)
-
-		dbFromID, fromExists := idMap[memFromID]
-		dbToID, toExists := idMap[memToID]
-
-		if !fromExists || !toExists {
-			logrus.Warnf("Skipping edge %s: node ID mapping not found", edgeKey)
+	// Storage keeps the first origin, so rewriting them is harmless
+	origins := make([]storage.GraphOrigin, 0, len(mg.origins))
+	for domain, o := range mg.origins {
+		if o.seed == "" {
 			continue
 		}
+		origins = append(origins, storage.GraphOrigin{
+			Domain:    domain,
+			Parent:    o.parent,
+			Seed:      o.seed,
+			SourceURL: o.sourceURL,
+		})
+	}
+	mg.mu.RUnlock()
 
-		// Write edge with weight times
-		for i := 0; i < weight; i++ {
-			if err := store.UpsertEdge(dbFromID, dbToID); err != nil {
-				if firstErr == nil {
-					firstErr = err
-				}
-				logrus.Warnf("Failed to flush edge %d->%d: %v", dbFromID, dbToID, err)
-				break
-			}
-		}
+	stats, err := store.WriteGraph(nodes, edges, origins)
+	if err != nil {
+		return err
+	}
+	maps.Copy(mg.flushed, weights)
 
-		edgesWritten++
+	// Written anchors are kept by storage, so memory can let them go
+	mg.mu.Lock()
+	for key := range weights {
+		delete(mg.anchors, key)
 	}
+	mg.mu.Unlock()
 
-	duration := time.Since(startTime)
-	logrus.Infof("Flush complete: %d nodes, %d edges written in %v", nodesWritten, edgesWritten, duration)
+	if stats.SkippedEdges > 0 {
+		logrus.Warnf("Skipped %d edges whose nodes were not found in the database", stats.SkippedEdges)
+	}
+	logrus.Infof("Flush complete: %d nodes, %d edges written in %v", stats.Nodes, stats.Edges, time.Since(startTime))
 
-	return firstErr
+	return nil
 }
 
 // LoadFromStorage populates in-memory graph from SQLite (for resume)
@@ -261,24 +470,15 @@ func (mg *MemoryGraph) ResetCrawlCount(nodeID int) error {
 	return nil
 }
 
-// SaveQueueState persists queue entries to database
-func (mg *MemoryGraph) SaveQueueState(store *storage.Storage, entries []storage.QueueEntry) error {
-	// Clear old queue state first
-	if err := store.ClearQueueEntries(); err != nil {
-		logrus.Warnf("Failed to clear old queue state: %v", err)
-	}
-
-	// Save each entry
-	saved := 0
-	for _, entry := range entries {
-		if err := store.SaveQueueEntry(entry.NodeID, entry.DomainName, entry.Depth); err != nil {
-			logrus.Warnf("Failed to save queue entry %s: %v", entry.DomainName, err)
-			continue
-		}
-		saved++
+// SaveQueueState persists queue entries to database with the named codec
+// (see storage.QueueCodec*), replacing the saved ones in one transaction
+func (mg *MemoryGraph) SaveQueueState(store *storage.Storage, entries []storage.QueueEntry, codec string) error {
+	startTime := time.Now()
+	if err := store.SaveQueue(entries, codec); err != nil {
+		return fmt.Errorf("failed to save queue state: %w", err)
 	}
 
-	logrus.Infof("Saved %d queue entries to database", saved)
+	logrus.Infof("Saved %d queue entries to database (%s codec) in %v", len(entries), codec, time.Since(startTime))
 	return nil
 }
 
@@ -4,114 +4,382 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 	"sync"
 	"time"
 
 	"github.com/alvmarrod/web-weaver/internal/storage"
 )
 
-// Tracker holds and manages crawl metrics
+// Tracker holds and manages crawl metrics; it is the Sink behind the
+// metrics file
+// Counters in data are this run's; when resuming, the previous runs'
+// cumulative counters are added to every snapshot
 type Tracker struct {
 	mu               sync.Mutex
 	data             storage.Metrics
 	totalFetchTimeMs int64
 	fetchCount       int
+	previous         *storage.RunCounters // nil for the first run of a crawl
+	finalized        bool                 // final metrics written; snapshots must not replace them
+	history          int                  // earlier versions of the metrics file kept, as path.1 (newest) to path.N
 }
 
-// NewTracker creates a new metrics tracker
-func NewTracker() *Tracker {
+// NewTracker creates a new metrics tracker for a run
+func NewTracker(runID string) *Tracker {
 	return &Tracker{
 		data: storage.Metrics{
+			RunID:     runID,
 			StartTime: time.Now(),
 		},
 	}
 }
 
-// IncrementNodesDiscovered increments the discovered nodes counter
-func (t *Tracker) IncrementNodesDiscovered() {
+// SetHistory keeps the n latest replaced versions of the metrics file next
+// to it, as path.1 (newest) to path.n; 0 keeps none
+func (t *Tracker) SetHistory(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.history = n
+}
+
+// Resume continues counting from the cumulative counters saved by a
+// previous run of the session
+func (t *Tracker) Resume(previousRunID string, previous storage.RunCounters) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.PreviousRunID = previousRunID
+	t.previous = &previous
+}
+
+// NodeDiscovered increments the discovered nodes counter
+func (t *Tracker) NodeDiscovered() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.data.NodesDiscovered++
 }
 
-// IncrementNodesCrawled increments the crawled nodes counter
-func (t *Tracker) IncrementNodesCrawled() {
+// NodeCrawled increments the crawled nodes counter
+func (t *Tracker) NodeCrawled() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.data.NodesCrawled++
 }
 
-// IncrementEdgesRecorded increments the edges counter
-func (t *Tracker) IncrementEdgesRecorded() {
+// EdgeRecorded increments the edges counter
+func (t *Tracker) EdgeRecorded() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.data.EdgesRecorded++
 }
 
-// IncrementPagesFetched increments the successful fetch counter
-func (t *Tracker) IncrementPagesFetched() {
+// PageFetched increments the successful fetch counter and records the
+// fetch duration, if known
+func (t *Tracker) PageFetched(duration time.Duration) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.data.PagesFetched++
+	if duration > 0 {
+		t.totalFetchTimeMs += duration.Milliseconds()
+		t.fetchCount++
+	}
 }
 
-// IncrementPagesFailed increments the failed fetch counter
-func (t *Tracker) IncrementPagesFailed() {
+// PageFailed increments the failed fetch counter
+func (t *Tracker) PageFailed() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.data.PagesFailed++
 }
 
-// RecordFetchTime records a page fetch duration
-func (t *Tracker) RecordFetchTime(duration time.Duration) {
+// RecordCacheStats records fetches avoided thanks to HTTP cache knowledge
+func (t *Tracker) RecordCacheStats(fromCache, notModified int) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.totalFetchTimeMs += duration.Milliseconds()
-	t.fetchCount++
+	t.data.PagesFromCache = fromCache
+	t.data.PagesNotModified = notModified
 }
 
-// GetSnapshot returns a copy of current metrics
-func (t *Tracker) GetSnapshot() storage.Metrics {
+// RecordAbandonedFetches records how many fetches hit the fetch deadline
+func (t *Tracker) RecordAbandonedFetches(abandoned int) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	t.data.FetchesAbandoned = abandoned
+}
 
-	snapshot := t.data
-	snapshot.TotalFetchTimeMs = t.totalFetchTimeMs
+// RecordRetriedFetches records how many failed fetches were retried
+func (t *Tracker) RecordRetriedFetches(retried int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.FetchesRetried = retried
+}
+
+// RecordHTTPFallbacks records how many front pages fell back to plain HTTP
+func (t *Tracker) RecordHTTPFallbacks(fallbacks int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.HTTPFallbacks = fallbacks
+}
+
+// RecordSitemapStats records the sitemap files read and the URLs they listed
+func (t *Tracker) RecordSitemapStats(files, urls int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.SitemapsRead = files
+	t.data.SitemapURLs = urls
+}
+
+// RecordReputationStats records the domains looked up for reputation and
+// those judged low reputation
+func (t *Tracker) RecordReputationStats(lookups, low int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.ReputationLookups = lookups
+	t.data.ReputationLow = low
+}
+
+// RecordSubdomainStats records the subdomain limiter's occupancy
+func (t *Tracker) RecordSubdomainStats(roots, subdomains, saturated int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.RootDomains = roots
+	t.data.SubdomainsCounted = subdomains
+	t.data.SaturatedRoots = saturated
+}
+
+// RecordDNSStats records DNS cache effectiveness
+func (t *Tracker) RecordDNSStats(hits, lookups, prefetched int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.DNSCacheHits = hits
+	t.data.DNSLookups = lookups
+	t.data.DNSPrefetched = prefetched
+}
+
+// RecordTopDomains records the top domain rankings for the final metrics
+func (t *Tracker) RecordTopDomains(top *storage.TopDomains) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.Top = top
+}
+
+// RecordTLDSkips records how many links the TLD filter skipped per TLD
+func (t *Tracker) RecordTLDSkips(skips map[string]int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.TLDSkips = skips
+}
+
+// RecordErrorCounts records how many failed fetches were recorded per
+// error category
+func (t *Tracker) RecordErrorCounts(counts map[string]int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.FetchErrors = counts
+}
+
+// RecordCategories records how many domains were classified per category
+func (t *Tracker) RecordCategories(categories map[string]int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.Categories = categories
+}
+
+// RecordLanguages records how many domains were crawled per declared language
+func (t *Tracker) RecordLanguages(languages map[string]int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.Languages = languages
+}
+
+// SampleRuntime records current heap, goroutine, and GC statistics along
+// with the size of the crawler's visited set
+func (t *Tracker) SampleRuntime(visitedSetSize int) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	goroutines := runtime.NumGoroutine()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.data.HeapInUseBytes = ms.HeapInuse
+	t.data.Goroutines = goroutines
+	t.data.VisitedSetSize = visitedSetSize
+	t.data.GCCycles = ms.NumGC
+	t.data.GCPauseTotalUs = ms.PauseTotalNs / 1000
+	if ms.NumGC > 0 {
+		t.data.GCPauseLastUs = ms.PauseNs[(ms.NumGC+255)%256] / 1000
+	}
 
-	// Calculate average fetch time
-	if t.fetchCount > 0 {
-		snapshot.AvgFetchTimeMs = t.totalFetchTimeMs / int64(t.fetchCount)
+	if heapMB := ms.HeapInuse / (1024 * 1024); heapMB > t.data.PeakHeapInUseMB {
+		t.data.PeakHeapInUseMB = heapMB
 	}
+}
+
+// runCounters returns this run's counters; the caller holds t.mu
+func (t *Tracker) runCounters() storage.RunCounters {
+	return storage.RunCounters{
+		NodesDiscovered:  t.data.NodesDiscovered,
+		NodesCrawled:     t.data.NodesCrawled,
+		EdgesRecorded:    t.data.EdgesRecorded,
+		PagesFetched:     t.data.PagesFetched,
+		PagesFailed:      t.data.PagesFailed,
+		PagesFromCache:   t.data.PagesFromCache,
+		PagesNotModified: t.data.PagesNotModified,
+		FetchesTimed:     t.fetchCount,
+		TotalFetchTimeMs: t.totalFetchTimeMs,
+	}
+}
+
+// cumulative returns the counters of this and all previous runs; the
+// caller holds t.mu
+func (t *Tracker) cumulative() storage.RunCounters {
+	if t.previous == nil {
+		return t.runCounters()
+	}
+	return t.previous.Add(t.runCounters())
+}
 
+// RunCounters returns this run's counters alone, e.g. for per-run budgets
+func (t *Tracker) RunCounters() storage.RunCounters {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.runCounters()
+}
+
+// Cumulative returns the counters of this and all previous runs of the
+// session, to be saved for the next run
+func (t *Tracker) Cumulative() storage.RunCounters {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cumulative()
+}
+
+// snapshot returns a copy of the metrics with cumulative counters and the
+// average fetch time filled in; the caller holds t.mu
+func (t *Tracker) snapshot() storage.Metrics {
+	snapshot := t.data
+	total := t.cumulative()
+	snapshot.NodesDiscovered = total.NodesDiscovered
+	snapshot.NodesCrawled = total.NodesCrawled
+	snapshot.EdgesRecorded = total.EdgesRecorded
+	snapshot.PagesFetched = total.PagesFetched
+	snapshot.PagesFailed = total.PagesFailed
+	snapshot.PagesFromCache = total.PagesFromCache
+	snapshot.PagesNotModified = total.PagesNotModified
+	snapshot.TotalFetchTimeMs = total.TotalFetchTimeMs
+	if total.FetchesTimed > 0 {
+		snapshot.AvgFetchTimeMs = total.TotalFetchTimeMs / int64(total.FetchesTimed)
+	}
+	if t.previous != nil {
+		run := t.runCounters()
+		snapshot.ThisRun = &run
+	}
 	return snapshot
 }
 
-// WriteToFile exports metrics to a JSON file
+// GetSnapshot returns a copy of current metrics
+func (t *Tracker) GetSnapshot() storage.Metrics {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshot()
+}
+
+// WriteToFile exports final metrics to a JSON file
 func (t *Tracker) WriteToFile(path, reason string) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
 	// Finalize metrics
+	t.finalized = true
 	t.data.EndTime = time.Now()
 	t.data.TerminationReason = reason
-	t.data.TotalFetchTimeMs = t.totalFetchTimeMs
 
-	// Calculate average
-	if t.fetchCount > 0 {
-		t.data.AvgFetchTimeMs = t.totalFetchTimeMs / int64(t.fetchCount)
+	return writeJSONAtomic(path, t.snapshot(), t.history)
+}
+
+// WriteSnapshot writes the metrics so far with termination_reason "running",
+// so a killed process still leaves a recent file behind
+func (t *Tracker) WriteSnapshot(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.finalized {
+		return nil
 	}
 
-	// Marshal to JSON
-	jsonData, err := json.MarshalIndent(t.data, "", "  ")
+	snapshot := t.snapshot()
+	snapshot.EndTime = time.Now()
+	snapshot.TerminationReason = "running"
+
+	return writeJSONAtomic(path, snapshot, t.history)
+}
+
+// writeJSONAtomic writes v as indented JSON via a synced temp file and
+// rename, so readers never see a partially written file and a crash leaves
+// either the old or the new one; the replaced file is rotated into the
+// history versions kept
+func writeJSONAtomic(path string, v any, history int) error {
+	jsonData, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal metrics: %w", err)
 	}
 
-	// Write to file
-	if err := os.WriteFile(path, jsonData, 0644); err != nil {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create metrics temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(jsonData); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set metrics file mode: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync metrics file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
 		return fmt.Errorf("failed to write metrics file: %w", err)
 	}
 
+	if history > 0 {
+		if err := rotateHistory(path, history); err != nil {
+			return fmt.Errorf("failed to rotate metrics file: %w", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace metrics file: %w", err)
+	}
+	return nil
+}
+
+// rotateHistory shifts path.1 to path.n-1 up by one, dropping path.n, and
+// keeps the current path as path.1
+// path.1 is a hard link where the filesystem allows, so path exists until
+// the rename replacing it
+func rotateHistory(path string, n int) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	for i := n - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	newest := path + ".1"
+	if err := os.Remove(newest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(path, newest); err != nil {
+		return os.Rename(path, newest)
+	}
 	return nil
 }
 
@@ -120,11 +388,19 @@ func (t *Tracker) LogProgress() string {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	return fmt.Sprintf("Nodes: %d discovered, %d crawled | Edges: %d | Pages: %d fetched, %d failed",
-		t.data.NodesDiscovered,
-		t.data.NodesCrawled,
-		t.data.EdgesRecorded,
-		t.data.PagesFetched,
-		t.data.PagesFailed,
+	s := t.snapshot()
+	return fmt.Sprintf("Nodes: %d discovered, %d crawled | Edges: %d | Pages: %d fetched, %d failed, %d cached, %d not modified | Roots: %d (%d at subdomain limit) | Heap: %dMB, goroutines: %d, visited: %d",
+		s.NodesDiscovered,
+		s.NodesCrawled,
+		s.EdgesRecorded,
+		s.PagesFetched,
+		s.PagesFailed,
+		s.PagesFromCache,
+		s.PagesNotModified,
+		s.RootDomains,
+		s.SaturatedRoots,
+		s.HeapInUseBytes/(1024*1024),
+		s.Goroutines,
+		s.VisitedSetSize,
 	)
 }
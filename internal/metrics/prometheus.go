@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// metricPrefix namespaces every exported metric
+const metricPrefix = "webweaver_"
+
+// LiveState is the crawler state sampled on every scrape, as the tracker
+// only holds counters
+type LiveState struct {
+	QueueSize      int
+	InFlight       int
+	ActiveWorkers  int
+	VisitedSetSize int
+}
+
+// Exporter serves the tracker's metrics at /metrics in the Prometheus text
+// exposition format, so long crawls can be monitored while they run
+// Counters are cumulative over the runs of a resumed session; the stats
+// recorded by the progress logger are as of its last tick
+type Exporter struct {
+	tracker *Tracker
+	live    func() LiveState // nil reports no live state
+	server  *http.Server     // nil when mounted on another listener
+}
+
+// NewExporter creates an exporter of tracker's metrics; live, if not nil,
+// is sampled on every scrape
+func NewExporter(tracker *Tracker, live func() LiveState) *Exporter {
+	return &Exporter{tracker: tracker, live: live}
+}
+
+// Start serves /metrics on its own listener at addr in the background
+func (e *Exporter) Start(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	e.server = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		logrus.Infof("Prometheus metrics listening on %s/metrics", addr)
+		if err := e.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Errorf("Prometheus metrics listener stopped: %v", err)
+		}
+	}()
+}
+
+// Shutdown stops the listener started by Start, if any
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	if e.server == nil {
+		return nil
+	}
+	return e.server.Shutdown(ctx)
+}
+
+// ServeHTTP writes the current metrics
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	out := bufio.NewWriter(w)
+	e.write(out)
+	if err := out.Flush(); err != nil {
+		logrus.Debugf("Failed to write Prometheus metrics: %v", err)
+	}
+}
+
+// write renders every metric
+func (e *Exporter) write(out *bufio.Writer) {
+	e.tracker.mu.Lock()
+	s := e.tracker.snapshot()
+	run := e.tracker.runCounters()
+	e.tracker.mu.Unlock()
+
+	writeMetric(out, "run_info", "gauge", "Current run, as labels", 1, "run_id", s.RunID)
+
+	writeMetric(out, "nodes_discovered_total", "counter", "Nodes discovered via links", s.NodesDiscovered)
+	writeMetric(out, "nodes_crawled_total", "counter", "Nodes scheduled for fetching", s.NodesCrawled)
+	writeMetric(out, "edges_recorded_total", "counter", "Edges recorded", s.EdgesRecorded)
+	writeMetric(out, "pages_fetched_total", "counter", "Pages fetched successfully", s.PagesFetched)
+	writeMetric(out, "pages_failed_total", "counter", "Page fetches that failed", s.PagesFailed)
+	writeMetric(out, "pages_from_cache_total", "counter", "Fetches skipped as still fresh", s.PagesFromCache)
+	writeMetric(out, "pages_not_modified_total", "counter", "Fetches revalidated with a 304", s.PagesNotModified)
+	writeMetric(out, "fetches_abandoned_total", "counter", "Fetches that hit fetch_deadline_ms", s.FetchesAbandoned)
+	writeMetric(out, "fetches_retried_total", "counter", "Failed fetches re-enqueued for a retry", s.FetchesRetried)
+	writeMetric(out, "http_fallbacks_total", "counter", "Front pages requested again over HTTP after HTTPS failed", s.HTTPFallbacks)
+	writeMetric(out, "fetch_time_seconds_total", "counter", "Time spent in timed fetches", float64(s.TotalFetchTimeMs)/1000)
+
+	writeLabeled(out, "fetch_errors_total", "counter", "Failed fetches this run by error category", "category", s.FetchErrors)
+
+	elapsed := time.Since(s.StartTime).Seconds()
+	pagesPerSec := 0.0
+	if elapsed > 0 {
+		pagesPerSec = float64(run.PagesFetched) / elapsed
+	}
+	writeMetric(out, "pages_per_second", "gauge", "Average fetch rate of this run", pagesPerSec)
+	writeMetric(out, "uptime_seconds", "gauge", "Time since this run started", elapsed)
+
+	if e.live != nil {
+		live := e.live()
+		writeMetric(out, "queue_size", "gauge", "Entries in the frontier", live.QueueSize)
+		writeMetric(out, "in_flight_requests", "gauge", "Fetches in flight", live.InFlight)
+		writeMetric(out, "active_workers", "gauge", "Workers allowed to fetch", live.ActiveWorkers)
+		writeMetric(out, "visited_set_size", "gauge", "Keys in the frontier's deduplication set", live.VisitedSetSize)
+	}
+
+	writeMetric(out, "root_domains", "gauge", "Root domains tracked by the subdomain limiter", s.RootDomains)
+	writeMetric(out, "saturated_roots", "gauge", "Root domains at max_subdomains_per_root", s.SaturatedRoots)
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	writeMetric(out, "heap_inuse_bytes", "gauge", "Heap in use", ms.HeapInuse)
+	writeMetric(out, "goroutines", "gauge", "Goroutines", runtime.NumGoroutine())
+	writeMetric(out, "gc_pause_seconds_total", "counter", "Time spent in GC stop-the-world pauses", float64(ms.PauseTotalNs)/1e9)
+}
+
+// writeMetric writes one sample with its HELP and TYPE lines; labels are
+// name, value pairs
+func writeMetric[V int | uint64 | float64](out *bufio.Writer, name, kind, help string, value V, labels ...string) {
+	fmt.Fprintf(out, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricPrefix, name, help, metricPrefix, name, kind)
+	fmt.Fprintf(out, "%s%s%s %v\n", metricPrefix, name, formatLabels(labels), value)
+}
+
+// writeLabeled writes one sample per entry of values, labeled by key
+func writeLabeled(out *bufio.Writer, name, kind, help, key string, values map[string]int) {
+	fmt.Fprintf(out, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricPrefix, name, help, metricPrefix, name, kind)
+	for _, k := range slices.Sorted(maps.Keys(values)) {
+		fmt.Fprintf(out, "%s%s%s %d\n", metricPrefix, name, formatLabels([]string{key, k}), values[k])
+	}
+}
+
+// formatLabels renders name, value pairs as {name="value",...}
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", labels[i], labels[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import "time"
+
+// Sink receives crawl events as they happen, e.g. to count them in the
+// metrics file or export them to a monitoring system
+// Events arrive from many workers at once, so sinks must be safe for
+// concurrent use and should not block
+type Sink interface {
+	NodeDiscovered()
+	NodeCrawled()
+	EdgeRecorded()
+	PageFetched(duration time.Duration) // zero if the fetch wasn't timed
+	PageFailed()
+}
+
+// Sinks fans every event out to each sink in order; an empty Sinks
+// discards events
+type Sinks []Sink
+
+// NodeDiscovered reports a newly discovered node to every sink
+func (s Sinks) NodeDiscovered() {
+	for _, sink := range s {
+		sink.NodeDiscovered()
+	}
+}
+
+// NodeCrawled reports a node scheduled for fetching to every sink
+func (s Sinks) NodeCrawled() {
+	for _, sink := range s {
+		sink.NodeCrawled()
+	}
+}
+
+// EdgeRecorded reports a recorded edge to every sink
+func (s Sinks) EdgeRecorded() {
+	for _, sink := range s {
+		sink.EdgeRecorded()
+	}
+}
+
+// PageFetched reports a successful fetch to every sink
+func (s Sinks) PageFetched(duration time.Duration) {
+	for _, sink := range s {
+		sink.PageFetched(duration)
+	}
+}
+
+// PageFailed reports a failed fetch to every sink
+func (s Sinks) PageFailed() {
+	for _, sink := range s {
+		sink.PageFailed()
+	}
+}
@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Email sends reports over SMTP with the metrics attached as JSON
+type Email struct {
+	Addr     string // host:port; port 465 uses implicit TLS, others STARTTLS when offered
+	Username string // empty disables authentication
+	Password string
+	From     string
+	To       []string
+}
+
+// Name identifies the channel in logs
+func (e *Email) Name() string {
+	return "email"
+}
+
+// Notify sends the report as a multipart message
+func (e *Email) Notify(ctx context.Context, report Report) error {
+	msg, err := e.buildMessage(report)
+	if err != nil {
+		return err
+	}
+
+	host, port, err := net.SplitHostPort(e.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address: %w", err)
+	}
+
+	conn, err := e.dial(ctx, host, port)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	// net/smtp has no context support; bound the whole exchange instead
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if _, isTLS := conn.(*tls.Conn); !isTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return fmt.Errorf("failed to start TLS: %w", err)
+			}
+		}
+	}
+
+	if e.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.Username, e.Password, host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(e.From); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, to := range e.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// dial opens the SMTP connection, using implicit TLS on port 465
+func (e *Email) dial(ctx context.Context, host, port string) (net.Conn, error) {
+	if port == "465" {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: host}}
+		return dialer.DialContext(ctx, "tcp", e.Addr)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", e.Addr)
+}
+
+// buildMessage renders the report as a MIME message with a text summary
+// and the metrics JSON as an attachment
+func (e *Email) buildMessage(report Report) ([]byte, error) {
+	metrics, err := report.MetricsJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=utf-8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	part.Write([]byte(report.Summary()))
+
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"application/json"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {`attachment; filename="metrics.json"`},
+	})
+	if err != nil {
+		return nil, err
+	}
+	part.Write([]byte(wrapBase64(metrics)))
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", report.Subject()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// wrapBase64 encodes data as base64 in 76-character lines
+func wrapBase64(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// Report describes a finished crawl
+type Report struct {
+	Session  string
+	SeedURLs []string
+	Metrics  storage.Metrics
+}
+
+// Subject returns a one-line outcome suitable for an email subject
+func (r Report) Subject() string {
+	return fmt.Sprintf("web-weaver crawl %q finished: %s (%d nodes, %d edges)",
+		r.Session, r.Metrics.TerminationReason, r.Metrics.NodesDiscovered, r.Metrics.EdgesRecorded)
+}
+
+// Summary returns a human-readable summary of the final metrics
+func (r Report) Summary() string {
+	m := r.Metrics
+	var b strings.Builder
+	fmt.Fprintf(&b, "Session:     %s\n", r.Session)
+	fmt.Fprintf(&b, "Run:         %s\n", m.RunID)
+	fmt.Fprintf(&b, "Seeds:       %s\n", strings.Join(r.SeedURLs, ", "))
+	fmt.Fprintf(&b, "Outcome:     %s\n", m.TerminationReason)
+	fmt.Fprintf(&b, "Duration:    %s (%s - %s)\n", m.EndTime.Sub(m.StartTime).Round(time.Second),
+		m.StartTime.Format(time.RFC3339), m.EndTime.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Nodes:       %d discovered, %d crawled\n", m.NodesDiscovered, m.NodesCrawled)
+	fmt.Fprintf(&b, "Edges:       %d\n", m.EdgesRecorded)
+	fmt.Fprintf(&b, "Pages:       %d fetched, %d failed (avg %dms)\n", m.PagesFetched, m.PagesFailed, m.AvgFetchTimeMs)
+	fmt.Fprintf(&b, "Peak heap:   %dMB\n", m.PeakHeapInUseMB)
+	return b.String()
+}
+
+// MetricsJSON returns the final metrics as written to the metrics file
+func (r Report) MetricsJSON() ([]byte, error) {
+	return json.MarshalIndent(r.Metrics, "", "  ")
+}
+
+// Notifier delivers a crawl report to an external channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, report Report) error
+}
+
+// FromConfig builds the notifiers enabled in the configuration
+func FromConfig(cfg *config.Config) []Notifier {
+	var notifiers []Notifier
+	if cfg.NotifySlackWebhook != "" {
+		notifiers = append(notifiers, &Slack{WebhookURL: cfg.NotifySlackWebhook})
+	}
+	if cfg.NotifySMTPAddr != "" {
+		notifiers = append(notifiers, &Email{
+			Addr:     cfg.NotifySMTPAddr,
+			Username: cfg.NotifySMTPUsername,
+			Password: cfg.NotifySMTPPassword,
+			From:     cfg.NotifyEmailFrom,
+			To:       cfg.NotifyEmailTo,
+		})
+	}
+	return notifiers
+}
+
+// SendAll delivers the report through every notifier, logging failures
+// A failing channel never prevents the others from being tried
+func SendAll(ctx context.Context, notifiers []Notifier, report Report) {
+	for _, n := range notifiers {
+		if err := n.Notify(ctx, report); err != nil {
+			logrus.Errorf("Failed to send %s notification: %v", n.Name(), err)
+			continue
+		}
+		logrus.Infof("Sent %s notification", n.Name())
+	}
+}
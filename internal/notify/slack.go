@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Slack posts reports to a Slack incoming webhook
+type Slack struct {
+	WebhookURL string
+	Client     *http.Client // nil uses http.DefaultClient
+}
+
+// Name identifies the channel in logs
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+// Notify posts the summary with the metrics JSON in a code block
+// Webhooks don't accept file uploads, so the metrics are inlined
+func (s *Slack) Notify(ctx context.Context, report Report) error {
+	metrics, err := report.MetricsJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s*\n```\n%s```\n```\n%s\n```", report.Subject(), report.Summary(), metrics),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
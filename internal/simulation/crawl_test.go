@@ -0,0 +1,119 @@
+package simulation_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/alvmarrod/web-weaver/internal/config"
+	"github.com/alvmarrod/web-weaver/internal/crawler"
+	"github.com/alvmarrod/web-weaver/internal/metrics"
+	"github.com/alvmarrod/web-weaver/internal/simulation"
+	"github.com/alvmarrod/web-weaver/internal/storage"
+)
+
+// Simulated crawl parameters; the site is the one -simulate crawls by default
+const (
+	crawlNodes    = 200
+	crawlFanOut   = 5
+	crawlFailures = 0.05
+	crawlSeed     = 1
+	crawlMaxDepth = 50
+	crawlTimeout  = 90 * time.Second // well above a healthy run
+)
+
+// TestSimulatedCrawl crawls a fixed-seed synthetic site to the end and
+// checks it against the site itself: every reachable domain becomes a node,
+// every link of a fetched page an edge, and the crawl ends because the queue
+// drained rather than on a budget or the failure threshold
+func TestSimulatedCrawl(t *testing.T) {
+	// The failing domains of the site log an error each
+	logrus.SetLevel(logrus.FatalLevel)
+
+	site, err := simulation.NewSite(simulation.Options{
+		Nodes:       crawlNodes,
+		FanOut:      crawlFanOut,
+		FailureRate: crawlFailures,
+		Seed:        crawlSeed,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	settings, err := json.Marshal(map[string]any{
+		"seed_url":           site.SeedURL(),
+		"max_depth":          crawlMaxDepth,
+		"concurrent_workers": 8,
+		"request_timeout_ms": 5000,
+		"retry_delay_ms":     10,
+		"db_path":            filepath.Join(dir, "crawler.db"),
+		"metrics_path":       filepath.Join(dir, "metrics.log"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, settings, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := storage.NewStorage(cfg.DBPath, cfg.Session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	c := crawler.NewCrawler(cfg, store, metrics.NewTracker("simulated-crawl"))
+	c.SetTransport(site)
+	if _, err := c.EnqueueSeed(site.SeedURL()); err != nil {
+		t.Fatal(err)
+	}
+	c.Start()
+
+	drained := make(chan struct{})
+	go func() {
+		c.WaitUntilEmpty()
+		close(drained)
+	}()
+
+	reason := storage.TerminationQueueEmpty
+	select {
+	case <-drained:
+	case <-c.FailureThresholdReached():
+		reason = storage.TerminationFailureThreshold
+	case <-time.After(crawlTimeout):
+		reason = storage.TerminationTimeBudget
+	}
+	c.Stop()
+	if reason != storage.TerminationQueueEmpty {
+		t.Fatalf("crawl ended with %s, want %s", reason, storage.TerminationQueueEmpty)
+	}
+
+	if err := c.FlushToStorage(); err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := store.ListNodes(storage.NodeFilter{}, 0, 10*crawlNodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edges, err := store.ListEdges(storage.EdgeFilter{}, 0, 10*crawlNodes*crawlFanOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := site.Reachable(crawlMaxDepth); len(nodes) != want {
+		t.Errorf("crawl recorded %d nodes, want %d", len(nodes), want)
+	}
+	if want := site.Links(crawlMaxDepth); len(edges) != want {
+		t.Errorf("crawl recorded %d edges, want %d", len(edges), want)
+	}
+}
@@ -0,0 +1,202 @@
+package simulation
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+)
+
+// hostSuffix is the reserved TLD used for synthetic hosts so they can never
+// collide with real domains
+const hostSuffix = ".test"
+
+// Options controls the shape of the generated site graph
+type Options struct {
+	Nodes       int     // Number of synthetic domains
+	FanOut      int     // Outbound cross-domain links per page
+	FailureRate float64 // Fraction of domains that answer with HTTP 500
+	Seed        int64   // RNG seed; same seed always yields the same graph
+}
+
+// Site is a deterministic synthetic web of domains served in-process
+// It implements both http.Handler (to run as a fixture web server) and
+// http.RoundTripper (to plug straight into the crawler's HTTP client)
+type Site struct {
+	opts    Options
+	links   [][]int
+	failing []bool
+}
+
+// NewSite generates a synthetic site graph from the given options
+func NewSite(opts Options) (*Site, error) {
+	if opts.Nodes < 1 {
+		return nil, fmt.Errorf("nodes must be >= 1")
+	}
+	if opts.FanOut < 0 {
+		return nil, fmt.Errorf("fan-out must be >= 0")
+	}
+	if opts.FailureRate < 0 || opts.FailureRate > 1 {
+		return nil, fmt.Errorf("failure rate must be between 0 and 1")
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	s := &Site{
+		opts:    opts,
+		links:   make([][]int, opts.Nodes),
+		failing: make([]bool, opts.Nodes),
+	}
+
+	for i := 0; i < opts.Nodes; i++ {
+		// The seed node never fails, otherwise the crawl would end immediately
+		s.failing[i] = i != 0 && rng.Float64() < opts.FailureRate
+
+		for j := 0; j < opts.FanOut && opts.Nodes > 1; j++ {
+			target := rng.Intn(opts.Nodes - 1)
+			if target >= i {
+				target++ // Skip self-links
+			}
+			s.links[i] = append(s.links[i], target)
+		}
+	}
+
+	return s, nil
+}
+
+// SeedURL returns the URL of the entry node of the synthetic graph
+func (s *Site) SeedURL() string {
+	return "https://" + Host(0) + "/"
+}
+
+// Host returns the synthetic hostname for a node index
+func Host(index int) string {
+	return "node-" + strconv.Itoa(index) + hostSuffix
+}
+
+// nodeIndex parses a synthetic hostname back into its node index
+func (s *Site) nodeIndex(host string) (int, bool) {
+	name := strings.TrimSuffix(strings.ToLower(host), hostSuffix)
+	if !strings.HasPrefix(name, "node-") {
+		return 0, false
+	}
+
+	index, err := strconv.Atoi(strings.TrimPrefix(name, "node-"))
+	if err != nil || index < 0 || index >= len(s.links) {
+		return 0, false
+	}
+	return index, true
+}
+
+// ServeHTTP renders the page for the requested host
+func (s *Site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	index, ok := s.nodeIndex(r.URL.Hostname())
+	if !ok {
+		index, ok = s.nodeIndex(r.Host)
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if s.failing[index] {
+		http.Error(w, "simulated failure", http.StatusInternalServerError)
+		return
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "<html><head><title>Node %d</title>", index)
+	fmt.Fprintf(&body, `<meta name="description" content="Synthetic node %d"></head><body>`, index)
+	for _, target := range s.links[index] {
+		fmt.Fprintf(&body, `<a href="https://%s/">%s</a>`, Host(target), Host(target))
+	}
+	// Same-domain and relative links exercise the filters
+	fmt.Fprintf(&body, `<a href="/about">About</a><a href="https://%s/self">Self</a>`, Host(index))
+	body.WriteString("</body></html>")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(body.String()))
+}
+
+// RoundTrip serves the request in-process without touching the network
+func (s *Site) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// Reachable returns the number of domains a correct crawl bounded by maxDepth
+// should record: every healthy page up to maxDepth is fetched and all of its
+// link targets become nodes
+func (s *Site) Reachable(maxDepth int) int {
+	depth := make(map[int]int, len(s.links))
+	depth[0] = 0
+	frontier := []int{0}
+
+	for len(frontier) > 0 {
+		current := frontier[0]
+		frontier = frontier[1:]
+
+		if s.failing[current] || depth[current] > maxDepth {
+			continue
+		}
+
+		for _, target := range s.links[current] {
+			if _, seen := depth[target]; seen {
+				continue
+			}
+			depth[target] = depth[current] + 1
+			frontier = append(frontier, target)
+		}
+	}
+
+	return len(depth)
+}
+
+// Links returns the number of distinct edges a correct crawl bounded by
+// maxDepth should record: one per link target of every page it fetches
+func (s *Site) Links(maxDepth int) int {
+	depth := make(map[int]int, len(s.links))
+	depth[0] = 0
+	frontier := []int{0}
+	edges := 0
+
+	for len(frontier) > 0 {
+		current := frontier[0]
+		frontier = frontier[1:]
+
+		if s.failing[current] || depth[current] > maxDepth {
+			continue
+		}
+
+		targets := make(map[int]bool, len(s.links[current]))
+		for _, target := range s.links[current] {
+			targets[target] = true
+			if _, seen := depth[target]; seen {
+				continue
+			}
+			depth[target] = depth[current] + 1
+			frontier = append(frontier, target)
+		}
+		edges += len(targets)
+	}
+
+	return edges
+}
+
+// Fingerprint returns a stable hash of the generated graph, useful to assert
+// that two runs used the same fixture
+func (s *Site) Fingerprint() uint64 {
+	h := fnv.New64a()
+	for i, targets := range s.links {
+		fmt.Fprintf(h, "%d:%t:%v;", i, s.failing[i], targets)
+	}
+	return h.Sum64()
+}
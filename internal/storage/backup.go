@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Backup copies the live database to destPath using SQLite's online backup
+// API, so it is safe to run while a crawl is writing to the same file
+// Returns the number of pages copied
+func (s *Storage) Backup(destPath string) (int, error) {
+	if _, err := os.Stat(destPath); err == nil {
+		return 0, fmt.Errorf("backup destination %s already exists", destPath)
+	}
+
+	destDB, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open backup destination: %w", err)
+	}
+	defer destDB.Close()
+
+	ctx := context.Background()
+
+	destConn, err := destDB.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to backup destination: %w", err)
+	}
+	defer destConn.Close()
+
+	srcConn, err := s.db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to source database: %w", err)
+	}
+	defer srcConn.Close()
+
+	var pages int
+	err = destConn.Raw(func(destRaw any) error {
+		return srcConn.Raw(func(srcRaw any) error {
+			dest, ok := destRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected destination driver connection %T", destRaw)
+			}
+			src, ok := srcRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected source driver connection %T", srcRaw)
+			}
+
+			backup, err := dest.Backup("main", src, "main")
+			if err != nil {
+				return fmt.Errorf("failed to start backup: %w", err)
+			}
+
+			// Copy everything in a single step so concurrent writes can't
+			// force the backup to restart
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return fmt.Errorf("failed to copy pages: %w", err)
+			}
+			pages = backup.PageCount()
+
+			return backup.Finish()
+		})
+	})
+	if err != nil {
+		os.Remove(destPath)
+		return 0, err
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BlockedDomain is a manually blocked domain; its subdomains are blocked too
+type BlockedDomain struct {
+	Domain    string    `json:"domain"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BlockDomain adds or updates a blocklist entry
+func (s *Storage) BlockDomain(domain, reason string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO blocked_domains (domain, reason)
+		VALUES (?, ?)
+		ON CONFLICT(domain) DO UPDATE SET reason = EXCLUDED.reason
+	`, strings.ToLower(domain), reason)
+	if err != nil {
+		return fmt.Errorf("failed to block domain: %w", err)
+	}
+	return nil
+}
+
+// UnblockDomain removes a blocklist entry; returns false if it didn't exist
+func (s *Storage) UnblockDomain(domain string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM blocked_domains WHERE domain = ?", strings.ToLower(domain))
+	if err != nil {
+		return false, fmt.Errorf("failed to unblock domain: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to unblock domain: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// ListBlockedDomains returns all blocklist entries ordered by domain
+func (s *Storage) ListBlockedDomains() ([]BlockedDomain, error) {
+	rows, err := s.db.Query(`
+		SELECT domain, COALESCE(reason, ''), created_at
+		FROM blocked_domains
+		ORDER BY domain ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blocked domains: %w", err)
+	}
+	defer rows.Close()
+
+	var blocked []BlockedDomain
+	for rows.Next() {
+		var b BlockedDomain
+		if err := rows.Scan(&b.Domain, &b.Reason, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked domain: %w", err)
+		}
+		blocked = append(blocked, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blocked domains: %w", err)
+	}
+
+	return blocked, nil
+}
+
+// TombstoneDomain hides a domain and its subdomains from resume, listings,
+// exports, and search without deleting their history
+// Returns the number of nodes tombstoned
+func (s *Storage) TombstoneDomain(domain string) (int, error) {
+	domain = strings.ToLower(domain)
+	result, err := s.db.Exec(`
+		UPDATE nodes SET tombstoned = 1, status = 'blocked'
+		WHERE domain_name = ? OR domain_name LIKE ?
+	`, domain, "%."+domain)
+	if err != nil {
+		return 0, fmt.Errorf("failed to tombstone domain: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to tombstone domain: %w", err)
+	}
+	return int(affected), nil
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// week is the unit of edge age for DecayEdges, in seconds
+const week = 7 * 24 * 60 * 60
+
+// DecayStats summarizes a DecayEdges call
+type DecayStats struct {
+	Decayed int // edges whose weight was lowered
+	Dropped int // edges deleted as their weight reached 0
+}
+
+// DecayEdges multiplies the weight of the session's edges by factor for
+// every whole week since they were stored or last decayed, rounding to the
+// nearest integer; edges that round down to 0 are deleted
+// Seeing an edge again adds to its weight without restarting its clock, so
+// old weight fades even on edges still linked. An edge whose rounded weight
+// wouldn't change keeps aging, so light edges fade too, only after more
+// weeks. Edges from before decay was enabled start aging at now
+func (s *Storage) DecayEdges(factor float64, now time.Time) (DecayStats, error) {
+	var stats DecayStats
+	if factor <= 0 || factor >= 1 {
+		return stats, fmt.Errorf("decay factor must be between 0 and 1, got %g", factor)
+	}
+	const sessionEdges = `from_node_id IN (SELECT node_id FROM nodes WHERE session = ?)`
+	nowUnix := now.Unix()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE edges SET weighted_at = ? WHERE weighted_at IS NULL AND `+sessionEdges, nowUnix, s.session); err != nil {
+		return stats, fmt.Errorf("failed to start edge aging: %w", err)
+	}
+
+	// Edges are decayed in one statement per age in weeks
+	rows, err := tx.Query(`SELECT DISTINCT (? - weighted_at) / ? FROM edges WHERE weighted_at <= ? AND `+sessionEdges,
+		nowUnix, week, nowUnix-week, s.session)
+	if err != nil {
+		return stats, fmt.Errorf("failed to query edge ages: %w", err)
+	}
+	var ages []int64
+	for rows.Next() {
+		var weeks int64
+		if err := rows.Scan(&weeks); err != nil {
+			rows.Close()
+			return stats, fmt.Errorf("failed to scan edge age: %w", err)
+		}
+		ages = append(ages, weeks)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, fmt.Errorf("failed to query edge ages: %w", err)
+	}
+
+	decay, err := tx.Prepare(`
+		UPDATE edges SET
+			weight = CAST(weight * ?1 + 0.5 AS INTEGER),
+			weighted_at = weighted_at + ?2 * ?3
+		WHERE (?4 - weighted_at) / ?3 = ?2
+			AND CAST(weight * ?1 + 0.5 AS INTEGER) < weight
+			AND from_node_id IN (SELECT node_id FROM nodes WHERE session = ?5)
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare edge decay: %w", err)
+	}
+	defer decay.Close()
+
+	for _, weeks := range ages {
+		result, err := decay.Exec(math.Pow(factor, float64(weeks)), weeks, week, nowUnix, s.session)
+		if err != nil {
+			return stats, fmt.Errorf("failed to decay edges %d weeks old: %w", weeks, err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			stats.Decayed += int(n)
+		}
+	}
+
+	result, err := tx.Exec(`DELETE FROM edges WHERE weight <= 0 AND `+sessionEdges, s.session)
+	if err != nil {
+		return stats, fmt.Errorf("failed to drop decayed edges: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil {
+		stats.Dropped = int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit edge decay: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"fmt"
+)
+
+// DepthStats summarizes a depth recomputation
+type DepthStats struct {
+	Reachable   int // nodes reached from the seeds
+	Changed     int // nodes whose last_depth was updated
+	Unreachable int // nodes not reachable from any seed, left untouched
+}
+
+// RecomputeDepths sets last_depth to the shortest directed hop count from
+// the nearest seed over the stored edges, skipping tombstoned nodes
+func (s *Storage) RecomputeDepths(seedIDs []int) (DepthStats, error) {
+	var stats DepthStats
+
+	adjacency, err := s.loadAdjacency("")
+	if err != nil {
+		return stats, err
+	}
+
+	// Multi-source BFS: every seed starts at depth 0
+	depth := make(map[int]int, len(adjacency))
+	frontier := make([]int, 0, len(seedIDs))
+	for _, id := range seedIDs {
+		if _, seen := depth[id]; !seen {
+			depth[id] = 0
+			frontier = append(frontier, id)
+		}
+	}
+	for len(frontier) > 0 {
+		var next []int
+		for _, current := range frontier {
+			for _, target := range adjacency[current] {
+				if _, seen := depth[target]; seen {
+					continue
+				}
+				depth[target] = depth[current] + 1
+				next = append(next, target)
+			}
+		}
+		frontier = next
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`UPDATE nodes SET last_depth = ? WHERE node_id = ? AND last_depth != ?`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare depth update: %w", err)
+	}
+	defer stmt.Close()
+
+	for id, d := range depth {
+		result, err := stmt.Exec(d, id, d)
+		if err != nil {
+			return stats, fmt.Errorf("failed to update depth of node %d: %w", id, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return stats, fmt.Errorf("failed to update depth of node %d: %w", id, err)
+		}
+		stats.Changed += int(affected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit depths: %w", err)
+	}
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM nodes WHERE session = ? AND tombstoned = 0`, s.session).Scan(&total); err != nil {
+		return stats, fmt.Errorf("failed to count nodes: %w", err)
+	}
+	stats.Reachable = len(depth)
+	stats.Unreachable = total - stats.Reachable
+
+	return stats, nil
+}
+
+// OutLinks returns the targets of every live node's stored edges in the
+// order they were first recorded, leaving out image edges, whose targets
+// are never crawled
+func (s *Storage) OutLinks() (map[int][]int, error) {
+	return s.loadAdjacency(EdgeImage)
+}
+
+// loadAdjacency reads the edges between the session's live nodes into an
+// adjacency list, in edge order, skipping edges of skipType if set
+func (s *Storage) loadAdjacency(skipType string) (map[int][]int, error) {
+	rows, err := s.db.Query(`
+		SELECT from_node_id, to_node_id
+		FROM edges
+		WHERE `+liveEdges+`
+		  AND edge_type != ?
+		ORDER BY edge_id
+	`, s.session, s.session, skipType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load edges: %w", err)
+	}
+	defer rows.Close()
+
+	adjacency := make(map[int][]int)
+	for rows.Next() {
+		var from, to int
+		if err := rows.Scan(&from, &to); err != nil {
+			return nil, fmt.Errorf("failed to scan edge: %w", err)
+		}
+		adjacency[from] = append(adjacency[from], to)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating edges: %w", err)
+	}
+
+	return adjacency, nil
+}
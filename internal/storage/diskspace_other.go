@@ -0,0 +1,10 @@
+//go:build !unix
+
+package storage
+
+import "errors"
+
+// FreeDiskBytes is not supported on this platform
+func FreeDiskBytes(dbPath string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+)
+
+// FreeDiskBytes returns the space available to unprivileged users on the
+// volume holding the given database file
+func FreeDiskBytes(dbPath string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(filepath.Dir(dbPath), &st); err != nil {
+		return 0, fmt.Errorf("failed to stat filesystem: %w", err)
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fetch error categories recorded in the errors table
+const (
+	ErrorDNS        = "dns"        // host name did not resolve
+	ErrorTimeout    = "timeout"    // request_timeout_ms or a dial timeout elapsed
+	ErrorConnection = "connection" // refused, reset, or unreachable
+	ErrorTLS        = "tls"        // handshake or certificate failure
+	ErrorHTTP       = "http"       // the server answered with an error status
+	ErrorOther      = "other"
+)
+
+// ErrorCategories lists every fetch error category
+var ErrorCategories = []string{ErrorDNS, ErrorTimeout, ErrorConnection, ErrorTLS, ErrorHTTP, ErrorOther}
+
+// FetchError is one failed fetch
+type FetchError struct {
+	ErrorID    int
+	Domain     string
+	URL        string
+	Category   string
+	StatusCode int // 0 when no response was received
+	Message    string
+	Attempt    int // crawl of the node this fetch was, starting at 1
+	OccurredAt time.Time
+}
+
+// FetchErrorFilter narrows ListFetchErrors; zero values match everything
+type FetchErrorFilter struct {
+	Domain   string
+	Category string
+	Since    time.Time
+}
+
+// SaveFetchErrors appends fetch errors to the current session in a single
+// transaction
+func (s *Storage) SaveFetchErrors(records []FetchError) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO errors (session, domain, url, category, status_code, message, attempt, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare error insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, r := range records {
+		if _, err := stmt.Exec(s.session, r.Domain, r.URL, r.Category, r.StatusCode,
+			r.Message, r.Attempt, r.OccurredAt.Unix()); err != nil {
+			return fmt.Errorf("failed to save error for %s: %w", r.Domain, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit errors: %w", err)
+	}
+	return nil
+}
+
+// ListFetchErrors returns the current session's fetch errors matching
+// filter, most recent first; limit <= 0 returns all
+func (s *Storage) ListFetchErrors(filter FetchErrorFilter, limit int) ([]FetchError, error) {
+	where := []string{"session = ?"}
+	args := []any{s.session}
+	if filter.Domain != "" {
+		where = append(where, "domain = ?")
+		args = append(args, filter.Domain)
+	}
+	if filter.Category != "" {
+		where = append(where, "category = ?")
+		args = append(args, filter.Category)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "occurred_at >= ?")
+		args = append(args, filter.Since.Unix())
+	}
+
+	query := `
+		SELECT error_id, domain, url, category, status_code, COALESCE(message, ''), attempt, occurred_at
+		FROM errors
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY occurred_at DESC, error_id DESC`
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list errors: %w", err)
+	}
+	defer rows.Close()
+
+	var records []FetchError
+	for rows.Next() {
+		var r FetchError
+		var occurredAt int64
+		if err := rows.Scan(&r.ErrorID, &r.Domain, &r.URL, &r.Category, &r.StatusCode,
+			&r.Message, &r.Attempt, &occurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan error: %w", err)
+		}
+		r.OccurredAt = time.Unix(occurredAt, 0)
+		records = append(records, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating errors: %w", err)
+	}
+
+	return records, nil
+}
+
+// CountFetchErrors returns the number of fetch errors per category in the
+// current session
+func (s *Storage) CountFetchErrors() (map[string]int, error) {
+	rows, err := s.db.Query(`
+		SELECT category, COUNT(*) FROM errors WHERE session = ? GROUP BY category
+	`, s.session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count errors: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var category string
+		var n int
+		if err := rows.Scan(&category, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan error count: %w", err)
+		}
+		counts[category] = n
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating error counts: %w", err)
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// SaveFetchTimes records when each domain of the current session was last
+// fetched
+func (s *Storage) SaveFetchTimes(times map[string]time.Time) error {
+	if len(times) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`UPDATE nodes SET last_fetched_at = ? WHERE session = ? AND domain_name = ?`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare fetch time update: %w", err)
+	}
+	defer stmt.Close()
+
+	for domain, at := range times {
+		if _, err := stmt.Exec(at.Unix(), s.session, domain); err != nil {
+			return fmt.Errorf("failed to save fetch time for %s: %w", domain, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit fetch times: %w", err)
+	}
+	return nil
+}
+
+// RecentFetches returns the domains fetched at or after since with their
+// latest fetch time, across every session of the database
+func (s *Storage) RecentFetches(since time.Time) (map[string]time.Time, error) {
+	rows, err := s.db.Query(`
+		SELECT domain_name, MAX(last_fetched_at) FROM nodes
+		WHERE last_fetched_at >= ?
+		GROUP BY domain_name
+	`, since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fetch times: %w", err)
+	}
+	defer rows.Close()
+
+	times := make(map[string]time.Time)
+	for rows.Next() {
+		var domain string
+		var at int64
+		if err := rows.Scan(&domain, &at); err != nil {
+			return nil, fmt.Errorf("failed to scan fetch time: %w", err)
+		}
+		times[domain] = time.Unix(at, 0)
+	}
+	return times, rows.Err()
+}
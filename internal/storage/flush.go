@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GraphEdge is a typed edge between two domains written by WriteGraph;
+// Weight is added to the stored weight, and AnchorText and Rel are kept
+// only if the edge has none yet
+type GraphEdge struct {
+	From, To   string
+	Type       string
+	Weight     int
+	AnchorText string
+	Rel        string
+}
+
+// GraphOrigin is the parent, seed and source page URL that first led to a
+// domain, written by WriteGraph; Parent is "" for seeds
+type GraphOrigin struct {
+	Domain, Parent, Seed string
+	SourceURL            string
+}
+
+// GraphWriteStats summarizes a WriteGraph call
+type GraphWriteStats struct {
+	Nodes        int // nodes inserted or updated
+	Edges        int // edges inserted or updated
+	Origins      int // origins recorded, including ones kept from earlier writes
+	SkippedEdges int // edges with an endpoint not found in the session
+}
+
+// WriteGraph writes nodes, edges and origins in one transaction, so an
+// interrupted write leaves the stored graph as it was
+// Nodes are upserted as UpsertNodeWithDepth does, with their crawl count,
+// and status, language, reputation, category and response when set; edges and origins refer to
+// nodes by domain and are written as UpsertEdge and SetOrigin do
+func (s *Storage) WriteGraph(nodes []*Node, edges []GraphEdge, origins []GraphOrigin) (GraphWriteStats, error) {
+	var stats GraphWriteStats
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	upsertNode, err := tx.Prepare(`
+		INSERT INTO nodes (session, domain_name, title, meta_description,
+			links_total, links_internal, links_external, external_domains, crawl_count, last_depth)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(session, domain_name) DO UPDATE SET
+			title = COALESCE(EXCLUDED.title, nodes.title),
+			meta_description = COALESCE(EXCLUDED.meta_description, nodes.meta_description),
+			links_total = COALESCE(EXCLUDED.links_total, nodes.links_total),
+			links_internal = COALESCE(EXCLUDED.links_internal, nodes.links_internal),
+			links_external = COALESCE(EXCLUDED.links_external, nodes.links_external),
+			external_domains = COALESCE(EXCLUDED.external_domains, nodes.external_domains),
+			crawl_count = EXCLUDED.crawl_count,
+			last_depth = EXCLUDED.last_depth
+		RETURNING node_id
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare node upsert: %w", err)
+	}
+	defer upsertNode.Close()
+
+	setOutcome, err := tx.Prepare(`
+		UPDATE nodes SET
+			status = COALESCE(NULLIF(?, ''), status),
+			language = COALESCE(NULLIF(?, ''), language),
+			reputation = COALESCE(NULLIF(?, ''), reputation),
+			category = COALESCE(NULLIF(?, ''), category),
+			sitemap_pages = COALESCE(NULLIF(?, 0), sitemap_pages)
+		WHERE node_id = ?
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare node update: %w", err)
+	}
+	defer setOutcome.Close()
+
+	setResponse, err := tx.Prepare(`
+		UPDATE nodes SET http_status = ?, response_time_ms = ?, content_length = ?, last_crawled_at = ?
+		WHERE node_id = ?
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare node response update: %w", err)
+	}
+	defer setResponse.Close()
+
+	upsertEdge, err := tx.Prepare(`
+		INSERT INTO edges (from_node_id, to_node_id, edge_type, weight, weighted_at, anchor_text, rel)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))
+		ON CONFLICT(from_node_id, to_node_id, edge_type) DO UPDATE SET
+			weight = weight + EXCLUDED.weight,
+			anchor_text = COALESCE(edges.anchor_text, EXCLUDED.anchor_text),
+			rel = COALESCE(edges.rel, EXCLUDED.rel)
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare edge upsert: %w", err)
+	}
+	defer upsertEdge.Close()
+
+	setOrigin, err := tx.Prepare(`
+		UPDATE nodes SET parent_node_id = NULLIF(?, 0), seed_node_id = ?, source_url = NULLIF(?, '')
+		WHERE node_id = ? AND seed_node_id IS NULL
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare origin update: %w", err)
+	}
+	defer setOrigin.Close()
+
+	lookup, err := tx.Prepare(`SELECT node_id FROM nodes WHERE session = ? AND domain_name = ?`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare node lookup: %w", err)
+	}
+	defer lookup.Close()
+
+	now := time.Now().Unix()
+	ids := make(map[string]int, len(nodes))
+	for _, node := range nodes {
+		var total, internal, external, externalDomains any
+		if links := node.LinkStats; links != nil {
+			total, internal, external, externalDomains = links.Total, links.Internal, links.External, links.ExternalDomains
+		}
+
+		var nodeID int
+		err := upsertNode.QueryRow(s.session, node.DomainName, node.Title, node.MetaDescription,
+			total, internal, external, externalDomains, node.CrawlCount, node.LastDepth).Scan(&nodeID)
+		if err != nil {
+			return stats, fmt.Errorf("failed to upsert node %s: %w", node.DomainName, err)
+		}
+		ids[node.DomainName] = nodeID
+
+		if node.Status != "" || node.Language != "" || node.Reputation != "" || node.Category != "" || node.SitemapPages > 0 {
+			if _, err := setOutcome.Exec(node.Status, node.Language, node.Reputation, node.Category, node.SitemapPages, nodeID); err != nil {
+				return stats, fmt.Errorf("failed to update node %s: %w", node.DomainName, err)
+			}
+		}
+		if r := node.Response; r != nil {
+			if _, err := setResponse.Exec(r.StatusCode, r.ResponseTimeMs, r.ContentLength, r.CrawledAt.Unix(), nodeID); err != nil {
+				return stats, fmt.Errorf("failed to update response of node %s: %w", node.DomainName, err)
+			}
+		}
+		stats.Nodes++
+	}
+
+	// idOf resolves domains not among nodes, e.g. the seed of an origin
+	// written by an earlier run; 0 if the session has no such node
+	idOf := func(domain string) (int, error) {
+		if id, ok := ids[domain]; ok {
+			return id, nil
+		}
+		var id int
+		err := lookup.QueryRow(s.session, domain).Scan(&id)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, fmt.Errorf("failed to look up node %s: %w", domain, err)
+		}
+		ids[domain] = id
+		return id, nil
+	}
+
+	for _, edge := range edges {
+		fromID, err := idOf(edge.From)
+		if err != nil {
+			return stats, err
+		}
+		toID, err := idOf(edge.To)
+		if err != nil {
+			return stats, err
+		}
+		if fromID == 0 || toID == 0 {
+			stats.SkippedEdges++
+			continue
+		}
+		if _, err := upsertEdge.Exec(fromID, toID, edge.Type, edge.Weight, now, edge.AnchorText, edge.Rel); err != nil {
+			return stats, fmt.Errorf("failed to upsert %s edge %s -> %s: %w", edge.Type, edge.From, edge.To, err)
+		}
+		stats.Edges++
+	}
+
+	for _, o := range origins {
+		nodeID, err := idOf(o.Domain)
+		if err != nil {
+			return stats, err
+		}
+		seedID, err := idOf(o.Seed)
+		if err != nil {
+			return stats, err
+		}
+		parentID := 0
+		if o.Parent != "" {
+			if parentID, err = idOf(o.Parent); err != nil {
+				return stats, err
+			}
+		}
+		if nodeID == 0 || seedID == 0 {
+			continue
+		}
+		if _, err := setOrigin.Exec(parentID, seedID, o.SourceURL, nodeID); err != nil {
+			return stats, fmt.Errorf("failed to set origin of %s: %w", o.Domain, err)
+		}
+		stats.Origins++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit graph: %w", err)
+	}
+	return stats, nil
+}
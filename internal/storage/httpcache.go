@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// HTTPCacheEntry holds the freshness and validators last seen for a URL
+type HTTPCacheEntry struct {
+	URL          string
+	ETag         string
+	LastModified string
+	FreshUntil   time.Time // zero when the response must be revalidated
+}
+
+// LoadHTTPCache returns all stored cache entries
+func (s *Storage) LoadHTTPCache() ([]HTTPCacheEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT url, COALESCE(etag, ''), COALESCE(last_modified, ''), fresh_until
+		FROM http_cache
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load http cache: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []HTTPCacheEntry
+	for rows.Next() {
+		var entry HTTPCacheEntry
+		var freshUntil int64
+		if err := rows.Scan(&entry.URL, &entry.ETag, &entry.LastModified, &freshUntil); err != nil {
+			return nil, fmt.Errorf("failed to scan http cache entry: %w", err)
+		}
+		if freshUntil > 0 {
+			entry.FreshUntil = time.Unix(freshUntil, 0)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating http cache: %w", err)
+	}
+
+	return entries, nil
+}
+
+// SaveHTTPCache upserts cache entries in a single transaction
+func (s *Storage) SaveHTTPCache(entries []HTTPCacheEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO http_cache (url, etag, last_modified, fresh_until, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(url) DO UPDATE SET
+			etag = EXCLUDED.etag,
+			last_modified = EXCLUDED.last_modified,
+			fresh_until = EXCLUDED.fresh_until,
+			updated_at = EXCLUDED.updated_at
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare http cache upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, entry := range entries {
+		var freshUntil int64
+		if !entry.FreshUntil.IsZero() {
+			freshUntil = entry.FreshUntil.Unix()
+		}
+		if _, err := stmt.Exec(entry.URL, entry.ETag, entry.LastModified, freshUntil); err != nil {
+			return fmt.Errorf("failed to save http cache entry %s: %w", entry.URL, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit http cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MergeStats summarizes a merge
+type MergeStats struct {
+	Nodes  int // nodes added or updated
+	Edges  int // edges added or updated
+	Errors int // error log rows copied
+}
+
+// nodeMergeKeep lists node columns a merge doesn't copy as is: the key,
+// the merged counters, and the IDs of other nodes, which are remapped
+var nodeMergeKeep = map[string]bool{
+	"node_id":        true,
+	"session":        true,
+	"domain_name":    true,
+	"crawl_count":    true,
+	"last_depth":     true,
+	"tombstoned":     true,
+	"parent_node_id": true,
+	"seed_node_id":   true,
+	"created_at":     true,
+}
+
+// Merge copies the graph of this session from the database at path into
+// this one, matching nodes by domain: the crawl of a shared frontier leaves
+// each process's pages in its own database. A node keeps the page outcome
+// of whichever database crawled it last, filling empty columns from the
+// other, the lower depth and the higher crawl count; an edge keeps the
+// higher weight. Error logs, run records, scheme checks, subdomain limits,
+// the blocklist and the HTTP cache are copied along; the saved queue isn't
+// Merging a database again changes nothing
+func (s *Storage) Merge(path string) (MergeStats, error) {
+	var stats MergeStats
+	ctx := context.Background()
+
+	// ATTACH holds per connection and can't run in a transaction
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return stats, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `ATTACH DATABASE ? AS merged`, path); err != nil {
+		return stats, fmt.Errorf("failed to attach %s: %w", path, err)
+	}
+	defer conn.ExecContext(ctx, `DETACH DATABASE merged`)
+
+	rows, err := conn.QueryContext(ctx, `SELECT name FROM pragma_table_info('nodes', 'main')`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to read node columns: %w", err)
+	}
+	var columns, updates []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return stats, fmt.Errorf("failed to read node columns: %w", err)
+		}
+		if nodeMergeKeep[name] {
+			continue
+		}
+		columns = append(columns, name)
+		// SQLite evaluates each SET expression against the old row
+		updates = append(updates, fmt.Sprintf(`%[1]s = CASE
+				WHEN COALESCE(excluded.last_crawled_at, 0) > COALESCE(nodes.last_crawled_at, 0) THEN COALESCE(excluded.%[1]s, nodes.%[1]s)
+				ELSE COALESCE(nodes.%[1]s, excluded.%[1]s) END`, name))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, fmt.Errorf("failed to read node columns: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	list := strings.Join(columns, ", ")
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO nodes (session, domain_name, crawl_count, last_depth, tombstoned, `+list+`)
+		SELECT ?, domain_name, crawl_count, last_depth, tombstoned, `+list+`
+		FROM merged.nodes WHERE session = ?
+		ON CONFLICT(session, domain_name) DO UPDATE SET
+			crawl_count = MAX(COALESCE(nodes.crawl_count, 0), COALESCE(excluded.crawl_count, 0)),
+			last_depth = MIN(COALESCE(nodes.last_depth, excluded.last_depth), COALESCE(excluded.last_depth, nodes.last_depth)),
+			tombstoned = MAX(COALESCE(nodes.tombstoned, 0), COALESCE(excluded.tombstoned, 0)),
+			`+strings.Join(updates, ",\n\t\t\t"),
+		s.session, s.session)
+	if err != nil {
+		return stats, fmt.Errorf("failed to merge nodes: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	stats.Nodes = int(n)
+
+	// Parent and seed IDs point into the merged database; map them by
+	// domain, keeping the ones already set
+	for _, column := range []string{"parent_node_id", "seed_node_id"} {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE nodes SET `+column+` = (
+				SELECT target.node_id
+				FROM merged.nodes src
+				JOIN merged.nodes ref ON ref.node_id = src.`+column+`
+				JOIN nodes target ON target.session = nodes.session AND target.domain_name = ref.domain_name
+				WHERE src.session = nodes.session AND src.domain_name = nodes.domain_name
+			)
+			WHERE session = ? AND `+column+` IS NULL`, s.session); err != nil {
+			return stats, fmt.Errorf("failed to map %s: %w", column, err)
+		}
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		INSERT INTO edges (from_node_id, to_node_id, edge_type, weight, weighted_at, anchor_text, rel)
+		SELECT from_node.node_id, to_node.node_id, e.edge_type, e.weight, e.weighted_at, e.anchor_text, e.rel
+		FROM merged.edges e
+		JOIN merged.nodes src_from ON src_from.node_id = e.from_node_id
+		JOIN merged.nodes src_to ON src_to.node_id = e.to_node_id
+		JOIN nodes from_node ON from_node.session = src_from.session AND from_node.domain_name = src_from.domain_name
+		JOIN nodes to_node ON to_node.session = src_to.session AND to_node.domain_name = src_to.domain_name
+		WHERE src_from.session = ?
+		ON CONFLICT(from_node_id, to_node_id, edge_type) DO UPDATE SET
+			weight = MAX(COALESCE(edges.weight, 0), COALESCE(excluded.weight, 0)),
+			anchor_text = COALESCE(edges.anchor_text, excluded.anchor_text),
+			rel = COALESCE(edges.rel, excluded.rel)`, s.session)
+	if err != nil {
+		return stats, fmt.Errorf("failed to merge edges: %w", err)
+	}
+	n, _ = result.RowsAffected()
+	stats.Edges = int(n)
+
+	result, err = tx.ExecContext(ctx, `
+		INSERT INTO errors (session, domain, url, category, status_code, message, attempt, occurred_at)
+		SELECT session, domain, url, category, status_code, message, attempt, occurred_at
+		FROM merged.errors src WHERE session = ? AND NOT EXISTS (
+			SELECT 1 FROM errors e
+			WHERE e.session = src.session AND e.domain = src.domain AND e.url = src.url
+				AND e.attempt = src.attempt AND e.occurred_at = src.occurred_at
+		)`, s.session)
+	if err != nil {
+		return stats, fmt.Errorf("failed to merge errors: %w", err)
+	}
+	n, _ = result.RowsAffected()
+	stats.Errors = int(n)
+
+	for _, table := range []struct{ name, query string }{
+		{"runs", `
+			INSERT OR IGNORE INTO crawl_sessions (run_id, session, seed_url, metrics_path, started_at, finished_at, termination_reason, counters)
+			SELECT run_id, session, seed_url, metrics_path, started_at, finished_at, termination_reason, counters
+			FROM merged.crawl_sessions WHERE session = ?`},
+		{"subdomain limits", `
+			INSERT OR IGNORE INTO subdomain_limits (session, root_domain, domain)
+			SELECT session, root_domain, domain FROM merged.subdomain_limits WHERE session = ?`},
+		{"scheme checks", `
+			INSERT INTO scheme_support (session, domain, https, http, http_to_https, checked_at)
+			SELECT session, domain, https, http, http_to_https, checked_at
+			FROM merged.scheme_support WHERE session = ?
+			ON CONFLICT(session, domain) DO UPDATE SET
+				https = excluded.https, http = excluded.http,
+				http_to_https = excluded.http_to_https, checked_at = excluded.checked_at
+			WHERE excluded.checked_at > scheme_support.checked_at`},
+	} {
+		if _, err := tx.ExecContext(ctx, table.query, s.session); err != nil {
+			return stats, fmt.Errorf("failed to merge %s: %w", table.name, err)
+		}
+	}
+	for _, table := range []struct{ name, query string }{
+		{"blocklist", `
+			INSERT OR IGNORE INTO blocked_domains (domain, reason, created_at)
+			SELECT domain, reason, created_at FROM merged.blocked_domains`},
+		{"HTTP cache", `
+			INSERT OR IGNORE INTO http_cache (url, etag, last_modified, fresh_until, updated_at)
+			SELECT url, etag, last_modified, fresh_until, updated_at FROM merged.http_cache`},
+	} {
+		if _, err := tx.ExecContext(ctx, table.query); err != nil {
+			return stats, fmt.Errorf("failed to merge %s: %w", table.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return stats, nil
+}
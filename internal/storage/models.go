@@ -1,42 +1,242 @@
 package storage
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
 
 // Node represents a domain or subdomain in the crawl graph
 type Node struct {
-	NodeID      int
-	DomainName  string
-	Description string
-	CrawlCount  int
-	LastDepth   int
-	CreatedAt   time.Time
+	NodeID          int
+	DomainName      string
+	Title           string     // page <title>
+	MetaDescription string     // <meta name="description"> content
+	Description     string     // display text: title, else meta description; derived on read
+	LinkStats       *LinkStats // nil until a page of the node has been analyzed
+	Response        *Response  // latest answer to a fetch of the front page; nil until one was fetched
+	CrawlCount      int
+	LastDepth       int
+	Status          string // outcome of the last crawl attempt, one of the Node* statuses
+	ParentNodeID    int    // node whose page first led here; 0 for seeds and unattributed nodes
+	SeedNodeID      int    // seed that first led here, the node itself for seeds; 0 if unattributed
+	SourceURL       string // page URL the node was first discovered on; "" for seeds
+	Language        string // primary language subtag declared by the front page, e.g. "en"; "" if undeclared
+	Reputation      string // verdict of the domain reputation lookup, one of the Reputation* verdicts; "" if not looked up
+	Category        string // kind of site the front page shows, one of the Category* categories; "" if not classified
+	SitemapPages    int    // pages on the node's own host listed in its sitemaps; 0 if none or not read
+	CreatedAt       time.Time
+}
+
+// Node statuses, by the outcome of the node's last crawl attempt
+const (
+	NodePending         = "pending"          // never attempted
+	NodeCrawled         = "crawled"          // fetched, or known fresh from the HTTP cache
+	NodeFailedTransient = "failed_transient" // failed in a way worth retrying (timeouts, 5xx, resets)
+	NodeFailedPermanent = "failed_permanent" // failed in a way retrying won't fix (NXDOMAIN, 4xx, bad certificates)
+	NodeBlocked         = "blocked"          // on the manual blocklist
+)
+
+// NodeStatuses lists every node status
+var NodeStatuses = []string{NodePending, NodeCrawled, NodeFailedTransient, NodeFailedPermanent, NodeBlocked}
+
+// Domain reputation verdicts
+const (
+	ReputationOK      = "ok"      // rated at or above the minimum score
+	ReputationLow     = "low"     // rated below the minimum score; never crawled
+	ReputationUnknown = "unknown" // not rated by any source, or every lookup failed
+)
+
+// Node categories, by the kind of site the front page shows
+const (
+	CategoryEcommerce     = "ecommerce"
+	CategoryBlog          = "blog"
+	CategoryNews          = "news"
+	CategoryDocumentation = "documentation"
+	CategoryParked        = "parked"     // placeholder, for-sale, or coming-soon page
+	CategoryErrorPage     = "error_page" // served successfully, but shows an error or a server's default page
+	CategoryOther         = "other"      // classified, but none of the above
+)
+
+// Categories lists every node category
+var Categories = []string{CategoryEcommerce, CategoryBlog, CategoryNews, CategoryDocumentation, CategoryParked, CategoryErrorPage, CategoryOther}
+
+// ParseCategories parses a comma-separated list of node categories; empty
+// means all
+func ParseCategories(list string) ([]string, error) {
+	var categories []string
+	for _, category := range strings.Split(list, ",") {
+		category = strings.TrimSpace(category)
+		if category == "" {
+			continue
+		}
+		if !slices.Contains(Categories, category) {
+			return nil, fmt.Errorf("unknown category %q (supported: %s)", category, strings.Join(Categories, ", "))
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// LinkStats summarizes the outbound links on the last fetched page of a node
+type LinkStats struct {
+	Total           int // http(s) links, before filtering
+	Internal        int // links within the same root domain
+	External        int // links to other root domains
+	ExternalDomains int // distinct external hosts linked to
+}
+
+// Response describes the latest answer to a fetch of a node's front page,
+// successful or not
+type Response struct {
+	StatusCode     int       // HTTP status; 0 if the fetch failed without one (DNS, refused, timeout)
+	ResponseTimeMs int64     // from sending the request to receiving the body
+	ContentLength  int64     // body bytes received
+	CrawledAt      time.Time // when the answer was received
+}
+
+// Edge types, by the mechanism that discovered the relationship
+const (
+	EdgeLink       = "link"       // <a href> in page content
+	EdgeRedirect   = "redirect"   // HTTP redirect to another root domain
+	EdgeCanonical  = "canonical"  // <link rel="canonical">
+	EdgeHreflang   = "hreflang"   // <link rel="alternate" hreflang>
+	EdgeSitemap    = "sitemap"    // URL listed in the source's sitemap.xml
+	EdgeFeed       = "feed"       // <link rel="alternate"> to an RSS or Atom feed
+	EdgeDependency = "dependency" // <script src> or stylesheet served from another root domain
+	EdgeImage      = "image"      // <img src> served from another root domain; never enqueued
+)
+
+// EdgeTypes lists every edge type
+var EdgeTypes = []string{EdgeLink, EdgeRedirect, EdgeCanonical, EdgeHreflang, EdgeSitemap, EdgeFeed, EdgeDependency, EdgeImage}
+
+// ValidEdgeType reports whether t is a known edge type
+func ValidEdgeType(t string) bool {
+	return slices.Contains(EdgeTypes, t)
+}
+
+// ParseEdgeTypes parses a comma-separated list of edge types; empty means all
+func ParseEdgeTypes(list string) ([]string, error) {
+	var types []string
+	for _, t := range strings.Split(list, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !ValidEdgeType(t) {
+			return nil, fmt.Errorf("unknown edge type %q (supported: %s)", t, strings.Join(EdgeTypes, ", "))
+		}
+		types = append(types, t)
+	}
+	return types, nil
 }
 
 // Edge represents a directed link between two nodes
+// The same pair may be linked once per type
 type Edge struct {
 	EdgeID     int
 	FromNodeID int
 	ToNodeID   int
+	Type       string
 	Weight     int
+	AnchorText string // text of the first link that created a link edge; "" if unknown
+	Rel        string // rel attribute of that link
+}
+
+// SearchResult is a node matched by a full-text search
+type SearchResult struct {
+	Node   Node
+	Score  float64 // Relevance boosted by degree, higher is better
+	Degree int     // Inbound + outbound edges
 }
 
 // QueueEntry represents an item in the BFS crawl queue
 type QueueEntry struct {
 	NodeID     int
 	DomainName string
+	URL        string // inner page to fetch in url crawl mode; "" for the front page
 	Depth      int
+	Priority   float64 // frontier score within a depth, higher first
+	Retries    int     // failed fetches of this entry retried this run; not persisted
 }
 
 // Metrics tracks crawl statistics for export on exit
 type Metrics struct {
-	StartTime         time.Time `json:"start_time"`
-	EndTime           time.Time `json:"end_time"`
-	NodesDiscovered   int       `json:"nodes_discovered"`
-	NodesCrawled      int       `json:"nodes_crawled"`
-	EdgesRecorded     int       `json:"edges_recorded"`
-	PagesFetched      int       `json:"pages_fetched"`
-	PagesFailed       int       `json:"pages_failed"`
-	TotalFetchTimeMs  int64     `json:"total_fetch_time_ms"`
-	AvgFetchTimeMs    int64     `json:"avg_fetch_time_ms"`
-	TerminationReason string    `json:"termination_reason"`
+	RunID             string         `json:"run_id"`
+	StartTime         time.Time      `json:"start_time"`
+	EndTime           time.Time      `json:"end_time"`
+	NodesDiscovered   int            `json:"nodes_discovered"`
+	NodesCrawled      int            `json:"nodes_crawled"`
+	EdgesRecorded     int            `json:"edges_recorded"`
+	PagesFetched      int            `json:"pages_fetched"`
+	PagesFailed       int            `json:"pages_failed"`
+	PagesFromCache    int            `json:"pages_from_cache"`   // skipped: still fresh per caching headers
+	PagesNotModified  int            `json:"pages_not_modified"` // revalidated with a 304
+	FetchesAbandoned  int            `json:"fetches_abandoned"`  // hit fetch_deadline_ms; also counted in pages_failed
+	FetchesRetried    int            `json:"fetches_retried"`    // transient failures re-enqueued per retry_attempts
+	HTTPFallbacks     int            `json:"http_fallbacks"`     // front pages requested again over HTTP per allow_http_fallback
+	RootDomains       int            `json:"root_domains"`       // tracked by the subdomain limiter
+	SubdomainsCounted int            `json:"subdomains_counted"`
+	SaturatedRoots    int            `json:"saturated_roots"` // at max_subdomains_per_root
+	DNSCacheHits      int            `json:"dns_cache_hits"`  // DNS stats stay zero without dns_prefetch_ahead
+	DNSLookups        int            `json:"dns_lookups"`
+	DNSPrefetched     int            `json:"dns_prefetched"`
+	TLDSkips          map[string]int `json:"tld_skips,omitempty"`    // links skipped per TLD by allowed_tlds/blocked_tlds
+	FetchErrors       map[string]int `json:"fetch_errors,omitempty"` // failed fetches this run per error category
+	SitemapsRead      int            `json:"sitemaps_read"`          // sitemap files, indexes included; zero without read_sitemaps
+	SitemapURLs       int            `json:"sitemap_urls"`
+	ReputationLookups int            `json:"reputation_lookups"`   // domains looked up; zero without domain_reputation
+	ReputationLow     int            `json:"reputation_low"`       // of which judged low reputation
+	Languages         map[string]int `json:"languages,omitempty"`  // domains crawled per declared language
+	Categories        map[string]int `json:"categories,omitempty"` // domains classified this run per category; empty without node_classification
+	TotalFetchTimeMs  int64          `json:"total_fetch_time_ms"`
+	AvgFetchTimeMs    int64          `json:"avg_fetch_time_ms"`
+	TerminationReason string         `json:"termination_reason"`
+
+	// Top domains of the session graph, added to the final metrics only
+	Top *TopDomains `json:"top,omitempty"`
+
+	// Set when resuming a session: the counters above then add up every
+	// run since the crawl started, and ThisRun holds this run's share
+	PreviousRunID string       `json:"previous_run_id,omitempty"`
+	ThisRun       *RunCounters `json:"this_run,omitempty"`
+
+	// Runtime stats for capacity planning (latest sample)
+	HeapInUseBytes  uint64 `json:"heap_in_use_bytes"`
+	Goroutines      int    `json:"goroutines"`
+	VisitedSetSize  int    `json:"visited_set_size"`
+	GCCycles        uint32 `json:"gc_cycles"`
+	GCPauseTotalUs  uint64 `json:"gc_pause_total_us"`
+	GCPauseLastUs   uint64 `json:"gc_pause_last_us"`
+	PeakHeapInUseMB uint64 `json:"peak_heap_in_use_mb"`
+}
+
+// RunCounters are the metrics counters that add up across the runs of a
+// resumed session
+type RunCounters struct {
+	NodesDiscovered  int   `json:"nodes_discovered"`
+	NodesCrawled     int   `json:"nodes_crawled"`
+	EdgesRecorded    int   `json:"edges_recorded"`
+	PagesFetched     int   `json:"pages_fetched"`
+	PagesFailed      int   `json:"pages_failed"`
+	PagesFromCache   int   `json:"pages_from_cache"`
+	PagesNotModified int   `json:"pages_not_modified"`
+	FetchesTimed     int   `json:"fetches_timed"` // fetches behind total_fetch_time_ms
+	TotalFetchTimeMs int64 `json:"total_fetch_time_ms"`
+}
+
+// Add returns the sum of c and other
+func (c RunCounters) Add(other RunCounters) RunCounters {
+	return RunCounters{
+		NodesDiscovered:  c.NodesDiscovered + other.NodesDiscovered,
+		NodesCrawled:     c.NodesCrawled + other.NodesCrawled,
+		EdgesRecorded:    c.EdgesRecorded + other.EdgesRecorded,
+		PagesFetched:     c.PagesFetched + other.PagesFetched,
+		PagesFailed:      c.PagesFailed + other.PagesFailed,
+		PagesFromCache:   c.PagesFromCache + other.PagesFromCache,
+		PagesNotModified: c.PagesNotModified + other.PagesNotModified,
+		FetchesTimed:     c.FetchesTimed + other.FetchesTimed,
+		TotalFetchTimeMs: c.TotalFetchTimeMs + other.TotalFetchTimeMs,
+	}
 }
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DiscoveryChain returns the chain of first discoveries that led to domain,
+// starting at its seed and ending at the node itself
+// The chain is cut short at a node without a recorded parent, so for an
+// unattributed node it holds only the node; nil means the domain is unknown
+func (s *Storage) DiscoveryChain(domain string) ([]*Node, error) {
+	node, err := s.GetNode(domain)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, nil
+	}
+
+	chain := []*Node{node}
+	seen := map[int]bool{node.NodeID: true}
+	for node.ParentNodeID != 0 {
+		// Origins form a tree, but guard against loops in hand-edited data
+		if seen[node.ParentNodeID] {
+			return nil, fmt.Errorf("discovery chain of %s loops at node %d", domain, node.ParentNodeID)
+		}
+
+		parent, err := s.GetNodeByID(node.ParentNodeID)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			break
+		}
+		seen[parent.NodeID] = true
+		chain = append(chain, parent)
+		node = parent
+	}
+
+	// Seed first
+	slices.Reverse(chain)
+	return chain, nil
+}
@@ -0,0 +1,354 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NodeFilter narrows node listings; zero values mean "no constraint"
+type NodeFilter struct {
+	MinDepth       *int // nil leaves depth unbounded; 0 is the seeds' depth
+	MaxDepth       *int
+	CreatedAfter   time.Time
+	DomainContains string
+	Seed           string   // domain of the seed that first led to the node
+	Status         string   // one of the storage.Node* statuses
+	Categories     []string // any of these storage.Category* categories; empty means all
+}
+
+// EdgeDirection selects which edges of a node to list
+type EdgeDirection string
+
+const (
+	EdgesOut  EdgeDirection = "out"
+	EdgesIn   EdgeDirection = "in"
+	EdgesBoth EdgeDirection = "both"
+)
+
+// EdgeFilter narrows edge listings; zero values mean "no constraint"
+type EdgeFilter struct {
+	NodeID     int
+	Direction  EdgeDirection
+	MinWeight  int
+	Types      []string // empty means all types
+	MaxNodeID  int      // when set, only edges between nodes up to this ID
+	Categories []string // when set, only edges between nodes of these storage.Category* categories
+}
+
+// liveNodeIDs selects the IDs of the session's non-tombstoned nodes
+// Takes the session as its only argument
+const liveNodeIDs = `SELECT node_id FROM nodes WHERE session = ? AND tombstoned = 0`
+
+// liveEdges restricts edges to those between live nodes of the session
+// Takes the session twice. The unary + keeps SQLite from probing the
+// (from_node_id, to_node_id) index with every pair of live node IDs, which
+// takes time quadratic in the graph's size
+const liveEdges = `+from_node_id IN (` + liveNodeIDs + `) AND +to_node_id IN (` + liveNodeIDs + `)`
+
+// displayDescription picks a node's display text: the title, else the meta
+// description, else the combined description written by older versions
+const displayDescription = `COALESCE(NULLIF(title, ''), NULLIF(meta_description, ''), description, '')`
+
+// nodeColumns is the column list scanned by scanNode
+const nodeColumns = `node_id, domain_name, COALESCE(title, ''), COALESCE(meta_description, ''), ` +
+	displayDescription + `, links_total, links_internal, links_external, external_domains, ` +
+	`crawl_count, last_depth, COALESCE(status, 'pending'), COALESCE(parent_node_id, 0), COALESCE(seed_node_id, 0), COALESCE(source_url, ''), ` +
+	`COALESCE(language, ''), COALESCE(reputation, ''), COALESCE(category, ''), COALESCE(sitemap_pages, 0), ` +
+	`http_status, response_time_ms, content_length, last_crawled_at, created_at`
+
+// scanNode scans a row selected with nodeColumns
+func scanNode(row interface{ Scan(...any) error }) (*Node, error) {
+	var node Node
+	var total, internal, external, externalDomains sql.NullInt64
+	var status, responseTime, contentLength, crawledAt sql.NullInt64
+	err := row.Scan(&node.NodeID, &node.DomainName, &node.Title, &node.MetaDescription, &node.Description,
+		&total, &internal, &external, &externalDomains,
+		&node.CrawlCount, &node.LastDepth, &node.Status, &node.ParentNodeID, &node.SeedNodeID, &node.SourceURL,
+		&node.Language, &node.Reputation, &node.Category, &node.SitemapPages, &status, &responseTime, &contentLength, &crawledAt, &node.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if crawledAt.Valid {
+		node.Response = &Response{
+			StatusCode:     int(status.Int64),
+			ResponseTimeMs: responseTime.Int64,
+			ContentLength:  contentLength.Int64,
+			CrawledAt:      time.Unix(crawledAt.Int64, 0),
+		}
+	}
+	if total.Valid {
+		node.LinkStats = &LinkStats{
+			Total:           int(total.Int64),
+			Internal:        int(internal.Int64),
+			External:        int(external.Int64),
+			ExternalDomains: int(externalDomains.Int64),
+		}
+	}
+	return &node, nil
+}
+
+// GetNodeByID retrieves a node of the current session by ID, returns nil if not found
+func (s *Storage) GetNodeByID(nodeID int) (*Node, error) {
+	node, err := scanNode(s.db.QueryRow(`SELECT `+nodeColumns+` FROM nodes WHERE node_id = ? AND session = ?`, nodeID, s.session))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node: %w", err)
+	}
+	return node, nil
+}
+
+// getNodesBatch bounds the domains bound to one GetNodes query, below
+// SQLite's host parameter limit
+const getNodesBatch = 500
+
+// GetNodes retrieves the nodes of the current session for domains, keyed
+// by domain; domains without a node are absent from the map
+// Domains are looked up in one query per getNodesBatch domains
+func (s *Storage) GetNodes(domains []string) (map[string]*Node, error) {
+	nodes := make(map[string]*Node, len(domains))
+	for start := 0; start < len(domains); start += getNodesBatch {
+		batch := domains[start:min(start+getNodesBatch, len(domains))]
+
+		args := make([]any, 0, len(batch)+1)
+		args = append(args, s.session)
+		for _, domain := range batch {
+			args = append(args, domain)
+		}
+
+		rows, err := s.db.Query(`
+			SELECT `+nodeColumns+`
+			FROM nodes
+			WHERE session = ? AND domain_name IN (?`+strings.Repeat(", ?", len(batch)-1)+`)
+		`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get nodes: %w", err)
+		}
+		for rows.Next() {
+			node, err := scanNode(rows)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan node: %w", err)
+			}
+			nodes[node.DomainName] = node
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error iterating nodes: %w", err)
+		}
+	}
+	return nodes, nil
+}
+
+// ListNodes returns up to limit nodes with node_id > afterID, ordered by ID
+func (s *Storage) ListNodes(filter NodeFilter, afterID, limit int) ([]*Node, error) {
+	where := []string{"node_id > ?", "session = ?", "tombstoned = 0"}
+	args := []any{afterID, s.session}
+
+	if filter.MinDepth != nil {
+		where = append(where, "last_depth >= ?")
+		args = append(args, *filter.MinDepth)
+	}
+	if filter.MaxDepth != nil {
+		where = append(where, "last_depth <= ?")
+		args = append(args, *filter.MaxDepth)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		where = append(where, "created_at > ?")
+		args = append(args, filter.CreatedAfter.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if filter.DomainContains != "" {
+		where = append(where, "domain_name LIKE ?")
+		args = append(args, "%"+strings.ToLower(filter.DomainContains)+"%")
+	}
+	if filter.Seed != "" {
+		where = append(where, "seed_node_id = (SELECT node_id FROM nodes WHERE session = ? AND domain_name = ?)")
+		args = append(args, s.session, strings.ToLower(filter.Seed))
+	}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if len(filter.Categories) > 0 {
+		where = append(where, "category IN (?"+strings.Repeat(", ?", len(filter.Categories)-1)+")")
+		for _, category := range filter.Categories {
+			args = append(args, category)
+		}
+	}
+	args = append(args, limit)
+
+	rows, err := s.db.Query(`
+		SELECT `+nodeColumns+`
+		FROM nodes
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY node_id ASC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []*Node
+	for rows.Next() {
+		node, err := scanNode(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan node: %w", err)
+		}
+		nodes = append(nodes, node)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating nodes: %w", err)
+	}
+
+	return nodes, nil
+}
+
+// ListEdges returns up to limit edges with edge_id > afterID, ordered by ID
+func (s *Storage) ListEdges(filter EdgeFilter, afterID, limit int) ([]*Edge, error) {
+	where := []string{
+		"edge_id > ?",
+		liveEdges,
+	}
+	args := []any{afterID, s.session, s.session}
+
+	if filter.NodeID > 0 {
+		switch filter.Direction {
+		case EdgesIn:
+			where = append(where, "to_node_id = ?")
+			args = append(args, filter.NodeID)
+		case EdgesBoth:
+			where = append(where, "(from_node_id = ? OR to_node_id = ?)")
+			args = append(args, filter.NodeID, filter.NodeID)
+		default:
+			where = append(where, "from_node_id = ?")
+			args = append(args, filter.NodeID)
+		}
+	}
+	if filter.MinWeight > 0 {
+		where = append(where, "weight >= ?")
+		args = append(args, filter.MinWeight)
+	}
+	if len(filter.Types) > 0 {
+		where = append(where, "edge_type IN (?"+strings.Repeat(", ?", len(filter.Types)-1)+")")
+		for _, edgeType := range filter.Types {
+			args = append(args, edgeType)
+		}
+	}
+	if filter.MaxNodeID > 0 {
+		where = append(where, "from_node_id <= ?", "to_node_id <= ?")
+		args = append(args, filter.MaxNodeID, filter.MaxNodeID)
+	}
+	if len(filter.Categories) > 0 {
+		inCategories := liveNodeIDs + " AND category IN (?" + strings.Repeat(", ?", len(filter.Categories)-1) + ")"
+		where = append(where, "+from_node_id IN ("+inCategories+")", "+to_node_id IN ("+inCategories+")")
+		for range 2 {
+			args = append(args, s.session)
+			for _, category := range filter.Categories {
+				args = append(args, category)
+			}
+		}
+	}
+	args = append(args, limit)
+
+	rows, err := s.db.Query(`
+		SELECT edge_id, from_node_id, to_node_id, edge_type, weight,
+			COALESCE(anchor_text, ''), COALESCE(rel, '')
+		FROM edges
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY edge_id ASC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list edges: %w", err)
+	}
+	defer rows.Close()
+
+	var edges []*Edge
+	for rows.Next() {
+		var edge Edge
+		if err := rows.Scan(&edge.EdgeID, &edge.FromNodeID, &edge.ToNodeID, &edge.Type, &edge.Weight, &edge.AnchorText, &edge.Rel); err != nil {
+			return nil, fmt.Errorf("failed to scan edge: %w", err)
+		}
+		edges = append(edges, &edge)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating edges: %w", err)
+	}
+
+	return edges, nil
+}
+
+// ShortestPath returns the node IDs on a shortest directed path from fromID
+// to toID, or nil if none exists within maxHops
+// Like ListEdges, it only follows edges between live nodes of the session,
+// so paths never run through tombstoned domains
+func (s *Storage) ShortestPath(fromID, toID, maxHops int) ([]int, error) {
+	var live bool
+	if err := s.db.QueryRow(`SELECT EXISTS (`+liveNodeIDs+` AND node_id = ?)`, s.session, fromID).Scan(&live); err != nil {
+		return nil, fmt.Errorf("failed to check node %d: %w", fromID, err)
+	}
+	if !live {
+		return nil, nil
+	}
+	if fromID == toID {
+		return []int{fromID}, nil
+	}
+
+	parent := map[int]int{fromID: 0}
+	frontier := []int{fromID}
+
+	for hop := 0; hop < maxHops && len(frontier) > 0; hop++ {
+		var next []int
+		for _, current := range frontier {
+			rows, err := s.db.Query(`
+				SELECT e.to_node_id FROM edges e
+				JOIN nodes n ON n.node_id = e.to_node_id
+				WHERE e.from_node_id = ? AND n.session = ? AND n.tombstoned = 0
+			`, current, s.session)
+			if err != nil {
+				return nil, fmt.Errorf("failed to expand node %d: %w", current, err)
+			}
+
+			var targets []int
+			for rows.Next() {
+				var target int
+				if err := rows.Scan(&target); err != nil {
+					rows.Close()
+					return nil, fmt.Errorf("failed to scan edge target: %w", err)
+				}
+				targets = append(targets, target)
+			}
+			rows.Close()
+
+			for _, target := range targets {
+				if _, seen := parent[target]; seen {
+					continue
+				}
+				parent[target] = current
+				if target == toID {
+					return buildPath(parent, fromID, toID), nil
+				}
+				next = append(next, target)
+			}
+		}
+		frontier = next
+	}
+
+	return nil, nil
+}
+
+// buildPath walks parent links back from toID
+func buildPath(parent map[int]int, fromID, toID int) []int {
+	var path []int
+	for id := toID; id != fromID; id = parent[id] {
+		path = append([]int{id}, path...)
+	}
+	return append([]int{fromID}, path...)
+}
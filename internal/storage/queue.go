@@ -0,0 +1,118 @@
+package storage
+
+import "fmt"
+
+// QueueImportStats summarizes a queue import
+type QueueImportStats struct {
+	Queued        int // entries appended to the saved queue
+	NewNodes      int // queued domains that were not yet nodes
+	AlreadyQueued int // entries whose domain and depth were already queued
+}
+
+// queuedAt identifies a saved queue entry for deduplication
+type queuedAt struct {
+	domain string
+	depth  int
+}
+
+// ImportQueue appends entries to the session's saved queue in one
+// transaction, so the next run resumes from them. Domains that are not yet
+// nodes are created uncrawled at the entry's depth; those at depth 0 are
+// attributed as seeds. Existing nodes keep their crawl history. Entries
+// already saved, as queue_state rows or in a binary snapshot, are skipped
+func (s *Storage) ImportQueue(entries []QueueEntry) (QueueImportStats, error) {
+	var stats QueueImportStats
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A binary snapshot is read back whole alongside the rows on resume
+	snapshot, err := loadQueueSnapshot(tx.QueryRow(`SELECT codec, data FROM queue_snapshots WHERE session = ?`, s.session))
+	if err != nil {
+		return stats, err
+	}
+	snapshotted := make(map[queuedAt]bool, len(snapshot))
+	for _, entry := range snapshot {
+		snapshotted[queuedAt{entry.DomainName, entry.Depth}] = true
+	}
+
+	insert, err := tx.Prepare(`
+		INSERT INTO nodes (session, domain_name, crawl_count, last_depth)
+		VALUES (?, ?, 0, ?)
+		ON CONFLICT(session, domain_name) DO NOTHING
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare node insert: %w", err)
+	}
+	defer insert.Close()
+
+	lookup, err := tx.Prepare(`SELECT node_id FROM nodes WHERE session = ? AND domain_name = ?`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare node lookup: %w", err)
+	}
+	defer lookup.Close()
+
+	attribute, err := tx.Prepare(`UPDATE nodes SET seed_node_id = node_id WHERE node_id = ?`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare seed attribution: %w", err)
+	}
+	defer attribute.Close()
+
+	queued, err := tx.Prepare(`SELECT COUNT(*) FROM queue_state WHERE session = ? AND node_id = ? AND depth = ?`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare queue lookup: %w", err)
+	}
+	defer queued.Close()
+
+	enqueue, err := tx.Prepare(`
+		INSERT INTO queue_state (session, node_id, domain_name, depth, priority)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare queue insert: %w", err)
+	}
+	defer enqueue.Close()
+
+	for _, entry := range entries {
+		result, err := insert.Exec(s.session, entry.DomainName, entry.Depth)
+		if err != nil {
+			return stats, fmt.Errorf("failed to insert node %s: %w", entry.DomainName, err)
+		}
+		created, _ := result.RowsAffected()
+
+		var nodeID int
+		if err := lookup.QueryRow(s.session, entry.DomainName).Scan(&nodeID); err != nil {
+			return stats, fmt.Errorf("failed to look up node %s: %w", entry.DomainName, err)
+		}
+		if created > 0 {
+			stats.NewNodes++
+			if entry.Depth == 0 {
+				if _, err := attribute.Exec(nodeID); err != nil {
+					return stats, fmt.Errorf("failed to attribute seed %s: %w", entry.DomainName, err)
+				}
+			}
+		}
+
+		var existing int
+		if err := queued.QueryRow(s.session, nodeID, entry.Depth).Scan(&existing); err != nil {
+			return stats, fmt.Errorf("failed to check queue for %s: %w", entry.DomainName, err)
+		}
+		if existing > 0 || snapshotted[queuedAt{entry.DomainName, entry.Depth}] {
+			stats.AlreadyQueued++
+			continue
+		}
+
+		if _, err := enqueue.Exec(s.session, nodeID, entry.DomainName, entry.Depth, entry.Priority); err != nil {
+			return stats, fmt.Errorf("failed to queue %s: %w", entry.DomainName, err)
+		}
+		stats.Queued++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit queue import: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Frontier persistence codecs
+const (
+	QueueCodecRows   = "rows"   // one queue_state row per entry, editable with SQL
+	QueueCodecBinary = "binary" // one compressed blob per session in queue_snapshots
+)
+
+// queueCodec encodes a whole saved frontier as one queue_snapshots blob
+type queueCodec interface {
+	Encode(w io.Writer, entries []QueueEntry) error
+	Decode(r io.Reader) ([]QueueEntry, error)
+}
+
+// queueCodecs are the snapshot codecs by name; QueueCodecRows writes
+// queue_state instead and has none
+var queueCodecs = map[string]queueCodec{
+	QueueCodecBinary: binaryQueueCodec{},
+}
+
+// binaryQueueMagic starts every binary snapshot, with its format version
+var binaryQueueMagic = []byte("WWQ\x01")
+
+// binaryQueueCodec writes the magic, then a zstd stream of the entry count
+// and, per entry, varints for node ID and depth, the priority's IEEE 754
+// bits, and length-prefixed domain and page URL. Domains share long runs
+// of text, so compression keeps a snapshot a fraction of their size
+type binaryQueueCodec struct{}
+
+// Encode writes entries to w
+func (binaryQueueCodec) Encode(w io.Writer, entries []QueueEntry) error {
+	if _, err := w.Write(binaryQueueMagic); err != nil {
+		return err
+	}
+	zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	if err != nil {
+		return err
+	}
+
+	buf := binary.AppendUvarint(nil, uint64(len(entries)))
+	for _, entry := range entries {
+		buf = binary.AppendVarint(buf, int64(entry.NodeID))
+		buf = binary.AppendVarint(buf, int64(entry.Depth))
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(entry.Priority))
+		buf = binary.AppendUvarint(buf, uint64(len(entry.DomainName)))
+		buf = append(buf, entry.DomainName...)
+		buf = binary.AppendUvarint(buf, uint64(len(entry.URL)))
+		buf = append(buf, entry.URL...)
+
+		// Hand the encoder bounded chunks rather than the whole frontier
+		if len(buf) >= 64*1024 {
+			if _, err := zw.Write(buf); err != nil {
+				zw.Close()
+				return err
+			}
+			buf = buf[:0]
+		}
+	}
+	if _, err := zw.Write(buf); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// Decode reads the entries Encode wrote
+func (binaryQueueCodec) Decode(r io.Reader) ([]QueueEntry, error) {
+	magic := make([]byte, len(binaryQueueMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != string(binaryQueueMagic) {
+		return nil, errors.New("not a binary queue snapshot")
+	}
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	br := bufio.NewReader(zr)
+
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("truncated queue snapshot: %w", err)
+	}
+	// The count is trusted only as far as the entries it announces are read
+	entries := make([]QueueEntry, 0, min(count, 1<<20))
+	for i := uint64(0); i < count; i++ {
+		entry, err := readBinaryQueueEntry(br)
+		if err != nil {
+			return nil, fmt.Errorf("truncated queue snapshot at entry %d: %w", i, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// readBinaryQueueEntry reads one entry of a binary snapshot
+func readBinaryQueueEntry(br *bufio.Reader) (QueueEntry, error) {
+	var entry QueueEntry
+	nodeID, err := binary.ReadVarint(br)
+	if err != nil {
+		return entry, err
+	}
+	depth, err := binary.ReadVarint(br)
+	if err != nil {
+		return entry, err
+	}
+	var bits [8]byte
+	if _, err := io.ReadFull(br, bits[:]); err != nil {
+		return entry, err
+	}
+	domain, err := readBinaryString(br)
+	if err != nil {
+		return entry, err
+	}
+	pageURL, err := readBinaryString(br)
+	if err != nil {
+		return entry, err
+	}
+
+	entry.NodeID = int(nodeID)
+	entry.Depth = int(depth)
+	entry.Priority = math.Float64frombits(binary.LittleEndian.Uint64(bits[:]))
+	entry.DomainName = domain
+	entry.URL = pageURL
+	return entry, nil
+}
+
+// readBinaryString reads a length-prefixed string
+func readBinaryString(br *bufio.Reader) (string, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return "", err
+	}
+	if n > 64*1024 {
+		return "", fmt.Errorf("string of %d bytes", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Replica serves reads, e.g. the HTTP API's, apart from the crawler's
+// connections, so slow analytical queries never hold up a flush
+// It either reads the live database through a read-only connection pool,
+// where WAL mode gives every statement a consistent snapshot, or a
+// periodically refreshed copy of it
+type Replica struct {
+	mu      sync.Mutex // guards current and every snapshot's refs
+	current *snapshot
+
+	// Snapshot mode only; each refresh writes a new file, deleted once it
+	// is replaced and no reader holds it
+	src       *Storage
+	dbPath    string
+	seq       int
+	refreshMu sync.Mutex // serializes refreshes
+}
+
+// snapshot is a storage the replica reads from and the readers holding it
+type snapshot struct {
+	store *Storage
+	path  string // snapshot file; "" for the live database
+	refs  int    // pinned readers, plus one while current
+}
+
+// OpenReplica opens a read-only connection pool on the live database at
+// dbPath, scoped to session
+func OpenReplica(dbPath, session string) (*Replica, error) {
+	store, err := openReadOnly(dbPath, session)
+	if err != nil {
+		return nil, err
+	}
+	return &Replica{current: &snapshot{store: store, refs: 1}}, nil
+}
+
+// OpenSnapshotReplica copies src to a snapshot next to dbPath and reads
+// from it until the next Refresh; snapshots left by an earlier run are
+// deleted
+func OpenSnapshotReplica(src *Storage, dbPath string) (*Replica, error) {
+	if stale, err := filepath.Glob(dbPath + ".snapshot-*"); err == nil {
+		for _, path := range stale {
+			os.Remove(path)
+		}
+	}
+	r := &Replica{src: src, dbPath: dbPath}
+	if err := r.Refresh(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Pin returns the storage to read from and holds it until release is
+// called; callers making several reads should keep the returned storage so
+// they all see the same snapshot
+func (r *Replica) Pin() (store *Storage, release func()) {
+	r.mu.Lock()
+	snap := r.current
+	snap.refs++
+	r.mu.Unlock()
+
+	var once sync.Once
+	return snap.store, func() { once.Do(func() { r.release(snap) }) }
+}
+
+// release drops a hold on snap, closing and deleting it once it is no
+// longer current and no reader holds it
+func (r *Replica) release(snap *snapshot) error {
+	r.mu.Lock()
+	snap.refs--
+	unused := snap.refs == 0
+	r.mu.Unlock()
+
+	if !unused {
+		return nil
+	}
+	err := snap.store.Close()
+	if snap.path != "" {
+		removeDatabase(snap.path)
+	}
+	return err
+}
+
+// Refresh replaces the snapshot with a fresh copy of the live database; a
+// no-op for replicas reading the live database
+// Readers holding the snapshot it replaces keep using it until they
+// release it
+func (r *Replica) Refresh() error {
+	if r.src == nil {
+		return nil
+	}
+	r.refreshMu.Lock()
+	defer r.refreshMu.Unlock()
+
+	path := r.dbPath + ".snapshot-" + strconv.Itoa(r.seq)
+	r.seq++
+	removeDatabase(path)
+
+	if _, err := r.src.Backup(path); err != nil {
+		removeDatabase(path)
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	store, err := openReadOnly(path, r.src.session)
+	if err != nil {
+		removeDatabase(path)
+		return err
+	}
+
+	r.mu.Lock()
+	previous := r.current
+	r.current = &snapshot{store: store, path: path, refs: 1}
+	r.mu.Unlock()
+
+	if previous != nil {
+		r.release(previous)
+	}
+	return nil
+}
+
+// Close releases the current snapshot; it is closed and deleted at once,
+// or when the last reader holding it releases it
+func (r *Replica) Close() error {
+	r.refreshMu.Lock()
+	defer r.refreshMu.Unlock()
+	return r.release(r.current)
+}
+
+// openReadOnly opens dbPath without initializing its schema, with a
+// connection pool that can't write
+func openReadOnly(dbPath, session string) (*Storage, error) {
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?mode=ro&_query_only=1&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open read-only database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to read-only database: %w", err)
+	}
+	return &Storage{db: db, session: session}, nil
+}
+
+// removeDatabase deletes a database file with its WAL and shared memory
+// files
+func removeDatabase(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(path + suffix)
+	}
+}
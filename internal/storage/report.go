@@ -0,0 +1,62 @@
+package storage
+
+import "fmt"
+
+// DomainCount is a domain with the value it is ranked by
+type DomainCount struct {
+	Domain string `json:"domain"`
+	Count  int    `json:"count"`
+}
+
+// TopDomains ranks the live domains of a session's graph; ties go to the
+// alphabetically first domain
+type TopDomains struct {
+	InDegree   []DomainCount `json:"in_degree"`   // distinct linking edges received
+	OutDegree  []DomainCount `json:"out_degree"`  // distinct edges sent
+	EdgeWeight []DomainCount `json:"edge_weight"` // total weight of inbound edges
+}
+
+// TopDomains returns the n highest ranked domains by in-degree, out-degree,
+// and inbound edge weight
+func (s *Storage) TopDomains(n int) (*TopDomains, error) {
+	var top TopDomains
+	var err error
+	if top.InDegree, err = s.topDomains("to_node_id", "COUNT(*)", n); err != nil {
+		return nil, err
+	}
+	if top.OutDegree, err = s.topDomains("from_node_id", "COUNT(*)", n); err != nil {
+		return nil, err
+	}
+	if top.EdgeWeight, err = s.topDomains("to_node_id", "SUM(weight)", n); err != nil {
+		return nil, err
+	}
+	return &top, nil
+}
+
+// topDomains ranks nodes by aggregate over the live edges grouped by the
+// endpoint column
+func (s *Storage) topDomains(endpoint, aggregate string, n int) ([]DomainCount, error) {
+	rows, err := s.db.Query(`
+		SELECT n.domain_name, `+aggregate+` AS value
+		FROM edges e
+		JOIN nodes n ON n.node_id = e.`+endpoint+`
+		WHERE `+liveEdges+`
+		GROUP BY n.node_id
+		ORDER BY value DESC, n.domain_name ASC
+		LIMIT ?
+	`, s.session, s.session, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank domains: %w", err)
+	}
+	defer rows.Close()
+
+	var ranked []DomainCount
+	for rows.Next() {
+		var dc DomainCount
+		if err := rows.Scan(&dc.Domain, &dc.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan ranked domain: %w", err)
+		}
+		ranked = append(ranked, dc)
+	}
+	return ranked, rows.Err()
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Termination reasons recorded in metrics and crawl_sessions
+const (
+	TerminationSignal           = "signal"            // SIGINT or SIGTERM
+	TerminationQueueEmpty       = "queue_empty"       // natural completion
+	TerminationTimeBudget       = "time_budget"       // time_budget_sec elapsed
+	TerminationNodeBudget       = "node_budget"       // node_budget nodes crawled
+	TerminationFailureThreshold = "failure_threshold" // too many recent fetches failed
+	TerminationDiskFull         = "disk_full"         // free space below min_free_disk_mb
+	TerminationPlateau          = "discovery_plateau" // new root domains dried up
+	TerminationAdminStop        = "admin_stop"        // POST /api/admin/stop
+	TerminationForcedExit       = "forced_exit"       // second signal during shutdown
+)
+
+// CrawlRun is one execution of the crawler against a session
+type CrawlRun struct {
+	RunID             string
+	Session           string
+	SeedURL           string
+	MetricsPath       string
+	StartedAt         time.Time
+	FinishedAt        time.Time // zero while running or if the process died
+	TerminationReason string
+}
+
+// StartRun records the start of a crawl run in the current session
+func (s *Storage) StartRun(run CrawlRun) error {
+	_, err := s.db.Exec(`
+		INSERT INTO crawl_sessions (run_id, session, seed_url, metrics_path, started_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, run.RunID, s.session, run.SeedURL, run.MetricsPath, run.StartedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to record run start: %w", err)
+	}
+	return nil
+}
+
+// FinishRun records how and when a crawl run ended
+func (s *Storage) FinishRun(runID, reason string) error {
+	_, err := s.db.Exec(`
+		UPDATE crawl_sessions SET finished_at = ?, termination_reason = ?
+		WHERE run_id = ?
+	`, time.Now().Unix(), reason, runID)
+	if err != nil {
+		return fmt.Errorf("failed to record run finish: %w", err)
+	}
+	return nil
+}
+
+// SaveRunCounters records the session's cumulative metrics counters as of
+// the given run, for the next run to continue from
+func (s *Storage) SaveRunCounters(runID string, counters RunCounters) error {
+	data, err := json.Marshal(counters)
+	if err != nil {
+		return fmt.Errorf("failed to encode run counters: %w", err)
+	}
+	if _, err := s.db.Exec(`UPDATE crawl_sessions SET counters = ? WHERE run_id = ?`, string(data), runID); err != nil {
+		return fmt.Errorf("failed to save run counters: %w", err)
+	}
+	return nil
+}
+
+// PreviousRunCounters returns the cumulative counters saved by the latest
+// other run of the session and that run's ID; nil if no run saved any
+func (s *Storage) PreviousRunCounters(runID string) (string, *RunCounters, error) {
+	var previousID, data string
+	err := s.db.QueryRow(`
+		SELECT run_id, counters FROM crawl_sessions
+		WHERE session = ? AND run_id != ? AND counters IS NOT NULL
+		ORDER BY started_at DESC, run_id DESC
+		LIMIT 1
+	`, s.session, runID).Scan(&previousID, &data)
+	if err == sql.ErrNoRows {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load previous run counters: %w", err)
+	}
+
+	var counters RunCounters
+	if err := json.Unmarshal([]byte(data), &counters); err != nil {
+		return "", nil, fmt.Errorf("failed to decode counters of run %s: %w", previousID, err)
+	}
+	return previousID, &counters, nil
+}
+
+// ListRuns returns the runs of the current session, most recent first
+func (s *Storage) ListRuns() ([]CrawlRun, error) {
+	rows, err := s.db.Query(`
+		SELECT run_id, session, COALESCE(seed_url, ''), COALESCE(metrics_path, ''),
+			started_at, finished_at, COALESCE(termination_reason, '')
+		FROM crawl_sessions
+		WHERE session = ?
+		ORDER BY started_at DESC, run_id DESC
+	`, s.session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []CrawlRun
+	for rows.Next() {
+		var run CrawlRun
+		var startedAt int64
+		var finishedAt sql.NullInt64
+		if err := rows.Scan(&run.RunID, &run.Session, &run.SeedURL, &run.MetricsPath,
+			&startedAt, &finishedAt, &run.TerminationReason); err != nil {
+			return nil, fmt.Errorf("failed to scan run: %w", err)
+		}
+		run.StartedAt = time.Unix(startedAt, 0)
+		if finishedAt.Valid {
+			run.FinishedAt = time.Unix(finishedAt.Int64, 0)
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating runs: %w", err)
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// SchemeSupport is what is known about the schemes a domain serves; nil
+// fields have not been observed yet
+type SchemeSupport struct {
+	Domain      string
+	HTTPS       *bool // answered over https://
+	HTTP        *bool // answered over http://
+	HTTPToHTTPS *bool // http:// answered with a redirect to https://
+	CheckedAt   time.Time
+}
+
+// HTTPSAdoption classifies the live domains of a session by the schemes
+// they were observed to serve
+type HTTPSAdoption struct {
+	Domains       int `json:"domains"`             // live domains with any observation
+	Redirecting   int `json:"redirecting"`         // HTTPS, with HTTP redirecting to it
+	Both          int `json:"both"`                // HTTPS and HTTP serving content
+	HTTPSOnly     int `json:"https_only"`          // HTTPS, HTTP refused
+	HTTPSUnprobed int `json:"https_no_http_probe"` // HTTPS, HTTP never probed
+	HTTPOnly      int `json:"http_only"`           // HTTP, HTTPS refused
+	Neither       int `json:"neither"`             // refused on every scheme tried
+}
+
+// SaveSchemeSupport records scheme observations for the current session;
+// fields left nil keep their stored value
+func (s *Storage) SaveSchemeSupport(records []SchemeSupport) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO scheme_support (session, domain, https, http, http_to_https, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(session, domain) DO UPDATE SET
+			https = COALESCE(excluded.https, https),
+			http = COALESCE(excluded.http, http),
+			http_to_https = COALESCE(excluded.http_to_https, http_to_https),
+			checked_at = excluded.checked_at
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare scheme support upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, r := range records {
+		if _, err := stmt.Exec(s.session, r.Domain, r.HTTPS, r.HTTP, r.HTTPToHTTPS,
+			r.CheckedAt.Unix()); err != nil {
+			return fmt.Errorf("failed to save scheme support for %s: %w", r.Domain, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit scheme support: %w", err)
+	}
+	return nil
+}
+
+// HTTPSAdoption reports how the session's live domains split by scheme
+func (s *Storage) HTTPSAdoption() (*HTTPSAdoption, error) {
+	var a HTTPSAdoption
+	err := s.db.QueryRow(`
+		SELECT COUNT(*),
+			COALESCE(SUM(https = 1 AND http_to_https = 1), 0),
+			COALESCE(SUM(https = 1 AND http = 1 AND COALESCE(http_to_https, 0) = 0), 0),
+			COALESCE(SUM(https = 1 AND http = 0), 0),
+			COALESCE(SUM(https = 1 AND http IS NULL), 0),
+			COALESCE(SUM(COALESCE(https, 0) = 0 AND http = 1), 0),
+			COALESCE(SUM(COALESCE(https, 0) = 0 AND COALESCE(http, 0) = 0), 0)
+		FROM scheme_support
+		WHERE session = ?
+		  AND domain IN (SELECT domain_name FROM nodes WHERE session = ? AND tombstoned = 0)
+	`, s.session, s.session).Scan(&a.Domains, &a.Redirecting, &a.Both, &a.HTTPSOnly,
+		&a.HTTPSUnprobed, &a.HTTPOnly, &a.Neither)
+	if err != nil {
+		return nil, fmt.Errorf("failed to report HTTPS adoption: %w", err)
+	}
+	return &a, nil
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// initSearchIndex creates the FTS5 index over node names, titles and descriptions
+// FTS5 requires building with `-tags sqlite_fts5`; without it search falls
+// back to LIKE matching
+func (s *Storage) initSearchIndex() error {
+	// Triggers are dropped whenever a build without FTS5 opens the database,
+	// so missing triggers mean the index may be stale
+	var triggers int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'nodes_fts_%'
+	`).Scan(&triggers)
+	if err != nil {
+		return fmt.Errorf("failed to check search index: %w", err)
+	}
+
+	err = s.createSearchTable()
+	if err == nil {
+		// Indexes from before titles were stored separately lack their columns
+		var hasTitle int
+		err = s.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('nodes_fts') WHERE name = 'title'`).Scan(&hasTitle)
+		if err == nil && hasTitle == 0 {
+			_, err = s.db.Exec(`
+				DROP TRIGGER IF EXISTS nodes_fts_ai;
+				DROP TRIGGER IF EXISTS nodes_fts_ad;
+				DROP TRIGGER IF EXISTS nodes_fts_au;
+				DROP TABLE nodes_fts;
+			`)
+			if err == nil {
+				triggers = 0
+				err = s.createSearchTable()
+			}
+		}
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			// Driver built without FTS5: make sure node writes don't hit the index
+			_, err = s.db.Exec(`
+				DROP TRIGGER IF EXISTS nodes_fts_ai;
+				DROP TRIGGER IF EXISTS nodes_fts_ad;
+				DROP TRIGGER IF EXISTS nodes_fts_au;
+			`)
+			return err
+		}
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TRIGGER IF NOT EXISTS nodes_fts_ai AFTER INSERT ON nodes BEGIN
+			INSERT INTO nodes_fts(rowid, domain_name, title, meta_description, description)
+			VALUES (new.node_id, new.domain_name, new.title, new.meta_description, new.description);
+		END;
+
+		CREATE TRIGGER IF NOT EXISTS nodes_fts_ad AFTER DELETE ON nodes BEGIN
+			INSERT INTO nodes_fts(nodes_fts, rowid, domain_name, title, meta_description, description)
+			VALUES ('delete', old.node_id, old.domain_name, old.title, old.meta_description, old.description);
+		END;
+
+		CREATE TRIGGER IF NOT EXISTS nodes_fts_au AFTER UPDATE OF domain_name, title, meta_description, description ON nodes BEGIN
+			INSERT INTO nodes_fts(nodes_fts, rowid, domain_name, title, meta_description, description)
+			VALUES ('delete', old.node_id, old.domain_name, old.title, old.meta_description, old.description);
+			INSERT INTO nodes_fts(rowid, domain_name, title, meta_description, description)
+			VALUES (new.node_id, new.domain_name, new.title, new.meta_description, new.description);
+		END;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create search index triggers: %w", err)
+	}
+
+	// Index nodes written while the triggers were absent
+	if triggers < 3 {
+		if _, err := s.db.Exec(`INSERT INTO nodes_fts(nodes_fts) VALUES ('rebuild')`); err != nil {
+			return fmt.Errorf("failed to build search index: %w", err)
+		}
+	}
+
+	s.hasFTS = true
+	return nil
+}
+
+// createSearchTable creates the FTS5 table if missing and checks it loads
+func (s *Storage) createSearchTable() error {
+	_, err := s.db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS nodes_fts USING fts5(
+		domain_name, title, meta_description, description,
+		content='nodes', content_rowid='node_id'
+	)`)
+	if err != nil {
+		return err
+	}
+	// An existing table is not loaded by CREATE ... IF NOT EXISTS
+	_, err = s.db.Exec(`SELECT rowid FROM nodes_fts LIMIT 0`)
+	return err
+}
+
+// SearchNodes returns nodes whose domain, title or description match the query,
+// ranked by text relevance boosted by graph degree (in + out edges)
+func (s *Storage) SearchNodes(query string, limit int) ([]SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var results []SearchResult
+	var err error
+	if s.hasFTS {
+		results, err = s.searchFTS(terms, limit*5)
+	} else {
+		results, err = s.searchLike(terms)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Well-connected nodes are more likely to be the site the user remembers
+	for i := range results {
+		results[i].Score *= 1 + math.Log1p(float64(results[i].Degree))
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Degree > results[j].Degree
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// searchFTS matches all terms as prefixes using the FTS5 index
+func (s *Storage) searchFTS(terms []string, limit int) ([]SearchResult, error) {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + term + `"*`
+	}
+
+	// bm25() is negative, lower is better
+	return s.querySearch(`
+		SELECT `+searchNodeColumns+`,
+			-bm25(nodes_fts),
+			(SELECT COUNT(*) FROM edges e WHERE e.from_node_id = n.node_id OR e.to_node_id = n.node_id)
+		FROM nodes_fts
+		JOIN nodes n ON n.node_id = nodes_fts.rowid
+		WHERE nodes_fts MATCH ? AND n.session = ? AND n.tombstoned = 0
+		ORDER BY bm25(nodes_fts)
+		LIMIT ?
+	`, strings.Join(quoted, " "), s.session, limit)
+}
+
+// searchLike matches all terms as substrings; every hit scores the same so
+// ranking falls back to degree alone
+func (s *Storage) searchLike(terms []string) ([]SearchResult, error) {
+	where := []string{"n.session = ?", "n.tombstoned = 0"}
+	args := []any{s.session}
+	for _, term := range terms {
+		where = append(where, "(n.domain_name LIKE ? OR n.title LIKE ? OR n.meta_description LIKE ? OR n.description LIKE ?)")
+		like := "%" + term + "%"
+		args = append(args, like, like, like, like)
+	}
+
+	return s.querySearch(`
+		SELECT `+searchNodeColumns+`,
+			1.0,
+			(SELECT COUNT(*) FROM edges e WHERE e.from_node_id = n.node_id OR e.to_node_id = n.node_id)
+		FROM nodes n
+		WHERE `+strings.Join(where, " AND "), args...)
+}
+
+// searchNodeColumns is nodeColumns qualified with the nodes alias n
+const searchNodeColumns = `n.node_id, n.domain_name, COALESCE(n.title, ''), COALESCE(n.meta_description, ''),
+	COALESCE(NULLIF(n.title, ''), NULLIF(n.meta_description, ''), n.description, ''),
+	n.crawl_count, n.last_depth, n.created_at`
+
+// querySearch runs a search query and scans the common result columns
+func (s *Storage) querySearch(query string, args ...any) ([]SearchResult, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.Node.NodeID, &r.Node.DomainName, &r.Node.Title, &r.Node.MetaDescription,
+			&r.Node.Description, &r.Node.CrawlCount, &r.Node.LastDepth, &r.Node.CreatedAt, &r.Score, &r.Degree); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		results = append(results, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search results: %w", err)
+	}
+
+	return results, nil
+}
+
+// searchTerms splits free text into lowercase alphanumeric terms, dropping
+// punctuation so user input can never break FTS query syntax
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
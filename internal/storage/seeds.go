@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// SeedImportStats summarizes a bulk seed import
+type SeedImportStats struct {
+	Inserted int // new nodes created at depth 0
+	Existing int // domains already in the session, left untouched
+	Queued   int // new nodes appended to a saved crawl queue
+}
+
+// ImportSeeds inserts the domains as uncrawled depth-0 seed nodes in one transaction
+// Existing nodes keep their depth, crawl count and seed attribution. When the
+// session has a saved queue the new nodes are appended to it, since resume
+// then reads the queue instead of scanning for resumable nodes
+func (s *Storage) ImportSeeds(domains []string) (SeedImportStats, error) {
+	var stats SeedImportStats
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var saved int
+	err = tx.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM queue_state WHERE session = ?) +
+			(SELECT COALESCE(SUM(entries), 0) FROM queue_snapshots WHERE session = ?)
+	`, s.session, s.session).Scan(&saved)
+	if err != nil {
+		return stats, fmt.Errorf("failed to count queue entries: %w", err)
+	}
+
+	insert, err := tx.Prepare(`
+		INSERT INTO nodes (session, domain_name, crawl_count, last_depth)
+		VALUES (?, ?, 0, 0)
+		ON CONFLICT(session, domain_name) DO NOTHING
+		RETURNING node_id
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare seed insert: %w", err)
+	}
+	defer insert.Close()
+
+	attribute, err := tx.Prepare(`UPDATE nodes SET seed_node_id = node_id WHERE node_id = ?`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare seed attribution: %w", err)
+	}
+	defer attribute.Close()
+
+	enqueue, err := tx.Prepare(`INSERT INTO queue_state (session, node_id, domain_name, depth) VALUES (?, ?, ?, 0)`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to prepare queue insert: %w", err)
+	}
+	defer enqueue.Close()
+
+	for _, domain := range domains {
+		var nodeID int
+		err := insert.QueryRow(s.session, domain).Scan(&nodeID)
+		if err == sql.ErrNoRows {
+			stats.Existing++
+			continue
+		}
+		if err != nil {
+			return stats, fmt.Errorf("failed to insert seed %s: %w", domain, err)
+		}
+		stats.Inserted++
+
+		if _, err := attribute.Exec(nodeID); err != nil {
+			return stats, fmt.Errorf("failed to attribute seed %s: %w", domain, err)
+		}
+
+		if saved > 0 {
+			if _, err := enqueue.Exec(s.session, nodeID, domain); err != nil {
+				return stats, fmt.Errorf("failed to queue seed %s: %w", domain, err)
+			}
+			stats.Queued++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit seeds: %w", err)
+	}
+
+	return stats, nil
+}
+
+// SeedSummary is a seed with the number of nodes it was first to reach
+type SeedSummary struct {
+	Node       *Node
+	Attributed int // nodes first reached through the seed, excluding itself
+}
+
+// SetOrigin records the parent, seed and source page URL that first led to
+// a node; parentID is 0 and sourceURL "" for seeds. Nodes that are already
+// attributed keep their origin
+func (s *Storage) SetOrigin(nodeID, parentID, seedID int, sourceURL string) error {
+	_, err := s.db.Exec(`
+		UPDATE nodes SET parent_node_id = NULLIF(?, 0), seed_node_id = ?, source_url = NULLIF(?, '')
+		WHERE node_id = ? AND seed_node_id IS NULL
+	`, parentID, seedID, sourceURL, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to set node origin: %w", err)
+	}
+	return nil
+}
+
+// ListSeeds returns the session's seeds, most productive first
+func (s *Storage) ListSeeds() ([]SeedSummary, error) {
+	counts := make(map[int]int)
+	rows, err := s.db.Query(`
+		SELECT seed_node_id, COUNT(*)
+		FROM nodes
+		WHERE session = ? AND tombstoned = 0 AND seed_node_id IS NOT NULL AND seed_node_id != node_id
+		GROUP BY seed_node_id
+	`, s.session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attributed nodes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var seedID, count int
+		if err := rows.Scan(&seedID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan attributed nodes: %w", err)
+		}
+		counts[seedID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attributed nodes: %w", err)
+	}
+
+	seedRows, err := s.db.Query(`
+		SELECT `+nodeColumns+`
+		FROM nodes
+		WHERE session = ? AND seed_node_id = node_id AND tombstoned = 0
+	`, s.session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list seeds: %w", err)
+	}
+	defer seedRows.Close()
+
+	var seeds []SeedSummary
+	for seedRows.Next() {
+		node, err := scanNode(seedRows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan seed: %w", err)
+		}
+		seeds = append(seeds, SeedSummary{Node: node, Attributed: counts[node.NodeID]})
+	}
+	if err := seedRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating seeds: %w", err)
+	}
+
+	sort.Slice(seeds, func(i, j int) bool {
+		if seeds[i].Attributed != seeds[j].Attributed {
+			return seeds[i].Attributed > seeds[j].Attributed
+		}
+		return seeds[i].Node.NodeID < seeds[j].Node.NodeID
+	})
+	return seeds, nil
+}
@@ -1,19 +1,24 @@
 package storage
 
 import (
+	"bytes"
 	"database/sql"
 	"fmt"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // Storage handles all database operations
 type Storage struct {
-	db *sql.DB
+	db      *sql.DB
+	session string // crawl session that nodes and queue state are scoped to
+	hasFTS  bool   // FTS5 search index available
 }
 
 // NewStorage creates a new Storage instance, opening/creating the DB and initializing schema
-func NewStorage(dbPath string) (*Storage, error) {
+// All node and queue operations are scoped to the given crawl session
+func NewStorage(dbPath, session string) (*Storage, error) {
 	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -24,7 +29,7 @@ func NewStorage(dbPath string) (*Storage, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	storage := &Storage{db: db}
+	storage := &Storage{db: db, session: session}
 
 	// Initialize schema
 	if err := storage.initSchema(); err != nil {
@@ -40,35 +45,126 @@ func (s *Storage) initSchema() error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS nodes (
 		node_id INTEGER PRIMARY KEY AUTOINCREMENT,
-		domain_name TEXT UNIQUE NOT NULL,
+		session TEXT NOT NULL DEFAULT 'default',
+		domain_name TEXT NOT NULL,
 		description TEXT,
+		title TEXT,
+		meta_description TEXT,
+		links_total INTEGER,
+		links_internal INTEGER,
+		links_external INTEGER,
+		external_domains INTEGER,
 		crawl_count INTEGER DEFAULT 0,
 		last_depth INTEGER DEFAULT 0,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		tombstoned INTEGER DEFAULT 0,
+		status TEXT NOT NULL DEFAULT 'pending',
+		parent_node_id INTEGER,
+		seed_node_id INTEGER,
+		source_url TEXT,
+		last_fetched_at INTEGER,
+		language TEXT,
+		reputation TEXT,
+		category TEXT,
+		http_status INTEGER,
+		response_time_ms INTEGER,
+		content_length INTEGER,
+		last_crawled_at INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(session, domain_name)
 	);
 
 	CREATE TABLE IF NOT EXISTS edges (
 		edge_id INTEGER PRIMARY KEY AUTOINCREMENT,
 		from_node_id INTEGER NOT NULL,
 		to_node_id INTEGER NOT NULL,
+		edge_type TEXT NOT NULL DEFAULT 'link',
 		weight INTEGER DEFAULT 1,
+		weighted_at INTEGER,
+		anchor_text TEXT,
+		rel TEXT,
 		FOREIGN KEY (from_node_id) REFERENCES nodes(node_id),
 		FOREIGN KEY (to_node_id) REFERENCES nodes(node_id),
-		UNIQUE(from_node_id, to_node_id)
+		UNIQUE(from_node_id, to_node_id, edge_type)
 	);
 
 	CREATE TABLE IF NOT EXISTS queue_state (
 		entry_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session TEXT NOT NULL DEFAULT 'default',
 		node_id INTEGER NOT NULL,
 		domain_name TEXT NOT NULL,
+		url TEXT,
 		depth INTEGER NOT NULL,
+		priority REAL NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS queue_snapshots (
+		session TEXT PRIMARY KEY,
+		codec TEXT NOT NULL,
+		entries INTEGER NOT NULL,
+		data BLOB NOT NULL,
+		saved_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS blocked_domains (
+		domain TEXT PRIMARY KEY,
+		reason TEXT,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
 
+	CREATE TABLE IF NOT EXISTS http_cache (
+		url TEXT PRIMARY KEY,
+		etag TEXT,
+		last_modified TEXT,
+		fresh_until INTEGER DEFAULT 0,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS crawl_sessions (
+		run_id TEXT PRIMARY KEY,
+		session TEXT NOT NULL DEFAULT 'default',
+		seed_url TEXT,
+		metrics_path TEXT,
+		started_at INTEGER NOT NULL,
+		finished_at INTEGER,
+		termination_reason TEXT,
+		counters TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS subdomain_limits (
+		session TEXT NOT NULL,
+		root_domain TEXT NOT NULL,
+		domain TEXT NOT NULL,
+		PRIMARY KEY (session, domain)
+	);
+
+	CREATE TABLE IF NOT EXISTS errors (
+		error_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session TEXT NOT NULL DEFAULT 'default',
+		domain TEXT NOT NULL,
+		url TEXT NOT NULL,
+		category TEXT NOT NULL,
+		status_code INTEGER DEFAULT 0,
+		message TEXT,
+		attempt INTEGER DEFAULT 1,
+		occurred_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS scheme_support (
+		session TEXT NOT NULL,
+		domain TEXT NOT NULL,
+		https INTEGER,
+		http INTEGER,
+		http_to_https INTEGER,
+		checked_at INTEGER NOT NULL,
+		PRIMARY KEY (session, domain)
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_nodes_domain ON nodes(domain_name);
 	CREATE INDEX IF NOT EXISTS idx_edges_from ON edges(from_node_id);
 	CREATE INDEX IF NOT EXISTS idx_edges_to ON edges(to_node_id);
 	CREATE INDEX IF NOT EXISTS idx_queue_state_node ON queue_state(node_id);
+	CREATE INDEX IF NOT EXISTS idx_errors_session_domain ON errors(session, domain);
 	`
 
 	_, err := s.db.Exec(schema)
@@ -83,26 +179,130 @@ func (s *Storage) initSchema() error {
 	// Ignore error if column already exists
 	// SQLite will return "duplicate column name" error which we can safely ignore
 
-	return nil
+	// Migration: Add tombstoned column for manually blocked nodes
+	s.db.Exec(`ALTER TABLE nodes ADD COLUMN tombstoned INTEGER DEFAULT 0;`)
+
+	// Migration: Add session column to queue state
+	s.db.Exec(`ALTER TABLE queue_state ADD COLUMN session TEXT NOT NULL DEFAULT 'default';`)
+
+	// Migration: Scope node uniqueness to sessions
+	migrated, err := s.migrateNodeSessions()
+	if err != nil {
+		return fmt.Errorf("failed to migrate nodes to sessions: %w", err)
+	}
+	if migrated {
+		// Rebuilding the table dropped its indices
+		if _, err := s.db.Exec(schema); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Store title and meta description separately; the old
+	// description column is kept as a display fallback
+	s.db.Exec(`ALTER TABLE nodes ADD COLUMN title TEXT;`)
+	s.db.Exec(`ALTER TABLE nodes ADD COLUMN meta_description TEXT;`)
+
+	// Migration: Outbound link statistics; NULL until the node's page is analyzed
+	for _, column := range []string{"links_total", "links_internal", "links_external", "external_domains"} {
+		s.db.Exec(`ALTER TABLE nodes ADD COLUMN ` + column + ` INTEGER;`)
+	}
+
+	// Migration: Seed attribution; NULL for nodes discovered before it existed
+	s.db.Exec(`ALTER TABLE nodes ADD COLUMN parent_node_id INTEGER;`)
+	s.db.Exec(`ALTER TABLE nodes ADD COLUMN seed_node_id INTEGER;`)
+	s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_nodes_seed ON nodes(seed_node_id);`)
+
+	// Migration: URL of the page a node was first discovered on
+	s.db.Exec(`ALTER TABLE nodes ADD COLUMN source_url TEXT;`)
+
+	// Migration: Crawl status; existing nodes are classified from their
+	// crawl counts and tombstones, as earlier failures weren't recorded
+	if _, err := s.db.Exec(`ALTER TABLE nodes ADD COLUMN status TEXT NOT NULL DEFAULT 'pending';`); err == nil {
+		s.db.Exec(`UPDATE nodes SET status = 'crawled' WHERE crawl_count > 0;`)
+		s.db.Exec(`UPDATE nodes SET status = 'blocked' WHERE tombstoned = 1;`)
+	}
+
+	// Migration: Cumulative metrics counters per run, for resumed sessions
+	s.db.Exec(`ALTER TABLE crawl_sessions ADD COLUMN counters TEXT;`)
+
+	// Migration: Time of each node's latest fetch, for the re-fetch guard
+	s.db.Exec(`ALTER TABLE nodes ADD COLUMN last_fetched_at INTEGER;`)
+
+	// Migration: Frontier priority of saved queue entries
+	s.db.Exec(`ALTER TABLE queue_state ADD COLUMN priority REAL NOT NULL DEFAULT 0;`)
+
+	// Migration: Inner pages queued in url crawl mode
+	s.db.Exec(`ALTER TABLE queue_state ADD COLUMN url TEXT;`)
+
+	// Migration: Domain reputation verdicts; NULL until looked up
+	s.db.Exec(`ALTER TABLE nodes ADD COLUMN reputation TEXT;`)
+
+	// Migration: Declared page languages, for language quotas
+	s.db.Exec(`ALTER TABLE nodes ADD COLUMN language TEXT;`)
+
+	// Migration: Content-based node categories; NULL until classified
+	s.db.Exec(`ALTER TABLE nodes ADD COLUMN category TEXT;`)
+
+	// Migration: Own pages listed in a node's sitemaps; NULL until read
+	s.db.Exec(`ALTER TABLE nodes ADD COLUMN sitemap_pages INTEGER;`)
+
+	// Migration: Latest front page response; NULL until the node is fetched
+	for _, column := range []string{"http_status", "response_time_ms", "content_length", "last_crawled_at"} {
+		s.db.Exec(`ALTER TABLE nodes ADD COLUMN ` + column + ` INTEGER;`)
+	}
+
+	// Migration: Typed edges, unique per (from, to, type)
+	migrated, err = s.migrateEdgeTypes()
+	if err != nil {
+		return fmt.Errorf("failed to migrate edge types: %w", err)
+	}
+	if migrated {
+		if _, err := s.db.Exec(schema); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Time each edge was last seen or decayed, for
+	// edge_decay_per_week; NULL until the first decay
+	s.db.Exec(`ALTER TABLE edges ADD COLUMN weighted_at INTEGER;`)
+
+	// Migration: Anchor text and rel of the link that created a link edge
+	s.db.Exec(`ALTER TABLE edges ADD COLUMN anchor_text TEXT;`)
+	s.db.Exec(`ALTER TABLE edges ADD COLUMN rel TEXT;`)
+
+	return s.initSearchIndex()
 }
 
-// UpsertNode inserts a new node or updates description if domain exists
+// UpsertNode inserts a new node or updates title and meta description if domain exists
 // Returns the node_id of the inserted/existing node
-func (s *Storage) UpsertNode(domain, description string) (int, error) {
-	return s.UpsertNodeWithDepth(domain, description, 0)
+func (s *Storage) UpsertNode(domain, title, metaDescription string) (int, error) {
+	return s.UpsertNodeWithDepth(domain, title, metaDescription, nil, 0)
 }
 
-// UpsertNodeWithDepth inserts a new node or updates description and depth if domain exists
+// UpsertNodeWithDepth inserts a new node or updates title, meta description,
+// link stats and depth if domain exists
+// Empty title or meta description and nil link stats keep the stored value
 // Returns the node_id of the inserted/existing node
-func (s *Storage) UpsertNodeWithDepth(domain, description string, depth int) (int, error) {
+func (s *Storage) UpsertNodeWithDepth(domain, title, metaDescription string, links *LinkStats, depth int) (int, error) {
+	var total, internal, external, externalDomains any
+	if links != nil {
+		total, internal, external, externalDomains = links.Total, links.Internal, links.External, links.ExternalDomains
+	}
+
 	// Insert or update
 	_, err := s.db.Exec(`
-		INSERT INTO nodes (domain_name, description, crawl_count, last_depth)
-		VALUES (?, ?, 0, ?)
-		ON CONFLICT(domain_name) DO UPDATE SET
-			description = COALESCE(EXCLUDED.description, nodes.description),
+		INSERT INTO nodes (session, domain_name, title, meta_description,
+			links_total, links_internal, links_external, external_domains, crawl_count, last_depth)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, 0, ?)
+		ON CONFLICT(session, domain_name) DO UPDATE SET
+			title = COALESCE(EXCLUDED.title, nodes.title),
+			meta_description = COALESCE(EXCLUDED.meta_description, nodes.meta_description),
+			links_total = COALESCE(EXCLUDED.links_total, nodes.links_total),
+			links_internal = COALESCE(EXCLUDED.links_internal, nodes.links_internal),
+			links_external = COALESCE(EXCLUDED.links_external, nodes.links_external),
+			external_domains = COALESCE(EXCLUDED.external_domains, nodes.external_domains),
 			last_depth = EXCLUDED.last_depth
-	`, domain, description, depth)
+	`, s.session, domain, title, metaDescription, total, internal, external, externalDomains, depth)
 
 	if err != nil {
 		return 0, fmt.Errorf("failed to upsert node: %w", err)
@@ -110,7 +310,7 @@ func (s *Storage) UpsertNodeWithDepth(domain, description string, depth int) (in
 
 	// Get the node_id
 	var nodeID int
-	err = s.db.QueryRow("SELECT node_id FROM nodes WHERE domain_name = ?", domain).Scan(&nodeID)
+	err = s.db.QueryRow("SELECT node_id FROM nodes WHERE session = ? AND domain_name = ?", s.session, domain).Scan(&nodeID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to retrieve node_id: %w", err)
 	}
@@ -127,6 +327,42 @@ func (s *Storage) IncrementCrawlCount(nodeID int) error {
 	return nil
 }
 
+// SetNodeStatus records the outcome of a node's last crawl attempt
+func (s *Storage) SetNodeStatus(nodeID int, status string) error {
+	_, err := s.db.Exec("UPDATE nodes SET status = ? WHERE node_id = ?", status, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to set node status: %w", err)
+	}
+	return nil
+}
+
+// SetNodeLanguage records the language declared by a node's front page
+func (s *Storage) SetNodeLanguage(nodeID int, language string) error {
+	_, err := s.db.Exec("UPDATE nodes SET language = ? WHERE node_id = ?", language, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to set node language: %w", err)
+	}
+	return nil
+}
+
+// SetNodeReputation records the verdict of a node's reputation lookup
+func (s *Storage) SetNodeReputation(nodeID int, verdict string) error {
+	_, err := s.db.Exec("UPDATE nodes SET reputation = ? WHERE node_id = ?", verdict, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to set node reputation: %w", err)
+	}
+	return nil
+}
+
+// SetNodeCategory records the category of a node's front page
+func (s *Storage) SetNodeCategory(nodeID int, category string) error {
+	_, err := s.db.Exec("UPDATE nodes SET category = ? WHERE node_id = ?", category, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to set node category: %w", err)
+	}
+	return nil
+}
+
 // ResetCrawlCount resets the crawl_count to 0 for a node
 func (s *Storage) ResetCrawlCount(nodeID int) error {
 	_, err := s.db.Exec("UPDATE nodes SET crawl_count = 0 WHERE node_id = ?", nodeID)
@@ -138,12 +374,11 @@ func (s *Storage) ResetCrawlCount(nodeID int) error {
 
 // GetNode retrieves a node by domain name, returns nil if not found
 func (s *Storage) GetNode(domain string) (*Node, error) {
-	var node Node
-	err := s.db.QueryRow(`
-		SELECT node_id, domain_name, description, crawl_count, created_at
+	node, err := scanNode(s.db.QueryRow(`
+		SELECT `+nodeColumns+`
 		FROM nodes
-		WHERE domain_name = ?
-	`, domain).Scan(&node.NodeID, &node.DomainName, &node.Description, &node.CrawlCount, &node.CreatedAt)
+		WHERE session = ? AND domain_name = ?
+	`, s.session, domain))
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -152,37 +387,28 @@ func (s *Storage) GetNode(domain string) (*Node, error) {
 		return nil, fmt.Errorf("failed to get node: %w", err)
 	}
 
-	return &node, nil
+	return node, nil
 }
 
 // GetNodeWithDepth retrieves a node with depth info by domain name, returns nil if not found
 func (s *Storage) GetNodeWithDepth(domain string) (*Node, int, error) {
-	var node Node
-	var lastDepth int
-	err := s.db.QueryRow(`
-		SELECT node_id, domain_name, description, crawl_count, created_at, last_depth
-		FROM nodes
-		WHERE domain_name = ?
-	`, domain).Scan(&node.NodeID, &node.DomainName, &node.Description, &node.CrawlCount, &node.CreatedAt, &lastDepth)
-
-	if err == sql.ErrNoRows {
-		return nil, 0, nil
-	}
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get node: %w", err)
+	node, err := s.GetNode(domain)
+	if err != nil || node == nil {
+		return nil, 0, err
 	}
-
-	return &node, lastDepth, nil
+	return node, node.LastDepth, nil
 }
 
-// UpsertEdge inserts a new edge or increments weight if it exists
-func (s *Storage) UpsertEdge(fromID, toID int) error {
+// UpsertEdge inserts a new edge of the given type and weight or adds the
+// weight to the existing edge of that type, which keeps aging from when it
+// was first stored or last decayed
+func (s *Storage) UpsertEdge(fromID, toID int, edgeType string, weight int) error {
 	_, err := s.db.Exec(`
-		INSERT INTO edges (from_node_id, to_node_id, weight)
-		VALUES (?, ?, 1)
-		ON CONFLICT(from_node_id, to_node_id) DO UPDATE SET
-			weight = weight + 1
-	`, fromID, toID)
+		INSERT INTO edges (from_node_id, to_node_id, edge_type, weight, weighted_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(from_node_id, to_node_id, edge_type) DO UPDATE SET
+			weight = weight + EXCLUDED.weight
+	`, fromID, toID, edgeType, weight, time.Now().Unix())
 
 	if err != nil {
 		return fmt.Errorf("failed to upsert edge: %w", err)
@@ -190,14 +416,19 @@ func (s *Storage) UpsertEdge(fromID, toID int) error {
 	return nil
 }
 
-// LoadResumableNodes returns all nodes with crawl_count < maxCrawls
+// LoadResumableNodes returns all nodes with crawl_count < maxCrawls that
+// are worth another attempt: never-attempted nodes first, then transient
+// failures, then re-crawls of crawled nodes
+// Permanent failures, blocked and low-reputation nodes are never resumed
 func (s *Storage) LoadResumableNodes(maxCrawls int) ([]*Node, error) {
 	rows, err := s.db.Query(`
-		SELECT node_id, domain_name, description, crawl_count, created_at, last_depth
+		SELECT `+nodeColumns+`
 		FROM nodes
-		WHERE crawl_count < ?
-		ORDER BY created_at ASC
-	`, maxCrawls)
+		WHERE session = ? AND crawl_count < ? AND tombstoned = 0
+			AND status NOT IN ('failed_permanent', 'blocked') AND COALESCE(reputation, '') != 'low'
+		ORDER BY CASE status WHEN 'pending' THEN 0 WHEN 'failed_transient' THEN 1 ELSE 2 END,
+			created_at ASC
+	`, s.session, maxCrawls)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to load resumable nodes: %w", err)
@@ -206,11 +437,11 @@ func (s *Storage) LoadResumableNodes(maxCrawls int) ([]*Node, error) {
 
 	var nodes []*Node
 	for rows.Next() {
-		var node Node
-		if err := rows.Scan(&node.NodeID, &node.DomainName, &node.Description, &node.CrawlCount, &node.CreatedAt, &node.LastDepth); err != nil {
+		node, err := scanNode(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan node: %w", err)
 		}
-		nodes = append(nodes, &node)
+		nodes = append(nodes, node)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -225,36 +456,100 @@ func (s *Storage) Close() error {
 	return s.db.Close()
 }
 
-// SaveQueueEntry saves a queue entry to persist crawl state
-func (s *Storage) SaveQueueEntry(nodeID int, domain string, depth int) error {
-	_, err := s.db.Exec(`
-		INSERT INTO queue_state (node_id, domain_name, depth)
-		VALUES (?, ?, ?)
-	`, nodeID, domain, depth)
+// CheckWritable verifies the database accepts writes by creating a table
+// in a transaction that is rolled back
+func (s *Storage) CheckWritable() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`CREATE TABLE preflight_check (id INTEGER)`)
+	return err
+}
 
+// SaveQueue replaces the session's saved queue with entries in one
+// transaction, written with the named codec (see QueueCodec*): as rows of
+// queue_state, or as one queue_snapshots blob
+// An entry's URL is the inner page of a url crawl mode entry, "" for a
+// front page
+func (s *Storage) SaveQueue(entries []QueueEntry, codec string) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to save queue entry: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	return nil
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM queue_state WHERE session = ?`, s.session); err != nil {
+		return fmt.Errorf("failed to clear queue entries: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM queue_snapshots WHERE session = ?`, s.session); err != nil {
+		return fmt.Errorf("failed to clear queue snapshot: %w", err)
+	}
+
+	if codec == QueueCodecRows {
+		insert, err := tx.Prepare(`
+			INSERT INTO queue_state (session, node_id, domain_name, url, depth, priority)
+			VALUES (?, ?, ?, NULLIF(?, ''), ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare queue entry insert: %w", err)
+		}
+		defer insert.Close()
+		for _, entry := range entries {
+			if _, err := insert.Exec(s.session, entry.NodeID, entry.DomainName, entry.URL, entry.Depth, entry.Priority); err != nil {
+				return fmt.Errorf("failed to save queue entry %s: %w", entry.DomainName, err)
+			}
+		}
+		return tx.Commit()
+	}
+
+	encoder, ok := queueCodecs[codec]
+	if !ok {
+		return fmt.Errorf("unknown queue codec %q", codec)
+	}
+	var data bytes.Buffer
+	if err := encoder.Encode(&data, entries); err != nil {
+		return fmt.Errorf("failed to encode queue: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO queue_snapshots (session, codec, entries, data, saved_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, s.session, codec, len(entries), data.Bytes(), time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to save queue snapshot: %w", err)
+	}
+	return tx.Commit()
 }
 
-// LoadQueueEntries loads all saved queue entries for resume
+// LoadQueueEntries loads all saved queue entries for resume: those of the
+// session's snapshot, if any, then its rows, which include entries
+// appended by db queue-import and db import-seeds
 func (s *Storage) LoadQueueEntries() ([]*QueueEntry, error) {
+	var entries []*QueueEntry
+	decoded, err := loadQueueSnapshot(s.db.QueryRow(`SELECT codec, data FROM queue_snapshots WHERE session = ?`, s.session))
+	if err != nil {
+		return nil, err
+	}
+	for i := range decoded {
+		entries = append(entries, &decoded[i])
+	}
+
 	rows, err := s.db.Query(`
-		SELECT node_id, domain_name, depth
+		SELECT node_id, domain_name, COALESCE(url, ''), depth, priority
 		FROM queue_state
+		WHERE session = ?
 		ORDER BY entry_id ASC
-	`)
+	`, s.session)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to load queue entries: %w", err)
 	}
 	defer rows.Close()
 
-	var entries []*QueueEntry
 	for rows.Next() {
 		var entry QueueEntry
-		if err := rows.Scan(&entry.NodeID, &entry.DomainName, &entry.Depth); err != nil {
+		if err := rows.Scan(&entry.NodeID, &entry.DomainName, &entry.URL, &entry.Depth, &entry.Priority); err != nil {
 			return nil, fmt.Errorf("failed to scan queue entry: %w", err)
 		}
 		entries = append(entries, &entry)
@@ -267,11 +562,166 @@ func (s *Storage) LoadQueueEntries() ([]*QueueEntry, error) {
 	return entries, nil
 }
 
-// ClearQueueEntries removes all saved queue entries (called on successful completion)
+// loadQueueSnapshot decodes the queue snapshot row read by snapshot, a
+// query of its codec and data; no row means no snapshot
+func loadQueueSnapshot(snapshot *sql.Row) ([]QueueEntry, error) {
+	var codec string
+	var data []byte
+	err := snapshot.Scan(&codec, &data)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to load queue snapshot: %w", err)
+	}
+	decoder, ok := queueCodecs[codec]
+	if !ok {
+		return nil, fmt.Errorf("queue snapshot has unknown codec %q", codec)
+	}
+	entries, err := decoder.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode queue snapshot: %w", err)
+	}
+	return entries, nil
+}
+
+// ClearQueueEntries removes the session's saved queue entries and snapshot
+// (called on successful completion)
 func (s *Storage) ClearQueueEntries() error {
-	_, err := s.db.Exec("DELETE FROM queue_state")
+	_, err := s.db.Exec("DELETE FROM queue_state WHERE session = ?", s.session)
 	if err != nil {
 		return fmt.Errorf("failed to clear queue entries: %w", err)
 	}
+	_, err = s.db.Exec("DELETE FROM queue_snapshots WHERE session = ?", s.session)
+	if err != nil {
+		return fmt.Errorf("failed to clear queue snapshot: %w", err)
+	}
 	return nil
 }
+
+// Session returns the crawl session this storage is scoped to
+func (s *Storage) Session() string {
+	return s.session
+}
+
+// migrateNodeSessions rebuilds a pre-session nodes table, whose domain_name
+// is globally unique, assigning existing rows to the default session
+// Returns true if the table was rebuilt
+func (s *Storage) migrateNodeSessions() (bool, error) {
+	var hasSession int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('nodes') WHERE name = 'session'`).Scan(&hasSession)
+	if err != nil {
+		return false, err
+	}
+	if hasSession > 0 {
+		return false, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		CREATE TABLE nodes_migrated (
+			node_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session TEXT NOT NULL DEFAULT 'default',
+			domain_name TEXT NOT NULL,
+			description TEXT,
+			crawl_count INTEGER DEFAULT 0,
+			last_depth INTEGER DEFAULT 0,
+			tombstoned INTEGER DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(session, domain_name)
+		);
+
+		INSERT INTO nodes_migrated (node_id, session, domain_name, description, crawl_count, last_depth, tombstoned, created_at)
+		SELECT node_id, 'default', domain_name, description, crawl_count, last_depth, tombstoned, created_at
+		FROM nodes;
+
+		DROP TABLE nodes;
+		ALTER TABLE nodes_migrated RENAME TO nodes;
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// migrateEdgeTypes rebuilds an untyped edges table, unique per node pair,
+// marking existing rows as content links
+// Returns true if the table was rebuilt
+func (s *Storage) migrateEdgeTypes() (bool, error) {
+	var hasType int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('edges') WHERE name = 'edge_type'`).Scan(&hasType)
+	if err != nil {
+		return false, err
+	}
+	if hasType > 0 {
+		return false, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		CREATE TABLE edges_migrated (
+			edge_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			from_node_id INTEGER NOT NULL,
+			to_node_id INTEGER NOT NULL,
+			edge_type TEXT NOT NULL DEFAULT 'link',
+			weight INTEGER DEFAULT 1,
+			FOREIGN KEY (from_node_id) REFERENCES nodes(node_id),
+			FOREIGN KEY (to_node_id) REFERENCES nodes(node_id),
+			UNIQUE(from_node_id, to_node_id, edge_type)
+		);
+
+		INSERT INTO edges_migrated (edge_id, from_node_id, to_node_id, edge_type, weight)
+		SELECT edge_id, from_node_id, to_node_id, 'link', weight
+		FROM edges;
+
+		DROP TABLE edges;
+		ALTER TABLE edges_migrated RENAME TO edges;
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListSessions returns every session in the database with its node count
+func (s *Storage) ListSessions() (map[string]int, error) {
+	rows, err := s.db.Query(`SELECT session, COUNT(*) FROM nodes GROUP BY session`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var count int
+		if err := rows.Scan(&name, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions[name] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import "fmt"
+
+// SaveSubdomains records the subdomains counted against each root domain's
+// max_subdomains_per_root limit; already stored ones are kept
+func (s *Storage) SaveSubdomains(subdomains map[string][]string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO subdomain_limits (session, root_domain, domain)
+		VALUES (?, ?, ?)
+		ON CONFLICT(session, domain) DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare subdomain insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for root, domains := range subdomains {
+		for _, domain := range domains {
+			if _, err := stmt.Exec(s.session, root, domain); err != nil {
+				return fmt.Errorf("failed to save subdomain %s: %w", domain, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit subdomains: %w", err)
+	}
+	return nil
+}
+
+// LoadSubdomains returns the session's counted subdomains keyed by root domain
+func (s *Storage) LoadSubdomains() (map[string][]string, error) {
+	rows, err := s.db.Query(`
+		SELECT root_domain, domain FROM subdomain_limits
+		WHERE session = ?
+		ORDER BY root_domain, domain
+	`, s.session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subdomains: %w", err)
+	}
+	defer rows.Close()
+
+	subdomains := make(map[string][]string)
+	for rows.Next() {
+		var root, domain string
+		if err := rows.Scan(&root, &domain); err != nil {
+			return nil, fmt.Errorf("failed to scan subdomain: %w", err)
+		}
+		subdomains[root] = append(subdomains[root], domain)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subdomains: %w", err)
+	}
+	return subdomains, nil
+}